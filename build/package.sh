@@ -28,10 +28,17 @@ cat deploy/crds/enterprise.splunk.com_licensemasters_crd.yaml >> release-${VERSI
 echo "---" >> release-${VERSION}/splunk-operator-crds.yaml
 cat deploy/crds/enterprise.splunk.com_searchheadclusters_crd.yaml >> release-${VERSION}/splunk-operator-crds.yaml
 echo "---" >> release-${VERSION}/splunk-operator-crds.yaml
+cat deploy/crds/enterprise.splunk.com_clustermasters_crd.yaml >> release-${VERSION}/splunk-operator-crds.yaml
+echo "---" >> release-${VERSION}/splunk-operator-crds.yaml
 cat deploy/crds/enterprise.splunk.com_indexerclusters_crd.yaml >> release-${VERSION}/splunk-operator-crds.yaml
 echo "---" >> release-${VERSION}/splunk-operator-crds.yaml
+cat deploy/crds/enterprise.splunk.com_monitoringconsoles_crd.yaml >> release-${VERSION}/splunk-operator-crds.yaml
+echo "---" >> release-${VERSION}/splunk-operator-crds.yaml
 cat deploy/crds/enterprise.splunk.com_sparks_crd.yaml >> release-${VERSION}/splunk-operator-crds.yaml
 
+echo Generating release-${VERSION}/splunk-operator-webhook.yaml
+cp deploy/webhook.yaml release-${VERSION}/splunk-operator-webhook.yaml
+
 echo Generating release-${VERSION}/splunk-operator-noadmin.yaml
 cat deploy/service_account.yaml deploy/role.yaml deploy/role_binding.yaml > release-${VERSION}/splunk-operator-noadmin.yaml
 echo "---" >> release-${VERSION}/splunk-operator-noadmin.yaml
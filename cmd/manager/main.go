@@ -41,7 +41,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
 	"github.com/splunk/splunk-operator/pkg/apis"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	"github.com/splunk/splunk-operator/pkg/controller"
+	"github.com/splunk/splunk-operator/pkg/webhook"
 	"github.com/splunk/splunk-operator/version"
 )
 
@@ -50,6 +52,7 @@ var (
 	metricsHost               = "0.0.0.0"
 	metricsPort         int32 = 8383
 	operatorMetricsPort int32 = 8686
+	webhookPort               = 9443
 )
 var log = logf.Log.WithName("cmd")
 
@@ -112,6 +115,7 @@ func main() {
 	mgr, err := manager.New(cfg, manager.Options{
 		Namespace:          namespace,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		Port:               webhookPort,
 	})
 	if err != nil {
 		log.Error(err, "")
@@ -132,6 +136,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup admission and conversion webhooks, if enabled; these require a certificate, and are disabled by default
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		log.Info("Registering admission and conversion webhooks.", "port", webhookPort)
+		if err := webhook.AddToManager(mgr); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	// Add the Metrics Service
 	addMetrics(ctx, cfg, namespace)
 
@@ -186,7 +199,8 @@ func addMetrics(ctx context.Context, cfg *rest.Config, namespace string) {
 func serveCRMetrics(cfg *rest.Config) error {
 	// Below function returns filtered operator/CustomResource specific GVKs.
 	// For more control override the below GVK list with your own custom logic.
-	filteredGVK, err := k8sutil.GetGVKsFromAddToScheme(apis.AddToScheme)
+	// Only the storage version is used, since other versions may require the conversion webhook.
+	filteredGVK, err := k8sutil.GetGVKsFromAddToScheme(enterprisev1.SchemeBuilder.AddToScheme)
 	if err != nil {
 		return err
 	}
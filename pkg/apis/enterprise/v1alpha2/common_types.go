@@ -106,8 +106,130 @@ type CommonSplunkSpec struct {
 	// LicenseMasterRef refers to a Splunk Enterprise license master managed by the operator within Kubernetes
 	LicenseMasterRef corev1.ObjectReference `json:"licenseMasterRef"`
 
-	// IndexerClusterRef refers to a Splunk Enterprise indexer cluster managed by the operator within Kubernetes
+	// ClusterMasterRef refers to a Splunk Enterprise indexer cluster master managed by the operator within Kubernetes
+	ClusterMasterRef corev1.ObjectReference `json:"clusterMasterRef"`
+
+	// IndexerClusterRef refers to a Splunk Enterprise indexer cluster managed by the operator within Kubernetes.
+	// Deprecated: use ClusterMasterRef; this refers to a cluster master with the same name as the indexer cluster.
 	IndexerClusterRef corev1.ObjectReference `json:"indexerClusterRef"`
+
+	// App repository containing Splunk app packages that are installed by the operator
+	AppRepo AppRepoSpec `json:"appRepo"`
+}
+
+// SmartStoreSpec defines the remote storage volumes and indexes used by Splunk SmartStore
+type SmartStoreSpec struct {
+	// List of S3-compatible remote storage volumes
+	Volumes []RemoteVolumeSpec `json:"volumes"`
+
+	// List of indexes that are stored on remote storage volumes
+	Indexes []RemoteIndexSpec `json:"indexes"`
+}
+
+// RemoteVolumeSpec defines an S3-compatible remote storage volume used by SmartStore or an app repository
+type RemoteVolumeSpec struct {
+	// Name of the remote storage volume
+	Name string `json:"name"`
+
+	// Endpoint URL for the remote storage service (e.g. https://s3-us-west-2.amazonaws.com)
+	Endpoint string `json:"endpoint"`
+
+	// Bucket and optional path prefix for the remote storage volume (e.g. "mybucket/smartstore")
+	Path string `json:"path"`
+
+	// Name of a Kubernetes Secret containing s3_access_key and s3_secret_key for the remote storage volume
+	SecretRef string `json:"secretRef"`
+}
+
+// RemoteIndexSpec defines an index that is stored on a SmartStore remote storage volume
+type RemoteIndexSpec struct {
+	// Name of the index
+	Name string `json:"name"`
+
+	// Name of the remote storage volume used to store the index (defaults to the first volume)
+	VolumeName string `json:"volumeName"`
+
+	// Location of the index within the remote storage volume (defaults to the name of the index)
+	RemotePath string `json:"remotePath"`
+}
+
+// RemoteVolumeStatus is used to track the status of a SmartStore remote storage volume
+type RemoteVolumeStatus struct {
+	// Name of the remote storage volume
+	Name string `json:"name"`
+
+	// Endpoint URL that was used to check the remote storage volume
+	Endpoint string `json:"endpoint"`
+
+	// Bucket and path prefix that was used to check the remote storage volume
+	Path string `json:"path"`
+
+	// true if the remote storage volume passed its connectivity check
+	Connected bool `json:"connected"`
+
+	// error message if the remote storage volume failed its connectivity check
+	Message string `json:"message"`
+}
+
+// AppRepoSpec defines a repository of Splunk app packages that are installed by the operator
+type AppRepoSpec struct {
+	// Interval in seconds to check for new or changed app packages, between 60 and 86400 (defaults to 600)
+	PollInterval int64 `json:"pollInterval"`
+
+	// List of S3-compatible remote storage volumes containing app packages
+	Volumes []RemoteVolumeSpec `json:"volumes"`
+
+	// List of app packages to install
+	Apps []AppSourceSpec `json:"apps"`
+}
+
+// AppSourceSpec defines the location of a Splunk app package and where it is installed
+type AppSourceSpec struct {
+	// Name of the app
+	Name string `json:"name"`
+
+	// Where the app is installed: "local" installs it on the instance itself (default), and "cluster" installs it
+	// on all members using the cluster master bundle (IndexerCluster) or the deployer bundle (SearchHeadCluster)
+	Scope string `json:"scope"`
+
+	// Name of the remote storage volume containing the app package (defaults to the first volume)
+	VolumeName string `json:"volumeName"`
+
+	// Name of a ConfigMap containing the app package in its binaryData, used instead of a remote storage volume
+	ConfigMapRef string `json:"configMapRef"`
+
+	// Location of the app package within the remote storage volume, or its key within the ConfigMap (defaults to <name>.tgz)
+	Path string `json:"path"`
+}
+
+// AppRepoStatus is used to track the status of app packages installed from an app repository
+type AppRepoStatus struct {
+	// time of the last check for new or changed app packages, in seconds since the epoch
+	LastPollTime int64 `json:"lastPollTime"`
+
+	// status of each app package
+	Apps []AppStatus `json:"apps"`
+}
+
+// AppStatus is used to track the install state of an app package
+type AppStatus struct {
+	// Name of the app
+	Name string `json:"name"`
+
+	// Where the app is installed ("local" or "cluster")
+	Scope string `json:"scope"`
+
+	// Location of the app package
+	Location string `json:"location"`
+
+	// Checksum of the latest version of the app package
+	Version string `json:"version"`
+
+	// Pending if the latest version is waiting to be installed, Ready if it has been installed, or Error
+	Phase ResourcePhase `json:"phase"`
+
+	// error message if the app package could not be retrieved
+	Message string `json:"message"`
 }
 
 // MetaObject is used to represent common interfaces of custom resources
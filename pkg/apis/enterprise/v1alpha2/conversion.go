@@ -0,0 +1,925 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+// DeprecatedRefsAnnotation is used to preserve references that are not supported by v1alpha3, so that
+// custom resources may be converted from v1alpha2 to v1alpha3 and back again without losing anything.
+const DeprecatedRefsAnnotation = "enterprise.splunk.com/v1alpha2-deprecated-refs"
+
+// HubStatusAnnotation is used to preserve status fields that are not supported by v1alpha2, so that custom
+// resources may be converted from v1alpha3 to v1alpha2 and back again without losing anything.
+const HubStatusAnnotation = "enterprise.splunk.com/v1alpha3-status"
+
+// HubFieldsAnnotation is used to preserve spec and status fields of a specific kind that are not supported by
+// v1alpha2, so that custom resources may be converted from v1alpha3 to v1alpha2 and back again without losing anything.
+const HubFieldsAnnotation = "enterprise.splunk.com/v1alpha3-fields"
+
+// HubSpecAnnotation is used to preserve common spec fields that are not supported by v1alpha2, so that custom
+// resources may be converted from v1alpha3 to v1alpha2 and back again without losing anything.
+const HubSpecAnnotation = "enterprise.splunk.com/v1alpha3-spec"
+
+// commonSplunkSpecHubFields is used to store the value of HubSpecAnnotation
+type commonSplunkSpecHubFields struct {
+	// UpdateStrategy is the value of CommonSplunkSpec.UpdateStrategy
+	UpdateStrategy v1alpha3.UpdateStrategySpec `json:"updateStrategy"`
+
+	// SecretRotation is the value of CommonSplunkSpec.SecretRotation
+	SecretRotation v1alpha3.SecretRotationSpec `json:"secretRotation"`
+
+	// SecretRef is the value of CommonSplunkSpec.SecretRef
+	SecretRef corev1.ObjectReference `json:"secretRef"`
+
+	// TLS is the value of CommonSplunkSpec.TLS
+	TLS v1alpha3.TLSSpec `json:"tls"`
+}
+
+// searchHeadClusterHubFields is used to store the value of HubFieldsAnnotation for a SearchHeadCluster
+type searchHeadClusterHubFields struct {
+	// BundleConfigMapRef is the value of SearchHeadClusterSpec.BundleConfigMapRef
+	BundleConfigMapRef string `json:"bundleConfigMapRef,omitempty"`
+
+	// DeployerBundle is the value of SearchHeadClusterStatus.DeployerBundle
+	DeployerBundle v1alpha3.SearchHeadClusterBundleStatus `json:"deployerBundle"`
+
+	// StaleMembers are the indices of SearchHeadClusterStatus.Members that are stale
+	StaleMembers []int `json:"staleMembers,omitempty"`
+}
+
+// clusterMasterHubFields is used to store the value of HubFieldsAnnotation for a ClusterMaster
+type clusterMasterHubFields struct {
+	// BundleConfigMapRef is the value of ClusterMasterSpec.BundleConfigMapRef
+	BundleConfigMapRef string `json:"bundleConfigMapRef,omitempty"`
+
+	// BundlePush is the value of ClusterMasterStatus.BundlePush
+	BundlePush v1alpha3.ClusterMasterBundlePushStatus `json:"bundlePush"`
+}
+
+// indexerClusterHubFields is used to store the value of HubFieldsAnnotation for an IndexerCluster
+type indexerClusterHubFields struct {
+	// RollingRestartPercentage is the value of IndexerClusterSpec.RollingRestartPercentage
+	RollingRestartPercentage int32 `json:"rollingRestartPercentage,omitempty"`
+
+	// RollingRestart is the value of IndexerClusterStatus.RollingRestart
+	RollingRestart v1alpha3.IndexerClusterRollingRestartStatus `json:"rollingRestart"`
+
+	// Bundle is the value of IndexerClusterStatus.Bundle
+	Bundle v1alpha3.IndexerClusterBundleStatus `json:"bundle"`
+
+	// RebalanceOnScaleUp is the value of IndexerClusterSpec.RebalanceOnScaleUp
+	RebalanceOnScaleUp v1alpha3.IndexerClusterRebalancePolicy `json:"rebalanceOnScaleUp"`
+
+	// Rebalance is the value of IndexerClusterStatus.Rebalance
+	Rebalance v1alpha3.IndexerClusterRebalanceStatus `json:"rebalance"`
+
+	// ReplicationFactorMet is the value of IndexerClusterStatus.ReplicationFactorMet
+	ReplicationFactorMet *bool `json:"replicationFactorMet,omitempty"`
+
+	// SearchFactorMet is the value of IndexerClusterStatus.SearchFactorMet
+	SearchFactorMet *bool `json:"searchFactorMet,omitempty"`
+
+	// Indexes is the value of IndexerClusterStatus.Indexes
+	Indexes []v1alpha3.IndexerClusterIndexStatus `json:"indexes,omitempty"`
+
+	// Peers are the fields of each of IndexerClusterStatus.Peers that are not supported by v1alpha2
+	Peers []indexerClusterPeerHubFields `json:"peers,omitempty"`
+
+	// SitePeers are the fields of the peers for each of IndexerClusterStatus.Sites that are not supported by v1alpha2
+	SitePeers [][]indexerClusterPeerHubFields `json:"sitePeers,omitempty"`
+}
+
+// indexerClusterPeerHubFields is used to store the fields of an IndexerClusterMemberStatus in HubFieldsAnnotation
+type indexerClusterPeerHubFields struct {
+	// Stale is the value of IndexerClusterMemberStatus.Stale
+	Stale bool `json:"stale,omitempty"`
+
+	// Site is the value of IndexerClusterMemberStatus.Site
+	Site string `json:"site,omitempty"`
+}
+
+// deprecatedRefs is used to store the value of DeprecatedRefsAnnotation
+type deprecatedRefs struct {
+	// IndexerClusterRef is the original value of CommonSplunkSpec.IndexerClusterRef
+	IndexerClusterRef corev1.ObjectReference `json:"indexerClusterRef"`
+
+	// ClusterMasterRef is the original value of CommonSplunkSpec.ClusterMasterRef, if it was replaced by IndexerClusterRef
+	ClusterMasterRef *corev1.ObjectReference `json:"clusterMasterRef,omitempty"`
+}
+
+// ConvertTo converts this Standalone to the hub version (v1alpha3)
+func (src *Standalone) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha3.Standalone)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecTo(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.Replicas = src.Spec.Replicas
+	dst.Spec.DFS = v1alpha3.DFSSpec{SparkRef: src.Spec.SparkRef, SparkImage: src.Spec.SparkImage}
+	dst.Spec.SmartStore = convertSmartStoreSpecTo(src.Spec.SmartStore)
+	dst.Status = v1alpha3.StandaloneStatus{
+		Phase:         v1alpha3.ResourcePhase(src.Status.Phase),
+		Replicas:      src.Status.Replicas,
+		ReadyReplicas: src.Status.ReadyReplicas,
+		Selector:      src.Status.Selector,
+		RemoteVolumes: convertRemoteVolumeStatusesTo(src.Status.RemoteVolumes),
+		AppRepo:       convertAppRepoStatusTo(src.Status.AppRepo),
+	}
+	return convertCommonStatusTo(&dst.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertFrom converts from the hub version (v1alpha3) to this Standalone
+func (dst *Standalone) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha3.Standalone)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecFrom(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.Replicas = src.Spec.Replicas
+	dst.Spec.SparkRef = src.Spec.DFS.SparkRef
+	dst.Spec.SparkImage = src.Spec.DFS.SparkImage
+	dst.Spec.SmartStore = convertSmartStoreSpecFrom(src.Spec.SmartStore)
+	dst.Status = StandaloneStatus{
+		Phase:         ResourcePhase(src.Status.Phase),
+		Replicas:      src.Status.Replicas,
+		ReadyReplicas: src.Status.ReadyReplicas,
+		Selector:      src.Status.Selector,
+		RemoteVolumes: convertRemoteVolumeStatusesFrom(src.Status.RemoteVolumes),
+		AppRepo:       convertAppRepoStatusFrom(src.Status.AppRepo),
+	}
+	return convertCommonStatusFrom(&src.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertTo converts this LicenseMaster to the hub version (v1alpha3)
+func (src *LicenseMaster) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha3.LicenseMaster)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecTo(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Status = v1alpha3.LicenseMasterStatus{
+		Phase:   v1alpha3.ResourcePhase(src.Status.Phase),
+		AppRepo: convertAppRepoStatusTo(src.Status.AppRepo),
+	}
+	return convertCommonStatusTo(&dst.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertFrom converts from the hub version (v1alpha3) to this LicenseMaster
+func (dst *LicenseMaster) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha3.LicenseMaster)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecFrom(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Status = LicenseMasterStatus{
+		Phase:   ResourcePhase(src.Status.Phase),
+		AppRepo: convertAppRepoStatusFrom(src.Status.AppRepo),
+	}
+	return convertCommonStatusFrom(&src.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertTo converts this SearchHeadCluster to the hub version (v1alpha3)
+func (src *SearchHeadCluster) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha3.SearchHeadCluster)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecTo(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.Replicas = src.Spec.Replicas
+	dst.Spec.DFS = v1alpha3.DFSSpec{SparkRef: src.Spec.SparkRef, SparkImage: src.Spec.SparkImage}
+	dst.Status = v1alpha3.SearchHeadClusterStatus{
+		Phase:           v1alpha3.ResourcePhase(src.Status.Phase),
+		DeployerPhase:   v1alpha3.ResourcePhase(src.Status.DeployerPhase),
+		Replicas:        src.Status.Replicas,
+		ReadyReplicas:   src.Status.ReadyReplicas,
+		Selector:        src.Status.Selector,
+		Captain:         src.Status.Captain,
+		CaptainReady:    src.Status.CaptainReady,
+		Initialized:     src.Status.Initialized,
+		MinPeersJoined:  src.Status.MinPeersJoined,
+		MaintenanceMode: src.Status.MaintenanceMode,
+		AppRepo:         convertAppRepoStatusTo(src.Status.AppRepo),
+	}
+	dst.Status.Members = convertSearchHeadClusterMemberStatusesTo(src.Status.Members)
+	var fields searchHeadClusterHubFields
+	if err := restoreHubFields(HubFieldsAnnotation, &fields, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.BundleConfigMapRef = fields.BundleConfigMapRef
+	dst.Status.DeployerBundle = fields.DeployerBundle
+	for _, i := range fields.StaleMembers {
+		if i < len(dst.Status.Members) {
+			dst.Status.Members[i].Stale = true
+		}
+	}
+	return convertCommonStatusTo(&dst.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertFrom converts from the hub version (v1alpha3) to this SearchHeadCluster
+func (dst *SearchHeadCluster) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha3.SearchHeadCluster)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecFrom(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.Replicas = src.Spec.Replicas
+	dst.Spec.SparkRef = src.Spec.DFS.SparkRef
+	dst.Spec.SparkImage = src.Spec.DFS.SparkImage
+	dst.Status = SearchHeadClusterStatus{
+		Phase:           ResourcePhase(src.Status.Phase),
+		DeployerPhase:   ResourcePhase(src.Status.DeployerPhase),
+		Replicas:        src.Status.Replicas,
+		ReadyReplicas:   src.Status.ReadyReplicas,
+		Selector:        src.Status.Selector,
+		Captain:         src.Status.Captain,
+		CaptainReady:    src.Status.CaptainReady,
+		Initialized:     src.Status.Initialized,
+		MinPeersJoined:  src.Status.MinPeersJoined,
+		MaintenanceMode: src.Status.MaintenanceMode,
+		AppRepo:         convertAppRepoStatusFrom(src.Status.AppRepo),
+	}
+	dst.Status.Members = convertSearchHeadClusterMemberStatusesFrom(src.Status.Members)
+	fields := searchHeadClusterHubFields{
+		BundleConfigMapRef: src.Spec.BundleConfigMapRef,
+		DeployerBundle:     src.Status.DeployerBundle,
+	}
+	for i := range src.Status.Members {
+		if src.Status.Members[i].Stale {
+			fields.StaleMembers = append(fields.StaleMembers, i)
+		}
+	}
+	if err := preserveHubFields(HubFieldsAnnotation, &fields, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	return convertCommonStatusFrom(&src.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertTo converts this ClusterMaster to the hub version (v1alpha3)
+func (src *ClusterMaster) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha3.ClusterMaster)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecTo(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.Multisite = v1alpha3.ClusterMasterMultisiteSpec(src.Spec.Multisite)
+	dst.Spec.SmartStore = convertSmartStoreSpecTo(src.Spec.SmartStore)
+	dst.Status = v1alpha3.ClusterMasterStatus{
+		Phase:                v1alpha3.ResourcePhase(src.Status.Phase),
+		Initialized:          src.Status.Initialized,
+		IndexingReady:        src.Status.IndexingReady,
+		ServiceReady:         src.Status.ServiceReady,
+		MaintenanceMode:      src.Status.MaintenanceMode,
+		RollingRestart:       src.Status.RollingRestart,
+		ReplicationFactorMet: src.Status.ReplicationFactorMet,
+		SearchFactorMet:      src.Status.SearchFactorMet,
+		ActiveBundle:         v1alpha3.ClusterMasterBundleStatus(src.Status.ActiveBundle),
+		LatestBundle:         v1alpha3.ClusterMasterBundleStatus(src.Status.LatestBundle),
+		RemoteVolumes:        convertRemoteVolumeStatusesTo(src.Status.RemoteVolumes),
+		AppRepo:              convertAppRepoStatusTo(src.Status.AppRepo),
+	}
+	var fields clusterMasterHubFields
+	if err := restoreHubFields(HubFieldsAnnotation, &fields, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.BundleConfigMapRef = fields.BundleConfigMapRef
+	dst.Status.BundlePush = fields.BundlePush
+	return convertCommonStatusTo(&dst.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertFrom converts from the hub version (v1alpha3) to this ClusterMaster
+func (dst *ClusterMaster) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha3.ClusterMaster)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecFrom(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.Multisite = ClusterMasterMultisiteSpec(src.Spec.Multisite)
+	dst.Spec.SmartStore = convertSmartStoreSpecFrom(src.Spec.SmartStore)
+	dst.Status = ClusterMasterStatus{
+		Phase:                ResourcePhase(src.Status.Phase),
+		Initialized:          src.Status.Initialized,
+		IndexingReady:        src.Status.IndexingReady,
+		ServiceReady:         src.Status.ServiceReady,
+		MaintenanceMode:      src.Status.MaintenanceMode,
+		RollingRestart:       src.Status.RollingRestart,
+		ReplicationFactorMet: src.Status.ReplicationFactorMet,
+		SearchFactorMet:      src.Status.SearchFactorMet,
+		ActiveBundle:         ClusterMasterBundleStatus(src.Status.ActiveBundle),
+		LatestBundle:         ClusterMasterBundleStatus(src.Status.LatestBundle),
+		RemoteVolumes:        convertRemoteVolumeStatusesFrom(src.Status.RemoteVolumes),
+		AppRepo:              convertAppRepoStatusFrom(src.Status.AppRepo),
+	}
+	fields := clusterMasterHubFields{
+		BundleConfigMapRef: src.Spec.BundleConfigMapRef,
+		BundlePush:         src.Status.BundlePush,
+	}
+	if err := preserveHubFields(HubFieldsAnnotation, &fields, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	return convertCommonStatusFrom(&src.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertTo converts this IndexerCluster to the hub version (v1alpha3)
+func (src *IndexerCluster) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha3.IndexerCluster)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecTo(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.Replicas = src.Spec.Replicas
+	dst.Spec.Multisite = v1alpha3.MultisiteSpec{}
+	if src.Spec.Multisite.Sites != nil {
+		dst.Spec.Multisite.Sites = make([]v1alpha3.IndexerClusterSiteSpec, len(src.Spec.Multisite.Sites))
+		for i := range src.Spec.Multisite.Sites {
+			dst.Spec.Multisite.Sites[i] = v1alpha3.IndexerClusterSiteSpec(src.Spec.Multisite.Sites[i])
+		}
+	}
+	dst.Status = v1alpha3.IndexerClusterStatus{
+		Phase:              v1alpha3.ResourcePhase(src.Status.Phase),
+		ClusterMasterPhase: v1alpha3.ResourcePhase(src.Status.ClusterMasterPhase),
+		Replicas:           src.Status.Replicas,
+		ReadyReplicas:      src.Status.ReadyReplicas,
+		Selector:           src.Status.Selector,
+		Initialized:        src.Status.Initialized,
+		IndexingReady:      src.Status.IndexingReady,
+		ServiceReady:       src.Status.ServiceReady,
+		MaintenanceMode:    src.Status.MaintenanceMode,
+		Peers:              convertIndexerClusterMemberStatusesTo(src.Status.Peers),
+	}
+	if src.Status.Sites != nil {
+		dst.Status.Sites = make([]v1alpha3.IndexerClusterSiteStatus, len(src.Status.Sites))
+		for i, site := range src.Status.Sites {
+			dst.Status.Sites[i] = v1alpha3.IndexerClusterSiteStatus{
+				Name:          site.Name,
+				Phase:         v1alpha3.ResourcePhase(site.Phase),
+				Replicas:      site.Replicas,
+				ReadyReplicas: site.ReadyReplicas,
+				Peers:         convertIndexerClusterMemberStatusesTo(site.Peers),
+			}
+		}
+	}
+	var fields indexerClusterHubFields
+	if err := restoreHubFields(HubFieldsAnnotation, &fields, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.RollingRestartPercentage = fields.RollingRestartPercentage
+	dst.Status.RollingRestart = fields.RollingRestart
+	dst.Status.Bundle = fields.Bundle
+	dst.Spec.RebalanceOnScaleUp = fields.RebalanceOnScaleUp
+	dst.Status.Rebalance = fields.Rebalance
+	dst.Status.ReplicationFactorMet = fields.ReplicationFactorMet
+	dst.Status.SearchFactorMet = fields.SearchFactorMet
+	dst.Status.Indexes = fields.Indexes
+	restorePeerHubFields(dst.Status.Peers, fields.Peers)
+	for i := range fields.SitePeers {
+		if i < len(dst.Status.Sites) {
+			restorePeerHubFields(dst.Status.Sites[i].Peers, fields.SitePeers[i])
+		}
+	}
+	return convertCommonStatusTo(&dst.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertFrom converts from the hub version (v1alpha3) to this IndexerCluster
+func (dst *IndexerCluster) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha3.IndexerCluster)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecFrom(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.Replicas = src.Spec.Replicas
+	dst.Spec.Multisite = MultisiteSpec{}
+	if src.Spec.Multisite.Sites != nil {
+		dst.Spec.Multisite.Sites = make([]IndexerClusterSiteSpec, len(src.Spec.Multisite.Sites))
+		for i := range src.Spec.Multisite.Sites {
+			dst.Spec.Multisite.Sites[i] = IndexerClusterSiteSpec(src.Spec.Multisite.Sites[i])
+		}
+	}
+	dst.Status = IndexerClusterStatus{
+		Phase:              ResourcePhase(src.Status.Phase),
+		ClusterMasterPhase: ResourcePhase(src.Status.ClusterMasterPhase),
+		Replicas:           src.Status.Replicas,
+		ReadyReplicas:      src.Status.ReadyReplicas,
+		Selector:           src.Status.Selector,
+		Initialized:        src.Status.Initialized,
+		IndexingReady:      src.Status.IndexingReady,
+		ServiceReady:       src.Status.ServiceReady,
+		MaintenanceMode:    src.Status.MaintenanceMode,
+		Peers:              convertIndexerClusterMemberStatusesFrom(src.Status.Peers),
+	}
+	if src.Status.Sites != nil {
+		dst.Status.Sites = make([]IndexerClusterSiteStatus, len(src.Status.Sites))
+		for i, site := range src.Status.Sites {
+			dst.Status.Sites[i] = IndexerClusterSiteStatus{
+				Name:          site.Name,
+				Phase:         ResourcePhase(site.Phase),
+				Replicas:      site.Replicas,
+				ReadyReplicas: site.ReadyReplicas,
+				Peers:         convertIndexerClusterMemberStatusesFrom(site.Peers),
+			}
+		}
+	}
+	fields := indexerClusterHubFields{
+		RollingRestartPercentage: src.Spec.RollingRestartPercentage,
+		RollingRestart:           src.Status.RollingRestart,
+		Bundle:                   src.Status.Bundle,
+		RebalanceOnScaleUp:       src.Spec.RebalanceOnScaleUp,
+		Rebalance:                src.Status.Rebalance,
+		ReplicationFactorMet:     src.Status.ReplicationFactorMet,
+		SearchFactorMet:          src.Status.SearchFactorMet,
+		Indexes:                  src.Status.Indexes,
+		Peers:                    getPeerHubFields(src.Status.Peers),
+	}
+	for i := range src.Status.Sites {
+		if peers := getPeerHubFields(src.Status.Sites[i].Peers); peers != nil {
+			if fields.SitePeers == nil {
+				fields.SitePeers = make([][]indexerClusterPeerHubFields, len(src.Status.Sites))
+			}
+			fields.SitePeers[i] = peers
+		}
+	}
+	if err := preserveHubFields(HubFieldsAnnotation, &fields, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	return convertCommonStatusFrom(&src.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertTo converts this MonitoringConsole to the hub version (v1alpha3)
+func (src *MonitoringConsole) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha3.MonitoringConsole)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecTo(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Status = v1alpha3.MonitoringConsoleStatus{
+		Phase:   v1alpha3.ResourcePhase(src.Status.Phase),
+		AppRepo: convertAppRepoStatusTo(src.Status.AppRepo),
+	}
+	if src.Status.Peers != nil {
+		dst.Status.Peers = make([]v1alpha3.MonitoringConsolePeerStatus, len(src.Status.Peers))
+		for i := range src.Status.Peers {
+			dst.Status.Peers[i] = v1alpha3.MonitoringConsolePeerStatus(src.Status.Peers[i])
+		}
+	}
+	return convertCommonStatusTo(&dst.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertFrom converts from the hub version (v1alpha3) to this MonitoringConsole
+func (dst *MonitoringConsole) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha3.MonitoringConsole)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	if err := convertCommonSplunkSpecFrom(&src.Spec.CommonSplunkSpec, &dst.Spec.CommonSplunkSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Status = MonitoringConsoleStatus{
+		Phase:   ResourcePhase(src.Status.Phase),
+		AppRepo: convertAppRepoStatusFrom(src.Status.AppRepo),
+	}
+	if src.Status.Peers != nil {
+		dst.Status.Peers = make([]MonitoringConsolePeerStatus, len(src.Status.Peers))
+		for i := range src.Status.Peers {
+			dst.Status.Peers[i] = MonitoringConsolePeerStatus(src.Status.Peers[i])
+		}
+	}
+	return convertCommonStatusFrom(&src.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertTo converts this Spark to the hub version (v1alpha3)
+func (src *Spark) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha3.Spark)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = v1alpha3.SparkSpec{
+		CommonSpec: v1alpha3.CommonSpec(src.Spec.CommonSpec),
+		Replicas:   src.Spec.Replicas,
+	}
+	dst.Status = v1alpha3.SparkStatus{
+		Phase:         v1alpha3.ResourcePhase(src.Status.Phase),
+		MasterPhase:   v1alpha3.ResourcePhase(src.Status.MasterPhase),
+		Replicas:      src.Status.Replicas,
+		ReadyReplicas: src.Status.ReadyReplicas,
+		Selector:      src.Status.Selector,
+	}
+	return convertCommonStatusTo(&dst.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// ConvertFrom converts from the hub version (v1alpha3) to this Spark
+func (dst *Spark) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha3.Spark)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = SparkSpec{
+		CommonSpec: CommonSpec(src.Spec.CommonSpec),
+		Replicas:   src.Spec.Replicas,
+	}
+	dst.Status = SparkStatus{
+		Phase:         ResourcePhase(src.Status.Phase),
+		MasterPhase:   ResourcePhase(src.Status.MasterPhase),
+		Replicas:      src.Status.Replicas,
+		ReadyReplicas: src.Status.ReadyReplicas,
+		Selector:      src.Status.Selector,
+	}
+	return convertCommonStatusFrom(&src.Status.CommonStatus, &dst.ObjectMeta)
+}
+
+// convertCommonSplunkSpecTo converts a v1alpha2 CommonSplunkSpec to v1alpha3. IndexerClusterRef is not supported
+// by v1alpha3, so it is used as the ClusterMasterRef when none is defined, and preserved using DeprecatedRefsAnnotation.
+func convertCommonSplunkSpecTo(src *CommonSplunkSpec, dst *v1alpha3.CommonSplunkSpec, meta *metav1.ObjectMeta) error {
+	*dst = v1alpha3.CommonSplunkSpec{
+		CommonSpec:       v1alpha3.CommonSpec(src.CommonSpec),
+		StorageClassName: src.StorageClassName,
+		EtcStorage:       src.EtcStorage,
+		VarStorage:       src.VarStorage,
+		Volumes:          src.Volumes,
+		Defaults:         src.Defaults,
+		DefaultsURL:      src.DefaultsURL,
+		LicenseURL:       src.LicenseURL,
+		LicenseMasterRef: src.LicenseMasterRef,
+		ClusterMasterRef: src.ClusterMasterRef,
+		AppRepo: v1alpha3.AppRepoSpec{
+			PollInterval: src.AppRepo.PollInterval,
+			Volumes:      convertRemoteVolumeSpecsTo(src.AppRepo.Volumes),
+		},
+	}
+	if src.AppRepo.Apps != nil {
+		dst.AppRepo.Apps = make([]v1alpha3.AppSourceSpec, len(src.AppRepo.Apps))
+		for i := range src.AppRepo.Apps {
+			dst.AppRepo.Apps[i] = v1alpha3.AppSourceSpec(src.AppRepo.Apps[i])
+		}
+	}
+	var fields commonSplunkSpecHubFields
+	if err := restoreHubFields(HubSpecAnnotation, &fields, meta); err != nil {
+		return err
+	}
+	dst.UpdateStrategy = fields.UpdateStrategy
+	dst.SecretRotation = fields.SecretRotation
+	dst.SecretRef = fields.SecretRef
+	dst.TLS = fields.TLS
+
+	if src.IndexerClusterRef == (corev1.ObjectReference{}) {
+		return nil
+	}
+	refs := deprecatedRefs{IndexerClusterRef: src.IndexerClusterRef}
+	if src.ClusterMasterRef.Name == "" && src.IndexerClusterRef.Name != "" {
+		clusterMasterRef := src.ClusterMasterRef
+		refs.ClusterMasterRef = &clusterMasterRef
+		dst.ClusterMasterRef = src.IndexerClusterRef
+	}
+	data, err := json.Marshal(&refs)
+	if err != nil {
+		return err
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[DeprecatedRefsAnnotation] = string(data)
+	return nil
+}
+
+// convertCommonSplunkSpecFrom converts a v1alpha3 CommonSplunkSpec to v1alpha2, restoring any references that were
+// preserved using DeprecatedRefsAnnotation.
+func convertCommonSplunkSpecFrom(src *v1alpha3.CommonSplunkSpec, dst *CommonSplunkSpec, meta *metav1.ObjectMeta) error {
+	*dst = CommonSplunkSpec{
+		CommonSpec:       CommonSpec(src.CommonSpec),
+		StorageClassName: src.StorageClassName,
+		EtcStorage:       src.EtcStorage,
+		VarStorage:       src.VarStorage,
+		Volumes:          src.Volumes,
+		Defaults:         src.Defaults,
+		DefaultsURL:      src.DefaultsURL,
+		LicenseURL:       src.LicenseURL,
+		LicenseMasterRef: src.LicenseMasterRef,
+		ClusterMasterRef: src.ClusterMasterRef,
+		AppRepo: AppRepoSpec{
+			PollInterval: src.AppRepo.PollInterval,
+			Volumes:      convertRemoteVolumeSpecsFrom(src.AppRepo.Volumes),
+		},
+	}
+	if src.AppRepo.Apps != nil {
+		dst.AppRepo.Apps = make([]AppSourceSpec, len(src.AppRepo.Apps))
+		for i := range src.AppRepo.Apps {
+			dst.AppRepo.Apps[i] = AppSourceSpec(src.AppRepo.Apps[i])
+		}
+	}
+	fields := commonSplunkSpecHubFields{UpdateStrategy: src.UpdateStrategy, SecretRotation: src.SecretRotation, SecretRef: src.SecretRef, TLS: src.TLS}
+	if err := preserveHubFields(HubSpecAnnotation, &fields, meta); err != nil {
+		return err
+	}
+
+	data, ok := meta.Annotations[DeprecatedRefsAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(meta.Annotations, DeprecatedRefsAnnotation)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	var refs deprecatedRefs
+	if err := json.Unmarshal([]byte(data), &refs); err != nil {
+		return fmt.Errorf("Invalid %s annotation: %v", DeprecatedRefsAnnotation, err)
+	}
+	dst.IndexerClusterRef = refs.IndexerClusterRef
+	// only restore the original ClusterMasterRef if it has not been changed since it was replaced
+	if refs.ClusterMasterRef != nil && src.ClusterMasterRef == refs.IndexerClusterRef {
+		dst.ClusterMasterRef = *refs.ClusterMasterRef
+	}
+	return nil
+}
+
+// convertCommonStatusTo restores the v1alpha3 CommonStatus that was preserved using HubStatusAnnotation, if any
+func convertCommonStatusTo(dst *v1alpha3.CommonStatus, meta *metav1.ObjectMeta) error {
+	data, ok := meta.Annotations[HubStatusAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(meta.Annotations, HubStatusAnnotation)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	if err := json.Unmarshal([]byte(data), dst); err != nil {
+		return fmt.Errorf("Invalid %s annotation: %v", HubStatusAnnotation, err)
+	}
+	return nil
+}
+
+// convertCommonStatusFrom preserves a v1alpha3 CommonStatus using HubStatusAnnotation, since v1alpha2 does not support it
+func convertCommonStatusFrom(src *v1alpha3.CommonStatus, meta *metav1.ObjectMeta) error {
+	if reflect.ValueOf(*src).IsZero() {
+		return nil
+	}
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[HubStatusAnnotation] = string(data)
+	return nil
+}
+
+// restoreHubFields restores fields that were preserved using an annotation (HubFieldsAnnotation or
+// HubSpecAnnotation), if any
+func restoreHubFields(annotation string, fields interface{}, meta *metav1.ObjectMeta) error {
+	data, ok := meta.Annotations[annotation]
+	if !ok {
+		return nil
+	}
+	delete(meta.Annotations, annotation)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	if err := json.Unmarshal([]byte(data), fields); err != nil {
+		return fmt.Errorf("Invalid %s annotation: %v", annotation, err)
+	}
+	return nil
+}
+
+// preserveHubFields preserves fields that are not supported by v1alpha2 using an annotation (HubFieldsAnnotation
+// or HubSpecAnnotation), unless they all have zero values
+func preserveHubFields(annotation string, fields interface{}, meta *metav1.ObjectMeta) error {
+	if reflect.ValueOf(fields).Elem().IsZero() {
+		return nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[annotation] = string(data)
+	return nil
+}
+
+// convertSmartStoreSpecTo converts a v1alpha2 SmartStoreSpec to v1alpha3
+func convertSmartStoreSpecTo(src SmartStoreSpec) v1alpha3.SmartStoreSpec {
+	dst := v1alpha3.SmartStoreSpec{Volumes: convertRemoteVolumeSpecsTo(src.Volumes)}
+	if src.Indexes != nil {
+		dst.Indexes = make([]v1alpha3.RemoteIndexSpec, len(src.Indexes))
+		for i := range src.Indexes {
+			dst.Indexes[i] = v1alpha3.RemoteIndexSpec(src.Indexes[i])
+		}
+	}
+	return dst
+}
+
+// convertSmartStoreSpecFrom converts a v1alpha3 SmartStoreSpec to v1alpha2
+func convertSmartStoreSpecFrom(src v1alpha3.SmartStoreSpec) SmartStoreSpec {
+	dst := SmartStoreSpec{Volumes: convertRemoteVolumeSpecsFrom(src.Volumes)}
+	if src.Indexes != nil {
+		dst.Indexes = make([]RemoteIndexSpec, len(src.Indexes))
+		for i := range src.Indexes {
+			dst.Indexes[i] = RemoteIndexSpec(src.Indexes[i])
+		}
+	}
+	return dst
+}
+
+// convertRemoteVolumeSpecsTo converts a list of v1alpha2 RemoteVolumeSpec to v1alpha3
+func convertRemoteVolumeSpecsTo(src []RemoteVolumeSpec) []v1alpha3.RemoteVolumeSpec {
+	if src == nil {
+		return nil
+	}
+	dst := make([]v1alpha3.RemoteVolumeSpec, len(src))
+	for i := range src {
+		dst[i] = v1alpha3.RemoteVolumeSpec(src[i])
+	}
+	return dst
+}
+
+// convertRemoteVolumeSpecsFrom converts a list of v1alpha3 RemoteVolumeSpec to v1alpha2
+func convertRemoteVolumeSpecsFrom(src []v1alpha3.RemoteVolumeSpec) []RemoteVolumeSpec {
+	if src == nil {
+		return nil
+	}
+	dst := make([]RemoteVolumeSpec, len(src))
+	for i := range src {
+		dst[i] = RemoteVolumeSpec(src[i])
+	}
+	return dst
+}
+
+// convertRemoteVolumeStatusesTo converts a list of v1alpha2 RemoteVolumeStatus to v1alpha3
+func convertRemoteVolumeStatusesTo(src []RemoteVolumeStatus) []v1alpha3.RemoteVolumeStatus {
+	if src == nil {
+		return nil
+	}
+	dst := make([]v1alpha3.RemoteVolumeStatus, len(src))
+	for i := range src {
+		dst[i] = v1alpha3.RemoteVolumeStatus(src[i])
+	}
+	return dst
+}
+
+// convertRemoteVolumeStatusesFrom converts a list of v1alpha3 RemoteVolumeStatus to v1alpha2
+func convertRemoteVolumeStatusesFrom(src []v1alpha3.RemoteVolumeStatus) []RemoteVolumeStatus {
+	if src == nil {
+		return nil
+	}
+	dst := make([]RemoteVolumeStatus, len(src))
+	for i := range src {
+		dst[i] = RemoteVolumeStatus(src[i])
+	}
+	return dst
+}
+
+// convertAppRepoStatusTo converts a v1alpha2 AppRepoStatus to v1alpha3
+func convertAppRepoStatusTo(src AppRepoStatus) v1alpha3.AppRepoStatus {
+	dst := v1alpha3.AppRepoStatus{LastPollTime: src.LastPollTime}
+	if src.Apps != nil {
+		dst.Apps = make([]v1alpha3.AppStatus, len(src.Apps))
+		for i, app := range src.Apps {
+			dst.Apps[i] = v1alpha3.AppStatus{
+				Name:     app.Name,
+				Scope:    app.Scope,
+				Location: app.Location,
+				Version:  app.Version,
+				Phase:    v1alpha3.ResourcePhase(app.Phase),
+				Message:  app.Message,
+			}
+		}
+	}
+	return dst
+}
+
+// convertAppRepoStatusFrom converts a v1alpha3 AppRepoStatus to v1alpha2
+func convertAppRepoStatusFrom(src v1alpha3.AppRepoStatus) AppRepoStatus {
+	dst := AppRepoStatus{LastPollTime: src.LastPollTime}
+	if src.Apps != nil {
+		dst.Apps = make([]AppStatus, len(src.Apps))
+		for i, app := range src.Apps {
+			dst.Apps[i] = AppStatus{
+				Name:     app.Name,
+				Scope:    app.Scope,
+				Location: app.Location,
+				Version:  app.Version,
+				Phase:    ResourcePhase(app.Phase),
+				Message:  app.Message,
+			}
+		}
+	}
+	return dst
+}
+
+// convertIndexerClusterMemberStatusesTo converts a list of v1alpha2 IndexerClusterMemberStatus to v1alpha3
+func convertIndexerClusterMemberStatusesTo(src []IndexerClusterMemberStatus) []v1alpha3.IndexerClusterMemberStatus {
+	if src == nil {
+		return nil
+	}
+	dst := make([]v1alpha3.IndexerClusterMemberStatus, len(src))
+	for i := range src {
+		dst[i] = v1alpha3.IndexerClusterMemberStatus{
+			ID:             src[i].ID,
+			Name:           src[i].Name,
+			Status:         src[i].Status,
+			ActiveBundleID: src[i].ActiveBundleID,
+			BucketCount:    src[i].BucketCount,
+			Searchable:     src[i].Searchable,
+		}
+	}
+	return dst
+}
+
+// convertIndexerClusterMemberStatusesFrom converts a list of v1alpha3 IndexerClusterMemberStatus to v1alpha2
+func convertIndexerClusterMemberStatusesFrom(src []v1alpha3.IndexerClusterMemberStatus) []IndexerClusterMemberStatus {
+	if src == nil {
+		return nil
+	}
+	dst := make([]IndexerClusterMemberStatus, len(src))
+	for i := range src {
+		// Stale and Site are preserved using HubFieldsAnnotation
+		dst[i] = IndexerClusterMemberStatus{
+			ID:             src[i].ID,
+			Name:           src[i].Name,
+			Status:         src[i].Status,
+			ActiveBundleID: src[i].ActiveBundleID,
+			BucketCount:    src[i].BucketCount,
+			Searchable:     src[i].Searchable,
+		}
+	}
+	return dst
+}
+
+// getPeerHubFields returns the fields of each peer that are not supported by v1alpha2, so that they can be preserved
+// using HubFieldsAnnotation, or nil if none of the peers have any
+func getPeerHubFields(peers []v1alpha3.IndexerClusterMemberStatus) []indexerClusterPeerHubFields {
+	var fields []indexerClusterPeerHubFields
+	for i := range peers {
+		if peers[i].Stale || peers[i].Site != "" {
+			if fields == nil {
+				fields = make([]indexerClusterPeerHubFields, len(peers))
+			}
+			fields[i] = indexerClusterPeerHubFields{Stale: peers[i].Stale, Site: peers[i].Site}
+		}
+	}
+	return fields
+}
+
+// restorePeerHubFields restores the fields of each peer, after they were preserved by getPeerHubFields
+func restorePeerHubFields(peers []v1alpha3.IndexerClusterMemberStatus, fields []indexerClusterPeerHubFields) {
+	for i := range fields {
+		if i < len(peers) {
+			peers[i].Stale = fields[i].Stale
+			peers[i].Site = fields[i].Site
+		}
+	}
+}
+
+// convertSearchHeadClusterMemberStatusesTo converts a list of v1alpha2 SearchHeadClusterMemberStatus to v1alpha3
+func convertSearchHeadClusterMemberStatusesTo(src []SearchHeadClusterMemberStatus) []v1alpha3.SearchHeadClusterMemberStatus {
+	if src == nil {
+		return nil
+	}
+	dst := make([]v1alpha3.SearchHeadClusterMemberStatus, len(src))
+	for i := range src {
+		dst[i] = v1alpha3.SearchHeadClusterMemberStatus{
+			Name:                        src[i].Name,
+			Status:                      src[i].Status,
+			Adhoc:                       src[i].Adhoc,
+			Registered:                  src[i].Registered,
+			ActiveHistoricalSearchCount: src[i].ActiveHistoricalSearchCount,
+			ActiveRealtimeSearchCount:   src[i].ActiveRealtimeSearchCount,
+		}
+	}
+	return dst
+}
+
+// convertSearchHeadClusterMemberStatusesFrom converts a list of v1alpha3 SearchHeadClusterMemberStatus to v1alpha2
+func convertSearchHeadClusterMemberStatusesFrom(src []v1alpha3.SearchHeadClusterMemberStatus) []SearchHeadClusterMemberStatus {
+	if src == nil {
+		return nil
+	}
+	dst := make([]SearchHeadClusterMemberStatus, len(src))
+	for i := range src {
+		// Stale is preserved using HubFieldsAnnotation
+		dst[i] = SearchHeadClusterMemberStatus{
+			Name:                        src[i].Name,
+			Status:                      src[i].Status,
+			Adhoc:                       src[i].Adhoc,
+			Registered:                  src[i].Registered,
+			ActiveHistoricalSearchCount: src[i].ActiveHistoricalSearchCount,
+			ActiveRealtimeSearchCount:   src[i].ActiveRealtimeSearchCount,
+		}
+	}
+	return dst
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+import (
+	"testing"
+	"time"
+
+	fuzz "github.com/google/gofuzz"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+// fuzzIterations is the number of random objects that are round-tripped for each kind and direction
+const fuzzIterations = 200
+
+// newTestFuzzer returns a fuzzer that fills custom resources with random values
+func newTestFuzzer() *fuzz.Fuzzer {
+	return fuzz.New().NilChance(0.3).NumElements(0, 2).Funcs(
+		// conversion does not change the type, so this is set by the caller
+		func(t *metav1.TypeMeta, c fuzz.Continue) {},
+		// leave out the annotations used to preserve fields, so that they are only set by conversion
+		func(m *metav1.ObjectMeta, c fuzz.Continue) {
+			c.FuzzNoCustom(m)
+			delete(m.Annotations, DeprecatedRefsAnnotation)
+			delete(m.Annotations, HubStatusAnnotation)
+			delete(m.Annotations, HubFieldsAnnotation)
+			delete(m.Annotations, HubSpecAnnotation)
+		},
+		// make it more likely that the different cases for IndexerClusterRef are tested
+		func(ref *corev1.ObjectReference, c fuzz.Continue) {
+			if c.RandBool() {
+				c.FuzzNoCustom(ref)
+			}
+		},
+	)
+}
+
+// conversionTester round-trips randomly generated objects through conversion, in both directions, and verifies nothing is lost
+func conversionTester(t *testing.T, newSpoke func() conversion.Convertible, newHub func() conversion.Hub) {
+	f := newTestFuzzer()
+
+	for i := 0; i < fuzzIterations; i++ {
+		// spoke -> hub -> spoke
+		original := newSpoke()
+		f.Fuzz(original)
+		input := original.DeepCopyObject().(conversion.Convertible)
+		hub := newHub()
+		if err := input.ConvertTo(hub); err != nil {
+			t.Fatalf("%T.ConvertTo() returned %v", input, err)
+		}
+		result := newSpoke()
+		if err := result.ConvertFrom(hub); err != nil {
+			t.Fatalf("%T.ConvertFrom() returned %v", result, err)
+		}
+		if !apiequality.Semantic.DeepEqual(original, input) {
+			t.Fatalf("%T.ConvertTo() modified its input: %s", input, diff.ObjectReflectDiff(original, input))
+		}
+		if !apiequality.Semantic.DeepEqual(original, result) {
+			t.Fatalf("%T round trip through %T failed: %s", original, hub, diff.ObjectReflectDiff(original, result))
+		}
+
+		// hub -> spoke -> hub
+		originalHub := newHub()
+		f.Fuzz(originalHub)
+		inputHub := originalHub.DeepCopyObject().(conversion.Hub)
+		spoke := newSpoke()
+		if err := spoke.ConvertFrom(inputHub); err != nil {
+			t.Fatalf("%T.ConvertFrom() returned %v", spoke, err)
+		}
+		resultHub := newHub()
+		if err := spoke.ConvertTo(resultHub); err != nil {
+			t.Fatalf("%T.ConvertTo() returned %v", spoke, err)
+		}
+		if !apiequality.Semantic.DeepEqual(originalHub, inputHub) {
+			t.Fatalf("%T.ConvertFrom() modified its input: %s", spoke, diff.ObjectReflectDiff(originalHub, inputHub))
+		}
+		if !apiequality.Semantic.DeepEqual(originalHub, resultHub) {
+			t.Fatalf("%T round trip through %T failed: %s", originalHub, spoke, diff.ObjectReflectDiff(originalHub, resultHub))
+		}
+	}
+}
+
+func TestStandaloneConversion(t *testing.T) {
+	conversionTester(t,
+		func() conversion.Convertible { return &Standalone{} },
+		func() conversion.Hub { return &v1alpha3.Standalone{} })
+}
+
+func TestLicenseMasterConversion(t *testing.T) {
+	conversionTester(t,
+		func() conversion.Convertible { return &LicenseMaster{} },
+		func() conversion.Hub { return &v1alpha3.LicenseMaster{} })
+}
+
+func TestSearchHeadClusterConversion(t *testing.T) {
+	conversionTester(t,
+		func() conversion.Convertible { return &SearchHeadCluster{} },
+		func() conversion.Hub { return &v1alpha3.SearchHeadCluster{} })
+}
+
+func TestClusterMasterConversion(t *testing.T) {
+	conversionTester(t,
+		func() conversion.Convertible { return &ClusterMaster{} },
+		func() conversion.Hub { return &v1alpha3.ClusterMaster{} })
+}
+
+func TestIndexerClusterConversion(t *testing.T) {
+	conversionTester(t,
+		func() conversion.Convertible { return &IndexerCluster{} },
+		func() conversion.Hub { return &v1alpha3.IndexerCluster{} })
+}
+
+func TestMonitoringConsoleConversion(t *testing.T) {
+	conversionTester(t,
+		func() conversion.Convertible { return &MonitoringConsole{} },
+		func() conversion.Hub { return &v1alpha3.MonitoringConsole{} })
+}
+
+func TestSparkConversion(t *testing.T) {
+	conversionTester(t,
+		func() conversion.Convertible { return &Spark{} },
+		func() conversion.Hub { return &v1alpha3.Spark{} })
+}
+
+func TestIndexerClusterRefConversion(t *testing.T) {
+	cr := IndexerCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "idxc1", Namespace: "test"},
+		Spec: IndexerClusterSpec{
+			CommonSplunkSpec: CommonSplunkSpec{IndexerClusterRef: corev1.ObjectReference{Name: "master1"}},
+		},
+	}
+
+	// indexerClusterRef is used as the clusterMasterRef when there is none
+	var hub v1alpha3.IndexerCluster
+	if err := cr.ConvertTo(&hub); err != nil {
+		t.Fatalf("ConvertTo() returned %v", err)
+	}
+	if hub.Spec.ClusterMasterRef.Name != "master1" {
+		t.Errorf("ConvertTo() ClusterMasterRef = %v; want master1", hub.Spec.ClusterMasterRef)
+	}
+	want := `{"indexerClusterRef":{"name":"master1"},"clusterMasterRef":{}}`
+	if got := hub.ObjectMeta.Annotations[DeprecatedRefsAnnotation]; got != want {
+		t.Errorf("ConvertTo() %s annotation = %s; want %s", DeprecatedRefsAnnotation, got, want)
+	}
+
+	// a clusterMasterRef changed using v1alpha3 takes precedence over the original value
+	hub.Spec.ClusterMasterRef.Name = "master2"
+	var result IndexerCluster
+	if err := result.ConvertFrom(&hub); err != nil {
+		t.Fatalf("ConvertFrom() returned %v", err)
+	}
+	if result.Spec.ClusterMasterRef.Name != "master2" || result.Spec.IndexerClusterRef.Name != "master1" {
+		t.Errorf("ConvertFrom() ClusterMasterRef,IndexerClusterRef = %v,%v; want master2,master1",
+			result.Spec.ClusterMasterRef, result.Spec.IndexerClusterRef)
+	}
+	if result.ObjectMeta.Annotations != nil {
+		t.Errorf("ConvertFrom() Annotations = %v; want nil", result.ObjectMeta.Annotations)
+	}
+
+	// invalid annotations are rejected
+	hub.ObjectMeta.Annotations[DeprecatedRefsAnnotation] = "{"
+	if err := result.ConvertFrom(&hub); err == nil {
+		t.Errorf("ConvertFrom() returned nil; want error for invalid annotation")
+	}
+}
+
+func TestHubStatusConversion(t *testing.T) {
+	hub := v1alpha3.Standalone{}
+	hub.Status.Phase = v1alpha3.PhaseReady
+	hub.Status.ObservedGeneration = 3
+	hub.Status.Conditions = []v1alpha3.Condition{
+		{Type: v1alpha3.ConditionReady, Status: corev1.ConditionTrue, ObservedGeneration: 3, Reason: "Ready"},
+	}
+	hub.Status.Secrets.Version = 2
+
+	// status fields that are not supported by v1alpha2 are preserved using an annotation
+	var spoke Standalone
+	if err := spoke.ConvertFrom(&hub); err != nil {
+		t.Fatalf("ConvertFrom() returned %v", err)
+	}
+	want := `{"observedGeneration":3,"conditions":[{"type":"Ready","status":"True","observedGeneration":3,"lastTransitionTime":null,"reason":"Ready","message":""}],"secrets":{"version":2,"rotationInProgress":false},"tls":{}}`
+	if got := spoke.ObjectMeta.Annotations[HubStatusAnnotation]; got != want {
+		t.Errorf("ConvertFrom() %s annotation = %s; want %s", HubStatusAnnotation, got, want)
+	}
+
+	// and restored when converted back to v1alpha3
+	var result v1alpha3.Standalone
+	if err := spoke.ConvertTo(&result); err != nil {
+		t.Fatalf("ConvertTo() returned %v", err)
+	}
+	if !apiequality.Semantic.DeepEqual(hub.Status, result.Status) {
+		t.Errorf("ConvertTo() Status = %v; want %v", result.Status, hub.Status)
+	}
+	if result.ObjectMeta.Annotations != nil {
+		t.Errorf("ConvertTo() Annotations = %v; want nil", result.ObjectMeta.Annotations)
+	}
+
+	// no annotation is needed if there is nothing to preserve
+	hub.Status.CommonStatus = v1alpha3.CommonStatus{}
+	spoke = Standalone{}
+	if err := spoke.ConvertFrom(&hub); err != nil {
+		t.Fatalf("ConvertFrom() returned %v", err)
+	}
+	if spoke.ObjectMeta.Annotations != nil {
+		t.Errorf("ConvertFrom() Annotations = %v; want nil", spoke.ObjectMeta.Annotations)
+	}
+
+	// invalid annotations are rejected
+	spoke.ObjectMeta.Annotations = map[string]string{HubStatusAnnotation: "{"}
+	if err := spoke.ConvertTo(&result); err == nil {
+		t.Errorf("ConvertTo() returned nil; want error for invalid annotation")
+	}
+}
+
+func TestHubStatusConversionWithoutConditions(t *testing.T) {
+	expiration := metav1.NewTime(time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	for name, status := range map[string]v1alpha3.CommonStatus{
+		"Secrets": {Secrets: v1alpha3.SecretsStatus{Version: 2, RotationInProgress: true, RotateRequest: "1"}},
+		"TLS":     {TLS: v1alpha3.TLSStatus{CAChecksum: "abc123", Expiration: &expiration}},
+	} {
+		// status is preserved even if the resource has not been reconciled and has no conditions
+		hub := v1alpha3.Standalone{}
+		hub.Status.CommonStatus = status
+		var spoke Standalone
+		if err := spoke.ConvertFrom(&hub); err != nil {
+			t.Fatalf("%s: ConvertFrom() returned %v", name, err)
+		}
+		if _, ok := spoke.ObjectMeta.Annotations[HubStatusAnnotation]; !ok {
+			t.Errorf("%s: ConvertFrom() Annotations = %v; want %s", name, spoke.ObjectMeta.Annotations, HubStatusAnnotation)
+		}
+		var result v1alpha3.Standalone
+		if err := spoke.ConvertTo(&result); err != nil {
+			t.Fatalf("%s: ConvertTo() returned %v", name, err)
+		}
+		if !apiequality.Semantic.DeepEqual(hub.Status, result.Status) {
+			t.Errorf("%s: round trip Status = %v; want %v", name, result.Status, hub.Status)
+		}
+	}
+}
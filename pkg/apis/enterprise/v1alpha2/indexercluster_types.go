@@ -30,8 +30,31 @@ import (
 type IndexerClusterSpec struct {
 	CommonSplunkSpec `json:",inline"`
 
-	// Number of search head pods; a search head cluster will be created if > 1
+	// Number of indexer cluster peers
 	Replicas int32 `json:"replicas"`
+
+	// Multisite configuration; when one or more sites are defined, a separate StatefulSet of indexer peers is created for each site.
+	// Each site must also be defined by the multisite configuration of the cluster master.
+	Multisite MultisiteSpec `json:"multisite"`
+}
+
+// MultisiteSpec defines the desired state of the indexer peers of a multisite indexer cluster
+type MultisiteSpec struct {
+	// List of sites that indexer peers are created for; multisite clustering is enabled when this is not empty
+	Sites []IndexerClusterSiteSpec `json:"sites"`
+}
+
+// IndexerClusterSiteSpec defines the desired state of a site within a multisite indexer cluster
+type IndexerClusterSiteSpec struct {
+	// Name of the site; this must be of the form "site<n>", where n is between 1 and 63
+	// +kubebuilder:validation:Pattern=^site([1-9]|[1-5][0-9]|6[0-3])$
+	Name string `json:"name"`
+
+	// Number of indexer peers for this site (defaults to 1)
+	Replicas int32 `json:"replicas"`
+
+	// Availability zone that pods for this site will be scheduled in, using the failure-domain.beta.kubernetes.io/zone node label
+	Zone string `json:"zone"`
 }
 
 // IndexerClusterMemberStatus is used to track the status of each indexer cluster peer.
@@ -60,7 +83,7 @@ type IndexerClusterStatus struct {
 	// current phase of the indexer cluster
 	Phase ResourcePhase `json:"phase"`
 
-	// current phase of the cluster master
+	// current phase of the cluster master referenced by clusterMasterRef
 	ClusterMasterPhase ResourcePhase `json:"clusterMasterPhase"`
 
 	// desired number of indexer peers
@@ -86,6 +109,27 @@ type IndexerClusterStatus struct {
 
 	// status of each indexer cluster peer
 	Peers []IndexerClusterMemberStatus `json:"peers"`
+
+	// status of each site, for multisite indexer clusters
+	Sites []IndexerClusterSiteStatus `json:"sites"`
+}
+
+// IndexerClusterSiteStatus is used to track the status of each site within a multisite indexer cluster
+type IndexerClusterSiteStatus struct {
+	// Name of the site
+	Name string `json:"name"`
+
+	// current phase of the indexer peers for this site
+	Phase ResourcePhase `json:"phase"`
+
+	// desired number of indexer peers for this site
+	Replicas int32 `json:"replicas"`
+
+	// current number of ready indexer peers for this site
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// status of each indexer cluster peer within this site
+	Peers []IndexerClusterMemberStatus `json:"peers"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
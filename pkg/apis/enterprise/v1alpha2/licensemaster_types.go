@@ -35,6 +35,9 @@ type LicenseMasterSpec struct {
 type LicenseMasterStatus struct {
 	// current phase of the license master
 	Phase ResourcePhase `json:"phase"`
+
+	// status of app packages installed from the app repository
+	AppRepo AppRepoStatus `json:"appRepo"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
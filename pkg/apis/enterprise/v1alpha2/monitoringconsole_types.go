@@ -0,0 +1,99 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// default all fields to being optional
+// +kubebuilder:validation:Optional
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
+// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+// see also https://book.kubebuilder.io/reference/markers/crd.html
+
+// MonitoringConsoleSpec defines the desired state of a Splunk Enterprise monitoring console.
+type MonitoringConsoleSpec struct {
+	CommonSplunkSpec `json:",inline"`
+}
+
+// MonitoringConsolePeerStatus defines the observed state of a search peer used by a Splunk Enterprise monitoring console.
+type MonitoringConsolePeerStatus struct {
+	// host and management port of the search peer
+	Name string `json:"name"`
+
+	// kind and name of the custom resource that manages the search peer
+	Owner string `json:"owner"`
+
+	// status of the search peer reported by the monitoring console (Up, Down, etc.), or Pending if it has not yet been added
+	Status string `json:"status"`
+}
+
+// MonitoringConsoleStatus defines the observed state of a Splunk Enterprise monitoring console.
+type MonitoringConsoleStatus struct {
+	// current phase of the monitoring console
+	Phase ResourcePhase `json:"phase"`
+
+	// status of each search peer that is monitored
+	Peers []MonitoringConsolePeerStatus `json:"peers"`
+
+	// status of app packages installed from the app repository
+	AppRepo AppRepoStatus `json:"appRepo"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MonitoringConsole is the Schema for a Splunk Enterprise monitoring console.
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=monitoringconsoles,scope=Namespaced,shortName=mc
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Status of monitoring console"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Age of monitoring console"
+type MonitoringConsole struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MonitoringConsoleSpec   `json:"spec,omitempty"`
+	Status MonitoringConsoleStatus `json:"status,omitempty"`
+}
+
+// GetIdentifier is a convenience function to return unique identifier for the Splunk enterprise deployment
+func (cr *MonitoringConsole) GetIdentifier() string {
+	return cr.ObjectMeta.Name
+}
+
+// GetNamespace is a convenience function to return namespace for a Splunk enterprise deployment
+func (cr *MonitoringConsole) GetNamespace() string {
+	return cr.ObjectMeta.Namespace
+}
+
+// GetTypeMeta is a convenience function to return a TypeMeta object
+func (cr *MonitoringConsole) GetTypeMeta() metav1.TypeMeta {
+	return cr.TypeMeta
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MonitoringConsoleList contains a list of MonitoringConsole
+type MonitoringConsoleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MonitoringConsole `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MonitoringConsole{}, &MonitoringConsoleList{})
+}
@@ -97,6 +97,9 @@ type SearchHeadClusterStatus struct {
 
 	// status of each search head cluster member
 	Members []SearchHeadClusterMemberStatus `json:"members"`
+
+	// status of app packages installed from the app repository
+	AppRepo AppRepoStatus `json:"appRepo"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
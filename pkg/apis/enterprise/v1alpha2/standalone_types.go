@@ -40,6 +40,9 @@ type StandaloneSpec struct {
 
 	// Image to use for Spark pod containers (overrides RELATED_IMAGE_SPLUNK_SPARK environment variables)
 	SparkImage string `json:"sparkImage"`
+
+	// SmartStore configuration for remote storage volumes and indexes
+	SmartStore SmartStoreSpec `json:"smartstore"`
 }
 
 // StandaloneStatus defines the observed state of a Splunk Enterprise standalone instances.
@@ -55,6 +58,12 @@ type StandaloneStatus struct {
 
 	// selector for pods, used by HorizontalPodAutoscaler
 	Selector string `json:"selector"`
+
+	// status of each SmartStore remote storage volume
+	RemoteVolumes []RemoteVolumeStatus `json:"remoteVolumes"`
+
+	// status of app packages installed from the app repository
+	AppRepo AppRepoStatus `json:"appRepo"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -9,6 +9,226 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppRepoSpec) DeepCopyInto(out *AppRepoSpec) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]RemoteVolumeSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Apps != nil {
+		in, out := &in.Apps, &out.Apps
+		*out = make([]AppSourceSpec, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppRepoSpec.
+func (in *AppRepoSpec) DeepCopy() *AppRepoSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AppRepoSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppRepoStatus) DeepCopyInto(out *AppRepoStatus) {
+	*out = *in
+	if in.Apps != nil {
+		in, out := &in.Apps, &out.Apps
+		*out = make([]AppStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppRepoStatus.
+func (in *AppRepoStatus) DeepCopy() *AppRepoStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AppRepoStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppSourceSpec) DeepCopyInto(out *AppSourceSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppSourceSpec.
+func (in *AppSourceSpec) DeepCopy() *AppSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AppSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppStatus) DeepCopyInto(out *AppStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppStatus.
+func (in *AppStatus) DeepCopy() *AppStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AppStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterMaster) DeepCopyInto(out *ClusterMaster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterMaster.
+func (in *ClusterMaster) DeepCopy() *ClusterMaster {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterMaster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterMaster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterMasterBundleStatus) DeepCopyInto(out *ClusterMasterBundleStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterMasterBundleStatus.
+func (in *ClusterMasterBundleStatus) DeepCopy() *ClusterMasterBundleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterMasterBundleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterMasterList) DeepCopyInto(out *ClusterMasterList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterMaster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterMasterList.
+func (in *ClusterMasterList) DeepCopy() *ClusterMasterList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterMasterList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterMasterList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterMasterMultisiteSpec) DeepCopyInto(out *ClusterMasterMultisiteSpec) {
+	*out = *in
+	if in.Sites != nil {
+		in, out := &in.Sites, &out.Sites
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterMasterMultisiteSpec.
+func (in *ClusterMasterMultisiteSpec) DeepCopy() *ClusterMasterMultisiteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterMasterMultisiteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterMasterSpec) DeepCopyInto(out *ClusterMasterSpec) {
+	*out = *in
+	in.CommonSplunkSpec.DeepCopyInto(&out.CommonSplunkSpec)
+	in.Multisite.DeepCopyInto(&out.Multisite)
+	in.SmartStore.DeepCopyInto(&out.SmartStore)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterMasterSpec.
+func (in *ClusterMasterSpec) DeepCopy() *ClusterMasterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterMasterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterMasterStatus) DeepCopyInto(out *ClusterMasterStatus) {
+	*out = *in
+	out.ActiveBundle = in.ActiveBundle
+	out.LatestBundle = in.LatestBundle
+	if in.RemoteVolumes != nil {
+		in, out := &in.RemoteVolumes, &out.RemoteVolumes
+		*out = make([]RemoteVolumeStatus, len(*in))
+		copy(*out, *in)
+	}
+	in.AppRepo.DeepCopyInto(&out.AppRepo)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterMasterStatus.
+func (in *ClusterMasterStatus) DeepCopy() *ClusterMasterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterMasterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CommonSpec) DeepCopyInto(out *CommonSpec) {
 	*out = *in
@@ -40,7 +260,9 @@ func (in *CommonSplunkSpec) DeepCopyInto(out *CommonSplunkSpec) {
 		}
 	}
 	out.LicenseMasterRef = in.LicenseMasterRef
+	out.ClusterMasterRef = in.ClusterMasterRef
 	out.IndexerClusterRef = in.IndexerClusterRef
+	in.AppRepo.DeepCopyInto(&out.AppRepo)
 	return
 }
 
@@ -131,10 +353,48 @@ func (in *IndexerClusterMemberStatus) DeepCopy() *IndexerClusterMemberStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IndexerClusterSiteSpec) DeepCopyInto(out *IndexerClusterSiteSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IndexerClusterSiteSpec.
+func (in *IndexerClusterSiteSpec) DeepCopy() *IndexerClusterSiteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IndexerClusterSiteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IndexerClusterSiteStatus) DeepCopyInto(out *IndexerClusterSiteStatus) {
+	*out = *in
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]IndexerClusterMemberStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IndexerClusterSiteStatus.
+func (in *IndexerClusterSiteStatus) DeepCopy() *IndexerClusterSiteStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IndexerClusterSiteStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IndexerClusterSpec) DeepCopyInto(out *IndexerClusterSpec) {
 	*out = *in
 	in.CommonSplunkSpec.DeepCopyInto(&out.CommonSplunkSpec)
+	in.Multisite.DeepCopyInto(&out.Multisite)
 	return
 }
 
@@ -156,6 +416,13 @@ func (in *IndexerClusterStatus) DeepCopyInto(out *IndexerClusterStatus) {
 		*out = make([]IndexerClusterMemberStatus, len(*in))
 		copy(*out, *in)
 	}
+	if in.Sites != nil {
+		in, out := &in.Sites, &out.Sites
+		*out = make([]IndexerClusterSiteStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -175,7 +442,7 @@ func (in *LicenseMaster) DeepCopyInto(out *LicenseMaster) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -250,6 +517,7 @@ func (in *LicenseMasterSpec) DeepCopy() *LicenseMasterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LicenseMasterStatus) DeepCopyInto(out *LicenseMasterStatus) {
 	*out = *in
+	in.AppRepo.DeepCopyInto(&out.AppRepo)
 	return
 }
 
@@ -263,6 +531,191 @@ func (in *LicenseMasterStatus) DeepCopy() *LicenseMasterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringConsole) DeepCopyInto(out *MonitoringConsole) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringConsole.
+func (in *MonitoringConsole) DeepCopy() *MonitoringConsole {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringConsole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MonitoringConsole) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringConsoleList) DeepCopyInto(out *MonitoringConsoleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MonitoringConsole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringConsoleList.
+func (in *MonitoringConsoleList) DeepCopy() *MonitoringConsoleList {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringConsoleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MonitoringConsoleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringConsolePeerStatus) DeepCopyInto(out *MonitoringConsolePeerStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringConsolePeerStatus.
+func (in *MonitoringConsolePeerStatus) DeepCopy() *MonitoringConsolePeerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringConsolePeerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringConsoleSpec) DeepCopyInto(out *MonitoringConsoleSpec) {
+	*out = *in
+	in.CommonSplunkSpec.DeepCopyInto(&out.CommonSplunkSpec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringConsoleSpec.
+func (in *MonitoringConsoleSpec) DeepCopy() *MonitoringConsoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringConsoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonitoringConsoleStatus) DeepCopyInto(out *MonitoringConsoleStatus) {
+	*out = *in
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]MonitoringConsolePeerStatus, len(*in))
+		copy(*out, *in)
+	}
+	in.AppRepo.DeepCopyInto(&out.AppRepo)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonitoringConsoleStatus.
+func (in *MonitoringConsoleStatus) DeepCopy() *MonitoringConsoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MonitoringConsoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MultisiteSpec) DeepCopyInto(out *MultisiteSpec) {
+	*out = *in
+	if in.Sites != nil {
+		in, out := &in.Sites, &out.Sites
+		*out = make([]IndexerClusterSiteSpec, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultisiteSpec.
+func (in *MultisiteSpec) DeepCopy() *MultisiteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MultisiteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteIndexSpec) DeepCopyInto(out *RemoteIndexSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteIndexSpec.
+func (in *RemoteIndexSpec) DeepCopy() *RemoteIndexSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteIndexSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteVolumeSpec) DeepCopyInto(out *RemoteVolumeSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteVolumeSpec.
+func (in *RemoteVolumeSpec) DeepCopy() *RemoteVolumeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteVolumeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemoteVolumeStatus) DeepCopyInto(out *RemoteVolumeStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteVolumeStatus.
+func (in *RemoteVolumeStatus) DeepCopy() *RemoteVolumeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RemoteVolumeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SearchHeadCluster) DeepCopyInto(out *SearchHeadCluster) {
 	*out = *in
@@ -366,6 +819,7 @@ func (in *SearchHeadClusterStatus) DeepCopyInto(out *SearchHeadClusterStatus) {
 		*out = make([]SearchHeadClusterMemberStatus, len(*in))
 		copy(*out, *in)
 	}
+	in.AppRepo.DeepCopyInto(&out.AppRepo)
 	return
 }
 
@@ -379,6 +833,32 @@ func (in *SearchHeadClusterStatus) DeepCopy() *SearchHeadClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmartStoreSpec) DeepCopyInto(out *SmartStoreSpec) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]RemoteVolumeSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Indexes != nil {
+		in, out := &in.Indexes, &out.Indexes
+		*out = make([]RemoteIndexSpec, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmartStoreSpec.
+func (in *SmartStoreSpec) DeepCopy() *SmartStoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SmartStoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Spark) DeepCopyInto(out *Spark) {
 	*out = *in
@@ -479,7 +959,7 @@ func (in *Standalone) DeepCopyInto(out *Standalone) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -539,6 +1019,7 @@ func (in *StandaloneSpec) DeepCopyInto(out *StandaloneSpec) {
 	*out = *in
 	in.CommonSplunkSpec.DeepCopyInto(&out.CommonSplunkSpec)
 	out.SparkRef = in.SparkRef
+	in.SmartStore.DeepCopyInto(&out.SmartStore)
 	return
 }
 
@@ -555,6 +1036,12 @@ func (in *StandaloneSpec) DeepCopy() *StandaloneSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StandaloneStatus) DeepCopyInto(out *StandaloneStatus) {
 	*out = *in
+	if in.RemoteVolumes != nil {
+		in, out := &in.RemoteVolumes, &out.RemoteVolumes
+		*out = make([]RemoteVolumeStatus, len(*in))
+		copy(*out, *in)
+	}
+	in.AppRepo.DeepCopyInto(&out.AppRepo)
 	return
 }
 
@@ -0,0 +1,180 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// default all fields to being optional
+// +kubebuilder:validation:Optional
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
+// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+// see also https://book.kubebuilder.io/reference/markers/crd.html
+
+// ClusterMasterSpec defines the desired state of a Splunk Enterprise indexer cluster master
+type ClusterMasterSpec struct {
+	CommonSplunkSpec `json:",inline"`
+
+	// Multisite configuration; multisite clustering is enabled when one or more sites are defined
+	Multisite ClusterMasterMultisiteSpec `json:"multisite"`
+
+	// SmartStore configuration for remote storage volumes and indexes; this is distributed to peers using the cluster master bundle
+	SmartStore SmartStoreSpec `json:"smartstore"`
+
+	// Name of a ConfigMap containing configuration files that are distributed to all indexer cluster peers using the
+	// cluster master bundle. Each key is the name of a file, such as indexes.conf, that is installed in
+	// master-apps/splunk-operator-bundle/local. Changes are validated and applied without restarting the cluster master.
+	BundleConfigMapRef string `json:"bundleConfigMapRef"`
+}
+
+// ClusterMasterMultisiteSpec defines the multisite configuration of an indexer cluster master
+type ClusterMasterMultisiteSpec struct {
+	// List of all sites in the indexer cluster; these must be of the form "site<n>", where n is between 1 and 63.
+	// The cluster master is assigned to the first site.
+	Sites []string `json:"sites"`
+
+	// Number of copies of each bucket to store on the origin site (defaults to 1)
+	SiteReplicationFactorOrigin int32 `json:"siteReplicationFactorOrigin"`
+
+	// Total number of copies of each bucket to store across all sites (defaults to number of sites)
+	SiteReplicationFactorTotal int32 `json:"siteReplicationFactorTotal"`
+
+	// Number of searchable copies of each bucket to store on the origin site (defaults to 1)
+	SiteSearchFactorOrigin int32 `json:"siteSearchFactorOrigin"`
+
+	// Total number of searchable copies of each bucket to store across all sites (defaults to number of sites)
+	SiteSearchFactorTotal int32 `json:"siteSearchFactorTotal"`
+}
+
+// ClusterMasterBundleStatus is used to track the status of a configuration bundle on the cluster master
+type ClusterMasterBundleStatus struct {
+	// checksum used to verify bundle integrity
+	Checksum string `json:"checksum"`
+
+	// timestamp of the bundle
+	Timestamp int64 `json:"timestamp"`
+}
+
+// ClusterMasterBundlePushStatus is used to track validation and distribution of the bundle ConfigMap to indexer cluster peers
+type ClusterMasterBundlePushStatus struct {
+	// checksum of the bundle ConfigMap contents that have been applied
+	ConfigMapChecksum string `json:"configMapChecksum"`
+
+	// checksum of the bundle ConfigMap contents that are being validated
+	PendingConfigMapChecksum string `json:"pendingConfigMapChecksum"`
+
+	// time that validation of the pending bundle ConfigMap contents started
+	ValidationStartTime *metav1.Time `json:"validationStartTime,omitempty"`
+
+	// errors reported by the cluster master when validating the pending bundle
+	ValidationErrors []string `json:"validationErrors"`
+
+	// checksum of the cluster bundle that was most recently applied by the operator
+	AppliedChecksum string `json:"appliedChecksum"`
+}
+
+// ClusterMasterStatus defines the observed state of a Splunk Enterprise indexer cluster master
+type ClusterMasterStatus struct {
+	// current phase of the cluster master
+	Phase ResourcePhase `json:"phase"`
+
+	CommonStatus `json:",inline"`
+
+	// Indicates if the cluster is initialized.
+	Initialized bool `json:"initialized"`
+
+	// Indicates if the cluster is ready for indexing.
+	IndexingReady bool `json:"indexingReady"`
+
+	// Indicates whether the master is ready to begin servicing, based on whether it is initialized.
+	ServiceReady bool `json:"serviceReady"`
+
+	// Indicates if the cluster is in maintenance mode.
+	MaintenanceMode bool `json:"maintenanceMode"`
+
+	// Indicates whether the master is restarting the peers in a cluster.
+	RollingRestart bool `json:"rollingRestart"`
+
+	// Indicates whether the replication factor is met for all buckets in the cluster.
+	ReplicationFactorMet bool `json:"replicationFactorMet"`
+
+	// Indicates whether the search factor is met for all buckets in the cluster.
+	SearchFactorMet bool `json:"searchFactorMet"`
+
+	// configuration bundle that is active on the indexer cluster peers
+	ActiveBundle ClusterMasterBundleStatus `json:"activeBundle"`
+
+	// most recent configuration bundle on the cluster master; this differs from activeBundle while a bundle push is in progress
+	LatestBundle ClusterMasterBundleStatus `json:"latestBundle"`
+
+	// status of each SmartStore remote storage volume
+	RemoteVolumes []RemoteVolumeStatus `json:"remoteVolumes"`
+
+	// status of app packages installed from the app repository
+	AppRepo AppRepoStatus `json:"appRepo"`
+
+	// status of the bundle ConfigMap that is distributed to peers
+	BundlePush ClusterMasterBundlePushStatus `json:"bundlePush"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterMaster is the Schema for a Splunk Enterprise indexer cluster master
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=clustermasters,scope=Namespaced,shortName=cm-idxc
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Status of cluster master"
+// +kubebuilder:printcolumn:name="Maintenance",type="boolean",JSONPath=".status.maintenanceMode",description="Indicates if the cluster is in maintenance mode"
+// +kubebuilder:printcolumn:name="RF Met",type="boolean",JSONPath=".status.replicationFactorMet",description="Indicates whether the replication factor is met"
+// +kubebuilder:printcolumn:name="SF Met",type="boolean",JSONPath=".status.searchFactorMet",description="Indicates whether the search factor is met"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Age of cluster master"
+type ClusterMaster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterMasterSpec   `json:"spec,omitempty"`
+	Status ClusterMasterStatus `json:"status,omitempty"`
+}
+
+// GetIdentifier is a convenience function to return unique identifier for the Splunk enterprise deployment
+func (cr *ClusterMaster) GetIdentifier() string {
+	return cr.ObjectMeta.Name
+}
+
+// GetNamespace is a convenience function to return namespace for a Splunk enterprise deployment
+func (cr *ClusterMaster) GetNamespace() string {
+	return cr.ObjectMeta.Namespace
+}
+
+// GetTypeMeta is a convenience function to return a TypeMeta object
+func (cr *ClusterMaster) GetTypeMeta() metav1.TypeMeta {
+	return cr.TypeMeta
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterMasterList contains a list of ClusterMaster
+type ClusterMasterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterMaster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterMaster{}, &ClusterMasterList{})
+}
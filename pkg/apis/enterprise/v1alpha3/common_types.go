@@ -0,0 +1,427 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ResourcePhase is used to represent the current phase of a custom resource
+// +kubebuilder:validation:Enum=Pending;Ready;Updating;ScalingUp;ScalingDown;Terminating;Error
+type ResourcePhase string
+
+const (
+	// PhasePending means a custom resource has just been created and is not yet ready
+	PhasePending ResourcePhase = "Pending"
+
+	// PhaseReady means a custom resource is ready and up to date
+	PhaseReady ResourcePhase = "Ready"
+
+	// PhaseUpdating means a custom resource is in the process of updating to a new desired state (spec)
+	PhaseUpdating ResourcePhase = "Updating"
+
+	// PhaseScalingUp means a customer resource is in the process of scaling up
+	PhaseScalingUp ResourcePhase = "ScalingUp"
+
+	// PhaseScalingDown means a customer resource is in the process of scaling down
+	PhaseScalingDown ResourcePhase = "ScalingDown"
+
+	// PhaseTerminating means a customer resource is in the process of being removed
+	PhaseTerminating ResourcePhase = "Terminating"
+
+	// PhaseError means an error occured with custom resource management
+	PhaseError ResourcePhase = "Error"
+)
+
+// ConditionType is used to represent one aspect of the state of a custom resource
+type ConditionType string
+
+const (
+	// ConditionReady means a custom resource has been fully reconciled and everything it manages is ready
+	ConditionReady ConditionType = "Ready"
+
+	// ConditionProgressing means a custom resource is working towards its desired state (spec) without errors
+	ConditionProgressing ConditionType = "Progressing"
+
+	// ConditionDegraded means the most recent attempt to reconcile a custom resource failed
+	ConditionDegraded ConditionType = "Degraded"
+
+	// ConditionSpecValid means the spec of a custom resource passed validation
+	ConditionSpecValid ConditionType = "SpecValid"
+
+	// ConditionSecretsReady means the secrets and config maps used by Splunk pods have been applied
+	ConditionSecretsReady ConditionType = "SecretsReady"
+
+	// ConditionSmartStoreReady means the SmartStore configuration has been applied and its volumes checked
+	ConditionSmartStoreReady ConditionType = "SmartStoreReady"
+
+	// ConditionTLSReady means the TLS certificates used by Splunk pods have been issued or validated
+	ConditionTLSReady ConditionType = "TLSReady"
+
+	// ConditionAppsReady means the app repository has been checked and its app packages staged
+	ConditionAppsReady ConditionType = "AppsReady"
+
+	// ConditionServicesReady means the Kubernetes services for a custom resource have been applied
+	ConditionServicesReady ConditionType = "ServicesReady"
+
+	// ConditionClusterMasterReady means the cluster master referenced by an indexer cluster exists and is ready
+	ConditionClusterMasterReady ConditionType = "ClusterMasterReady"
+
+	// ConditionDeployerReady means the deployer of a search head cluster is ready
+	ConditionDeployerReady ConditionType = "DeployerReady"
+
+	// ConditionSparkMasterReady means the master of a spark cluster is ready
+	ConditionSparkMasterReady ConditionType = "SparkMasterReady"
+
+	// ConditionPodsReady means all pods of a custom resource are ready and up to date
+	ConditionPodsReady ConditionType = "PodsReady"
+
+	// ConditionClusterReady means an indexer cluster is ready for service and meets its replication and search factors
+	ConditionClusterReady ConditionType = "ClusterReady"
+
+	// ConditionPeersReady means all search peers of a monitoring console have been added and are up
+	ConditionPeersReady ConditionType = "PeersReady"
+
+	// ConditionBundleReady means the latest contents of a cluster master's bundle ConfigMap have been validated and applied
+	ConditionBundleReady ConditionType = "BundleReady"
+
+	// ConditionPaused means reconciling a custom resource has been suspended using PausedAnnotation
+	ConditionPaused ConditionType = "Paused"
+)
+
+// PausedAnnotation is used to suspend reconciling a custom resource. When it is set to "true", the operator only
+// refreshes the status of the custom resource, and does not modify anything that it manages.
+const PausedAnnotation = "enterprise.splunk.com/paused"
+
+// RotateSecretsAnnotation is used to request rotation of the secrets used by a Splunk Enterprise resource. Secrets are
+// rotated whenever its value changes; for example, it may be set to the current time.
+const RotateSecretsAnnotation = "enterprise.splunk.com/rotate-secrets"
+
+// Condition describes one aspect of the state of a custom resource
+type Condition struct {
+	// type of condition
+	Type ConditionType `json:"type"`
+
+	// status of the condition (True, False or Unknown)
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// generation of the custom resource that the condition was observed for
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// last time the condition changed from one status to another
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// one-word CamelCase reason for the condition's current status
+	Reason string `json:"reason"`
+
+	// human readable message with details about the condition's current status
+	Message string `json:"message"`
+}
+
+// default all fields to being optional
+// +kubebuilder:validation:Optional
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
+// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+// see also https://book.kubebuilder.io/reference/markers/crd.html
+
+// CommonSpec defines the desired state of parameters that are common across all CRD types
+type CommonSpec struct {
+	// Image to use for Splunk pod containers (overrides RELATED_IMAGE_SPLUNK_ENTERPRISE environment variables)
+	Image string `json:"image"`
+
+	// Sets pull policy for all images (either “Always” or the default: “IfNotPresent”)
+	// +kubebuilder:validation:Enum=Always;IfNotPresent
+	ImagePullPolicy string `json:"imagePullPolicy"`
+
+	// Name of Scheduler to use for pod placement (defaults to “default-scheduler”)
+	SchedulerName string `json:"schedulerName"`
+
+	// Kubernetes Affinity rules that control how pods are assigned to particular nodes.
+	Affinity corev1.Affinity `json:"affinity"`
+
+	// resource requirements for the pod containers
+	Resources corev1.ResourceRequirements `json:"resources"`
+
+	// ServiceTemplate is a template used to create Kubernetes services
+	ServiceTemplate corev1.Service `json:"serviceTemplate"`
+}
+
+// CommonSplunkSpec defines the desired state of parameters that are common across all Splunk Enterprise CRD types
+type CommonSplunkSpec struct {
+	CommonSpec `json:",inline"`
+
+	// Name of StorageClass to use for persistent volume claims
+	StorageClassName string `json:"storageClassName"`
+
+	// Storage capacity to request for /opt/splunk/etc persistent volume claims (default=”1Gi”)
+	EtcStorage string `json:"etcStorage"`
+
+	// Storage capacity to request for /opt/splunk/var persistent volume claims (default=”50Gi”)
+	VarStorage string `json:"varStorage"`
+
+	// List of one or more Kubernetes volumes. These will be mounted in all pod containers as as /mnt/<name>
+	Volumes []corev1.Volume `json:"volumes"`
+
+	// Inline map of default.yml overrides used to initialize the environment
+	Defaults string `json:"defaults"`
+
+	// Full path or URL for one or more default.yml files, separated by commas
+	DefaultsURL string `json:"defaultsUrl"`
+
+	// Full path or URL for a Splunk Enterprise license file
+	LicenseURL string `json:"licenseUrl"`
+
+	// LicenseMasterRef refers to a Splunk Enterprise license master managed by the operator within Kubernetes
+	LicenseMasterRef corev1.ObjectReference `json:"licenseMasterRef"`
+
+	// ClusterMasterRef refers to a Splunk Enterprise indexer cluster master managed by the operator within Kubernetes
+	ClusterMasterRef corev1.ObjectReference `json:"clusterMasterRef"`
+
+	// SecretRef refers to a user-managed Secret containing the admin password and other secrets, which is used instead
+	// of randomly generated values (see docs for the required keys)
+	SecretRef corev1.ObjectReference `json:"secretRef"`
+
+	// App repository containing Splunk app packages that are installed by the operator
+	AppRepo AppRepoSpec `json:"appRepo"`
+
+	// Strategy used to recycle pods when their pod template has been updated
+	UpdateStrategy UpdateStrategySpec `json:"updateStrategy"`
+
+	// Schedule used to rotate the admin password and other secrets
+	SecretRotation SecretRotationSpec `json:"secretRotation"`
+
+	// TLS certificates used by splunkd, Splunk Web, HTTP event collector and forwarding (S2S)
+	TLS TLSSpec `json:"tls"`
+}
+
+// TLSSpec defines the certificates used to secure the network interfaces of a Splunk Enterprise resource
+type TLSSpec struct {
+	// Enables TLS using certificates issued by the operator's internal CA, or using SecretName if provided
+	Enabled bool `json:"enabled"`
+
+	// Name of a user-managed Secret in the same namespace that contains tls.crt, tls.key and ca.crt; the certificate
+	// must be valid for the DNS names of all pods and services of the resource
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// SecretRotationSpec defines a schedule for rotating the secrets used by a Splunk Enterprise resource
+type SecretRotationSpec struct {
+	// Interval in seconds between automatic rotations, at least 3600 (defaults to 0, which only rotates secrets when
+	// requested using the enterprise.splunk.com/rotate-secrets annotation)
+	// +kubebuilder:validation:Minimum=0
+	Interval int64 `json:"interval"`
+}
+
+// UpdateStrategySpec defines how the operator recycles the pods of a StatefulSet to apply updates to their pod template
+type UpdateStrategySpec struct {
+	// Maximum number of pods that may be recycled at the same time (defaults to 1)
+	// +kubebuilder:validation:Minimum=0
+	MaxUnavailable int32 `json:"maxUnavailable"`
+
+	// Only pods with an ordinal greater than or equal to the partition are updated; this may be used to update a
+	// canary before updating the remaining pods (defaults to 0, which updates all pods)
+	// +kubebuilder:validation:Minimum=0
+	Partition int32 `json:"partition"`
+
+	// Minimum number of seconds that a recycled pod must be ready before another pod is recycled (defaults to 0)
+	// +kubebuilder:validation:Minimum=0
+	MinReadySeconds int32 `json:"minReadySeconds"`
+
+	// Pauses the rollout once this many pods have been updated, for staged rollouts; increase it or set it to 0 to
+	// continue (defaults to 0, which never pauses)
+	// +kubebuilder:validation:Minimum=0
+	PauseAfter int32 `json:"pauseAfter"`
+}
+
+// DFSSpec defines the desired state of Data Fabric Search (DFS), which runs searches using a Spark cluster
+type DFSSpec struct {
+	// SparkRef refers to a Spark cluster managed by the operator within Kubernetes
+	// When defined, Data Fabric Search (DFS) will be enabled and configured to use the Spark cluster.
+	SparkRef corev1.ObjectReference `json:"sparkRef"`
+
+	// Image to use for Spark pod containers (overrides RELATED_IMAGE_SPLUNK_SPARK environment variables)
+	SparkImage string `json:"sparkImage"`
+}
+
+// SmartStoreSpec defines the remote storage volumes and indexes used by Splunk SmartStore
+type SmartStoreSpec struct {
+	// List of S3-compatible remote storage volumes
+	Volumes []RemoteVolumeSpec `json:"volumes"`
+
+	// List of indexes that are stored on remote storage volumes
+	Indexes []RemoteIndexSpec `json:"indexes"`
+}
+
+// RemoteVolumeSpec defines an S3-compatible remote storage volume used by SmartStore or an app repository
+type RemoteVolumeSpec struct {
+	// Name of the remote storage volume
+	Name string `json:"name"`
+
+	// Endpoint URL for the remote storage service (e.g. https://s3-us-west-2.amazonaws.com)
+	Endpoint string `json:"endpoint"`
+
+	// Bucket and optional path prefix for the remote storage volume (e.g. "mybucket/smartstore")
+	Path string `json:"path"`
+
+	// Name of a Kubernetes Secret containing s3_access_key and s3_secret_key for the remote storage volume
+	SecretRef string `json:"secretRef"`
+}
+
+// RemoteIndexSpec defines an index that is stored on a SmartStore remote storage volume
+type RemoteIndexSpec struct {
+	// Name of the index
+	Name string `json:"name"`
+
+	// Name of the remote storage volume used to store the index (defaults to the first volume)
+	VolumeName string `json:"volumeName"`
+
+	// Location of the index within the remote storage volume (defaults to the name of the index)
+	RemotePath string `json:"remotePath"`
+}
+
+// CommonStatus defines the observed state of parameters that are common across all CRD types
+type CommonStatus struct {
+	// generation of the custom resource that was most recently reconciled
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// conditions describing the outcome of the most recent reconcile
+	Conditions []Condition `json:"conditions"`
+
+	// status of the secrets used by a Splunk Enterprise resource
+	Secrets SecretsStatus `json:"secrets"`
+
+	// status of the TLS certificates used by a Splunk Enterprise resource
+	TLS TLSStatus `json:"tls"`
+}
+
+// SecretsStatus tracks the version and rotation of the secrets used by a Splunk Enterprise resource
+type SecretsStatus struct {
+	// version of the secrets, which is incremented whenever they are rotated or updated to match a referenced resource
+	Version int64 `json:"version"`
+
+	// true while new secrets are being applied to running instances
+	RotationInProgress bool `json:"rotationInProgress"`
+
+	// value of the enterprise.splunk.com/rotate-secrets annotation when secrets were most recently rotated
+	RotateRequest string `json:"rotateRequest,omitempty"`
+
+	// time when secrets were most recently rotated
+	LastRotationTime *metav1.Time `json:"lastRotationTime,omitempty"`
+}
+
+// TLSStatus tracks the TLS certificates used by a Splunk Enterprise resource
+type TLSStatus struct {
+	// checksum of the CA certificate used by all pods, once they have been updated to use it; the operator verifies
+	// the certificates of Splunk instances using this CA
+	CAChecksum string `json:"caChecksum,omitempty"`
+
+	// time when the certificates issued by the operator expire, and are renewed 30 days beforehand
+	Expiration *metav1.Time `json:"expiration,omitempty"`
+}
+
+// RemoteVolumeStatus is used to track the status of a SmartStore remote storage volume
+type RemoteVolumeStatus struct {
+	// Name of the remote storage volume
+	Name string `json:"name"`
+
+	// Endpoint URL that was used to check the remote storage volume
+	Endpoint string `json:"endpoint"`
+
+	// Bucket and path prefix that was used to check the remote storage volume
+	Path string `json:"path"`
+
+	// true if the remote storage volume passed its connectivity check
+	Connected bool `json:"connected"`
+
+	// error message if the remote storage volume failed its connectivity check
+	Message string `json:"message"`
+}
+
+// AppRepoSpec defines a repository of Splunk app packages that are installed by the operator
+type AppRepoSpec struct {
+	// Interval in seconds to check for new or changed app packages, between 60 and 86400 (defaults to 600)
+	PollInterval int64 `json:"pollInterval"`
+
+	// List of S3-compatible remote storage volumes containing app packages
+	Volumes []RemoteVolumeSpec `json:"volumes"`
+
+	// List of app packages to install
+	Apps []AppSourceSpec `json:"apps"`
+}
+
+// AppSourceSpec defines the location of a Splunk app package and where it is installed
+type AppSourceSpec struct {
+	// Name of the app
+	Name string `json:"name"`
+
+	// Where the app is installed: "local" installs it on the instance itself (default), and "cluster" installs it
+	// on all members using the cluster master bundle (IndexerCluster) or the deployer bundle (SearchHeadCluster)
+	Scope string `json:"scope"`
+
+	// Name of the remote storage volume containing the app package (defaults to the first volume)
+	VolumeName string `json:"volumeName"`
+
+	// Name of a ConfigMap containing the app package in its binaryData, used instead of a remote storage volume
+	ConfigMapRef string `json:"configMapRef"`
+
+	// Location of the app package within the remote storage volume, or its key within the ConfigMap (defaults to <name>.tgz)
+	Path string `json:"path"`
+}
+
+// AppRepoStatus is used to track the status of app packages installed from an app repository
+type AppRepoStatus struct {
+	// time of the last check for new or changed app packages, in seconds since the epoch
+	LastPollTime int64 `json:"lastPollTime"`
+
+	// status of each app package
+	Apps []AppStatus `json:"apps"`
+}
+
+// AppStatus is used to track the install state of an app package
+type AppStatus struct {
+	// Name of the app
+	Name string `json:"name"`
+
+	// Where the app is installed ("local" or "cluster")
+	Scope string `json:"scope"`
+
+	// Location of the app package
+	Location string `json:"location"`
+
+	// Checksum of the latest version of the app package
+	Version string `json:"version"`
+
+	// Pending if the latest version is waiting to be installed, Ready if it has been installed, or Error
+	Phase ResourcePhase `json:"phase"`
+
+	// error message if the app package could not be retrieved
+	Message string `json:"message"`
+}
+
+// MetaObject is used to represent common interfaces of custom resources
+type MetaObject interface {
+	GetIdentifier() string
+	GetNamespace() string
+	GetTypeMeta() metav1.TypeMeta
+	GetObjectMeta() metav1.Object
+	GetObjectKind() schema.ObjectKind
+	DeepCopyObject() runtime.Object
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+// v1alpha3 is the storage version of all enterprise.splunk.com custom resources, and the hub
+// that other versions are converted to and from by the conversion webhook.
+
+// Hub marks this type as a conversion hub.
+func (*Standalone) Hub() {}
+
+// Hub marks this type as a conversion hub.
+func (*LicenseMaster) Hub() {}
+
+// Hub marks this type as a conversion hub.
+func (*SearchHeadCluster) Hub() {}
+
+// Hub marks this type as a conversion hub.
+func (*ClusterMaster) Hub() {}
+
+// Hub marks this type as a conversion hub.
+func (*IndexerCluster) Hub() {}
+
+// Hub marks this type as a conversion hub.
+func (*MonitoringConsole) Hub() {}
+
+// Hub marks this type as a conversion hub.
+func (*Spark) Hub() {}
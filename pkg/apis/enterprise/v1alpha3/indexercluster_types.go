@@ -0,0 +1,301 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// default all fields to being optional
+// +kubebuilder:validation:Optional
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
+// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+// see also https://book.kubebuilder.io/reference/markers/crd.html
+
+// IndexerClusterSpec defines the desired state of a Splunk Enterprise indexer cluster
+type IndexerClusterSpec struct {
+	CommonSplunkSpec `json:",inline"`
+
+	// Number of indexer cluster peers
+	Replicas int32 `json:"replicas"`
+
+	// Multisite configuration; when one or more sites are defined, a separate StatefulSet of indexer peers is created for each site.
+	// Each site must also be defined by the multisite configuration of the cluster master.
+	Multisite MultisiteSpec `json:"multisite"`
+
+	// Percentage of indexer peers restarted at a time, when a searchable rolling restart is used to apply changes to
+	// the cluster bundle in master-apps on the cluster master. The cluster master restarts all of its peers, including
+	// those of other indexer clusters that share it. When this is 0 (the default), the operator does not initiate
+	// rolling restarts. Changes to defaults and defaultsUrl always recycle pods.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	RollingRestartPercentage int32 `json:"rollingRestartPercentage"`
+
+	// Policy for rebalancing bucket data across indexer peers after the indexer cluster is scaled up
+	RebalanceOnScaleUp IndexerClusterRebalancePolicy `json:"rebalanceOnScaleUp"`
+}
+
+// IndexerClusterRebalancePolicy defines when a data rebalance is started after an indexer cluster is scaled up
+type IndexerClusterRebalancePolicy struct {
+	// If true, a data rebalance is started on the cluster master once all peers are up after scaling up
+	Enabled bool `json:"enabled"`
+
+	// Minimum skew in bucket counts, as the difference between the largest and smallest bucket count of any peer
+	// relative to the largest, for a data rebalance to be worthwhile (percentage, defaults to 20)
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	BucketSkewThreshold int32 `json:"bucketSkewThreshold"`
+}
+
+// MultisiteSpec defines the desired state of the indexer peers of a multisite indexer cluster
+type MultisiteSpec struct {
+	// List of sites that indexer peers are created for; multisite clustering is enabled when this is not empty
+	Sites []IndexerClusterSiteSpec `json:"sites"`
+}
+
+// IndexerClusterSiteSpec defines the desired state of a site within a multisite indexer cluster
+type IndexerClusterSiteSpec struct {
+	// Name of the site; this must be of the form "site<n>", where n is between 1 and 63
+	// +kubebuilder:validation:Pattern=^site([1-9]|[1-5][0-9]|6[0-3])$
+	Name string `json:"name"`
+
+	// Number of indexer peers for this site (defaults to 1)
+	Replicas int32 `json:"replicas"`
+
+	// Availability zone that pods for this site will be scheduled in, using the failure-domain.beta.kubernetes.io/zone node label
+	Zone string `json:"zone"`
+}
+
+// IndexerClusterMemberStatus is used to track the status of each indexer cluster peer.
+type IndexerClusterMemberStatus struct {
+	// Unique identifier or GUID for the peer
+	ID string `json:"guid"`
+
+	// Name of the indexer cluster peer
+	Name string `json:"name"`
+
+	// Status of the indexer cluster peer
+	Status string `json:"status"`
+
+	// The ID of the configuration bundle currently being used by the master.
+	ActiveBundleID string `json:"activeBundleId"`
+
+	// Count of the number of buckets on this peer, across all indexes.
+	BucketCount int64 `json:"bucketCount"`
+
+	// Flag indicating if this peer belongs to the current committed generation and is searchable.
+	Searchable bool `json:"searchable"`
+
+	// Site that the peer belongs to, for multisite indexer clusters
+	Site string `json:"site"`
+
+	// Indicates that the cluster master could not be polled recently, so the status of this peer may be out of date.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// IndexerClusterStatus defines the observed state of a Splunk Enterprise indexer cluster
+type IndexerClusterStatus struct {
+	// current phase of the indexer cluster
+	Phase ResourcePhase `json:"phase"`
+
+	CommonStatus `json:",inline"`
+
+	// current phase of the cluster master referenced by clusterMasterRef
+	ClusterMasterPhase ResourcePhase `json:"clusterMasterPhase"`
+
+	// desired number of indexer peers
+	Replicas int32 `json:"replicas"`
+
+	// current number of ready indexer peers
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// selector for pods, used by HorizontalPodAutoscaler
+	Selector string `json:"selector"`
+
+	// Indicates if the cluster is initialized.
+	Initialized bool `json:"initialized"`
+
+	// Indicates if the cluster is ready for indexing.
+	IndexingReady bool `json:"indexingReady"`
+
+	// Indicates whether the master is ready to begin servicing, based on whether it is initialized.
+	ServiceReady bool `json:"serviceReady"`
+
+	// true if the operator has enabled maintenance mode on the cluster master, while recycling peers for updates;
+	// maintenance mode is shared with other indexer clusters that reference the same cluster master, and is only
+	// disabled once none of them have this set
+	MaintenanceMode bool `json:"maintenanceMode"`
+
+	// Indicates if the cluster master reports that the replication factor is met (and the site replication factor,
+	// for multisite indexer clusters); this is not set if the health of the cluster could not be polled
+	ReplicationFactorMet *bool `json:"replicationFactorMet,omitempty"`
+
+	// Indicates if the cluster master reports that the search factor is met (and the site search factor, for
+	// multisite indexer clusters); this is not set if the health of the cluster could not be polled
+	SearchFactorMet *bool `json:"searchFactorMet,omitempty"`
+
+	// fixup tasks and excess buckets of each index, as reported by the cluster master
+	Indexes []IndexerClusterIndexStatus `json:"indexes"`
+
+	// status of each indexer cluster peer
+	Peers []IndexerClusterMemberStatus `json:"peers"`
+
+	// status of each site, for multisite indexer clusters
+	Sites []IndexerClusterSiteStatus `json:"sites"`
+
+	// status of searchable rolling restarts used to apply configuration changes
+	RollingRestart IndexerClusterRollingRestartStatus `json:"rollingRestart"`
+
+	// status of the cluster master bundle that is distributed to indexer peers
+	Bundle IndexerClusterBundleStatus `json:"bundle"`
+
+	// status of data rebalancing after the indexer cluster is scaled up
+	Rebalance IndexerClusterRebalanceStatus `json:"rebalance"`
+}
+
+// IndexerClusterIndexStatus is used to track the health of each index in an indexer cluster
+type IndexerClusterIndexStatus struct {
+	// Name of the index
+	Name string `json:"name"`
+
+	// number of buckets with fixup tasks pending to meet the replication factor
+	ReplicationFixupTasks int64 `json:"replicationFixupTasks"`
+
+	// number of buckets with fixup tasks pending to meet the search factor
+	SearchFixupTasks int64 `json:"searchFixupTasks"`
+
+	// number of buckets that have more copies than the replication factor requires
+	ExcessBuckets int64 `json:"excessBuckets"`
+
+	// number of buckets that have more searchable copies than the search factor requires
+	ExcessSearchableBuckets int64 `json:"excessSearchableBuckets"`
+}
+
+// IndexerClusterRebalanceStatus is used to track data rebalances that are started after an indexer cluster is scaled up
+type IndexerClusterRebalanceStatus struct {
+	// true if the indexer cluster has been scaled up, and a data rebalance has not yet been considered
+	Pending bool `json:"pending"`
+
+	// true if a data rebalance started by the operator is in progress
+	InProgress bool `json:"inProgress"`
+
+	// percentage of the data rebalance in progress that has completed
+	PercentComplete int32 `json:"percentComplete"`
+
+	// skew in bucket counts across peers (percentage) when a data rebalance was last considered
+	BucketSkew int32 `json:"bucketSkew"`
+
+	// time that the most recent data rebalance was started
+	LastStartTime *metav1.Time `json:"lastStartTime,omitempty"`
+
+	// time that the most recent data rebalance completed
+	LastCompletionTime *metav1.Time `json:"lastCompletionTime,omitempty"`
+}
+
+// IndexerClusterBundleStatus is used to track the rollout of the cluster master bundle to indexer cluster peers
+type IndexerClusterBundleStatus struct {
+	// checksum of the bundle that is active on the cluster master
+	AppliedChecksum string `json:"appliedChecksum"`
+
+	// errors reported by the cluster master when validating the most recent bundle
+	ValidationErrors []string `json:"validationErrors"`
+
+	// number of indexer peers that have activated the bundle
+	UpdatedPeers int32 `json:"updatedPeers"`
+}
+
+// IndexerClusterRollingRestartStatus is used to track searchable rolling restarts of indexer cluster peers, which
+// apply changes to the cluster bundle that is active on the cluster master
+type IndexerClusterRollingRestartStatus struct {
+	// checksum of the cluster bundle that has been applied to all indexer peers
+	AppliedConfigHash string `json:"appliedConfigHash"`
+
+	// checksum of the cluster bundle being applied by a rolling restart that is in progress
+	PendingConfigHash string `json:"pendingConfigHash"`
+
+	// true if a rolling restart requested by the operator is in progress
+	InProgress bool `json:"inProgress"`
+
+	// time that the most recent rolling restart was requested
+	LastRestartTime *metav1.Time `json:"lastRestartTime,omitempty"`
+}
+
+// IndexerClusterSiteStatus is used to track the status of each site within a multisite indexer cluster
+type IndexerClusterSiteStatus struct {
+	// Name of the site
+	Name string `json:"name"`
+
+	// current phase of the indexer peers for this site
+	Phase ResourcePhase `json:"phase"`
+
+	// desired number of indexer peers for this site
+	Replicas int32 `json:"replicas"`
+
+	// current number of ready indexer peers for this site
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// status of each indexer cluster peer within this site
+	Peers []IndexerClusterMemberStatus `json:"peers"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IndexerCluster is the Schema for a Splunk Enterprise indexer cluster
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:resource:path=indexerclusters,scope=Namespaced,shortName=idc;idxc
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Status of indexer cluster"
+// +kubebuilder:printcolumn:name="Master",type="string",JSONPath=".status.clusterMasterPhase",description="Status of cluster master"
+// +kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".status.replicas",description="Desired number of indexer peers"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas",description="Current number of ready indexer peers"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Age of indexer cluster"
+type IndexerCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IndexerClusterSpec   `json:"spec,omitempty"`
+	Status IndexerClusterStatus `json:"status,omitempty"`
+}
+
+// GetIdentifier is a convenience function to return unique identifier for the Splunk enterprise deployment
+func (cr *IndexerCluster) GetIdentifier() string {
+	return cr.ObjectMeta.Name
+}
+
+// GetNamespace is a convenience function to return namespace for a Splunk enterprise deployment
+func (cr *IndexerCluster) GetNamespace() string {
+	return cr.ObjectMeta.Namespace
+}
+
+// GetTypeMeta is a convenience function to return a TypeMeta object
+func (cr *IndexerCluster) GetTypeMeta() metav1.TypeMeta {
+	return cr.TypeMeta
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// IndexerClusterList contains a list of IndexerCluster
+type IndexerClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []IndexerCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&IndexerCluster{}, &IndexerClusterList{})
+}
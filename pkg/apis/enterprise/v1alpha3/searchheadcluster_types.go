@@ -0,0 +1,177 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// default all fields to being optional
+// +kubebuilder:validation:Optional
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
+// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
+// see also https://book.kubebuilder.io/reference/markers/crd.html
+
+// SearchHeadClusterSpec defines the desired state of a Splunk Enterprise search head cluster
+type SearchHeadClusterSpec struct {
+	CommonSplunkSpec `json:",inline"`
+
+	// Number of search head pods; a search head cluster will be created if > 1
+	Replicas int32 `json:"replicas"`
+
+	// Data Fabric Search (DFS) configuration
+	DFS DFSSpec `json:"dfs"`
+
+	// Name of a ConfigMap containing configuration files that the deployer pushes to all search head cluster members.
+	// Each key is the name of a file, such as savedsearches.conf, that is installed in
+	// shcluster/apps/splunk-operator-bundle/local. Changes are pushed without restarting the deployer.
+	BundleConfigMapRef string `json:"bundleConfigMapRef"`
+}
+
+// SearchHeadClusterMemberStatus is used to track the status of each search head cluster member
+type SearchHeadClusterMemberStatus struct {
+	// Name of the search head cluster member
+	Name string `json:"name"`
+
+	// Indicates the status of the member.
+	Status string `json:"status"`
+
+	// Flag that indicates if this member can run scheduled searches.
+	Adhoc bool `json:"adhocSearchhead"`
+
+	// Indicates if this member is registered with the searchhead cluster captain.
+	Registered bool `json:"registered"`
+
+	// Number of currently running historical searches.
+	ActiveHistoricalSearchCount int `json:"activeHistoricalSearchCount"`
+
+	// Number of currently running realtime searches.
+	ActiveRealtimeSearchCount int `json:"activeRealtimeSearchCount"`
+
+	// Indicates that the member could not be polled recently, so its status may be out of date.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// SearchHeadClusterBundleStatus is used to track pushes of the bundle ConfigMap from the deployer to search head cluster members
+type SearchHeadClusterBundleStatus struct {
+	// checksum of the bundle ConfigMap contents that were most recently pushed to the members
+	AppliedChecksum string `json:"appliedChecksum"`
+
+	// checksum of the bundle ConfigMap contents waiting to be pushed
+	PendingChecksum string `json:"pendingChecksum"`
+
+	// time that changes to the bundle ConfigMap contents were detected
+	ChangeDetectedTime *metav1.Time `json:"changeDetectedTime,omitempty"`
+
+	// true while the deployer is pushing the bundle to the captain
+	PushInProgress bool `json:"pushInProgress"`
+
+	// time that the most recent push was started
+	LastPushTime *metav1.Time `json:"lastPushTime,omitempty"`
+}
+
+// SearchHeadClusterStatus defines the observed state of a Splunk Enterprise search head cluster
+type SearchHeadClusterStatus struct {
+	// current phase of the search head cluster
+	Phase ResourcePhase `json:"phase"`
+
+	CommonStatus `json:",inline"`
+
+	// current phase of the deployer
+	DeployerPhase ResourcePhase `json:"deployerPhase"`
+
+	// desired number of search head cluster members
+	Replicas int32 `json:"replicas"`
+
+	// current number of ready search head cluster members
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// selector for pods, used by HorizontalPodAutoscaler
+	Selector string `json:"selector"`
+
+	// name or label of the search head captain
+	Captain string `json:"captain"`
+
+	// true if the search head cluster's captain is ready to service requests
+	CaptainReady bool `json:"captainReady"`
+
+	// true if the search head cluster has finished initialization
+	Initialized bool `json:"initialized"`
+
+	// true if the minimum number of search head cluster members have joined
+	MinPeersJoined bool `json:"minPeersJoined"`
+
+	// true if the search head cluster is in maintenance mode
+	MaintenanceMode bool `json:"maintenanceMode"`
+
+	// status of each search head cluster member
+	Members []SearchHeadClusterMemberStatus `json:"members"`
+
+	// status of app packages installed from the app repository
+	AppRepo AppRepoStatus `json:"appRepo"`
+
+	// status of the bundle ConfigMap that is pushed to members by the deployer
+	DeployerBundle SearchHeadClusterBundleStatus `json:"deployerBundle"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SearchHeadCluster is the Schema for a Splunk Enterprise search head cluster
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:resource:path=searchheadclusters,scope=Namespaced,shortName=shc
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Status of search head cluster"
+// +kubebuilder:printcolumn:name="Deployer",type="string",JSONPath=".status.deployerPhase",description="Status of the deployer"
+// +kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".status.replicas",description="Desired number of search head cluster members"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas",description="Current number of ready search head cluster members"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Age of search head cluster"
+type SearchHeadCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SearchHeadClusterSpec   `json:"spec,omitempty"`
+	Status SearchHeadClusterStatus `json:"status,omitempty"`
+}
+
+// GetIdentifier is a convenience function to return unique identifier for the Splunk enterprise deployment
+func (cr *SearchHeadCluster) GetIdentifier() string {
+	return cr.ObjectMeta.Name
+}
+
+// GetNamespace is a convenience function to return namespace for a Splunk enterprise deployment
+func (cr *SearchHeadCluster) GetNamespace() string {
+	return cr.ObjectMeta.Namespace
+}
+
+// GetTypeMeta is a convenience function to return a TypeMeta object
+func (cr *SearchHeadCluster) GetTypeMeta() metav1.TypeMeta {
+	return cr.TypeMeta
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// SearchHeadClusterList contains a list of SearcHead
+type SearchHeadClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SearchHeadCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SearchHeadCluster{}, &SearchHeadClusterList{})
+}
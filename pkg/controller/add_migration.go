@@ -0,0 +1,10 @@
+package controller
+
+import (
+	"github.com/splunk/splunk-operator/pkg/controller/migration"
+)
+
+func init() {
+	// AddToManagerFuncs is a list of functions to create controllers and add them to a manager.
+	AddToManagerFuncs = append(AddToManagerFuncs, migration.Add)
+}
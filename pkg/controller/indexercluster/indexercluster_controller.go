@@ -18,8 +18,10 @@ import (
 	"context"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -28,7 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	splunkreconcile "github.com/splunk/splunk-operator/pkg/splunk/reconcile"
 )
 
@@ -52,14 +54,14 @@ func Add(mgr manager.Manager) error {
 		return err
 	}
 	reconciler := ReconcileIndexerCluster{
-		client: client,
+		client: splunkreconcile.NewControllerClient(client, mgr.GetEventRecorderFor("splunk-operator")),
 		scheme: mgr.GetScheme(),
 	}
 	return add(mgr, &reconciler)
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r *ReconcileIndexerCluster) error {
 	// Create a new controller
 	c, err := controller.New("indexer-controller", mgr, controller.Options{Reconciler: r})
 	if err != nil {
@@ -81,6 +83,40 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to ClusterMasters, and requeue every IndexerCluster that references them
+	err = c.Watch(&source.Kind{Type: &enterprisev1.ClusterMaster{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.getIndexerClusterRequests),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the secrets of LicenseMasters, and requeue every IndexerCluster that borrows their pass4SymmKey
+	err = c.Watch(&source.Kind{Type: &enterprisev1.LicenseMaster{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(r.getSecretsSourceRequests),
+	}, splunkreconcile.SecretsVersionChanged)
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to Secrets and ConfigMaps that are owned by an IndexerCluster or used by its volumes, and requeue
+	// it so that pods are recycled whenever their contents change
+	for _, obj := range []runtime.Object{&corev1.Secret{}, &corev1.ConfigMap{}} {
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &enterprisev1.IndexerCluster{},
+		})
+		if err != nil {
+			return err
+		}
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.getVolumeSourceRequests),
+		}, splunkreconcile.ConfigDataChanged)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -91,10 +127,77 @@ var _ reconcile.Reconciler = &ReconcileIndexerCluster{}
 type ReconcileIndexerCluster struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
+	client splunkreconcile.ControllerClient
 	scheme *runtime.Scheme
 }
 
+// getVolumeSourceRequests returns reconcile requests for every IndexerCluster in the same namespace that uses a changed Secret
+// or ConfigMap as a volume, or a changed Secret as its secretRef
+func (r *ReconcileIndexerCluster) getVolumeSourceRequests(a handler.MapObject) []reconcile.Request {
+	var list enterprisev1.IndexerClusterList
+	err := r.client.List(context.TODO(), &list, client.InNamespace(a.Meta.GetNamespace()))
+	if err != nil {
+		log.Error(err, "Unable to list IndexerClusters")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, item := range list.Items {
+		if splunkreconcile.IsVolumeSource(&item.Spec.CommonSplunkSpec, a.Object, a.Meta.GetName()) ||
+			splunkreconcile.IsSecretRef(&item, &item.Spec.CommonSplunkSpec, a.Object, a.Meta.GetNamespace(), a.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: item.GetName(), Namespace: item.GetNamespace()},
+			})
+		}
+	}
+	return requests
+}
+
+// getIndexerClusterRequests returns reconcile requests for every IndexerCluster in the same namespace that references a changed ClusterMaster
+func (r *ReconcileIndexerCluster) getIndexerClusterRequests(a handler.MapObject) []reconcile.Request {
+	var idxcList enterprisev1.IndexerClusterList
+	err := r.client.List(context.TODO(), &idxcList, client.InNamespace(a.Meta.GetNamespace()))
+	if err != nil {
+		log.Error(err, "Unable to list IndexerClusters", "Namespace", a.Meta.GetNamespace())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, idxc := range idxcList.Items {
+		ref := idxc.Spec.ClusterMasterRef
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = idxc.GetNamespace()
+		}
+		if ref.Name == a.Meta.GetName() && namespace == a.Meta.GetNamespace() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: idxc.GetName(), Namespace: idxc.GetNamespace()},
+			})
+		}
+	}
+	return requests
+}
+
+// getSecretsSourceRequests returns reconcile requests for every IndexerCluster that references a LicenseMaster whose secrets have changed
+func (r *ReconcileIndexerCluster) getSecretsSourceRequests(a handler.MapObject) []reconcile.Request {
+	var list enterprisev1.IndexerClusterList
+	err := r.client.List(context.TODO(), &list)
+	if err != nil {
+		log.Error(err, "Unable to list IndexerClusters")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, item := range list.Items {
+		if splunkreconcile.IsSecretsSource(&item, &item.Spec.CommonSplunkSpec, a.Object, a.Meta.GetNamespace(), a.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: item.GetName(), Namespace: item.GetNamespace()},
+			})
+		}
+	}
+	return requests
+}
+
 // Reconcile reads that state of the cluster for a IndexerCluster object and makes changes based on the state read
 // and what is in the IndexerCluster.Spec
 // TODO(user): Modify this Reconcile function to implement your Controller logic.  This example creates
@@ -120,9 +223,14 @@ func (r *ReconcileIndexerCluster) Reconcile(request reconcile.Request) (reconcil
 		return reconcile.Result{}, err
 	}
 
-	instance.TypeMeta.APIVersion = "enterprise.splunk.com/v1alpha2"
+	instance.TypeMeta.APIVersion = "enterprise.splunk.com/v1alpha3"
 	instance.TypeMeta.Kind = "IndexerCluster"
 
+	// paused custom resources only have their status refreshed
+	if splunkreconcile.IsPaused(instance) {
+		reqLogger.Info("IndexerCluster reconciliation is paused", "Annotation", enterprisev1.PausedAnnotation)
+	}
+
 	result, err := splunkreconcile.ApplyIndexerCluster(r.client, instance)
 	if err != nil {
 		reqLogger.Error(err, "IndexerCluster reconciliation requeued", "RequeueAfter", result.RequeueAfter)
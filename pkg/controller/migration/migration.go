@@ -0,0 +1,118 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+var log = logf.Log.WithName("storage_version_migration")
+
+// Add creates a storage version migrator and adds it to the Manager. The Manager will Start it after the operator
+// has become the leader, so that custom resources created using older API versions are rewritten using the
+// current storage version (v1alpha3).
+func Add(mgr manager.Manager) error {
+	// Use a new go client to work-around issues with the operator sdk design (see controllers)
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return err
+	}
+	namespace, err := k8sutil.GetWatchNamespace()
+	if err != nil {
+		return err
+	}
+	return mgr.Add(&storageVersionMigrator{client: c, namespace: namespace})
+}
+
+// storageVersionMigrator rewrites all enterprise.splunk.com custom resources using the storage version
+type storageVersionMigrator struct {
+	client    client.Client
+	namespace string
+}
+
+// Start runs the storage version migration once, and returns when it has finished
+func (m *storageVersionMigrator) Start(stop <-chan struct{}) error {
+	count, err := MigrateStorageVersion(m.client, m.namespace)
+	if err != nil {
+		// this is retried by the next operator restart; the conversion webhook continues to serve older versions until then
+		log.Error(err, "Storage version migration failed", "migrated", count)
+		return nil
+	}
+	log.Info("Storage version migration complete", "migrated", count)
+	return nil
+}
+
+// getCustomResourceLists returns an empty list for each kind of enterprise.splunk.com custom resource
+func getCustomResourceLists() []runtime.Object {
+	return []runtime.Object{
+		&enterprisev1.StandaloneList{},
+		&enterprisev1.LicenseMasterList{},
+		&enterprisev1.SearchHeadClusterList{},
+		&enterprisev1.ClusterMasterList{},
+		&enterprisev1.IndexerClusterList{},
+		&enterprisev1.MonitoringConsoleList{},
+		&enterprisev1.SparkList{},
+	}
+}
+
+// MigrateStorageVersion rewrites every enterprise.splunk.com custom resource in a namespace (or all namespaces, if
+// empty) without changing it. The API server stores each one using the current storage version, converting it
+// from the version it was stored as using the conversion webhook. This allows older versions to be removed
+// from the status.storedVersions of each CustomResourceDefinition. It returns the number of custom resources updated.
+// Custom resources that cannot be updated (e.g. because a webhook denies the update) are logged and skipped, so that
+// the rest are still migrated; an error reporting how many were skipped is returned once all have been tried.
+func MigrateStorageVersion(c client.Client, namespace string) (int, error) {
+	count, failed := 0, 0
+	for _, list := range getCustomResourceLists() {
+		err := c.List(context.TODO(), list, client.InNamespace(namespace))
+		if err != nil {
+			return count, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return count, err
+		}
+		for _, item := range items {
+			err = c.Update(context.TODO(), item)
+			if err != nil {
+				// ignore custom resources that were deleted or updated since they were listed
+				if errors.IsNotFound(err) || errors.IsConflict(err) {
+					continue
+				}
+				accessor, _ := meta.Accessor(item)
+				log.Error(err, "Unable to migrate custom resource", "kind", fmt.Sprintf("%T", item),
+					"name", accessor.GetName(), "namespace", accessor.GetNamespace())
+				failed++
+				continue
+			}
+			count++
+		}
+	}
+	if failed > 0 {
+		return count, fmt.Errorf("Unable to migrate %d custom resources", failed)
+	}
+	return count, nil
+}
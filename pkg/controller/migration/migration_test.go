@@ -0,0 +1,93 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migration
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+func TestMigrateStorageVersion(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := enterprisev1.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() returned %v", err)
+	}
+	c := fake.NewFakeClientWithScheme(scheme,
+		&enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}},
+		&enterprisev1.ClusterMaster{ObjectMeta: metav1.ObjectMeta{Name: "master1", Namespace: "test"}},
+		&enterprisev1.IndexerCluster{ObjectMeta: metav1.ObjectMeta{Name: "idxc1", Namespace: "test"}},
+		&enterprisev1.Spark{ObjectMeta: metav1.ObjectMeta{Name: "spark1", Namespace: "other"}},
+	)
+
+	test := func(namespace string, want int) {
+		got, err := MigrateStorageVersion(c, namespace)
+		if err != nil {
+			t.Errorf("MigrateStorageVersion(%s) returned %v", namespace, err)
+		}
+		if got != want {
+			t.Errorf("MigrateStorageVersion(%s) = %d; want %d", namespace, got, want)
+		}
+	}
+
+	test("test", 3)
+	test("other", 1)
+	test("", 4)
+	test("empty", 0)
+}
+
+// denyingClient is used to emulate a webhook that denies updates to custom resources with a given name
+type denyingClient struct {
+	client.Client
+	name string
+}
+
+func (c denyingClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if cr, ok := obj.(metav1.Object); ok && cr.GetName() == c.name {
+		return errors.New("admission webhook denied the request")
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestMigrateStorageVersionSkipsFailures(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := enterprisev1.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() returned %v", err)
+	}
+	c := denyingClient{
+		Client: fake.NewFakeClientWithScheme(scheme,
+			&enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}},
+			&enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack2", Namespace: "test"}},
+			&enterprisev1.ClusterMaster{ObjectMeta: metav1.ObjectMeta{Name: "master1", Namespace: "test"}},
+		),
+		name: "stack1",
+	}
+
+	got, err := MigrateStorageVersion(c, "test")
+	if err == nil || !strings.Contains(err.Error(), "Unable to migrate 1 custom resources") {
+		t.Errorf("MigrateStorageVersion() returned %v; want Unable to migrate 1 custom resources", err)
+	}
+	if got != 2 {
+		t.Errorf("MigrateStorageVersion() = %d; want 2", got)
+	}
+}
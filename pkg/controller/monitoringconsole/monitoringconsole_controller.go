@@ -0,0 +1,243 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoringconsole
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splunkreconcile "github.com/splunk/splunk-operator/pkg/splunk/reconcile"
+)
+
+var log = logf.Log.WithName("controller_monitoringconsole")
+
+// Add creates a new MonitoringConsole Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	// Use a new go client to work-around issues with the operator sdk design.
+	// If WATCH_NAMESPACE is empty for monitoring cluster-wide custom Splunk resources,
+	// the default caching client will attempt to list all resources in all namespaces for
+	// any get requests, even if the request is namespace-scoped.
+	options := client.Options{}
+	client, err := client.New(mgr.GetConfig(), options)
+	if err != nil {
+		return err
+	}
+	reconciler := ReconcileMonitoringConsole{
+		client: splunkreconcile.NewControllerClient(client, mgr.GetEventRecorderFor("splunk-operator")),
+		scheme: mgr.GetScheme(),
+	}
+	return add(mgr, &reconciler)
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r *ReconcileMonitoringConsole) error {
+	// Create a new controller
+	c, err := controller.New("monitoringconsole-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource MonitoringConsole
+	err = c.Watch(&source.Kind{Type: &enterprisev1.MonitoringConsole{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to secondary resource StatefulSets and requeue the owner MonitoringConsole
+	err = c.Watch(&source.Kind{Type: &appsv1.StatefulSet{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &enterprisev1.MonitoringConsole{},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to all other Splunk Enterprise resources, and requeue every MonitoringConsole in the same namespace
+	// so that its search peers are kept in sync
+	mapper := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.getMonitoringConsoleRequests)}
+	for _, obj := range []runtime.Object{
+		&enterprisev1.Standalone{},
+		&enterprisev1.LicenseMaster{},
+		&enterprisev1.SearchHeadCluster{},
+		&enterprisev1.ClusterMaster{},
+		&enterprisev1.IndexerCluster{},
+	} {
+		err = c.Watch(&source.Kind{Type: obj}, mapper)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for changes to the secrets of LicenseMasters and ClusterMasters, and requeue every MonitoringConsole that borrows
+	// their pass4SymmKey or idxc_secret
+	for _, obj := range []runtime.Object{&enterprisev1.LicenseMaster{}, &enterprisev1.ClusterMaster{}} {
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.getSecretsSourceRequests),
+		}, splunkreconcile.SecretsVersionChanged)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for changes to Secrets and ConfigMaps that are owned by a MonitoringConsole or used by its volumes, and requeue
+	// it so that pods are recycled whenever their contents change
+	for _, obj := range []runtime.Object{&corev1.Secret{}, &corev1.ConfigMap{}} {
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &enterprisev1.MonitoringConsole{},
+		})
+		if err != nil {
+			return err
+		}
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.getVolumeSourceRequests),
+		}, splunkreconcile.ConfigDataChanged)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// blank assignment to verify that ReconcileMonitoringConsole implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileMonitoringConsole{}
+
+// ReconcileMonitoringConsole reconciles a MonitoringConsole object
+type ReconcileMonitoringConsole struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client splunkreconcile.ControllerClient
+	scheme *runtime.Scheme
+}
+
+// getVolumeSourceRequests returns reconcile requests for every MonitoringConsole in the same namespace that uses a changed Secret
+// or ConfigMap as a volume, or a changed Secret as its secretRef
+func (r *ReconcileMonitoringConsole) getVolumeSourceRequests(a handler.MapObject) []reconcile.Request {
+	var list enterprisev1.MonitoringConsoleList
+	err := r.client.List(context.TODO(), &list, client.InNamespace(a.Meta.GetNamespace()))
+	if err != nil {
+		log.Error(err, "Unable to list MonitoringConsoles")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, item := range list.Items {
+		if splunkreconcile.IsVolumeSource(&item.Spec.CommonSplunkSpec, a.Object, a.Meta.GetName()) ||
+			splunkreconcile.IsSecretRef(&item, &item.Spec.CommonSplunkSpec, a.Object, a.Meta.GetNamespace(), a.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: item.GetName(), Namespace: item.GetNamespace()},
+			})
+		}
+	}
+	return requests
+}
+
+// getMonitoringConsoleRequests returns reconcile requests for every MonitoringConsole in the same namespace as a changed object
+func (r *ReconcileMonitoringConsole) getMonitoringConsoleRequests(a handler.MapObject) []reconcile.Request {
+	var mcList enterprisev1.MonitoringConsoleList
+	err := r.client.List(context.TODO(), &mcList, client.InNamespace(a.Meta.GetNamespace()))
+	if err != nil {
+		log.Error(err, "Unable to list MonitoringConsoles", "Namespace", a.Meta.GetNamespace())
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, mc := range mcList.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: mc.GetNamespace(), Name: mc.GetName()},
+		})
+	}
+	return requests
+}
+
+// getSecretsSourceRequests returns reconcile requests for every MonitoringConsole that references a LicenseMaster or ClusterMaster whose secrets have changed
+func (r *ReconcileMonitoringConsole) getSecretsSourceRequests(a handler.MapObject) []reconcile.Request {
+	var list enterprisev1.MonitoringConsoleList
+	err := r.client.List(context.TODO(), &list)
+	if err != nil {
+		log.Error(err, "Unable to list MonitoringConsoles")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, item := range list.Items {
+		if splunkreconcile.IsSecretsSource(&item, &item.Spec.CommonSplunkSpec, a.Object, a.Meta.GetNamespace(), a.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: item.GetName(), Namespace: item.GetNamespace()},
+			})
+		}
+	}
+	return requests
+}
+
+// Reconcile reads that state of the cluster for a MonitoringConsole object and makes changes based on the state read
+// and what is in the MonitoringConsole.Spec
+// Note:
+// The Controller will requeue the Request to be processed again if the returned error is non-nil or
+// Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
+func (r *ReconcileMonitoringConsole) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.Info("Reconciling MonitoringConsole")
+
+	// Fetch the MonitoringConsole instance
+	instance := &enterprisev1.MonitoringConsole{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		return reconcile.Result{}, err
+	}
+
+	instance.TypeMeta.APIVersion = "enterprise.splunk.com/v1alpha3"
+	instance.TypeMeta.Kind = "MonitoringConsole"
+
+	// paused custom resources only have their status refreshed
+	if splunkreconcile.IsPaused(instance) {
+		reqLogger.Info("MonitoringConsole reconciliation is paused", "Annotation", enterprisev1.PausedAnnotation)
+	}
+
+	result, err := splunkreconcile.ApplyMonitoringConsole(r.client, instance)
+	if err != nil {
+		reqLogger.Error(err, "MonitoringConsole reconciliation requeued", "RequeueAfter", result.RequeueAfter)
+		return result, nil
+	}
+	if result.Requeue {
+		reqLogger.Info("MonitoringConsole reconciliation requeued", "RequeueAfter", result.RequeueAfter)
+		return result, nil
+	}
+
+	reqLogger.Info("MonitoringConsole reconciliation complete")
+	return reconcile.Result{}, nil
+}
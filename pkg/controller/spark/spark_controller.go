@@ -28,7 +28,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	splunkreconcile "github.com/splunk/splunk-operator/pkg/splunk/reconcile"
 )
 
@@ -52,7 +52,7 @@ func Add(mgr manager.Manager) error {
 		return err
 	}
 	reconciler := ReconcileSpark{
-		client: client,
+		client: splunkreconcile.NewControllerClient(client, mgr.GetEventRecorderFor("splunk-operator")),
 		scheme: mgr.GetScheme(),
 	}
 	return add(mgr, &reconciler)
@@ -91,7 +91,7 @@ var _ reconcile.Reconciler = &ReconcileSpark{}
 type ReconcileSpark struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
+	client splunkreconcile.ControllerClient
 	scheme *runtime.Scheme
 }
 
@@ -120,9 +120,14 @@ func (r *ReconcileSpark) Reconcile(request reconcile.Request) (reconcile.Result,
 		return reconcile.Result{}, err
 	}
 
-	instance.TypeMeta.APIVersion = "enterprise.splunk.com/v1alpha2"
+	instance.TypeMeta.APIVersion = "enterprise.splunk.com/v1alpha3"
 	instance.TypeMeta.Kind = "Spark"
 
+	// paused custom resources only have their status refreshed
+	if splunkreconcile.IsPaused(instance) {
+		reqLogger.Info("Spark reconciliation is paused", "Annotation", enterprisev1.PausedAnnotation)
+	}
+
 	result, err := splunkreconcile.ApplySpark(r.client, instance)
 	if err != nil {
 		reqLogger.Error(err, "Spark reconciliation requeued", "RequeueAfter", result.RequeueAfter)
@@ -18,8 +18,10 @@ import (
 	"context"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -28,7 +30,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	splunkreconcile "github.com/splunk/splunk-operator/pkg/splunk/reconcile"
 )
 
@@ -52,14 +54,14 @@ func Add(mgr manager.Manager) error {
 		return err
 	}
 	reconciler := ReconcileStandalone{
-		client: client,
+		client: splunkreconcile.NewControllerClient(client, mgr.GetEventRecorderFor("splunk-operator")),
 		scheme: mgr.GetScheme(),
 	}
 	return add(mgr, &reconciler)
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r *ReconcileStandalone) error {
 	// Create a new controller
 	c, err := controller.New("standalone-controller", mgr, controller.Options{Reconciler: r})
 	if err != nil {
@@ -81,6 +83,35 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to the secrets of LicenseMasters and ClusterMasters, and requeue every Standalone that borrows
+	// their pass4SymmKey or idxc_secret
+	for _, obj := range []runtime.Object{&enterprisev1.LicenseMaster{}, &enterprisev1.ClusterMaster{}} {
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.getSecretsSourceRequests),
+		}, splunkreconcile.SecretsVersionChanged)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for changes to Secrets and ConfigMaps that are owned by a Standalone or used by its volumes, and requeue
+	// it so that pods are recycled whenever their contents change
+	for _, obj := range []runtime.Object{&corev1.Secret{}, &corev1.ConfigMap{}} {
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForOwner{
+			IsController: true,
+			OwnerType:    &enterprisev1.Standalone{},
+		})
+		if err != nil {
+			return err
+		}
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.getVolumeSourceRequests),
+		}, splunkreconcile.ConfigDataChanged)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -91,10 +122,52 @@ var _ reconcile.Reconciler = &ReconcileStandalone{}
 type ReconcileStandalone struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client client.Client
+	client splunkreconcile.ControllerClient
 	scheme *runtime.Scheme
 }
 
+// getVolumeSourceRequests returns reconcile requests for every Standalone in the same namespace that uses a changed Secret
+// or ConfigMap as a volume, or a changed Secret as its secretRef
+func (r *ReconcileStandalone) getVolumeSourceRequests(a handler.MapObject) []reconcile.Request {
+	var list enterprisev1.StandaloneList
+	err := r.client.List(context.TODO(), &list, client.InNamespace(a.Meta.GetNamespace()))
+	if err != nil {
+		log.Error(err, "Unable to list Standalones")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, item := range list.Items {
+		if splunkreconcile.IsVolumeSource(&item.Spec.CommonSplunkSpec, a.Object, a.Meta.GetName()) ||
+			splunkreconcile.IsSecretRef(&item, &item.Spec.CommonSplunkSpec, a.Object, a.Meta.GetNamespace(), a.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: item.GetName(), Namespace: item.GetNamespace()},
+			})
+		}
+	}
+	return requests
+}
+
+// getSecretsSourceRequests returns reconcile requests for every Standalone that references a LicenseMaster or ClusterMaster whose secrets have changed
+func (r *ReconcileStandalone) getSecretsSourceRequests(a handler.MapObject) []reconcile.Request {
+	var list enterprisev1.StandaloneList
+	err := r.client.List(context.TODO(), &list)
+	if err != nil {
+		log.Error(err, "Unable to list Standalones")
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	for _, item := range list.Items {
+		if splunkreconcile.IsSecretsSource(&item, &item.Spec.CommonSplunkSpec, a.Object, a.Meta.GetNamespace(), a.Meta.GetName()) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: item.GetName(), Namespace: item.GetNamespace()},
+			})
+		}
+	}
+	return requests
+}
+
 // Reconcile reads that state of the cluster for a Standalone object and makes changes based on the state read
 // and what is in the Standalone.Spec
 // TODO(user): Modify this Reconcile function to implement your Controller logic.  This example creates
@@ -120,9 +193,14 @@ func (r *ReconcileStandalone) Reconcile(request reconcile.Request) (reconcile.Re
 		return reconcile.Result{}, err
 	}
 
-	instance.TypeMeta.APIVersion = "enterprise.splunk.com/v1alpha2"
+	instance.TypeMeta.APIVersion = "enterprise.splunk.com/v1alpha3"
 	instance.TypeMeta.Kind = "Standalone"
 
+	// paused custom resources only have their status refreshed
+	if splunkreconcile.IsPaused(instance) {
+		reqLogger.Info("Standalone reconciliation is paused", "Annotation", enterprisev1.PausedAnnotation)
+	}
+
 	result, err := splunkreconcile.ApplyStandalone(r.client, instance)
 	if err != nil {
 		reqLogger.Error(err, "Standalone reconciliation requeued", "RequeueAfter", result.RequeueAfter)
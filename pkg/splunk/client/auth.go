@@ -0,0 +1,153 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidCredentials is returned when Splunk rejects the username and password used to log in
+var ErrInvalidCredentials = errors.New("Invalid username or password")
+
+// Authenticator is used by SplunkClient to add credentials to each REST API request
+type Authenticator interface {
+	// Authenticate adds credentials to a request that is about to be sent by c
+	Authenticate(c *SplunkClient, request *http.Request) error
+}
+
+// BasicAuthenticator authenticates requests using HTTP basic authentication
+type BasicAuthenticator struct {
+	Username string
+	Password string
+}
+
+// Authenticate for BasicAuthenticator adds the username and password to a request
+func (a *BasicAuthenticator) Authenticate(c *SplunkClient, request *http.Request) error {
+	request.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// TokenAuthenticator authenticates requests using a Splunk authentication token.
+// See https://docs.splunk.com/Documentation/Splunk/latest/Security/UseAuthTokens
+type TokenAuthenticator struct {
+	Token string
+}
+
+// Authenticate for TokenAuthenticator adds the token to a request
+func (a *TokenAuthenticator) Authenticate(c *SplunkClient, request *http.Request) error {
+	request.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+// SessionKeyAuthenticator authenticates requests using a session key, which is obtained by logging in with a username
+// and password. The session key may be shared by many clients, and is refreshed automatically when it expires.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTUM/RESTusing#Authentication_and_authorization
+type SessionKeyAuthenticator struct {
+	Username string
+	Password string
+
+	mutex      sync.Mutex
+	sessionKey string
+}
+
+// Authenticate for SessionKeyAuthenticator adds the session key to a request, logging in first if necessary
+func (a *SessionKeyAuthenticator) Authenticate(c *SplunkClient, request *http.Request) error {
+	sessionKey, err := a.getSessionKey(request.Context(), c)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Splunk "+sessionKey)
+	return nil
+}
+
+// Login for SessionKeyAuthenticator logs in using c to obtain a session key, unless it already has one. Returns
+// ErrInvalidCredentials if the username or password were rejected.
+func (a *SessionKeyAuthenticator) Login(c *SplunkClient) error {
+	return a.LoginContext(context.Background(), c)
+}
+
+// LoginContext is like Login, but the request is cancelled when ctx is done
+func (a *SessionKeyAuthenticator) LoginContext(ctx context.Context, c *SplunkClient) error {
+	_, err := a.getSessionKey(ctx, c)
+	return err
+}
+
+// Expire for SessionKeyAuthenticator discards a session key that is no longer accepted, so that the next request
+// logs in again. Nothing changes if the session key has already been refreshed by another request.
+func (a *SessionKeyAuthenticator) Expire(request *http.Request) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if request.Header.Get("Authorization") == "Splunk "+a.sessionKey {
+		a.sessionKey = ""
+	}
+}
+
+// getSessionKey for SessionKeyAuthenticator returns the current session key, logging in to obtain one if necessary
+func (a *SessionKeyAuthenticator) getSessionKey(ctx context.Context, c *SplunkClient) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.sessionKey != "" {
+		return a.sessionKey, nil
+	}
+
+	endpoint := fmt.Sprintf("%s/services/auth/login", c.ManagementURI)
+	form := url.Values{
+		"username":    {a.Username},
+		"password":    {a.Password},
+		"output_mode": {"json"},
+	}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := c.Client.Do(request.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer closeResponse(response)
+	var data []byte
+	if response.Body != nil {
+		if data, err = ioutil.ReadAll(response.Body); err != nil {
+			return "", fmt.Errorf("Unable to read response from %s: %v", request.URL, err)
+		}
+	}
+	if response.StatusCode == 401 {
+		return "", ErrInvalidCredentials
+	}
+	if response.StatusCode != 200 {
+		return "", newResponseError(request.URL.String(), response.StatusCode, 200, data)
+	}
+
+	apiResponse := struct {
+		SessionKey string `json:"sessionKey"`
+	}{}
+	if err = json.Unmarshal(data, &apiResponse); err != nil {
+		return "", err
+	}
+	if apiResponse.SessionKey == "" {
+		return "", fmt.Errorf("Received empty session key from %s", request.URL)
+	}
+	a.sessionKey = apiResponse.SessionKey
+	return a.sessionKey, nil
+}
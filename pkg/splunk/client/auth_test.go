@@ -0,0 +1,154 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasicAuthenticator(t *testing.T) {
+	request, _ := http.NewRequest("GET", "https://localhost:8089/services/server/info", nil)
+	auth := &BasicAuthenticator{Username: "splunk-operator", Password: "p@ssw0rd"}
+	if err := auth.Authenticate(nil, request); err != nil {
+		t.Errorf("BasicAuthenticator.Authenticate() returned %v; want nil", err)
+	}
+	username, password, ok := request.BasicAuth()
+	if !ok || username != "splunk-operator" || password != "p@ssw0rd" {
+		t.Errorf("BasicAuthenticator.Authenticate() credentials = %s:%s; want splunk-operator:p@ssw0rd", username, password)
+	}
+
+	// basic authentication is used by default
+	c := NewSplunkClient("https://localhost:8089", "admin", "p@ssw0rd")
+	request, _ = http.NewRequest("GET", "https://localhost:8089/services/server/info", nil)
+	c.Client = &headerRecorder{}
+	c.Do(request, 200, nil)
+	if username, password, _ = request.BasicAuth(); username != "admin" || password != "p@ssw0rd" {
+		t.Errorf("SplunkClient.Do() credentials = %s:%s; want admin:p@ssw0rd", username, password)
+	}
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	c := NewSplunkClient("https://localhost:8089", "", "")
+	c.Authenticator = &TokenAuthenticator{Token: "t0k3n"}
+	recorder := &headerRecorder{}
+	c.Client = recorder
+	request, _ := http.NewRequest("GET", "https://localhost:8089/services/server/info", nil)
+	if err := c.Do(request, 200, nil); err != nil {
+		t.Errorf("SplunkClient.Do() returned %v; want nil", err)
+	}
+	if got := recorder.headers[0]; got != "Bearer t0k3n" {
+		t.Errorf("TokenAuthenticator.Authenticate() Authorization = %s; want Bearer t0k3n", got)
+	}
+}
+
+func TestSessionKeyAuthenticator(t *testing.T) {
+	// the server issues a new session key for each login, and only accepts the most recent one
+	logins := 0
+	var requests []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/auth/login" {
+			r.ParseForm()
+			if r.Form.Get("username") != "splunk-operator" || r.Form.Get("password") != "p@ssw0rd" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			logins++
+			fmt.Fprintf(w, `{"sessionKey":"key%d"}`, logins)
+			return
+		}
+		r.ParseForm()
+		requests = append(requests, fmt.Sprintf("%s %s", r.Header.Get("Authorization"), r.Form.Get("name")))
+		if r.Header.Get("Authorization") != fmt.Sprintf("Splunk key%d", logins) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	auth := &SessionKeyAuthenticator{Username: "splunk-operator", Password: "p@ssw0rd"}
+	c := NewSplunkClient(server.URL, "", "")
+	c.Authenticator = auth
+	post := func() error {
+		request, _ := http.NewRequest("POST", server.URL+"/services/test", strings.NewReader("name=value"))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return c.Do(request, 200, nil)
+	}
+
+	// logs in once, and reuses the session key
+	if err := post(); err != nil {
+		t.Errorf("SplunkClient.Do() returned %v; want nil", err)
+	}
+	if err := auth.Login(c); err != nil {
+		t.Errorf("SessionKeyAuthenticator.Login() returned %v; want nil", err)
+	}
+	if err := post(); err != nil || logins != 1 {
+		t.Errorf("SplunkClient.Do() returned %v after %d logins; want nil after 1", err, logins)
+	}
+
+	// session key is shared with other clients, and refreshed when it expires
+	logins++
+	other := NewSplunkClient(server.URL, "", "")
+	other.Authenticator = auth
+	if err := other.Get("/services/server/info", nil); err != nil || logins != 3 {
+		t.Errorf("SplunkClient.Get() returned %v after %d logins; want nil after 3", err, logins)
+	}
+	if err := post(); err != nil || logins != 3 {
+		t.Errorf("SplunkClient.Do() returned %v after %d logins; want nil after 3", err, logins)
+	}
+
+	// request body is sent again when retried
+	logins++
+	if err := post(); err != nil {
+		t.Errorf("SplunkClient.Do() returned %v; want nil", err)
+	}
+	if got := requests[len(requests)-1]; got != "Splunk key5 value" {
+		t.Errorf("SplunkClient.Do() retried request = %s; want Splunk key5 value", got)
+	}
+
+	// invalid credentials are reported
+	auth = &SessionKeyAuthenticator{Username: "splunk-operator", Password: "wrong"}
+	c.Authenticator = auth
+	if err := auth.Login(c); err != ErrInvalidCredentials {
+		t.Errorf("SessionKeyAuthenticator.Login() returned %v; want %v", err, ErrInvalidCredentials)
+	}
+	if err := post(); err != ErrInvalidCredentials {
+		t.Errorf("SplunkClient.Do() returned %v; want %v", err, ErrInvalidCredentials)
+	}
+
+	// login is cancelled when the context is done
+	auth = &SessionKeyAuthenticator{Username: "splunk-operator", Password: "p@ssw0rd"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := auth.LoginContext(ctx, c); err == nil || logins != 5 {
+		t.Errorf("SessionKeyAuthenticator.LoginContext() returned %v after %d logins; want error after 5", err, logins)
+	}
+}
+
+// headerRecorder is used to record the Authorization header of requests
+type headerRecorder struct {
+	headers []string
+}
+
+// Do for headerRecorder records the Authorization header, and returns an empty response
+func (r *headerRecorder) Do(request *http.Request) (*http.Response, error) {
+	r.headers = append(r.headers, request.Header.Get("Authorization"))
+	return &http.Response{StatusCode: 200, Body: http.NoBody}, nil
+}
@@ -15,12 +15,18 @@
 package client
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -41,6 +47,13 @@ type SplunkClient struct {
 	// password for authentication
 	Password string
 
+	// Authenticator used to add credentials to requests (optional, defaults to basic authentication using Username
+	// and Password)
+	Authenticator Authenticator
+
+	// Retry is used to retry GET requests that fail because an instance is unreachable or not ready
+	Retry RetryPolicy
+
 	// HTTP client used to process requests
 	Client SplunkHTTPClient
 }
@@ -51,6 +64,7 @@ func NewSplunkClient(managementURI, username, password string) *SplunkClient {
 		ManagementURI: managementURI,
 		Username:      username,
 		Password:      password,
+		Retry:         DefaultRetryPolicy,
 		Client: &http.Client{
 			Timeout: 5 * time.Second,
 			Transport: &http.Transport{
@@ -60,37 +74,127 @@ func NewSplunkClient(managementURI, username, password string) *SplunkClient {
 	}
 }
 
+// NewVerifiedSplunkClient returns a new SplunkClient object initialized with a username and password, which verifies
+// the certificates of Splunk instances using the CA certificates in rootCAs.
+func NewVerifiedSplunkClient(managementURI, username, password string, rootCAs *x509.CertPool) *SplunkClient {
+	return &SplunkClient{
+		ManagementURI: managementURI,
+		Username:      username,
+		Password:      password,
+		Retry:         DefaultRetryPolicy,
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: rootCAs},
+			},
+		},
+	}
+}
+
 // Do processes a Splunk REST API request and unmarshals response into obj, if not nil.
 func (c *SplunkClient) Do(request *http.Request, expectedStatus int, obj interface{}) error {
+	return c.DoContext(context.Background(), request, expectedStatus, obj)
+}
+
+// DoContext is like Do, but the request is cancelled when ctx is done. GET requests that fail because the instance
+// is unreachable or not ready are retried using the client's RetryPolicy. Requests that time out are only retried if
+// ctx has a deadline that leaves time for another attempt, so that an unresponsive instance cannot block callers for
+// several client timeouts.
+func (c *SplunkClient) DoContext(ctx context.Context, request *http.Request, expectedStatus int, obj interface{}) error {
+	request = request.WithContext(ctx)
+	attempts := 1
+	if request.Method == "GET" && c.Retry.MaxAttempts > 1 {
+		attempts = c.Retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		err := c.do(request, expectedStatus, obj)
+		if err == nil || attempt >= attempts || !IsNotReady(err) || ctx.Err() != nil {
+			return err
+		}
+		backoff := c.Retry.getBackoff(attempt)
+		if isTimeout(err) && !hasBudget(ctx, backoff) {
+			return err
+		}
+		if err = sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+	}
+}
+
+// do sends a request once, logging in again if a session key has expired, and unmarshals response into obj, if not nil
+func (c *SplunkClient) do(request *http.Request, expectedStatus int, obj interface{}) error {
 	// send HTTP response and check status
-	request.SetBasicAuth(c.Username, c.Password)
-	response, err := c.Client.Do(request)
+	response, err := c.send(request)
 	if err != nil {
 		return err
 	}
+	if session, ok := c.Authenticator.(*SessionKeyAuthenticator); ok && response.StatusCode == 401 {
+		// session keys expire, so log in again and retry once
+		closeResponse(response)
+		session.Expire(request)
+		if request.GetBody != nil {
+			if request.Body, err = request.GetBody(); err != nil {
+				return err
+			}
+		}
+		response, err = c.send(request)
+		if err != nil {
+			return err
+		}
+	}
+	defer closeResponse(response)
+	var data []byte
+	if response.Body != nil {
+		if data, err = ioutil.ReadAll(response.Body); err != nil {
+			return fmt.Errorf("Unable to read response from %s: %v", request.URL, err)
+		}
+	}
 	if response.StatusCode != expectedStatus {
-		return fmt.Errorf("Response code=%d from %s; want %d", response.StatusCode, request.URL, expectedStatus)
+		return newResponseError(request.URL.String(), response.StatusCode, expectedStatus, data)
 	}
 	if obj == nil {
 		return nil
 	}
 
 	// unmarshall response if obj != nil
-	data, _ := ioutil.ReadAll(response.Body)
 	if len(data) == 0 {
 		return fmt.Errorf("Received empty response body from %s", request.URL)
 	}
 	return json.Unmarshal(data, obj)
 }
 
+// closeResponse closes the body of a response, if it has one
+func closeResponse(response *http.Response) {
+	if response.Body != nil {
+		response.Body.Close()
+	}
+}
+
+// send adds credentials to a request, and sends it using the HTTP client
+func (c *SplunkClient) send(request *http.Request) (*http.Response, error) {
+	auth := c.Authenticator
+	if auth == nil {
+		auth = &BasicAuthenticator{Username: c.Username, Password: c.Password}
+	}
+	if err := auth.Authenticate(c, request); err != nil {
+		return nil, err
+	}
+	return c.Client.Do(request)
+}
+
 // Get sends a REST API request and unmarshals response into obj, if not nil.
 func (c *SplunkClient) Get(path string, obj interface{}) error {
+	return c.GetContext(context.Background(), path, obj)
+}
+
+// GetContext is like Get, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetContext(ctx context.Context, path string, obj interface{}) error {
 	endpoint := fmt.Sprintf("%s%s?count=0&output_mode=json", c.ManagementURI, path)
 	request, err := http.NewRequest("GET", endpoint, nil)
 	if err != nil {
 		return err
 	}
-	return c.Do(request, 200, obj)
+	return c.DoContext(ctx, request, 200, obj)
 }
 
 // SearchHeadCaptainInfo represents the status of the search head cluster.
@@ -131,13 +235,18 @@ type SearchHeadCaptainInfo struct {
 // You can use this on any member of a search head cluster.
 // See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#shcluster.2Fcaptain.2Finfo
 func (c *SplunkClient) GetSearchHeadCaptainInfo() (*SearchHeadCaptainInfo, error) {
+	return c.GetSearchHeadCaptainInfoContext(context.Background())
+}
+
+// GetSearchHeadCaptainInfoContext is like GetSearchHeadCaptainInfo, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetSearchHeadCaptainInfoContext(ctx context.Context) (*SearchHeadCaptainInfo, error) {
 	apiResponse := struct {
 		Entry []struct {
 			Content SearchHeadCaptainInfo `json:"content"`
 		} `json:"entry"`
 	}{}
 	path := "/services/shcluster/captain/info"
-	err := c.Get(path, &apiResponse)
+	err := c.GetContext(ctx, path, &apiResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -200,13 +309,18 @@ type SearchHeadCaptainMemberInfo struct {
 // You can only use this on a search head cluster captain.
 // See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#shcluster.2Fcaptain.2Fmembers
 func (c *SplunkClient) GetSearchHeadCaptainMembers() (map[string]SearchHeadCaptainMemberInfo, error) {
+	return c.GetSearchHeadCaptainMembersContext(context.Background())
+}
+
+// GetSearchHeadCaptainMembersContext is like GetSearchHeadCaptainMembers, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetSearchHeadCaptainMembersContext(ctx context.Context) (map[string]SearchHeadCaptainMemberInfo, error) {
 	apiResponse := struct {
 		Entry []struct {
 			Content SearchHeadCaptainMemberInfo `json:"content"`
 		} `json:"entry"`
 	}{}
 	path := "/services/shcluster/captain/members"
-	err := c.Get(path, &apiResponse)
+	err := c.GetContext(ctx, path, &apiResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -257,13 +371,18 @@ type SearchHeadClusterMemberInfo struct {
 // You can use this on any member of a search head cluster.
 // See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#shcluster.2Fmember.2Finfo
 func (c *SplunkClient) GetSearchHeadClusterMemberInfo() (*SearchHeadClusterMemberInfo, error) {
+	return c.GetSearchHeadClusterMemberInfoContext(context.Background())
+}
+
+// GetSearchHeadClusterMemberInfoContext is like GetSearchHeadClusterMemberInfo, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetSearchHeadClusterMemberInfoContext(ctx context.Context) (*SearchHeadClusterMemberInfo, error) {
 	apiResponse := struct {
 		Entry []struct {
 			Content SearchHeadClusterMemberInfo `json:"content"`
 		} `json:"entry"`
 	}{}
 	path := "/services/shcluster/member/info"
-	err := c.Get(path, &apiResponse)
+	err := c.GetContext(ctx, path, &apiResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -277,6 +396,11 @@ func (c *SplunkClient) GetSearchHeadClusterMemberInfo() (*SearchHeadClusterMembe
 // You can use this on any member of a search head cluster.
 // See https://docs.splunk.com/Documentation/Splunk/latest/DistSearch/SHdetention
 func (c *SplunkClient) SetSearchHeadDetention(detain bool) error {
+	return c.SetSearchHeadDetentionContext(context.Background(), detain)
+}
+
+// SetSearchHeadDetentionContext is like SetSearchHeadDetention, but the request is cancelled when ctx is done
+func (c *SplunkClient) SetSearchHeadDetentionContext(ctx context.Context, detain bool) error {
 	mode := "off"
 	if detain {
 		mode = "on"
@@ -286,13 +410,38 @@ func (c *SplunkClient) SetSearchHeadDetention(detain bool) error {
 	if err != nil {
 		return err
 	}
-	return c.Do(request, 200, nil)
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// TransferSearchHeadCaptaincy transfers captaincy of a search head cluster to another member, where mgmtURI is the
+// management URI of the member that should become captain (e.g. "https://server:8089").
+// You can use this on any member of a search head cluster.
+// See https://docs.splunk.com/Documentation/Splunk/latest/DistSearch/Transfercaptaincy
+func (c *SplunkClient) TransferSearchHeadCaptaincy(mgmtURI string) error {
+	return c.TransferSearchHeadCaptaincyContext(context.Background(), mgmtURI)
+}
+
+// TransferSearchHeadCaptaincyContext is like TransferSearchHeadCaptaincy, but the request is cancelled when ctx is done
+func (c *SplunkClient) TransferSearchHeadCaptaincyContext(ctx context.Context, mgmtURI string) error {
+	endpoint := fmt.Sprintf("%s/services/shcluster/member/control/control/transfer_captaincy", c.ManagementURI)
+	form := url.Values{"mgmt_uri": {mgmtURI}}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.DoContext(ctx, request, 200, nil)
 }
 
 // RemoveSearchHeadClusterMember removes a search head cluster member.
 // You can use this on any member of a search head cluster.
 // See https://docs.splunk.com/Documentation/Splunk/latest/DistSearch/Removeaclustermember
 func (c *SplunkClient) RemoveSearchHeadClusterMember() error {
+	return c.RemoveSearchHeadClusterMemberContext(context.Background())
+}
+
+// RemoveSearchHeadClusterMemberContext is like RemoveSearchHeadClusterMember, but the request is cancelled when ctx is done
+func (c *SplunkClient) RemoveSearchHeadClusterMemberContext(ctx context.Context) error {
 	// sent request to remove from search head cluster consensus
 	endpoint := fmt.Sprintf("%s/services/shcluster/member/consensus/default/remove_server?output_mode=json", c.ManagementURI)
 	request, err := http.NewRequest("POST", endpoint, nil)
@@ -300,46 +449,48 @@ func (c *SplunkClient) RemoveSearchHeadClusterMember() error {
 		return err
 	}
 
-	// send HTTP response and check status
-	request.SetBasicAuth(c.Username, c.Password)
-	response, err := c.Client.Do(request)
-	if err != nil {
+	err = c.DoContext(ctx, request, 200, nil)
+	var responseErr *ResponseError
+	if err == nil || !errors.As(err, &responseErr) || responseErr.StatusCode != 503 {
 		return err
 	}
-	if response.StatusCode == 200 {
-		return nil
-	}
-	if response.StatusCode != 503 {
-		return fmt.Errorf("Response code=%d from %s; want %d", response.StatusCode, request.URL, 200)
-	}
-
-	// unmarshall 503 response
-	apiResponse := struct {
-		Messages []struct {
-			Text string `json:"text"`
-		} `json:"messages"`
-	}{}
-	data, _ := ioutil.ReadAll(response.Body)
-	if len(data) == 0 {
-		return fmt.Errorf("Received 503 response with empty body from %s", request.URL)
-	}
-	err = json.Unmarshal(data, &apiResponse)
-	if err != nil {
-		return fmt.Errorf("Failed to unmarshal response from %s: %v", request.URL, err)
-	}
 
 	// check if request failed because member was already removed
-	if len(apiResponse.Messages) == 0 {
-		return fmt.Errorf("Received 503 response with empty Messages from %s", request.URL)
+	if len(responseErr.Messages) == 0 {
+		return fmt.Errorf("Received 503 response without messages from %s", request.URL)
 	}
 	msg1 := regexp.MustCompile(`Server .* is not part of configuration, hence cannot be removed`)
 	msg2 := regexp.MustCompile(`This node is not part of any cluster configuration`)
-	if msg1.Match([]byte(apiResponse.Messages[0].Text)) || msg2.Match([]byte(apiResponse.Messages[0].Text)) {
+	if msg1.MatchString(responseErr.Messages[0]) || msg2.MatchString(responseErr.Messages[0]) {
 		// it was already removed -> ignore error
 		return nil
 	}
 
-	return fmt.Errorf("Received unrecognized 503 response from %s", request.URL)
+	return fmt.Errorf("Received unrecognized 503 response from %s: %s", request.URL, responseErr.Messages[0])
+}
+
+// PushSearchHeadClusterBundle pushes the contents of shcluster/apps from a deployer to all members of a search head
+// cluster, where captainURI is the management URI of the current captain (e.g. "https://server:8089").
+// You can only use this on a search head cluster deployer.
+// See https://docs.splunk.com/Documentation/Splunk/latest/DistSearch/PropagateSHCconfigurationchanges
+func (c *SplunkClient) PushSearchHeadClusterBundle(captainURI string) error {
+	return c.PushSearchHeadClusterBundleContext(context.Background(), captainURI)
+}
+
+// PushSearchHeadClusterBundleContext is like PushSearchHeadClusterBundle, but the request is cancelled when ctx is done
+func (c *SplunkClient) PushSearchHeadClusterBundleContext(ctx context.Context, captainURI string) error {
+	endpoint := fmt.Sprintf("%s/services/apps/deploy", c.ManagementURI)
+	form := url.Values{
+		"target":      {captainURI},
+		"action":      {"all"},
+		"advertising": {"true"},
+	}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.DoContext(ctx, request, 200, nil)
 }
 
 // ClusterBundleInfo represents the status of a configuration bundle.
@@ -354,6 +505,31 @@ type ClusterBundleInfo struct {
 	Timestamp int64 `json:"timestamp"`
 }
 
+// ClusterValidatedBundleInfo represents the status of a configuration bundle that has been validated by the cluster master.
+type ClusterValidatedBundleInfo struct {
+	ClusterBundleInfo
+
+	// Indicates whether the bundle passed validation
+	IsValidBundle bool `json:"is_valid_bundle"`
+}
+
+// ClusterApplyBundleStatus represents the status of the most recent attempt to validate or apply a configuration bundle.
+type ClusterApplyBundleStatus struct {
+	// Information about the most recent bundle that failed validation
+	InvalidBundle struct {
+		ClusterBundleInfo
+
+		// Errors reported by the cluster master when validating the bundle
+		BundleValidationErrors []string `json:"bundle_validation_errors_on_master"`
+	} `json:"invalid_bundle"`
+
+	// Indicates whether peers have been told to reload the bundle
+	ReloadBundleIssued bool `json:"reload_bundle_issued"`
+
+	// Status of the most recent bundle operation, such as "None" or "Bundle validation is in progress."
+	Status string `json:"status"`
+}
+
 // ClusterMasterInfo represents the status of the indexer cluster master.
 // See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Finfo
 type ClusterMasterInfo struct {
@@ -382,6 +558,12 @@ type ClusterMasterInfo struct {
 	// In steady state, this is equal to active_bundle. If it is not equal, then pushing the latest bundle to all peers is in process (or needs to be started).
 	LatestBundle ClusterBundleInfo `json:"latest_bundle"`
 
+	// The most recent bundle that was validated by the master.
+	LastValidatedBundle ClusterValidatedBundleInfo `json:"last_validated_bundle"`
+
+	// Status of the most recent attempt to validate or apply the master-apps configuration bundle.
+	ApplyBundleStatus ClusterApplyBundleStatus `json:"apply_bundle_status"`
+
 	// Timestamp corresponding to the creation of the master.
 	StartTime int64 `json:"start_time"`
 }
@@ -390,13 +572,18 @@ type ClusterMasterInfo struct {
 // You can only use this on a cluster master.
 // See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Finfo
 func (c *SplunkClient) GetClusterMasterInfo() (*ClusterMasterInfo, error) {
+	return c.GetClusterMasterInfoContext(context.Background())
+}
+
+// GetClusterMasterInfoContext is like GetClusterMasterInfo, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetClusterMasterInfoContext(ctx context.Context) (*ClusterMasterInfo, error) {
 	apiResponse := struct {
 		Entry []struct {
 			Content ClusterMasterInfo `json:"content"`
 		} `json:"entry"`
 	}{}
 	path := "/services/cluster/master/info"
-	err := c.Get(path, &apiResponse)
+	err := c.GetContext(ctx, path, &apiResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -406,6 +593,381 @@ func (c *SplunkClient) GetClusterMasterInfo() (*ClusterMasterInfo, error) {
 	return &apiResponse.Entry[0].Content, nil
 }
 
+// SetClusterMaintenanceMode enables or disables maintenance mode for an indexer cluster, which halts most bucket
+// fixup activity while peers are restarted.
+// You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/Indexer/Usemaintenancemode
+func (c *SplunkClient) SetClusterMaintenanceMode(enable bool) error {
+	return c.SetClusterMaintenanceModeContext(context.Background(), enable)
+}
+
+// SetClusterMaintenanceModeContext is like SetClusterMaintenanceMode, but the request is cancelled when ctx is done
+func (c *SplunkClient) SetClusterMaintenanceModeContext(ctx context.Context, enable bool) error {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/control/default/maintenance?mode=%t", c.ManagementURI, enable)
+	request, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// RollingRestartIndexerClusterPeers initiates a searchable rolling restart of the peers in an indexer cluster.
+// percentPeersToRestart is the percentage of peers that may be restarted at the same time (0 uses the cluster default).
+// You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/Indexer/Userollingrestart
+func (c *SplunkClient) RollingRestartIndexerClusterPeers(percentPeersToRestart int32) error {
+	return c.RollingRestartIndexerClusterPeersContext(context.Background(), percentPeersToRestart)
+}
+
+// RollingRestartIndexerClusterPeersContext is like RollingRestartIndexerClusterPeers, but the request is cancelled when ctx is done
+func (c *SplunkClient) RollingRestartIndexerClusterPeersContext(ctx context.Context, percentPeersToRestart int32) error {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/control/control/restart?searchable=true", c.ManagementURI)
+	if percentPeersToRestart > 0 {
+		endpoint = fmt.Sprintf("%s&percent_peers_to_restart=%d", endpoint, percentPeersToRestart)
+	}
+	request, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// ValidateClusterBundle asks the cluster master to validate the current contents of master-apps as a new configuration
+// bundle, without distributing it to peers. Validation completes asynchronously; use GetClusterMasterInfo to check the results.
+// You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Fcontrol.2Fdefault.2Fvalidate_bundle
+func (c *SplunkClient) ValidateClusterBundle() error {
+	return c.ValidateClusterBundleContext(context.Background())
+}
+
+// ValidateClusterBundleContext is like ValidateClusterBundle, but the request is cancelled when ctx is done
+func (c *SplunkClient) ValidateClusterBundleContext(ctx context.Context) error {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/control/default/validate_bundle?check-restart=true", c.ManagementURI)
+	request, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// ApplyClusterBundle asks the cluster master to distribute the current contents of master-apps to all peers.
+// Nothing is distributed if the bundle is identical to the one that is already active.
+// You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Fcontrol.2Fdefault.2Fapply
+func (c *SplunkClient) ApplyClusterBundle() error {
+	return c.ApplyClusterBundleContext(context.Background())
+}
+
+// ApplyClusterBundleContext is like ApplyClusterBundle, but the request is cancelled when ctx is done
+func (c *SplunkClient) ApplyClusterBundleContext(ctx context.Context) error {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/control/default/apply?ignore_identical_bundle=true", c.ManagementURI)
+	request, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// StartClusterRebalance asks the cluster master to start a searchable data rebalance, which moves bucket copies between
+// peers so that each peer holds roughly the same number of buckets. The rebalance runs asynchronously; use
+// GetClusterRebalanceStatus to check its progress.
+// You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/Indexer/Rebalancethecluster
+func (c *SplunkClient) StartClusterRebalance() error {
+	return c.StartClusterRebalanceContext(context.Background())
+}
+
+// StartClusterRebalanceContext is like StartClusterRebalance, but the request is cancelled when ctx is done
+func (c *SplunkClient) StartClusterRebalanceContext(ctx context.Context) error {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/control/control/rebalance_buckets?action=start&searchable=true", c.ManagementURI)
+	request, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// ClusterRebalanceStatus represents the status of a data rebalance of an indexer cluster.
+type ClusterRebalanceStatus struct {
+	// true if a data rebalance is running
+	InProgress bool
+
+	// percentage of the data rebalance that has completed, if it is running
+	PercentComplete float64
+}
+
+// GetClusterRebalanceStatus queries the cluster master for the status of a data rebalance. The cluster master reports
+// the status as a message, for example "Data rebalance is running. 45.27% complete" or "Data rebalance is not running".
+// You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/Indexer/Rebalancethecluster
+func (c *SplunkClient) GetClusterRebalanceStatus() (*ClusterRebalanceStatus, error) {
+	return c.GetClusterRebalanceStatusContext(context.Background())
+}
+
+// GetClusterRebalanceStatusContext is like GetClusterRebalanceStatus, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetClusterRebalanceStatusContext(ctx context.Context) (*ClusterRebalanceStatus, error) {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/control/control/rebalance_buckets?action=status&output_mode=json", c.ManagementURI)
+	request, err := http.NewRequest("POST", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	apiResponse := struct {
+		Messages []struct {
+			Text string `json:"text"`
+		} `json:"messages"`
+	}{}
+	err = c.DoContext(ctx, request, 200, &apiResponse)
+	if err != nil {
+		return nil, err
+	}
+	if len(apiResponse.Messages) < 1 {
+		return nil, fmt.Errorf("Invalid response from %s", request.URL)
+	}
+
+	status := ClusterRebalanceStatus{}
+	text := strings.ToLower(apiResponse.Messages[0].Text)
+	if strings.Contains(text, "not running") {
+		return &status, nil
+	}
+	status.InProgress = true
+	percent := regexp.MustCompile(`([0-9]+(\.[0-9]+)?)\s*%`).FindStringSubmatch(text)
+	if percent != nil {
+		fmt.Sscanf(percent[1], "%g", &status.PercentComplete)
+	}
+	return &status, nil
+}
+
+// ClusterMasterGenerationInfo represents the current generation of the indexer cluster, as seen by the cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Fgeneration
+type ClusterMasterGenerationInfo struct {
+	// The current generation ID of the indexer cluster.
+	GenerationID int64 `json:"generation_id"`
+
+	// Indicates whether the replication factor is met for all buckets in the cluster.
+	ReplicationFactorMet bool `json:"replication_factor_met"`
+
+	// Indicates whether the search factor is met for all buckets in the cluster.
+	SearchFactorMet bool `json:"search_factor_met"`
+
+	// Indicates whether the cluster was forced to be searchable, even if the search factor is not met.
+	WasForcedSearchable bool `json:"was_forced_searchable"`
+}
+
+// GetClusterMasterGeneration queries the cluster master for info about the current generation of the indexer cluster.
+// You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Fgeneration
+func (c *SplunkClient) GetClusterMasterGeneration() (*ClusterMasterGenerationInfo, error) {
+	return c.GetClusterMasterGenerationContext(context.Background())
+}
+
+// GetClusterMasterGenerationContext is like GetClusterMasterGeneration, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetClusterMasterGenerationContext(ctx context.Context) (*ClusterMasterGenerationInfo, error) {
+	apiResponse := struct {
+		Entry []struct {
+			Content ClusterMasterGenerationInfo `json:"content"`
+		} `json:"entry"`
+	}{}
+	path := "/services/cluster/master/generation"
+	err := c.GetContext(ctx, path, &apiResponse)
+	if err != nil {
+		return nil, err
+	}
+	if len(apiResponse.Entry) < 1 {
+		return nil, fmt.Errorf("Invalid response from %s%s", c.ManagementURI, path)
+	}
+	return &apiResponse.Entry[0].Content, nil
+}
+
+// ClusterMasterHealth represents the health of the indexer cluster, as seen by the cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Fhealth
+type ClusterMasterHealth struct {
+	// Indicates whether all data in the cluster is searchable.
+	AllDataIsSearchable bool `json:"all_data_is_searchable"`
+
+	// Indicates whether all peers are up.
+	AllPeersAreUp bool `json:"all_peers_are_up"`
+
+	// Indicates whether multisite clustering is enabled.
+	Multisite bool `json:"multisite"`
+
+	// Indicates whether there are no bucket fixup tasks in progress.
+	NoFixupTasksInProgress bool `json:"no_fixup_tasks_in_progress"`
+
+	// Indicates whether the replication factor is met for all buckets in the cluster.
+	ReplicationFactorMet bool `json:"replication_factor_met"`
+
+	// Indicates whether the search factor is met for all buckets in the cluster.
+	SearchFactorMet bool `json:"search_factor_met"`
+
+	// Indicates whether the site replication factor is met, for multisite indexer clusters.
+	SiteReplicationFactorMet bool `json:"site_replication_factor_met"`
+
+	// Indicates whether the site search factor is met, for multisite indexer clusters.
+	SiteSearchFactorMet bool `json:"site_search_factor_met"`
+}
+
+// GetClusterMasterHealth queries the cluster master for the health of the indexer cluster.
+// You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Fhealth
+func (c *SplunkClient) GetClusterMasterHealth() (*ClusterMasterHealth, error) {
+	return c.GetClusterMasterHealthContext(context.Background())
+}
+
+// GetClusterMasterHealthContext is like GetClusterMasterHealth, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetClusterMasterHealthContext(ctx context.Context) (*ClusterMasterHealth, error) {
+	// each flag is reported as "1" or "0"
+	apiResponse := struct {
+		Entry []struct {
+			Content map[string]interface{} `json:"content"`
+		} `json:"entry"`
+	}{}
+	path := "/services/cluster/master/health"
+	err := c.GetContext(ctx, path, &apiResponse)
+	if err != nil {
+		return nil, err
+	}
+	if len(apiResponse.Entry) < 1 {
+		return nil, fmt.Errorf("Invalid response from %s%s", c.ManagementURI, path)
+	}
+	content := apiResponse.Entry[0].Content
+	return &ClusterMasterHealth{
+		AllDataIsSearchable:      parseFlag(content["all_data_is_searchable"]),
+		AllPeersAreUp:            parseFlag(content["all_peers_are_up"]),
+		Multisite:                parseFlag(content["multisite"]),
+		NoFixupTasksInProgress:   parseFlag(content["no_fixup_tasks_in_progress"]),
+		ReplicationFactorMet:     parseFlag(content["replication_factor_met"]),
+		SearchFactorMet:          parseFlag(content["search_factor_met"]),
+		SiteReplicationFactorMet: parseFlag(content["site_replication_factor_met"]),
+		SiteSearchFactorMet:      parseFlag(content["site_search_factor_met"]),
+	}, nil
+}
+
+// parseFlag returns the value of a flag in a REST API response, which Splunk may report as a boolean, a number or a
+// string such as "1"
+func parseFlag(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		flag, err := strconv.ParseBool(v)
+		return err == nil && flag
+	}
+	return false
+}
+
+const (
+	// FixupLevelReplicationFactor is used to list buckets that are being fixed up to meet the replication factor
+	FixupLevelReplicationFactor = "replication_factor"
+
+	// FixupLevelSearchFactor is used to list buckets that are being fixed up to meet the search factor
+	FixupLevelSearchFactor = "search_factor"
+)
+
+// ClusterMasterFixupTask represents a bucket that is being fixed up by the cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Ffixup
+type ClusterMasterFixupTask struct {
+	// ID of the bucket being fixed up.
+	BucketID string `json:"-"`
+
+	// Name of the index that the bucket belongs to.
+	Index string `json:"index"`
+
+	// Most recent reason for the fixup, and when it was given.
+	Latest struct {
+		Reason    string `json:"reason"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"latest"`
+}
+
+// GetClusterMasterFixupTasks queries the cluster master for buckets that are being fixed up at the given level, such
+// as FixupLevelReplicationFactor. You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Ffixup
+func (c *SplunkClient) GetClusterMasterFixupTasks(level string) ([]ClusterMasterFixupTask, error) {
+	return c.GetClusterMasterFixupTasksContext(context.Background(), level)
+}
+
+// GetClusterMasterFixupTasksContext is like GetClusterMasterFixupTasks, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetClusterMasterFixupTasksContext(ctx context.Context, level string) ([]ClusterMasterFixupTask, error) {
+	endpoint := fmt.Sprintf("%s/services/cluster/master/fixup?level=%s&count=0&output_mode=json", c.ManagementURI, url.QueryEscape(level))
+	request, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	apiResponse := struct {
+		Entry []struct {
+			Name    string                 `json:"name"`
+			Content ClusterMasterFixupTask `json:"content"`
+		} `json:"entry"`
+	}{}
+	err = c.DoContext(ctx, request, 200, &apiResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]ClusterMasterFixupTask, len(apiResponse.Entry))
+	for i, e := range apiResponse.Entry {
+		tasks[i] = e.Content
+		tasks[i].BucketID = e.Name
+	}
+	return tasks, nil
+}
+
+// ClusterMasterIndexInfo represents the replication status of an index in the indexer cluster.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Findexes
+type ClusterMasterIndexInfo struct {
+	// Name of the index.
+	Name string `json:"-"`
+
+	// Number of buckets with more copies than the replication factor requires.
+	BucketsWithExcessCopies int64 `json:"buckets_with_excess_copies"`
+
+	// Number of buckets with more searchable copies than the search factor requires.
+	BucketsWithExcessSearchableCopies int64 `json:"buckets_with_excess_searchable_copies"`
+
+	// Total number of excess bucket copies, across all buckets in the index.
+	TotalExcessBucketCopies int64 `json:"total_excess_bucket_copies"`
+
+	// Total number of excess searchable bucket copies, across all buckets in the index.
+	TotalExcessSearchableCopies int64 `json:"total_excess_searchable_copies"`
+
+	// Number of buckets in the index.
+	NumBuckets int64 `json:"num_buckets"`
+
+	// Size of the index, in bytes.
+	IndexSize int64 `json:"index_size"`
+}
+
+// GetClusterMasterIndexes queries the cluster master for the replication status of each index, by name.
+// You can only use this on a cluster master.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Findexes
+func (c *SplunkClient) GetClusterMasterIndexes() (map[string]ClusterMasterIndexInfo, error) {
+	return c.GetClusterMasterIndexesContext(context.Background())
+}
+
+// GetClusterMasterIndexesContext is like GetClusterMasterIndexes, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetClusterMasterIndexesContext(ctx context.Context) (map[string]ClusterMasterIndexInfo, error) {
+	apiResponse := struct {
+		Entry []struct {
+			Name    string                 `json:"name"`
+			Content ClusterMasterIndexInfo `json:"content"`
+		} `json:"entry"`
+	}{}
+	path := "/services/cluster/master/indexes"
+	err := c.GetContext(ctx, path, &apiResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make(map[string]ClusterMasterIndexInfo)
+	for _, e := range apiResponse.Entry {
+		e.Content.Name = e.Name
+		indexes[e.Name] = e.Content
+	}
+	return indexes, nil
+}
+
 // IndexerClusterPeerInfo represents the status of a indexer cluster peer.
 // See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fslave.2Finfo
 type IndexerClusterPeerInfo struct {
@@ -437,13 +999,18 @@ type IndexerClusterPeerInfo struct {
 // You can use this on any peer in an indexer cluster.
 // See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fslave.2Finfo
 func (c *SplunkClient) GetIndexerClusterPeerInfo() (*IndexerClusterPeerInfo, error) {
+	return c.GetIndexerClusterPeerInfoContext(context.Background())
+}
+
+// GetIndexerClusterPeerInfoContext is like GetIndexerClusterPeerInfo, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetIndexerClusterPeerInfoContext(ctx context.Context) (*IndexerClusterPeerInfo, error) {
 	apiResponse := struct {
 		Entry []struct {
 			Content IndexerClusterPeerInfo `json:"content"`
 		} `json:"entry"`
 	}{}
 	path := "/services/cluster/slave/info"
-	err := c.Get(path, &apiResponse)
+	err := c.GetContext(ctx, path, &apiResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -559,6 +1126,11 @@ type ClusterMasterPeerInfo struct {
 // You can only use this on a cluster master.
 // See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTcluster#cluster.2Fmaster.2Fpeers
 func (c *SplunkClient) GetClusterMasterPeers() (map[string]ClusterMasterPeerInfo, error) {
+	return c.GetClusterMasterPeersContext(context.Background())
+}
+
+// GetClusterMasterPeersContext is like GetClusterMasterPeers, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetClusterMasterPeersContext(ctx context.Context) (map[string]ClusterMasterPeerInfo, error) {
 	apiResponse := struct {
 		Entry []struct {
 			Name    string                `json:"name"`
@@ -566,7 +1138,7 @@ func (c *SplunkClient) GetClusterMasterPeers() (map[string]ClusterMasterPeerInfo
 		} `json:"entry"`
 	}{}
 	path := "/services/cluster/master/peers"
-	err := c.Get(path, &apiResponse)
+	err := c.GetContext(ctx, path, &apiResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -584,19 +1156,29 @@ func (c *SplunkClient) GetClusterMasterPeers() (map[string]ClusterMasterPeerInfo
 // You can only use this on a cluster master.
 // See https://docs.splunk.com/Documentation/Splunk/8.0.2/Indexer/Removepeerfrommasterlist
 func (c *SplunkClient) RemoveIndexerClusterPeer(id string) error {
+	return c.RemoveIndexerClusterPeerContext(context.Background(), id)
+}
+
+// RemoveIndexerClusterPeerContext is like RemoveIndexerClusterPeer, but the request is cancelled when ctx is done
+func (c *SplunkClient) RemoveIndexerClusterPeerContext(ctx context.Context, id string) error {
 	// sent request to remove from search head cluster consensus
 	endpoint := fmt.Sprintf("%s/services/cluster/master/control/control/remove_peers?peers=%s", c.ManagementURI, id)
 	request, err := http.NewRequest("POST", endpoint, nil)
 	if err != nil {
 		return err
 	}
-	return c.Do(request, 200, nil)
+	return c.DoContext(ctx, request, 200, nil)
 }
 
 // DecommissionIndexerClusterPeer takes an indexer cluster peer offline using the decommission endpoint.
 // You can use this on any peer in an indexer cluster.
 // See https://docs.splunk.com/Documentation/Splunk/latest/Indexer/Takeapeeroffline
 func (c *SplunkClient) DecommissionIndexerClusterPeer(enforceCounts bool) error {
+	return c.DecommissionIndexerClusterPeerContext(context.Background(), enforceCounts)
+}
+
+// DecommissionIndexerClusterPeerContext is like DecommissionIndexerClusterPeer, but the request is cancelled when ctx is done
+func (c *SplunkClient) DecommissionIndexerClusterPeerContext(ctx context.Context, enforceCounts bool) error {
 	enforceCountsAsInt := 0
 	if enforceCounts {
 		enforceCountsAsInt = 1
@@ -606,5 +1188,220 @@ func (c *SplunkClient) DecommissionIndexerClusterPeer(enforceCounts bool) error
 	if err != nil {
 		return err
 	}
-	return c.Do(request, 200, nil)
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// SearchPeerInfo represents the status of a distributed search peer.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTsearch#search.2Fdistributed.2Fpeers
+type SearchPeerInfo struct {
+	// The host and management port of the search peer.
+	Name string `json:"name"`
+
+	// The server name of the search peer.
+	PeerName string `json:"peerName"`
+
+	// Unique identifier or GUID for the search peer.
+	GUID string `json:"guid"`
+
+	// Indicates if the search peer is disabled.
+	Disabled bool `json:"disabled"`
+
+	// Roles of the search peer, such as indexer or cluster_master.
+	ServerRoles []string `json:"server_roles"`
+
+	// Status of the search peer (Up, Down, etc.).
+	Status string `json:"status"`
+
+	// Version of Splunk Enterprise running on the search peer.
+	Version string `json:"version"`
+}
+
+// GetSearchPeers queries a search head for info about its distributed search peers.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTsearch#search.2Fdistributed.2Fpeers
+func (c *SplunkClient) GetSearchPeers() (map[string]SearchPeerInfo, error) {
+	return c.GetSearchPeersContext(context.Background())
+}
+
+// GetSearchPeersContext is like GetSearchPeers, but the request is cancelled when ctx is done
+func (c *SplunkClient) GetSearchPeersContext(ctx context.Context) (map[string]SearchPeerInfo, error) {
+	apiResponse := struct {
+		Entry []struct {
+			Name    string         `json:"name"`
+			Content SearchPeerInfo `json:"content"`
+		} `json:"entry"`
+	}{}
+	path := "/services/search/distributed/peers"
+	err := c.GetContext(ctx, path, &apiResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	peers := make(map[string]SearchPeerInfo)
+	for _, e := range apiResponse.Entry {
+		e.Content.Name = e.Name
+		peers[e.Name] = e.Content
+	}
+
+	return peers, nil
+}
+
+// AddSearchPeer adds a distributed search peer to a search head, where peer is the host and management port of the peer.
+// The username and password are used to authenticate with the search peer, and establish trust.
+// See https://docs.splunk.com/Documentation/Splunk/latest/DistSearch/Configuredistributedsearch
+func (c *SplunkClient) AddSearchPeer(peer, username, password string) error {
+	return c.AddSearchPeerContext(context.Background(), peer, username, password)
+}
+
+// AddSearchPeerContext is like AddSearchPeer, but the request is cancelled when ctx is done
+func (c *SplunkClient) AddSearchPeerContext(ctx context.Context, peer, username, password string) error {
+	endpoint := fmt.Sprintf("%s/services/search/distributed/peers", c.ManagementURI)
+	form := url.Values{
+		"name":           {peer},
+		"remoteUsername": {username},
+		"remotePassword": {password},
+	}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.DoContext(ctx, request, 201, nil)
+}
+
+// RemoveSearchPeer removes a distributed search peer from a search head, where peer is the host and management port of the peer.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTsearch#search.2Fdistributed.2Fpeers.2F.7Bname.7D
+func (c *SplunkClient) RemoveSearchPeer(peer string) error {
+	return c.RemoveSearchPeerContext(context.Background(), peer)
+}
+
+// RemoveSearchPeerContext is like RemoveSearchPeer, but the request is cancelled when ctx is done
+func (c *SplunkClient) RemoveSearchPeerContext(ctx context.Context, peer string) error {
+	endpoint := fmt.Sprintf("%s/services/search/distributed/peers/%s", c.ManagementURI, url.PathEscape(peer))
+	request, err := http.NewRequest("DELETE", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// SetAdminPassword changes the password of the admin user, where oldPassword must match the password currently in use.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTaccess#authentication.2Fusers.2F.7Bname.7D
+func (c *SplunkClient) SetAdminPassword(oldPassword, newPassword string) error {
+	return c.SetAdminPasswordContext(context.Background(), oldPassword, newPassword)
+}
+
+// SetAdminPasswordContext is like SetAdminPassword, but the request is cancelled when ctx is done
+func (c *SplunkClient) SetAdminPasswordContext(ctx context.Context, oldPassword, newPassword string) error {
+	endpoint := fmt.Sprintf("%s/services/authentication/users/admin", c.ManagementURI)
+	form := url.Values{
+		"password":    {newPassword},
+		"oldpassword": {oldPassword},
+	}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// CreateRole creates a role that has only the given capabilities.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTaccess#authorization.2Froles
+func (c *SplunkClient) CreateRole(name string, capabilities []string) error {
+	return c.CreateRoleContext(context.Background(), name, capabilities)
+}
+
+// CreateRoleContext is like CreateRole, but the request is cancelled when ctx is done
+func (c *SplunkClient) CreateRoleContext(ctx context.Context, name string, capabilities []string) error {
+	endpoint := fmt.Sprintf("%s/services/authorization/roles", c.ManagementURI)
+	form := url.Values{
+		"name":         {name},
+		"capabilities": capabilities,
+	}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.DoContext(ctx, request, 201, nil)
+}
+
+// UpdateRole changes the capabilities of an existing role.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTaccess#authorization.2Froles.2F.7Bname.7D
+func (c *SplunkClient) UpdateRole(name string, capabilities []string) error {
+	return c.UpdateRoleContext(context.Background(), name, capabilities)
+}
+
+// UpdateRoleContext is like UpdateRole, but the request is cancelled when ctx is done
+func (c *SplunkClient) UpdateRoleContext(ctx context.Context, name string, capabilities []string) error {
+	endpoint := fmt.Sprintf("%s/services/authorization/roles/%s", c.ManagementURI, url.PathEscape(name))
+	form := url.Values{"capabilities": capabilities}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// CreateUser creates a user with the given password and roles.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTaccess#authentication.2Fusers
+func (c *SplunkClient) CreateUser(name, password string, roles []string) error {
+	return c.CreateUserContext(context.Background(), name, password, roles)
+}
+
+// CreateUserContext is like CreateUser, but the request is cancelled when ctx is done
+func (c *SplunkClient) CreateUserContext(ctx context.Context, name, password string, roles []string) error {
+	endpoint := fmt.Sprintf("%s/services/authentication/users", c.ManagementURI)
+	form := url.Values{
+		"name":     {name},
+		"password": {password},
+		"roles":    roles,
+	}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.DoContext(ctx, request, 201, nil)
+}
+
+// UpdateUser changes the password and roles of an existing user, other than the user that is logged in.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTaccess#authentication.2Fusers.2F.7Bname.7D
+func (c *SplunkClient) UpdateUser(name, password string, roles []string) error {
+	return c.UpdateUserContext(context.Background(), name, password, roles)
+}
+
+// UpdateUserContext is like UpdateUser, but the request is cancelled when ctx is done
+func (c *SplunkClient) UpdateUserContext(ctx context.Context, name, password string, roles []string) error {
+	endpoint := fmt.Sprintf("%s/services/authentication/users/%s", c.ManagementURI, url.PathEscape(name))
+	form := url.Values{
+		"password": {password},
+		"roles":    roles,
+	}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.DoContext(ctx, request, 200, nil)
+}
+
+// SetPass4SymmKey changes the pass4SymmKey in the general stanza of server.conf, which is used to authenticate with
+// the license master. The new key takes effect when Splunk is restarted.
+// See https://docs.splunk.com/Documentation/Splunk/latest/RESTREF/RESTconf#configs.2Fconf-.7Bfile.7D.2F.7Bstanza.7D
+func (c *SplunkClient) SetPass4SymmKey(key string) error {
+	return c.SetPass4SymmKeyContext(context.Background(), key)
+}
+
+// SetPass4SymmKeyContext is like SetPass4SymmKey, but the request is cancelled when ctx is done
+func (c *SplunkClient) SetPass4SymmKeyContext(ctx context.Context, key string) error {
+	endpoint := fmt.Sprintf("%s/services/configs/conf-server/general", c.ManagementURI)
+	form := url.Values{"pass4SymmKey": {key}}
+	request, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.DoContext(ctx, request, 200, nil)
 }
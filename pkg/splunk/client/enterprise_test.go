@@ -15,8 +15,15 @@
 package client
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
 )
@@ -26,6 +33,7 @@ func splunkClientTester(t *testing.T, testMethod string, status int, body string
 	mockSplunkClient.AddHandler(wantRequest, status, body, nil)
 	c := NewSplunkClient("https://localhost:8089", "admin", "p@ssw0rd")
 	c.Client = mockSplunkClient
+	c.Retry = RetryPolicy{} // each test expects a single request; retries are tested by TestDoContext
 	err := test(*c)
 	if err != nil {
 		t.Errorf("%s err = %v", testMethod, err)
@@ -33,6 +41,115 @@ func splunkClientTester(t *testing.T, testMethod string, status int, body string
 	mockSplunkClient.CheckRequests(t, testMethod)
 }
 
+func TestNewVerifiedSplunkClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// certificate is verified using the CA
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+	c := NewVerifiedSplunkClient(server.URL, "admin", "p@ssw0rd", rootCAs)
+	if err := c.Get("/services/server/info", nil); err != nil {
+		t.Errorf("Get() with trusted CA err = %v; want nil", err)
+	}
+
+	// certificate signed by any other CA is rejected
+	c = NewVerifiedSplunkClient(server.URL, "admin", "p@ssw0rd", x509.NewCertPool())
+	if err := c.Get("/services/server/info", nil); err == nil {
+		t.Errorf("Get() with untrusted CA err = nil; want error")
+	}
+
+	// certificates are not verified by default
+	c = NewSplunkClient(server.URL, "admin", "p@ssw0rd")
+	if err := c.Get("/services/server/info", nil); err != nil {
+		t.Errorf("Get() without CA err = %v; want nil", err)
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	var requests int
+	var status int
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"messages":[{"type":"ERROR","text":"Not ready"}]}`)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	c := NewSplunkClient(server.URL, "admin", "p@ssw0rd")
+	c.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	test := func(method string, wantStatus, wantRequests int) {
+		requests = 0
+		request, _ := http.NewRequest(method, server.URL+"/services/server/info", nil)
+		err := c.DoContext(context.Background(), request, 200, nil)
+		if GetStatusCode(err) != wantStatus || requests != wantRequests {
+			t.Errorf("DoContext() %s with status %d returned %v after %d requests; want status %d after %d requests",
+				method, status, err, requests, wantStatus, wantRequests)
+		}
+	}
+
+	// GET requests are retried until the instance is ready
+	status = 503
+	test("GET", 0, 3)
+
+	// other requests are not idempotent, so they are not retried
+	test("POST", 503, 1)
+
+	// real failures are not retried
+	status = 500
+	test("GET", 500, 1)
+
+	// retries give up after MaxAttempts
+	status = 503
+	c.Retry.MaxAttempts = 2
+	test("GET", 503, 2)
+
+	// messages from Splunk are included in errors
+	requests = 0
+	err := c.Get("/services/server/info", nil)
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) || !IsNotReady(err) || len(responseErr.Messages) != 1 || responseErr.Messages[0] != "Not ready" {
+		t.Errorf("Get() returned %v; want ResponseError with message Not ready", err)
+	}
+
+	// retries stop when the context is done
+	requests = 0
+	c.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.GetContext(ctx, "/services/server/info", nil); err != context.DeadlineExceeded || requests != 1 {
+		t.Errorf("GetContext() returned %v after %d requests; want %v after 1 request", err, requests, context.DeadlineExceeded)
+	}
+}
+
+func TestDoContextTimeout(t *testing.T) {
+	c := NewSplunkClient("https://localhost:8089", "admin", "p@ssw0rd")
+	c.Retry = RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	test := func(ctx context.Context, wantRequests int) {
+		mockSplunkClient := &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(spltest.MockHTTPHandler{Method: "GET", URL: "https://localhost:8089/services/server/info", Err: timeoutError{}})
+		c.Client = mockSplunkClient
+		request, _ := http.NewRequest("GET", "https://localhost:8089/services/server/info", nil)
+		err := c.DoContext(ctx, request, 200, nil)
+		if !isTimeout(err) || len(mockSplunkClient.GotRequests) != wantRequests {
+			t.Errorf("DoContext() returned %v after %d requests; want timeout after %d requests", err, len(mockSplunkClient.GotRequests), wantRequests)
+		}
+	}
+
+	// timeouts are not retried without a deadline
+	test(context.Background(), 1)
+
+	// timeouts are retried while the deadline leaves time for another request
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	test(ctx, 3)
+}
+
 func TestGetSearchHeadCaptainInfo(t *testing.T) {
 	wantRequest, _ := http.NewRequest("GET", "https://localhost:8089/services/shcluster/captain/info?count=0&output_mode=json", nil)
 	wantCaptainLabel := "splunk-s2-search-head-0"
@@ -158,6 +275,14 @@ func TestSetSearchHeadDetention(t *testing.T) {
 	splunkClientTester(t, "TestSetSearchHeadDetention", 200, "", wantRequest, test)
 }
 
+func TestTransferSearchHeadCaptaincy(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/shcluster/member/control/control/transfer_captaincy", nil)
+	test := func(c SplunkClient) error {
+		return c.TransferSearchHeadCaptaincy("https://splunk-s1-search-head-1.splunk-s1-search-head-headless.splunk.svc.cluster.local:8089")
+	}
+	splunkClientTester(t, "TestTransferSearchHeadCaptaincy", 200, "", wantRequest, test)
+}
+
 func TestRemoveSearchHeadClusterMember(t *testing.T) {
 	// test for 200 response first (sent on first removal request)
 	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/shcluster/member/consensus/default/remove_server?output_mode=json", nil)
@@ -200,6 +325,24 @@ func TestRemoveSearchHeadClusterMember(t *testing.T) {
 	splunkClientTester(t, "TestRemoveSearchHeadClusterMember", 404, "", wantRequest, test)
 }
 
+func TestPushSearchHeadClusterBundle(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/apps/deploy", nil)
+	test := func(c SplunkClient) error {
+		return c.PushSearchHeadClusterBundle("https://splunk-s1-search-head-0.splunk-s1-search-head-headless.splunk.svc.cluster.local:8089")
+	}
+	splunkClientTester(t, "TestPushSearchHeadClusterBundle", 200, "", wantRequest, test)
+
+	// test error response
+	test = func(c SplunkClient) error {
+		err := c.PushSearchHeadClusterBundle("https://splunk-s1-search-head-0.splunk-s1-search-head-headless.splunk.svc.cluster.local:8089")
+		if err == nil {
+			t.Errorf("PushSearchHeadClusterBundle returned nil; want error")
+		}
+		return nil
+	}
+	splunkClientTester(t, "TestPushSearchHeadClusterBundle", 500, "", wantRequest, test)
+}
+
 func TestGetClusterMasterInfo(t *testing.T) {
 	wantRequest, _ := http.NewRequest("GET", "https://localhost:8089/services/cluster/master/info?count=0&output_mode=json", nil)
 	wantInfo := ClusterMasterInfo{
@@ -219,14 +362,24 @@ func TestGetClusterMasterInfo(t *testing.T) {
 			Checksum:   "14310A4AABD23E85BBD4559C4A3B59F8",
 			Timestamp:  1583870198,
 		},
+		LastValidatedBundle: ClusterValidatedBundleInfo{
+			ClusterBundleInfo: ClusterBundleInfo{
+				BundlePath: "/opt/splunk/var/run/splunk/cluster/remote-bundle/0af7c0e95f313f7be3b0cb1d878df9a1-1583948640.bundle",
+				Checksum:   "14310A4AABD23E85BBD4559C4A3B59F8",
+				Timestamp:  1583948640,
+			},
+			IsValidBundle: true,
+		},
 		StartTime: 1583948636,
 	}
+	wantInfo.ApplyBundleStatus.InvalidBundle.BundleValidationErrors = []string{}
+	wantInfo.ApplyBundleStatus.Status = "None"
 	test := func(c SplunkClient) error {
 		gotInfo, err := c.GetClusterMasterInfo()
 		if err != nil {
 			return err
 		}
-		if *gotInfo != wantInfo {
+		if !reflect.DeepEqual(*gotInfo, wantInfo) {
 			t.Errorf("info.Status=%v; want %v", *gotInfo, wantInfo)
 		}
 		return nil
@@ -249,6 +402,226 @@ func TestGetClusterMasterInfo(t *testing.T) {
 	splunkClientTester(t, "TestGetClusterMasterInfo", 500, "", wantRequest, test)
 }
 
+func TestSetClusterMaintenanceMode(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/default/maintenance?mode=true", nil)
+	test := func(c SplunkClient) error {
+		return c.SetClusterMaintenanceMode(true)
+	}
+	splunkClientTester(t, "TestSetClusterMaintenanceMode", 200, "", wantRequest, test)
+
+	wantRequest, _ = http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/default/maintenance?mode=false", nil)
+	test = func(c SplunkClient) error {
+		return c.SetClusterMaintenanceMode(false)
+	}
+	splunkClientTester(t, "TestSetClusterMaintenanceMode", 200, "", wantRequest, test)
+}
+
+func TestRollingRestartIndexerClusterPeers(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/control/restart?searchable=true&percent_peers_to_restart=20", nil)
+	test := func(c SplunkClient) error {
+		return c.RollingRestartIndexerClusterPeers(20)
+	}
+	splunkClientTester(t, "TestRollingRestartIndexerClusterPeers", 200, "", wantRequest, test)
+
+	wantRequest, _ = http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/control/restart?searchable=true", nil)
+	test = func(c SplunkClient) error {
+		return c.RollingRestartIndexerClusterPeers(0)
+	}
+	splunkClientTester(t, "TestRollingRestartIndexerClusterPeers", 200, "", wantRequest, test)
+}
+
+func TestValidateClusterBundle(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/default/validate_bundle?check-restart=true", nil)
+	test := func(c SplunkClient) error {
+		return c.ValidateClusterBundle()
+	}
+	splunkClientTester(t, "TestValidateClusterBundle", 200, "", wantRequest, test)
+}
+
+func TestApplyClusterBundle(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/default/apply?ignore_identical_bundle=true", nil)
+	test := func(c SplunkClient) error {
+		return c.ApplyClusterBundle()
+	}
+	splunkClientTester(t, "TestApplyClusterBundle", 200, "", wantRequest, test)
+}
+
+func TestStartClusterRebalance(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/control/rebalance_buckets?action=start&searchable=true", nil)
+	test := func(c SplunkClient) error {
+		return c.StartClusterRebalance()
+	}
+	splunkClientTester(t, "TestStartClusterRebalance", 200, "", wantRequest, test)
+}
+
+func TestGetClusterRebalanceStatus(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/cluster/master/control/control/rebalance_buckets?action=status&output_mode=json", nil)
+	testStatus := func(body string, want ClusterRebalanceStatus) {
+		test := func(c SplunkClient) error {
+			got, err := c.GetClusterRebalanceStatus()
+			if err != nil {
+				return err
+			}
+			if *got != want {
+				t.Errorf("GetClusterRebalanceStatus() = %+v; want %+v", *got, want)
+			}
+			return nil
+		}
+		splunkClientTester(t, "TestGetClusterRebalanceStatus", 200, body, wantRequest, test)
+	}
+	testStatus(`{"messages":[{"type":"INFO","text":"Data rebalance is running. 45.27% complete"}]}`, ClusterRebalanceStatus{InProgress: true, PercentComplete: 45.27})
+	testStatus(`{"messages":[{"type":"INFO","text":"Data rebalance is not running"}]}`, ClusterRebalanceStatus{})
+
+	// test empty response
+	test := func(c SplunkClient) error {
+		_, err := c.GetClusterRebalanceStatus()
+		if err == nil {
+			t.Errorf("GetClusterRebalanceStatus() returned nil; want error")
+		}
+		return nil
+	}
+	splunkClientTester(t, "TestGetClusterRebalanceStatus", 200, `{"messages":[]}`, wantRequest, test)
+}
+
+func TestGetClusterMasterGeneration(t *testing.T) {
+	wantRequest, _ := http.NewRequest("GET", "https://localhost:8089/services/cluster/master/generation?count=0&output_mode=json", nil)
+	wantInfo := ClusterMasterGenerationInfo{
+		GenerationID:         26,
+		ReplicationFactorMet: true,
+		SearchFactorMet:      false,
+	}
+	test := func(c SplunkClient) error {
+		gotInfo, err := c.GetClusterMasterGeneration()
+		if err != nil {
+			return err
+		}
+		if *gotInfo != wantInfo {
+			t.Errorf("info=%v; want %v", *gotInfo, wantInfo)
+		}
+		return nil
+	}
+	body := `{"links":{},"origin":"https://localhost:8089/services/cluster/master/generation","updated":"2020-03-18T01:04:53+00:00","generator":{"build":"a7f645ddaf91","version":"8.0.2"},"entry":[{"name":"master","id":"https://localhost:8089/services/cluster/master/generation/master","content":{"eai:acl":null,"generation_id":26,"generation_peers":{},"pending_generation_id":27,"pending_last_attempt":0,"pending_last_reason":"","replication_factor_met":true,"search_factor_met":false,"was_forced_searchable":false}}],"paging":{"total":1,"perPage":30,"offset":0},"messages":[]}`
+	splunkClientTester(t, "TestGetClusterMasterGeneration", 200, body, wantRequest, test)
+
+	// test body with no entries
+	test = func(c SplunkClient) error {
+		_, err := c.GetClusterMasterGeneration()
+		if err == nil {
+			t.Errorf("GetClusterMasterGeneration returned nil; want error")
+		}
+		return nil
+	}
+	body = `{"entry":[]}`
+	splunkClientTester(t, "TestGetClusterMasterGeneration", 200, body, wantRequest, test)
+
+	// test error code
+	splunkClientTester(t, "TestGetClusterMasterGeneration", 500, "", wantRequest, test)
+}
+
+func TestGetClusterMasterHealth(t *testing.T) {
+	wantRequest, _ := http.NewRequest("GET", "https://localhost:8089/services/cluster/master/health?count=0&output_mode=json", nil)
+	wantHealth := ClusterMasterHealth{
+		AllDataIsSearchable:    true,
+		AllPeersAreUp:          true,
+		NoFixupTasksInProgress: false,
+		ReplicationFactorMet:   true,
+		SearchFactorMet:        false,
+	}
+	test := func(c SplunkClient) error {
+		gotHealth, err := c.GetClusterMasterHealth()
+		if err != nil {
+			return err
+		}
+		if *gotHealth != wantHealth {
+			t.Errorf("health=%v; want %v", *gotHealth, wantHealth)
+		}
+		return nil
+	}
+	body := `{"links":{},"origin":"https://localhost:8089/services/cluster/master/health","updated":"2020-06-18T21:42:13+00:00","generator":{"build":"a7f645ddaf91","version":"8.0.2"},"entry":[{"name":"master","id":"https://localhost:8089/services/cluster/master/health/master","content":{"all_data_is_searchable":"1","all_peers_are_up":"1","cm_version_is_compatible":"1","eai:acl":null,"multisite":"0","no_fixup_tasks_in_progress":"0","pre_flight_check":"1","ready_for_searchable_rolling_restart":"1","replication_factor_met":"1","search_factor_met":"0","splunk_version_peer_count":"{ 8.0.2: 3 }"}}],"paging":{"total":1,"perPage":30,"offset":0},"messages":[]}`
+	splunkClientTester(t, "TestGetClusterMasterHealth", 200, body, wantRequest, test)
+
+	// test body with no entries
+	test = func(c SplunkClient) error {
+		_, err := c.GetClusterMasterHealth()
+		if err == nil {
+			t.Errorf("GetClusterMasterHealth returned nil; want error")
+		}
+		return nil
+	}
+	body = `{"entry":[]}`
+	splunkClientTester(t, "TestGetClusterMasterHealth", 200, body, wantRequest, test)
+
+	// test error code
+	splunkClientTester(t, "TestGetClusterMasterHealth", 500, "", wantRequest, test)
+}
+
+func TestParseFlag(t *testing.T) {
+	for value, want := range map[interface{}]bool{"1": true, "0": false, "true": true, "": false, true: true, float64(1): true, float64(0): false, nil: false} {
+		if got := parseFlag(value); got != want {
+			t.Errorf("parseFlag(%v) = %t; want %t", value, got, want)
+		}
+	}
+}
+
+func TestGetClusterMasterFixupTasks(t *testing.T) {
+	wantRequest, _ := http.NewRequest("GET", "https://localhost:8089/services/cluster/master/fixup?level=replication_factor&count=0&output_mode=json", nil)
+	test := func(c SplunkClient) error {
+		tasks, err := c.GetClusterMasterFixupTasks(FixupLevelReplicationFactor)
+		if err != nil {
+			return err
+		}
+		if len(tasks) != 2 {
+			t.Fatalf("len(tasks)=%d; want 2", len(tasks))
+		}
+		if tasks[0].BucketID != "_internal~12~B5E4F8E5-4B4C-4A37-8C3B-7E2A8C3D3C6C" || tasks[0].Index != "_internal" || tasks[0].Latest.Reason != "bucket hasn't rolled yet" || tasks[1].Index != "main" {
+			t.Errorf("tasks=%v; want _internal and main buckets", tasks)
+		}
+		return nil
+	}
+	body := `{"links":{},"origin":"https://localhost:8089/services/cluster/master/fixup","updated":"2020-06-18T21:42:13+00:00","generator":{"build":"a7f645ddaf91","version":"8.0.2"},"entry":[{"name":"_internal~12~B5E4F8E5-4B4C-4A37-8C3B-7E2A8C3D3C6C","content":{"eai:acl":null,"index":"_internal","initial":{"reason":"added peer","timestamp":1592516400},"latest":{"reason":"bucket hasn't rolled yet","timestamp":1592516533}}},{"name":"main~3~B5E4F8E5-4B4C-4A37-8C3B-7E2A8C3D3C6C","content":{"eai:acl":null,"index":"main","initial":{"reason":"added peer","timestamp":1592516400},"latest":{"reason":"streaming failure","timestamp":1592516533}}}],"paging":{"total":2,"perPage":30,"offset":0},"messages":[]}`
+	splunkClientTester(t, "TestGetClusterMasterFixupTasks", 200, body, wantRequest, test)
+
+	// test error code
+	test = func(c SplunkClient) error {
+		_, err := c.GetClusterMasterFixupTasks(FixupLevelReplicationFactor)
+		if err == nil {
+			t.Errorf("GetClusterMasterFixupTasks returned nil; want error")
+		}
+		return nil
+	}
+	splunkClientTester(t, "TestGetClusterMasterFixupTasks", 500, "", wantRequest, test)
+}
+
+func TestGetClusterMasterIndexes(t *testing.T) {
+	wantRequest, _ := http.NewRequest("GET", "https://localhost:8089/services/cluster/master/indexes?count=0&output_mode=json", nil)
+	wantIndexes := map[string]ClusterMasterIndexInfo{
+		"_internal": {Name: "_internal", BucketsWithExcessCopies: 2, BucketsWithExcessSearchableCopies: 1, TotalExcessBucketCopies: 3, TotalExcessSearchableCopies: 1, NumBuckets: 45, IndexSize: 12345678},
+		"main":      {Name: "main", NumBuckets: 4, IndexSize: 1024},
+	}
+	test := func(c SplunkClient) error {
+		gotIndexes, err := c.GetClusterMasterIndexes()
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(gotIndexes, wantIndexes) {
+			t.Errorf("indexes=%v; want %v", gotIndexes, wantIndexes)
+		}
+		return nil
+	}
+	body := `{"links":{},"origin":"https://localhost:8089/services/cluster/master/indexes","updated":"2020-06-18T21:42:13+00:00","generator":{"build":"a7f645ddaf91","version":"8.0.2"},"entry":[{"name":"_internal","content":{"buckets_with_excess_copies":2,"buckets_with_excess_searchable_copies":1,"eai:acl":null,"index_size":12345678,"is_searchable":true,"num_buckets":45,"total_excess_bucket_copies":3,"total_excess_searchable_copies":1}},{"name":"main","content":{"buckets_with_excess_copies":0,"buckets_with_excess_searchable_copies":0,"eai:acl":null,"index_size":1024,"is_searchable":true,"num_buckets":4,"total_excess_bucket_copies":0,"total_excess_searchable_copies":0}}],"paging":{"total":2,"perPage":30,"offset":0},"messages":[]}`
+	splunkClientTester(t, "TestGetClusterMasterIndexes", 200, body, wantRequest, test)
+
+	// test error code
+	test = func(c SplunkClient) error {
+		_, err := c.GetClusterMasterIndexes()
+		if err == nil {
+			t.Errorf("GetClusterMasterIndexes returned nil; want error")
+		}
+		return nil
+	}
+	splunkClientTester(t, "TestGetClusterMasterIndexes", 500, "", wantRequest, test)
+}
+
 func TestGetIndexerClusterPeerInfo(t *testing.T) {
 	wantRequest, _ := http.NewRequest("GET", "https://localhost:8089/services/cluster/slave/info?count=0&output_mode=json", nil)
 	wantMemberStatus := "Up"
@@ -343,3 +716,138 @@ func TestDecommissionIndexerClusterPeer(t *testing.T) {
 	}
 	splunkClientTester(t, "TestDecommissionIndexerClusterPeer", 200, "", wantRequest, test)
 }
+
+func TestGetSearchPeers(t *testing.T) {
+	wantRequest, _ := http.NewRequest("GET", "https://localhost:8089/services/search/distributed/peers?count=0&output_mode=json", nil)
+	wantPeers := map[string]string{
+		"splunk-s1-indexer-0.splunk-s1-indexer-headless.splunk.svc.cluster.local:8089":               "Up",
+		"splunk-s1-cluster-master-0.splunk-s1-cluster-master-headless.splunk.svc.cluster.local:8089": "Down",
+	}
+	test := func(c SplunkClient) error {
+		peers, err := c.GetSearchPeers()
+		if err != nil {
+			return err
+		}
+		if len(peers) != len(wantPeers) {
+			t.Errorf("len(peers)=%d; want %d", len(peers), len(wantPeers))
+		}
+		for name, wantStatus := range wantPeers {
+			p, ok := peers[name]
+			if !ok {
+				t.Errorf("wanted peer not found: %s", name)
+				continue
+			}
+			if p.Name != name {
+				t.Errorf("peer %s want Name=%s: got %s", name, name, p.Name)
+			}
+			if p.Status != wantStatus {
+				t.Errorf("peer %s want Status=%s: got %s", name, wantStatus, p.Status)
+			}
+		}
+		return nil
+	}
+	body := `{"links":{"create":"/services/search/distributed/peers/_new"},"origin":"https://localhost:8089/services/search/distributed/peers","updated":"2020-06-01T18:24:12+00:00","generator":{"build":"a7f645ddaf91","version":"8.0.2"},"entry":[{"name":"splunk-s1-indexer-0.splunk-s1-indexer-headless.splunk.svc.cluster.local:8089","id":"https://localhost:8089/services/search/distributed/peers/splunk-s1-indexer-0.splunk-s1-indexer-headless.splunk.svc.cluster.local%3A8089","updated":"1970-01-01T00:00:00+00:00","author":"system","content":{"disabled":false,"guid":"D39B1729-E2C5-4273-B9B2-534DA7C2F866","peerName":"splunk-s1-indexer-0","server_roles":["indexer","cluster_slave"],"status":"Up","version":"8.0.2"}},{"name":"splunk-s1-cluster-master-0.splunk-s1-cluster-master-headless.splunk.svc.cluster.local:8089","id":"https://localhost:8089/services/search/distributed/peers/splunk-s1-cluster-master-0.splunk-s1-cluster-master-headless.splunk.svc.cluster.local%3A8089","updated":"1970-01-01T00:00:00+00:00","author":"system","content":{"disabled":false,"guid":"","peerName":"","server_roles":[],"status":"Down","version":""}}],"paging":{"total":2,"perPage":30,"offset":0},"messages":[]}`
+	splunkClientTester(t, "TestGetSearchPeers", 200, body, wantRequest, test)
+
+	// test error response
+	test = func(c SplunkClient) error {
+		_, err := c.GetSearchPeers()
+		if err == nil {
+			t.Errorf("GetSearchPeers returned nil; want error")
+		}
+		return nil
+	}
+	splunkClientTester(t, "TestGetSearchPeers", 503, "", wantRequest, test)
+}
+
+func TestAddSearchPeer(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/search/distributed/peers", nil)
+	test := func(c SplunkClient) error {
+		return c.AddSearchPeer("splunk-s1-indexer-0.splunk-s1-indexer-headless.splunk.svc.cluster.local:8089", "admin", "p@ssw0rd")
+	}
+	splunkClientTester(t, "TestAddSearchPeer", 201, "", wantRequest, test)
+
+	// test error response
+	test = func(c SplunkClient) error {
+		err := c.AddSearchPeer("splunk-s1-indexer-0.splunk-s1-indexer-headless.splunk.svc.cluster.local:8089", "admin", "p@ssw0rd")
+		if err == nil {
+			t.Errorf("AddSearchPeer returned nil; want error")
+		}
+		return nil
+	}
+	splunkClientTester(t, "TestAddSearchPeer", 400, "", wantRequest, test)
+}
+
+func TestRemoveSearchPeer(t *testing.T) {
+	wantRequest, _ := http.NewRequest("DELETE", "https://localhost:8089/services/search/distributed/peers/splunk-s1-indexer-0.splunk-s1-indexer-headless.splunk.svc.cluster.local:8089", nil)
+	test := func(c SplunkClient) error {
+		return c.RemoveSearchPeer("splunk-s1-indexer-0.splunk-s1-indexer-headless.splunk.svc.cluster.local:8089")
+	}
+	splunkClientTester(t, "TestRemoveSearchPeer", 200, "", wantRequest, test)
+}
+
+func TestSetAdminPassword(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/authentication/users/admin", nil)
+	test := func(c SplunkClient) error {
+		return c.SetAdminPassword("p@ssw0rd", "n3wp@ssw0rd")
+	}
+	splunkClientTester(t, "TestSetAdminPassword", 200, "", wantRequest, test)
+
+	// test error response
+	test = func(c SplunkClient) error {
+		if err := c.SetAdminPassword("p@ssw0rd", "n3wp@ssw0rd"); err == nil {
+			t.Errorf("SetAdminPassword returned nil; want error")
+		}
+		return nil
+	}
+	splunkClientTester(t, "TestSetAdminPassword", 401, "", wantRequest, test)
+}
+
+func TestCreateRole(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/authorization/roles", nil)
+	test := func(c SplunkClient) error {
+		return c.CreateRole("splunk_operator", []string{"list_indexer_cluster"})
+	}
+	splunkClientTester(t, "TestCreateRole", 201, "", wantRequest, test)
+}
+
+func TestUpdateRole(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/authorization/roles/splunk_operator", nil)
+	test := func(c SplunkClient) error {
+		return c.UpdateRole("splunk_operator", []string{"list_indexer_cluster"})
+	}
+	splunkClientTester(t, "TestUpdateRole", 200, "", wantRequest, test)
+}
+
+func TestCreateUser(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/authentication/users", nil)
+	test := func(c SplunkClient) error {
+		return c.CreateUser("splunk-operator", "p@ssw0rd", []string{"splunk_operator"})
+	}
+	splunkClientTester(t, "TestCreateUser", 201, "", wantRequest, test)
+
+	// test error response, when the user already exists
+	test = func(c SplunkClient) error {
+		if err := c.CreateUser("splunk-operator", "p@ssw0rd", []string{"splunk_operator"}); err == nil {
+			t.Errorf("CreateUser returned nil; want error")
+		}
+		return nil
+	}
+	splunkClientTester(t, "TestCreateUser", 409, "", wantRequest, test)
+}
+
+func TestUpdateUser(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/authentication/users/splunk-operator", nil)
+	test := func(c SplunkClient) error {
+		return c.UpdateUser("splunk-operator", "p@ssw0rd", []string{"splunk_operator"})
+	}
+	splunkClientTester(t, "TestUpdateUser", 200, "", wantRequest, test)
+}
+
+func TestSetPass4SymmKey(t *testing.T) {
+	wantRequest, _ := http.NewRequest("POST", "https://localhost:8089/services/configs/conf-server/general", nil)
+	test := func(c SplunkClient) error {
+		return c.SetPass4SymmKey("s3cr3t")
+	}
+	splunkClientTester(t, "TestSetPass4SymmKey", 200, "", wantRequest, test)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ResponseError is returned when a Splunk REST API request receives a response with an unexpected status code
+type ResponseError struct {
+	// URL of the request
+	URL string
+
+	// StatusCode of the response
+	StatusCode int
+
+	// ExpectedStatus is the status code that was expected
+	ExpectedStatus int
+
+	// Messages contains the text of each message in the response body, if any
+	Messages []string
+}
+
+// Error for ResponseError returns a description of the response, including any messages from Splunk
+func (e *ResponseError) Error() string {
+	msg := fmt.Sprintf("Response code=%d from %s; want %d", e.StatusCode, e.URL, e.ExpectedStatus)
+	if len(e.Messages) > 0 {
+		msg = fmt.Sprintf("%s: %s", msg, strings.Join(e.Messages, "; "))
+	}
+	return msg
+}
+
+// newResponseError returns a ResponseError for a response, parsing messages from its body if it contains any
+func newResponseError(url string, statusCode, expectedStatus int, body []byte) *ResponseError {
+	apiResponse := struct {
+		Messages []struct {
+			Text string `json:"text"`
+		} `json:"messages"`
+	}{}
+	err := &ResponseError{URL: url, StatusCode: statusCode, ExpectedStatus: expectedStatus}
+	if json.Unmarshal(body, &apiResponse) == nil {
+		for _, m := range apiResponse.Messages {
+			if m.Text != "" {
+				err.Messages = append(err.Messages, m.Text)
+			}
+		}
+	}
+	return err
+}
+
+// GetStatusCode returns the status code of the response that caused err, or 0 if err was not caused by a response
+func GetStatusCode(err error) int {
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotReady returns true if err indicates that a Splunk instance is unreachable, or is not ready to handle requests
+// yet, rather than a real failure that is unlikely to go away by itself
+func IsNotReady(err error) bool {
+	switch GetStatusCode(err) {
+	case 429, 502, 503, 504:
+		return true
+	case 0:
+		// connections that are refused, names that don't resolve yet and timeouts, but not certificate errors
+		var opErr *net.OpError
+		var dnsErr *net.DNSError
+		return errors.As(err, &opErr) || errors.As(err, &dnsErr) || isTimeout(err)
+	}
+	return false
+}
+
+// isTimeout returns true if err indicates that a request timed out
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestResponseError(t *testing.T) {
+	body := `{"messages":[{"type":"ERROR","text":"Cluster master is not ready"},{"type":"ERROR","text":"Try again later"}]}`
+	err := newResponseError("https://localhost:8089/services/cluster/master/info", 503, 200, []byte(body))
+	want := "Response code=503 from https://localhost:8089/services/cluster/master/info; want 200: Cluster master is not ready; Try again later"
+	if err.Error() != want {
+		t.Errorf("ResponseError.Error() = %s; want %s", err.Error(), want)
+	}
+
+	// body is not required to contain messages
+	err = newResponseError("https://localhost:8089/services/server/info", 500, 200, []byte("not json"))
+	want = "Response code=500 from https://localhost:8089/services/server/info; want 200"
+	if err.Error() != want || len(err.Messages) != 0 {
+		t.Errorf("ResponseError.Error() = %s; want %s", err.Error(), want)
+	}
+}
+
+func TestGetStatusCode(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", newResponseError("https://localhost:8089", 404, 200, nil))
+	if got := GetStatusCode(err); got != 404 {
+		t.Errorf("GetStatusCode() = %d; want 404", got)
+	}
+	if got := GetStatusCode(errors.New("failed")); got != 0 {
+		t.Errorf("GetStatusCode() = %d; want 0", got)
+	}
+}
+
+// timeoutError is used to emulate a request that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsNotReady(t *testing.T) {
+	test := func(err error, want bool) {
+		if got := IsNotReady(err); got != want {
+			t.Errorf("IsNotReady(%v) = %t; want %t", err, got, want)
+		}
+	}
+	for _, status := range []int{429, 502, 503, 504} {
+		test(newResponseError("https://localhost:8089", status, 200, nil), true)
+	}
+	for _, status := range []int{400, 401, 403, 404, 500} {
+		test(newResponseError("https://localhost:8089", status, 200, nil), false)
+	}
+	test(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true)
+	test(&net.DNSError{Err: "no such host", Name: "splunk-stack1-cluster-master-service"}, true)
+	test(fmt.Errorf("request failed: %w", timeoutError{}), true)
+	test(errors.New("x509: certificate signed by unknown authority"), false)
+	test(nil, false)
+}
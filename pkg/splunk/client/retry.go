@@ -0,0 +1,81 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures how SplunkClient retries idempotent requests that fail because a Splunk instance is
+// unreachable or not ready, as reported by IsNotReady. Requests that time out are only retried within the deadline
+// of their context.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request is sent; retries are disabled if this is less than 2
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait before the first retry, which doubles for each retry after that
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait before any retry
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of each backoff that is randomized (0 to 1), so that clients don't retry in lockstep
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the RetryPolicy used by new clients
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     2 * time.Second,
+	Jitter:         0.5,
+}
+
+// getBackoff for RetryPolicy returns the time to wait before sending a request again, after the given number of
+// failed attempts
+func (p RetryPolicy) getBackoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for n := 1; n < attempt && (p.MaxBackoff == 0 || backoff < p.MaxBackoff); n++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		jitter := time.Duration(p.Jitter * float64(backoff))
+		backoff = backoff - jitter + time.Duration(rand.Int63n(int64(jitter)+1))
+	}
+	return backoff
+}
+
+// sleepContext waits for the given duration, or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// hasBudget returns true if ctx has a deadline that leaves time for another request after waiting for backoff
+func hasBudget(ctx context.Context, backoff time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) > backoff
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyGetBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, want := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		if got := p.getBackoff(attempt + 1); got != want*time.Millisecond {
+			t.Errorf("getBackoff(%d) = %v; want %v", attempt+1, got, want*time.Millisecond)
+		}
+	}
+
+	// jitter randomizes the given fraction of each backoff
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.getBackoff(2); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("getBackoff(2) = %v; want between 100ms and 200ms", got)
+		}
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	if err := sleepContext(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("sleepContext() returned %v; want nil", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sleepContext(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("sleepContext() returned %v; want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// default region used to sign requests when it can't be determined from the endpoint
+	defaultS3Region = "us-east-1"
+
+	// hash of an empty request body
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	// payload hash used for presigned URLs
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+
+	// format used for x-amz-date values
+	amzDateFormat = "20060102T150405Z"
+)
+
+// s3RegionRegex is used to extract the region from AWS S3 endpoints (e.g. s3-us-west-2.amazonaws.com or s3.us-west-2.amazonaws.com)
+var s3RegionRegex = regexp.MustCompile(`s3[.-]([a-z0-9-]+)\.amazonaws\.com`)
+
+// S3Client is a simple object used to send requests to S3-compatible remote storage services
+type S3Client struct {
+	// endpoint URL for the remote storage service (e.g. "https://s3-us-west-2.amazonaws.com")
+	Endpoint string
+
+	// region used to sign requests
+	Region string
+
+	// access key used for authentication
+	AccessKey string
+
+	// secret key used for authentication
+	SecretKey string
+
+	// HTTP client used to process requests
+	Client SplunkHTTPClient
+
+	// returns the current time; used to sign requests
+	Now func() time.Time
+}
+
+// NewS3Client returns a new S3Client object initialized with an access key and secret key.
+func NewS3Client(endpoint, accessKey, secretKey string) *S3Client {
+	return &S3Client{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    GetS3Region(endpoint),
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // don't verify ssl certs
+			},
+		},
+		Now: time.Now,
+	}
+}
+
+// GetS3Region returns the region of an S3 endpoint, or us-east-1 if it can't be determined.
+func GetS3Region(endpoint string) string {
+	match := s3RegionRegex.FindStringSubmatch(endpoint)
+	if len(match) < 2 || match[1] == "external-1" {
+		return defaultS3Region
+	}
+	return match[1]
+}
+
+// s3ErrorResponse represents the body of an error response from an S3-compatible service
+type s3ErrorResponse struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// CheckRemoteVolume verifies that objects within a remote storage volume can be listed.
+// The path is a bucket name, optionally followed by a path prefix (e.g. "mybucket/smartstore").
+func (c *S3Client) CheckRemoteVolume(path string) error {
+	parts := strings.SplitN(strings.Trim(path, "/"), "/", 2)
+	bucket := parts[0]
+	if bucket == "" {
+		return fmt.Errorf("Invalid remote volume path \"%s\"", path)
+	}
+	prefix := ""
+	if len(parts) > 1 {
+		prefix = parts[1] + "/"
+	}
+
+	query := map[string]string{
+		"list-type": "2",
+		"max-keys":  "1",
+		"prefix":    prefix,
+	}
+	request, err := c.newRequest("GET", "/"+bucket, query)
+	if err != nil {
+		return err
+	}
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer closeResponse(response)
+	if response.StatusCode != 200 {
+		var s3Error s3ErrorResponse
+		if response.Body != nil {
+			data, _ := ioutil.ReadAll(response.Body)
+			xml.Unmarshal(data, &s3Error)
+		}
+		if s3Error.Code != "" {
+			return fmt.Errorf("Response code=%d from %s; want %d: %s: %s", response.StatusCode, request.URL, 200, s3Error.Code, s3Error.Message)
+		}
+		return fmt.Errorf("Response code=%d from %s; want %d", response.StatusCode, request.URL, 200)
+	}
+	return nil
+}
+
+// GetObjectChecksum returns the ETag of an object stored in a remote storage volume, which changes whenever the object changes.
+// The path is a bucket name followed by the key of the object (e.g. "mybucket/apps/app.tgz").
+func (c *S3Client) GetObjectChecksum(path string) (string, error) {
+	bucket, key, err := splitObjectPath(path)
+	if err != nil {
+		return "", err
+	}
+	request, err := c.newRequest("HEAD", fmt.Sprintf("/%s/%s", bucket, key), nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := c.Client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer closeResponse(response)
+	if response.StatusCode != 200 {
+		return "", fmt.Errorf("Response code=%d from %s; want %d", response.StatusCode, request.URL, 200)
+	}
+	etag := strings.Trim(response.Header.Get("ETag"), "\"")
+	if etag == "" {
+		return "", fmt.Errorf("Response from %s is missing ETag header", request.URL)
+	}
+	return etag, nil
+}
+
+// GetPresignedURL returns a URL that can be used to download an object from a remote storage volume without credentials.
+// If the client has no access key, the URL is not signed.
+// The path is a bucket name followed by the key of the object (e.g. "mybucket/apps/app.tgz").
+func (c *S3Client) GetPresignedURL(path string, expires time.Duration) (string, error) {
+	bucket, key, err := splitObjectPath(path)
+	if err != nil {
+		return "", err
+	}
+	canonicalURI := S3URIEncode(fmt.Sprintf("/%s/%s", bucket, key), false)
+	if c.AccessKey == "" {
+		// anonymous access does not require a signature
+		return fmt.Sprintf("%s%s", c.Endpoint, canonicalURI), nil
+	}
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	now := c.Now().UTC()
+	amzDate := now.Format(amzDateFormat)
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", amzDate[:8], c.Region)
+	query := map[string]string{
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    fmt.Sprintf("%s/%s", c.AccessKey, scope),
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       fmt.Sprintf("%d", int64(expires.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	canonicalQuery := S3CanonicalQuery(query)
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		canonicalURI,
+		canonicalQuery,
+		fmt.Sprintf("host:%s\n", endpoint.Host),
+		"host",
+		unsignedPayload,
+	}, "\n")
+	signature := s3Signature(c.SecretKey, amzDate, scope, c.Region, canonicalRequest)
+
+	return fmt.Sprintf("%s%s?%s&X-Amz-Signature=%s", c.Endpoint, canonicalURI, canonicalQuery, signature), nil
+}
+
+// splitObjectPath returns the bucket and key for the path of an object
+func splitObjectPath(path string) (string, string, error) {
+	parts := strings.SplitN(strings.Trim(path, "/"), "/", 2)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("Invalid object path \"%s\"", path)
+	}
+	return parts[0], parts[1], nil
+}
+
+// newRequest for S3Client returns a new HTTP request that is signed using AWS Signature Version 4
+func (c *S3Client) newRequest(method, path string, query map[string]string) (*http.Request, error) {
+	canonicalURI := S3URIEncode(path, false)
+	canonicalQuery := S3CanonicalQuery(query)
+	endpoint := fmt.Sprintf("%s%s", c.Endpoint, canonicalURI)
+	if canonicalQuery != "" {
+		endpoint = fmt.Sprintf("%s?%s", endpoint, canonicalQuery)
+	}
+	request, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.Now().UTC()
+	request.Header.Set("x-amz-date", now.Format(amzDateFormat))
+	request.Header.Set("x-amz-content-sha256", emptyPayloadHash)
+	if c.AccessKey != "" {
+		request.Header.Set("Authorization", S3Authorization(request, c.Region, c.AccessKey, c.SecretKey))
+	}
+	return request, nil
+}
+
+// S3Authorization returns an AWS Signature Version 4 Authorization header value for a request.
+// The request must include x-amz-date and x-amz-content-sha256 headers.
+func S3Authorization(request *http.Request, region, accessKey, secretKey string) string {
+	amzDate := request.Header.Get("x-amz-date")
+	date := amzDate
+	if len(date) > 8 {
+		date = date[:8]
+	}
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		S3URIEncode(request.URL.Path, false),
+		S3CanonicalQuery(flattenQuery(request.URL.Query())),
+		fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", request.URL.Host, request.Header.Get("x-amz-content-sha256"), amzDate),
+		signedHeaders,
+		request.Header.Get("x-amz-content-sha256"),
+	}, "\n")
+	signature := s3Signature(secretKey, amzDate, scope, region, canonicalRequest)
+
+	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature)
+}
+
+// s3Signature returns the AWS Signature Version 4 signature for a canonical request
+func s3Signature(secretKey, amzDate, scope, region, canonicalRequest string) string {
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	date := strings.SplitN(scope, "/", 2)[0]
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// S3CanonicalQuery returns a query string that is sorted and encoded as required by AWS Signature Version 4.
+func S3CanonicalQuery(query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, len(keys))
+	for idx, k := range keys {
+		params[idx] = fmt.Sprintf("%s=%s", S3URIEncode(k, true), S3URIEncode(query[k], true))
+	}
+	return strings.Join(params, "&")
+}
+
+// S3URIEncode encodes a string as required by AWS Signature Version 4, optionally encoding slashes.
+func S3URIEncode(s string, encodeSlash bool) string {
+	var result strings.Builder
+	for _, b := range []byte(s) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' || (b == '/' && !encodeSlash) {
+			result.WriteByte(b)
+		} else {
+			fmt.Fprintf(&result, "%%%02X", b)
+		}
+	}
+	return result.String()
+}
+
+// flattenQuery returns the first value for each key in url.Values
+func flattenQuery(values url.Values) map[string]string {
+	result := make(map[string]string)
+	for k, v := range values {
+		if len(v) > 0 {
+			result[k] = v[0]
+		}
+	}
+	return result
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data using key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
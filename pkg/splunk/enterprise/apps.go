@@ -0,0 +1,245 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+const (
+	// AppScopeLocal is used for apps that are installed on the instance itself
+	AppScopeLocal = "local"
+
+	// AppScopeCluster is used for apps that are installed on all cluster members using the cluster master or deployer bundle
+	AppScopeCluster = "cluster"
+
+	// default number of seconds between checks for new or changed app packages
+	defaultAppPollInterval = 600
+
+	// minimum number of seconds between checks for new or changed app packages
+	minAppPollInterval = 60
+
+	// maximum number of seconds between checks for new or changed app packages
+	maxAppPollInterval = 86400
+
+	// annotation used to recycle pods when app packages change
+	appsChecksumAnnotation = "enterprise.splunk.com/apps-checksum"
+)
+
+// validateAppRepoSpec checks validity and makes default updates to an AppRepoSpec, and returns error if something is wrong.
+// Apps are installed on instances of instanceType, which determines the scopes that are supported.
+func validateAppRepoSpec(spec *enterprisev1.AppRepoSpec, instanceType InstanceType) error {
+	if spec.PollInterval == 0 {
+		spec.PollInterval = defaultAppPollInterval
+	}
+	if spec.PollInterval < minAppPollInterval || spec.PollInterval > maxAppPollInterval {
+		return fmt.Errorf("appRepo pollInterval must be between %d and %d seconds", minAppPollInterval, maxAppPollInterval)
+	}
+
+	volumes, err := validateRemoteVolumes(spec.Volumes, "appRepo")
+	if err != nil {
+		return err
+	}
+
+	apps := make(map[string]bool)
+	for idx := range spec.Apps {
+		app := &spec.Apps[idx]
+		if app.Name == "" {
+			return fmt.Errorf("appRepo app %d is missing a name", idx)
+		}
+		if apps[app.Name] {
+			return fmt.Errorf("appRepo app \"%s\" is defined more than once", app.Name)
+		}
+		apps[app.Name] = true
+
+		switch app.Scope {
+		case "":
+			app.Scope = AppScopeLocal
+		case AppScopeLocal:
+		case AppScopeCluster:
+			if instanceType != SplunkClusterMaster && instanceType != SplunkDeployer {
+				return fmt.Errorf("appRepo app \"%s\" uses scope \"%s\", which is only supported for clusters", app.Name, app.Scope)
+			}
+		default:
+			return fmt.Errorf("appRepo app \"%s\" has invalid scope \"%s\"", app.Name, app.Scope)
+		}
+
+		if app.ConfigMapRef != "" {
+			if app.VolumeName != "" {
+				return fmt.Errorf("appRepo app \"%s\" cannot use both volumeName and configMapRef", app.Name)
+			}
+		} else {
+			if app.VolumeName == "" {
+				if len(spec.Volumes) == 0 {
+					return fmt.Errorf("appRepo app \"%s\" requires a remote volume or configMapRef", app.Name)
+				}
+				app.VolumeName = spec.Volumes[0].Name
+			}
+			if !volumes[app.VolumeName] {
+				return fmt.Errorf("appRepo app \"%s\" refers to undefined volume \"%s\"", app.Name, app.VolumeName)
+			}
+		}
+
+		app.Path = strings.Trim(app.Path, "/")
+		if app.Path == "" {
+			app.Path = fmt.Sprintf("%s.tgz", app.Name)
+		}
+	}
+
+	return nil
+}
+
+// GetAppObjectPath returns the bucket and key of an app package stored in a remote storage volume.
+func GetAppObjectPath(spec *enterprisev1.AppRepoSpec, app *enterprisev1.AppSourceSpec) string {
+	for _, volume := range spec.Volumes {
+		if volume.Name == app.VolumeName {
+			return fmt.Sprintf("%s/%s", volume.Path, app.Path)
+		}
+	}
+	return ""
+}
+
+// GetAppPackagePath returns the path of an app package that is mounted from a ConfigMap within Splunk pods.
+func GetAppPackagePath(app *enterprisev1.AppSourceSpec) string {
+	return fmt.Sprintf("/mnt/splunk-app-%s/%s", app.ConfigMapRef, app.Path)
+}
+
+// GetAppLocation returns a string describing the location of an app package.
+func GetAppLocation(spec *enterprisev1.AppRepoSpec, app *enterprisev1.AppSourceSpec) string {
+	if app.ConfigMapRef != "" {
+		return fmt.Sprintf("configmap:%s/%s", app.ConfigMapRef, app.Path)
+	}
+	return fmt.Sprintf("s3://%s", GetAppObjectPath(spec, app))
+}
+
+// getAppInstallTarget returns the splunk-ansible app_paths_install target used for apps with scope that are installed on instanceType
+func getAppInstallTarget(scope string, instanceType InstanceType) string {
+	if scope == AppScopeCluster {
+		switch instanceType {
+		case SplunkClusterMaster:
+			return "idxc"
+		case SplunkDeployer:
+			return "shc"
+		}
+	}
+	return "default"
+}
+
+// GetAppRepoSecret returns a Kubernetes Secret containing app repository configuration for a Splunk Enterprise resource.
+// The packageURLs map contains the URL or path used to download each app package, using the name of the app as its key.
+// Apps that are missing from packageURLs are not installed.
+func GetAppRepoSecret(cr enterprisev1.MetaObject, spec *enterprisev1.AppRepoSpec, instanceType InstanceType, packageURLs map[string]string) *corev1.Secret {
+	targets := []string{"default", "idxc", "shc"}
+	packages := make(map[string][]string)
+	for _, app := range spec.Apps {
+		if url, ok := packageURLs[app.Name]; ok {
+			target := getAppInstallTarget(app.Scope, instanceType)
+			packages[target] = append(packages[target], url)
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString(`
+splunk:
+    app_paths_install:
+`)
+	for _, target := range targets {
+		if len(packages[target]) == 0 {
+			fmt.Fprintf(&result, "        %s: []\n", target)
+			continue
+		}
+		fmt.Fprintf(&result, "        %s:\n", target)
+		for _, url := range packages[target] {
+			fmt.Fprintf(&result, "            - %q\n", url)
+		}
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetSplunkAppsName(cr.GetIdentifier(), instanceType),
+			Namespace: cr.GetNamespace(),
+		},
+		Data: map[string][]byte{
+			"default.yml": []byte(result.String()),
+		},
+	}
+}
+
+// addAppRepoToPodTemplate modifies the podTemplateSpec object to install app packages from an app repository.
+func addAppRepoToPodTemplate(podTemplateSpec *corev1.PodTemplateSpec, cr enterprisev1.MetaObject, spec *enterprisev1.AppRepoSpec, status *enterprisev1.AppRepoStatus, instanceType InstanceType) {
+	// Explicitly set the default value here so we can compare for changes correctly with current statefulset.
+	secretVolDefaultMode := int32(corev1.SecretVolumeSourceDefaultMode)
+	configMapVolDefaultMode := int32(corev1.ConfigMapVolumeSourceDefaultMode)
+	optional := true
+
+	// add app repository configuration to all splunk containers
+	addSplunkVolumeToTemplate(podTemplateSpec, "apps", corev1.VolumeSource{
+		Secret: &corev1.SecretVolumeSource{
+			SecretName:  GetSplunkAppsName(cr.GetIdentifier(), instanceType),
+			DefaultMode: &secretVolDefaultMode,
+		},
+	})
+
+	// add ConfigMaps containing app packages to all splunk containers
+	configMaps := make(map[string]bool)
+	for _, app := range spec.Apps {
+		if app.ConfigMapRef == "" || configMaps[app.ConfigMapRef] {
+			continue
+		}
+		configMaps[app.ConfigMapRef] = true
+		addSplunkVolumeToTemplate(podTemplateSpec, "app-"+app.ConfigMapRef, corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: app.ConfigMapRef},
+				DefaultMode:          &configMapVolDefaultMode,
+				Optional:             &optional,
+			},
+		})
+	}
+
+	// append app repository default.yml to the defaults used by each container
+	for idx := range podTemplateSpec.Spec.Containers {
+		for envIdx := range podTemplateSpec.Spec.Containers[idx].Env {
+			env := &podTemplateSpec.Spec.Containers[idx].Env[envIdx]
+			if env.Name == "SPLUNK_DEFAULTS_URL" {
+				env.Value = fmt.Sprintf("%s,%s", env.Value, "/mnt/splunk-apps/default.yml")
+			}
+		}
+	}
+
+	// recycle pods whenever the version of an app package changes, so that it is (re)installed
+	if podTemplateSpec.ObjectMeta.Annotations == nil {
+		podTemplateSpec.ObjectMeta.Annotations = make(map[string]string)
+	}
+	podTemplateSpec.ObjectMeta.Annotations[appsChecksumAnnotation] = getAppsChecksum(status)
+}
+
+// getAppsChecksum returns a checksum of the versions of all app packages
+func getAppsChecksum(status *enterprisev1.AppRepoStatus) string {
+	hash := sha256.New()
+	for _, app := range status.Apps {
+		if app.Version != "" {
+			fmt.Fprintf(hash, "%s:%s:%s\n", app.Name, app.Scope, app.Version)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
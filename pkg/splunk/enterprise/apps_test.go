@@ -0,0 +1,215 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"strings"
+	"testing"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getAppRepoTestSpec() enterprisev1.AppRepoSpec {
+	return enterprisev1.AppRepoSpec{
+		Volumes: []enterprisev1.RemoteVolumeSpec{
+			{Name: "apps", Endpoint: "http://minio:9000", Path: "s3://bucket1/apps/", SecretRef: "s3-keys"},
+		},
+		Apps: []enterprisev1.AppSourceSpec{
+			{Name: "app1"},
+			{Name: "app2", Scope: "cluster", Path: "/cluster/app2-1.0.tgz"},
+			{Name: "app3", ConfigMapRef: "app-packages"},
+		},
+	}
+}
+
+func TestValidateAppRepoSpec(t *testing.T) {
+	spec := getAppRepoTestSpec()
+	if err := validateAppRepoSpec(&spec, SplunkClusterMaster); err != nil {
+		t.Errorf("validateAppRepoSpec() returned error: %v", err)
+	}
+	if spec.PollInterval != 600 {
+		t.Errorf("validateAppRepoSpec() pollInterval = %d; want %d", spec.PollInterval, 600)
+	}
+	if spec.Volumes[0].Path != "bucket1/apps" {
+		t.Errorf("validateAppRepoSpec() volume path = %s; want %s", spec.Volumes[0].Path, "bucket1/apps")
+	}
+	want := []enterprisev1.AppSourceSpec{
+		{Name: "app1", Scope: "local", VolumeName: "apps", Path: "app1.tgz"},
+		{Name: "app2", Scope: "cluster", VolumeName: "apps", Path: "cluster/app2-1.0.tgz"},
+		{Name: "app3", Scope: "local", ConfigMapRef: "app-packages", Path: "app3.tgz"},
+	}
+	for idx := range want {
+		if spec.Apps[idx] != want[idx] {
+			t.Errorf("validateAppRepoSpec() app %d = %v; want %v", idx, spec.Apps[idx], want[idx])
+		}
+	}
+
+	test := func(instanceType InstanceType, update func(spec *enterprisev1.AppRepoSpec), want string) {
+		spec := getAppRepoTestSpec()
+		update(&spec)
+		err := validateAppRepoSpec(&spec, instanceType)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validateAppRepoSpec() returned %v; want %s", err, want)
+		}
+	}
+
+	test(SplunkClusterMaster, func(spec *enterprisev1.AppRepoSpec) { spec.PollInterval = 30 }, "pollInterval")
+	test(SplunkClusterMaster, func(spec *enterprisev1.AppRepoSpec) { spec.Volumes[0].Endpoint = "" }, "missing an endpoint")
+	test(SplunkClusterMaster, func(spec *enterprisev1.AppRepoSpec) { spec.Apps[1].Name = "" }, "missing a name")
+	test(SplunkClusterMaster, func(spec *enterprisev1.AppRepoSpec) { spec.Apps[1].Name = "app1" }, "defined more than once")
+	test(SplunkClusterMaster, func(spec *enterprisev1.AppRepoSpec) { spec.Apps[0].Scope = "global" }, "invalid scope")
+	test(SplunkStandalone, func(spec *enterprisev1.AppRepoSpec) {}, "only supported for clusters")
+	test(SplunkClusterMaster, func(spec *enterprisev1.AppRepoSpec) { spec.Apps[2].VolumeName = "apps" }, "cannot use both")
+	test(SplunkClusterMaster, func(spec *enterprisev1.AppRepoSpec) { spec.Apps[0].VolumeName = "missing" }, "undefined volume")
+	test(SplunkClusterMaster, func(spec *enterprisev1.AppRepoSpec) { spec.Volumes = nil }, "requires a remote volume or configMapRef")
+}
+
+func TestGetAppRepoSecret(t *testing.T) {
+	cr := enterprisev1.SearchHeadCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cr.Spec.AppRepo = getAppRepoTestSpec()
+	if err := ValidateSearchHeadClusterSpec(&cr.Spec); err != nil {
+		t.Errorf("ValidateSearchHeadClusterSpec() returned error: %v", err)
+	}
+
+	if got := GetAppObjectPath(&cr.Spec.AppRepo, &cr.Spec.AppRepo.Apps[1]); got != "bucket1/apps/cluster/app2-1.0.tgz" {
+		t.Errorf("GetAppObjectPath() = %s; want %s", got, "bucket1/apps/cluster/app2-1.0.tgz")
+	}
+	if got := GetAppLocation(&cr.Spec.AppRepo, &cr.Spec.AppRepo.Apps[1]); got != "s3://bucket1/apps/cluster/app2-1.0.tgz" {
+		t.Errorf("GetAppLocation() = %s; want %s", got, "s3://bucket1/apps/cluster/app2-1.0.tgz")
+	}
+	if got := GetAppLocation(&cr.Spec.AppRepo, &cr.Spec.AppRepo.Apps[2]); got != "configmap:app-packages/app3.tgz" {
+		t.Errorf("GetAppLocation() = %s; want %s", got, "configmap:app-packages/app3.tgz")
+	}
+
+	test := func(instanceType InstanceType, packageURLs map[string]string, want string) {
+		secret := GetAppRepoSecret(&cr, &cr.Spec.AppRepo, instanceType, packageURLs)
+		if secret.GetName() != GetSplunkAppsName("stack1", instanceType) || secret.GetNamespace() != "test" {
+			t.Errorf("GetAppRepoSecret() name = %s/%s; want %s", secret.GetNamespace(), secret.GetName(), GetSplunkAppsName("stack1", instanceType))
+		}
+		if got := string(secret.Data["default.yml"]); got != want {
+			t.Errorf("GetAppRepoSecret() default.yml = %s;\nwant %s", got, want)
+		}
+	}
+
+	packageURLs := map[string]string{
+		"app1": "http://minio:9000/bucket1/apps/app1.tgz",
+		"app2": "http://minio:9000/bucket1/apps/cluster/app2-1.0.tgz",
+		"app3": GetAppPackagePath(&cr.Spec.AppRepo.Apps[2]),
+	}
+	test(SplunkDeployer, packageURLs, `
+splunk:
+    app_paths_install:
+        default:
+            - "http://minio:9000/bucket1/apps/app1.tgz"
+            - "/mnt/splunk-app-app-packages/app3.tgz"
+        idxc: []
+        shc:
+            - "http://minio:9000/bucket1/apps/cluster/app2-1.0.tgz"
+`)
+	test(SplunkClusterMaster, packageURLs, `
+splunk:
+    app_paths_install:
+        default:
+            - "http://minio:9000/bucket1/apps/app1.tgz"
+            - "/mnt/splunk-app-app-packages/app3.tgz"
+        idxc:
+            - "http://minio:9000/bucket1/apps/cluster/app2-1.0.tgz"
+        shc: []
+`)
+
+	// apps without URLs are not installed
+	delete(packageURLs, "app1")
+	delete(packageURLs, "app3")
+	test(SplunkDeployer, packageURLs, `
+splunk:
+    app_paths_install:
+        default: []
+        idxc: []
+        shc:
+            - "http://minio:9000/bucket1/apps/cluster/app2-1.0.tgz"
+`)
+}
+
+func TestAddAppRepoToPodTemplate(t *testing.T) {
+	cr := enterprisev1.SearchHeadCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cr.Spec.AppRepo = getAppRepoTestSpec()
+	cr.Spec.AppRepo.Apps = append(cr.Spec.AppRepo.Apps, enterprisev1.AppSourceSpec{Name: "app4", ConfigMapRef: "app-packages"})
+	if err := ValidateSearchHeadClusterSpec(&cr.Spec); err != nil {
+		t.Errorf("ValidateSearchHeadClusterSpec() returned error: %v", err)
+	}
+	cr.Status.AppRepo.Apps = []enterprisev1.AppStatus{
+		{Name: "app1", Scope: "local", Version: "v1", Phase: enterprisev1.PhaseReady},
+		{Name: "app2", Scope: "cluster", Phase: enterprisev1.PhaseError},
+	}
+	ss, err := GetDeployerStatefulSet(&cr)
+	if err != nil {
+		t.Errorf("GetDeployerStatefulSet() returned error: %v", err)
+	}
+
+	volumes := make(map[string]string)
+	for _, volume := range ss.Spec.Template.Spec.Volumes {
+		if volume.Secret != nil {
+			volumes[volume.Name] = volume.Secret.SecretName
+		} else if volume.ConfigMap != nil {
+			volumes[volume.Name] = volume.ConfigMap.Name
+		}
+	}
+	if volumes["mnt-splunk-apps"] != "splunk-stack1-search-head-apps" {
+		t.Errorf("GetDeployerStatefulSet() missing apps volume: %v", ss.Spec.Template.Spec.Volumes)
+	}
+	if volumes["mnt-splunk-app-app-packages"] != "app-packages" {
+		t.Errorf("GetDeployerStatefulSet() missing app package ConfigMap volume: %v", ss.Spec.Template.Spec.Volumes)
+	}
+	if len(volumes) != 3 {
+		t.Errorf("GetDeployerStatefulSet() volumes = %v; want secrets, apps and one ConfigMap", volumes)
+	}
+
+	foundEnv := false
+	for _, env := range ss.Spec.Template.Spec.Containers[0].Env {
+		if env.Name == "SPLUNK_DEFAULTS_URL" && strings.HasSuffix(env.Value, ",/mnt/splunk-apps/default.yml") {
+			foundEnv = true
+		}
+	}
+	if !foundEnv {
+		t.Errorf("GetDeployerStatefulSet() SPLUNK_DEFAULTS_URL missing apps defaults: %v", ss.Spec.Template.Spec.Containers[0].Env)
+	}
+
+	// checksum should only change when versions change
+	checksum := ss.Spec.Template.ObjectMeta.Annotations[appsChecksumAnnotation]
+	if checksum == "" {
+		t.Errorf("GetDeployerStatefulSet() missing %s annotation", appsChecksumAnnotation)
+	}
+	cr.Status.AppRepo.Apps[0].Phase = enterprisev1.PhasePending
+	ss, _ = GetDeployerStatefulSet(&cr)
+	if ss.Spec.Template.ObjectMeta.Annotations[appsChecksumAnnotation] != checksum {
+		t.Errorf("GetDeployerStatefulSet() %s annotation changed without new versions", appsChecksumAnnotation)
+	}
+	cr.Status.AppRepo.Apps[1].Version = "v1"
+	ss, _ = GetDeployerStatefulSet(&cr)
+	if ss.Spec.Template.ObjectMeta.Annotations[appsChecksumAnnotation] == checksum {
+		t.Errorf("GetDeployerStatefulSet() %s annotation did not change", appsChecksumAnnotation)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// app directory used for the contents of a cluster master's bundle ConfigMap; this is pushed to peers using the cluster bundle
+	clusterBundleAppDir = "/opt/splunk/etc/master-apps/splunk-operator-bundle/local"
+
+	// app directory used for the contents of a deployer's bundle ConfigMap; this is pushed to search heads using the deployer bundle
+	deployerBundleAppDir = "/opt/splunk/etc/shcluster/apps/splunk-operator-bundle/local"
+)
+
+// addBundleToPodTemplate modifies the podTemplateSpec object to mount a bundle ConfigMap in appDir.
+// The kubelet refreshes the mounted files whenever the ConfigMap changes, so that the bundle can be
+// distributed without restarting the pod.
+func addBundleToPodTemplate(podTemplateSpec *corev1.PodTemplateSpec, configMapRef, appDir string) {
+	// Explicitly set the default value here so we can compare for changes correctly with current statefulset.
+	configMapVolDefaultMode := int32(corev1.ConfigMapVolumeSourceDefaultMode)
+
+	podTemplateSpec.Spec.Volumes = append(podTemplateSpec.Spec.Volumes, corev1.Volume{
+		Name: "mnt-splunk-bundle",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMapRef},
+				DefaultMode:          &configMapVolDefaultMode,
+			},
+		},
+	})
+
+	for idx := range podTemplateSpec.Spec.Containers {
+		containerSpec := &podTemplateSpec.Spec.Containers[idx]
+		containerSpec.VolumeMounts = append(containerSpec.VolumeMounts, corev1.VolumeMount{
+			Name:      "mnt-splunk-bundle",
+			MountPath: appDir,
+			ReadOnly:  true,
+		})
+	}
+}
+
+// GetClusterBundleChecksum returns a checksum of the contents of a cluster master or deployer's bundle ConfigMap
+func GetClusterBundleChecksum(configMap *corev1.ConfigMap) string {
+	data, _ := json.Marshal([]interface{}{configMap.Data, configMap.BinaryData})
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"testing"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAddBundleToPodTemplate(t *testing.T) {
+	cr := enterprisev1.ClusterMaster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cr.Spec.BundleConfigMapRef = "bundle1"
+	if err := ValidateClusterMasterSpec(&cr.Spec); err != nil {
+		t.Errorf("ValidateClusterMasterSpec() returned error: %v", err)
+	}
+	ss, err := GetClusterMasterStatefulSet(&cr)
+	if err != nil {
+		t.Errorf("GetClusterMasterStatefulSet() returned error: %v", err)
+	}
+
+	foundVolume := false
+	for _, volume := range ss.Spec.Template.Spec.Volumes {
+		if volume.Name == "mnt-splunk-bundle" && volume.ConfigMap != nil && volume.ConfigMap.Name == "bundle1" {
+			foundVolume = true
+		}
+	}
+	if !foundVolume {
+		t.Errorf("GetClusterMasterStatefulSet() missing bundle volume: %v", ss.Spec.Template.Spec.Volumes)
+	}
+
+	foundMount := false
+	for _, mount := range ss.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if mount.Name == "mnt-splunk-bundle" && mount.MountPath == clusterBundleAppDir && mount.ReadOnly {
+			foundMount = true
+		}
+	}
+	if !foundMount {
+		t.Errorf("GetClusterMasterStatefulSet() missing bundle volume mount: %v", ss.Spec.Template.Spec.Containers[0].VolumeMounts)
+	}
+
+	// deployers mount the bundle in shcluster/apps
+	shc := enterprisev1.SearchHeadCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	shc.Spec.BundleConfigMapRef = "bundle2"
+	if err := ValidateSearchHeadClusterSpec(&shc.Spec); err != nil {
+		t.Errorf("ValidateSearchHeadClusterSpec() returned error: %v", err)
+	}
+	ss, err = GetDeployerStatefulSet(&shc)
+	if err != nil {
+		t.Errorf("GetDeployerStatefulSet() returned error: %v", err)
+	}
+	foundMount = false
+	for _, mount := range ss.Spec.Template.Spec.Containers[0].VolumeMounts {
+		if mount.Name == "mnt-splunk-bundle" && mount.MountPath == deployerBundleAppDir {
+			foundMount = true
+		}
+	}
+	if !foundMount {
+		t.Errorf("GetDeployerStatefulSet() missing bundle volume mount: %v", ss.Spec.Template.Spec.Containers[0].VolumeMounts)
+	}
+}
+
+func TestGetClusterBundleChecksum(t *testing.T) {
+	configMap := corev1.ConfigMap{
+		Data: map[string]string{"indexes.conf": "[web]\nhomePath = $SPLUNK_DB/web/db\n"},
+	}
+	checksum := GetClusterBundleChecksum(&configMap)
+	if checksum == "" {
+		t.Errorf("GetClusterBundleChecksum() returned empty checksum")
+	}
+
+	// changes to the contents should change the checksum
+	configMap.Data["props.conf"] = "[web]\nTRUNCATE = 0\n"
+	if GetClusterBundleChecksum(&configMap) == checksum {
+		t.Errorf("GetClusterBundleChecksum() did not change after updating contents")
+	}
+
+	// changes to metadata should not
+	checksum = GetClusterBundleChecksum(&configMap)
+	configMap.ObjectMeta.Labels = map[string]string{"app": "bundle"}
+	if GetClusterBundleChecksum(&configMap) != checksum {
+		t.Errorf("GetClusterBundleChecksum() changed after updating labels")
+	}
+}
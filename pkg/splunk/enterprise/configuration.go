@@ -15,7 +15,10 @@
 package enterprise
 
 import (
+	"bytes"
 	"fmt"
+	"regexp"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -23,11 +26,14 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	"github.com/splunk/splunk-operator/pkg/splunk/resources"
 	"github.com/splunk/splunk-operator/pkg/splunk/spark"
 )
 
+// siteNameRegex matches valid names for sites of a multisite indexer cluster
+var siteNameRegex = regexp.MustCompile(`^site([1-9]|[1-5][0-9]|6[0-3])$`)
+
 // getSplunkLabels returns a map of labels to use for Splunk Enterprise components.
 func getSplunkLabels(identifier string, instanceType InstanceType) map[string]string {
 	return resources.GetLabels(instanceType.ToKind(), instanceType.ToString(), identifier)
@@ -100,8 +106,18 @@ func GetStandaloneStatefulSet(cr *enterprisev1.Standalone) (*appsv1.StatefulSet,
 	}
 
 	// add spark and java mounts to search head containers
-	if cr.Spec.SparkRef.Name != "" {
-		addDFCToPodTemplate(&ss.Spec.Template, cr.Spec.SparkRef, cr.Spec.SparkImage, cr.Spec.ImagePullPolicy, cr.Spec.Replicas > 1)
+	if cr.Spec.DFS.SparkRef.Name != "" {
+		addDFCToPodTemplate(&ss.Spec.Template, cr.Spec.DFS.SparkRef, cr.Spec.DFS.SparkImage, cr.Spec.ImagePullPolicy, cr.Spec.Replicas > 1)
+	}
+
+	// add SmartStore configuration
+	if len(cr.Spec.SmartStore.Volumes) > 0 {
+		addSmartStoreToPodTemplate(&ss.Spec.Template, cr, &cr.Spec.SmartStore, SplunkStandalone)
+	}
+
+	// add apps from app repository
+	if len(cr.Spec.AppRepo.Apps) > 0 {
+		addAppRepoToPodTemplate(&ss.Spec.Template, cr, &cr.Spec.AppRepo, &cr.Status.AppRepo, SplunkStandalone)
 	}
 
 	return ss, nil
@@ -124,8 +140,8 @@ func GetSearchHeadStatefulSet(cr *enterprisev1.SearchHeadCluster) (*appsv1.State
 	}
 
 	// add spark and java mounts to search head containers
-	if cr.Spec.SparkRef.Name != "" {
-		addDFCToPodTemplate(&ss.Spec.Template, cr.Spec.SparkRef, cr.Spec.SparkImage, cr.Spec.ImagePullPolicy, cr.Spec.Replicas > 1)
+	if cr.Spec.DFS.SparkRef.Name != "" {
+		addDFCToPodTemplate(&ss.Spec.Template, cr.Spec.DFS.SparkRef, cr.Spec.DFS.SparkImage, cr.Spec.ImagePullPolicy, cr.Spec.Replicas > 1)
 	}
 
 	return ss, nil
@@ -133,22 +149,125 @@ func GetSearchHeadStatefulSet(cr *enterprisev1.SearchHeadCluster) (*appsv1.State
 
 // GetIndexerStatefulSet returns a Kubernetes StatefulSet object for Splunk Enterprise indexers.
 func GetIndexerStatefulSet(cr *enterprisev1.IndexerCluster) (*appsv1.StatefulSet, error) {
-	return getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkIndexer, cr.Spec.Replicas, getIndexerExtraEnv(cr, cr.Spec.Replicas))
+	return getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkIndexer, cr.Spec.Replicas, getIndexerExtraEnv(cr))
 }
 
-// GetClusterMasterStatefulSet returns a Kubernetes StatefulSet object for a Splunk Enterprise license master.
-func GetClusterMasterStatefulSet(cr *enterprisev1.IndexerCluster) (*appsv1.StatefulSet, error) {
-	return getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkClusterMaster, 1, getIndexerExtraEnv(cr, cr.Spec.Replicas))
+// GetIndexerSiteStatefulSet returns a Kubernetes StatefulSet object for Splunk Enterprise indexers within one site of a multisite indexer cluster.
+func GetIndexerSiteStatefulSet(cr *enterprisev1.IndexerCluster, site *enterprisev1.IndexerClusterSiteSpec) (*appsv1.StatefulSet, error) {
+
+	// get indexer env variables with site
+	env := getIndexerExtraEnv(cr)
+	env = append(env, []corev1.EnvVar{
+		{Name: "SPLUNK_SITE", Value: site.Name},
+		{Name: "SPLUNK_MULTISITE_MASTER", Value: getClusterMasterURL(&cr.Spec.CommonSplunkSpec)},
+	}...)
+
+	// get generic statefulset for Splunk Enterprise objects
+	ss, err := getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkIndexer, site.Replicas, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// use site identifier for names, and add site label to selector so that each site has its own pods
+	siteIdentifier := GetSplunkSiteIdentifier(cr.GetIdentifier(), site.Name)
+	ss.ObjectMeta.Name = GetSplunkStatefulsetName(SplunkIndexer, siteIdentifier)
+	ss.Spec.ServiceName = GetSplunkServiceName(SplunkIndexer, siteIdentifier, true)
+	ss.Spec.Selector.MatchLabels[siteLabel] = site.Name
+	ss.Spec.Template.ObjectMeta.Labels[siteLabel] = site.Name
+	for idx := range ss.Spec.VolumeClaimTemplates {
+		ss.Spec.VolumeClaimTemplates[idx].ObjectMeta.Labels[siteLabel] = site.Name
+	}
+
+	// schedule pods for this site in its availability zone
+	if site.Zone != "" {
+		ss.Spec.Template.Spec.Affinity = resources.AppendZoneNodeAffinity(ss.Spec.Template.Spec.Affinity, site.Zone)
+	}
+
+	return ss, nil
+}
+
+// GetIndexerSiteService returns a Kubernetes headless Service object for Splunk Enterprise indexers within one site of a multisite indexer cluster.
+func GetIndexerSiteService(cr *enterprisev1.IndexerCluster, site *enterprisev1.IndexerClusterSiteSpec) *corev1.Service {
+	service := GetSplunkService(cr, cr.Spec.CommonSpec, SplunkIndexer, true)
+	service.ObjectMeta.Name = GetSplunkServiceName(SplunkIndexer, GetSplunkSiteIdentifier(cr.GetIdentifier(), site.Name), true)
+	service.ObjectMeta.Labels[siteLabel] = site.Name
+	service.Spec.Selector[siteLabel] = site.Name
+	return service
+}
+
+// GetClusterMasterStatefulSet returns a Kubernetes StatefulSet object for a Splunk Enterprise indexer cluster master.
+func GetClusterMasterStatefulSet(cr *enterprisev1.ClusterMaster) (*appsv1.StatefulSet, error) {
+	ss, err := getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkClusterMaster, 1, getClusterMasterMultisiteExtraEnv(cr))
+	if err != nil {
+		return nil, err
+	}
+
+	// add SmartStore configuration, which is distributed to peers using the cluster bundle
+	if len(cr.Spec.SmartStore.Volumes) > 0 {
+		addSmartStoreToPodTemplate(&ss.Spec.Template, cr, &cr.Spec.SmartStore, SplunkClusterMaster)
+	}
+
+	// add apps from app repository, which may be distributed to peers using the cluster bundle
+	if len(cr.Spec.AppRepo.Apps) > 0 {
+		addAppRepoToPodTemplate(&ss.Spec.Template, cr, &cr.Spec.AppRepo, &cr.Status.AppRepo, SplunkClusterMaster)
+	}
+
+	// add bundle ConfigMap, which is validated and distributed to peers whenever it changes
+	if cr.Spec.BundleConfigMapRef != "" {
+		addBundleToPodTemplate(&ss.Spec.Template, cr.Spec.BundleConfigMapRef, clusterBundleAppDir)
+	}
+
+	return ss, nil
 }
 
 // GetDeployerStatefulSet returns a Kubernetes StatefulSet object for a Splunk Enterprise license master.
 func GetDeployerStatefulSet(cr *enterprisev1.SearchHeadCluster) (*appsv1.StatefulSet, error) {
-	return getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkDeployer, 1, getSearchHeadExtraEnv(cr, cr.Spec.Replicas))
+	ss, err := getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkDeployer, 1, getSearchHeadExtraEnv(cr, cr.Spec.Replicas))
+	if err != nil {
+		return nil, err
+	}
+
+	// add apps from app repository, which may be distributed to search heads using the deployer bundle
+	if len(cr.Spec.AppRepo.Apps) > 0 {
+		addAppRepoToPodTemplate(&ss.Spec.Template, cr, &cr.Spec.AppRepo, &cr.Status.AppRepo, SplunkDeployer)
+	}
+
+	// add bundle ConfigMap, which is pushed to search heads whenever it changes
+	if cr.Spec.BundleConfigMapRef != "" {
+		addBundleToPodTemplate(&ss.Spec.Template, cr.Spec.BundleConfigMapRef, deployerBundleAppDir)
+	}
+
+	return ss, nil
 }
 
 // GetLicenseMasterStatefulSet returns a Kubernetes StatefulSet object for a Splunk Enterprise license master.
 func GetLicenseMasterStatefulSet(cr *enterprisev1.LicenseMaster) (*appsv1.StatefulSet, error) {
-	return getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkLicenseMaster, 1, []corev1.EnvVar{})
+	ss, err := getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkLicenseMaster, 1, []corev1.EnvVar{})
+	if err != nil {
+		return nil, err
+	}
+
+	// add apps from app repository
+	if len(cr.Spec.AppRepo.Apps) > 0 {
+		addAppRepoToPodTemplate(&ss.Spec.Template, cr, &cr.Spec.AppRepo, &cr.Status.AppRepo, SplunkLicenseMaster)
+	}
+
+	return ss, nil
+}
+
+// GetMonitoringConsoleStatefulSet returns a Kubernetes StatefulSet object for a Splunk Enterprise monitoring console.
+func GetMonitoringConsoleStatefulSet(cr *enterprisev1.MonitoringConsole) (*appsv1.StatefulSet, error) {
+	ss, err := getSplunkStatefulSet(cr, &cr.Spec.CommonSplunkSpec, SplunkMonitoringConsole, 1, []corev1.EnvVar{})
+	if err != nil {
+		return nil, err
+	}
+
+	// add apps from app repository
+	if len(cr.Spec.AppRepo.Apps) > 0 {
+		addAppRepoToPodTemplate(&ss.Spec.Template, cr, &cr.Spec.AppRepo, &cr.Status.AppRepo, SplunkMonitoringConsole)
+	}
+
+	return ss, nil
 }
 
 // GetSplunkService returns a Kubernetes Service object for Splunk instances configured for a Splunk Enterprise resource.
@@ -247,6 +366,9 @@ func setServiceTemplateDefaults(spec *enterprisev1.CommonSplunkSpec) {
 	}
 }
 
+// minSecretRotationInterval is the minimum number of seconds between automatic rotations of secrets
+const minSecretRotationInterval = 3600
+
 // validateCommonSplunkSpec checks validity and makes default updates to a CommonSplunkSpec, and returns error if something is wrong.
 func validateCommonSplunkSpec(spec *enterprisev1.CommonSplunkSpec) error {
 	// if not specified via spec or env, image defaults to splunk/splunk
@@ -263,43 +385,287 @@ func validateCommonSplunkSpec(spec *enterprisev1.CommonSplunkSpec) error {
 		},
 	}
 
+	// storage capacities are parsed when volume claims are created, but invalid values should be caught early
+	if _, err := resources.ParseResourceQuantity(spec.EtcStorage, ""); err != nil {
+		return fmt.Errorf("%s: %s", "etcStorage", err)
+	}
+	if _, err := resources.ParseResourceQuantity(spec.VarStorage, ""); err != nil {
+		return fmt.Errorf("%s: %s", "varStorage", err)
+	}
+
+	if err := validateUpdateStrategy(&spec.UpdateStrategy); err != nil {
+		return err
+	}
+
+	if spec.SecretRotation.Interval != 0 && spec.SecretRotation.Interval < minSecretRotationInterval {
+		return fmt.Errorf("secretRotation.interval must be 0 or at least %d; value=%d", minSecretRotationInterval, spec.SecretRotation.Interval)
+	}
+
+	if spec.SecretRef.Name != "" && spec.SecretRotation.Interval != 0 {
+		return fmt.Errorf("secretRotation.interval must be 0 when secretRef is used; value=%d", spec.SecretRotation.Interval)
+	}
+
+	if err := validateTLSSpec(&spec.TLS); err != nil {
+		return err
+	}
+
 	setVolumeDefaults(spec)
 	setServiceTemplateDefaults(spec)
 
 	return resources.ValidateCommonSpec(&spec.CommonSpec, defaultResources)
 }
 
+// validateUpdateStrategy checks validity and makes default updates to an UpdateStrategySpec, and returns error if something is wrong.
+func validateUpdateStrategy(spec *enterprisev1.UpdateStrategySpec) error {
+	if spec.MaxUnavailable < 0 {
+		return fmt.Errorf("updateStrategy.maxUnavailable must not be negative; value=%d", spec.MaxUnavailable)
+	}
+	if spec.Partition < 0 {
+		return fmt.Errorf("updateStrategy.partition must not be negative; value=%d", spec.Partition)
+	}
+	if spec.MinReadySeconds < 0 {
+		return fmt.Errorf("updateStrategy.minReadySeconds must not be negative; value=%d", spec.MinReadySeconds)
+	}
+	if spec.PauseAfter < 0 {
+		return fmt.Errorf("updateStrategy.pauseAfter must not be negative; value=%d", spec.PauseAfter)
+	}
+	if spec.MaxUnavailable == 0 {
+		spec.MaxUnavailable = 1
+	}
+	return nil
+}
+
+// validateMultisiteSpec checks validity and makes default updates to a MultisiteSpec, and returns error if something is wrong.
+func validateMultisiteSpec(spec *enterprisev1.MultisiteSpec) error {
+	sites := make(map[string]bool)
+	for idx := range spec.Sites {
+		site := &spec.Sites[idx]
+		if err := validateSiteName(site.Name, sites); err != nil {
+			return err
+		}
+		if site.Replicas < 0 {
+			return fmt.Errorf("replicas for site \"%s\" must not be negative; value=%d", site.Name, site.Replicas)
+		}
+		if site.Replicas == 0 {
+			site.Replicas = 1
+		}
+	}
+	return nil
+}
+
+// validateClusterMasterMultisiteSpec checks validity and makes default updates to a ClusterMasterMultisiteSpec, and returns error if something is wrong.
+func validateClusterMasterMultisiteSpec(spec *enterprisev1.ClusterMasterMultisiteSpec) error {
+	if len(spec.Sites) == 0 {
+		return nil
+	}
+
+	sites := make(map[string]bool)
+	for _, site := range spec.Sites {
+		if err := validateSiteName(site, sites); err != nil {
+			return err
+		}
+	}
+
+	numSites := int32(len(spec.Sites))
+	if spec.SiteReplicationFactorOrigin == 0 {
+		spec.SiteReplicationFactorOrigin = 1
+	}
+	if spec.SiteReplicationFactorTotal == 0 {
+		spec.SiteReplicationFactorTotal = numSites
+	}
+	if spec.SiteSearchFactorOrigin == 0 {
+		spec.SiteSearchFactorOrigin = 1
+	}
+	if spec.SiteSearchFactorTotal == 0 {
+		spec.SiteSearchFactorTotal = numSites
+	}
+	if spec.SiteReplicationFactorOrigin > spec.SiteReplicationFactorTotal {
+		return fmt.Errorf("siteReplicationFactorOrigin (%d) must not be greater than siteReplicationFactorTotal (%d)", spec.SiteReplicationFactorOrigin, spec.SiteReplicationFactorTotal)
+	}
+	if spec.SiteSearchFactorOrigin > spec.SiteSearchFactorTotal {
+		return fmt.Errorf("siteSearchFactorOrigin (%d) must not be greater than siteSearchFactorTotal (%d)", spec.SiteSearchFactorOrigin, spec.SiteSearchFactorTotal)
+	}
+	if spec.SiteSearchFactorTotal > spec.SiteReplicationFactorTotal {
+		return fmt.Errorf("siteSearchFactorTotal (%d) must not be greater than siteReplicationFactorTotal (%d)", spec.SiteSearchFactorTotal, spec.SiteReplicationFactorTotal)
+	}
+
+	return nil
+}
+
+// validateSiteName checks that name is a valid site name that is not already in sites, and adds it to sites.
+func validateSiteName(name string, sites map[string]bool) error {
+	if !siteNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid site name \"%s\"; must be site1 through site63", name)
+	}
+	if sites[name] {
+		return fmt.Errorf("site \"%s\" is defined more than once", name)
+	}
+	sites[name] = true
+	return nil
+}
+
+// validateRemoteVolumes checks validity and makes default updates to a list of remote storage volumes used by feature,
+// and returns the set of volume names or error if something is wrong.
+func validateRemoteVolumes(volumes []enterprisev1.RemoteVolumeSpec, feature string) (map[string]bool, error) {
+	names := make(map[string]bool)
+	for idx := range volumes {
+		volume := &volumes[idx]
+		if volume.Name == "" {
+			return nil, fmt.Errorf("%s volume %d is missing a name", feature, idx)
+		}
+		if names[volume.Name] {
+			return nil, fmt.Errorf("%s volume \"%s\" is defined more than once", feature, volume.Name)
+		}
+		names[volume.Name] = true
+		if volume.Endpoint == "" {
+			return nil, fmt.Errorf("%s volume \"%s\" is missing an endpoint", feature, volume.Name)
+		}
+		volume.Path = strings.Trim(strings.TrimPrefix(volume.Path, "s3://"), "/")
+		if volume.Path == "" {
+			return nil, fmt.Errorf("%s volume \"%s\" is missing a path", feature, volume.Name)
+		}
+	}
+	return names, nil
+}
+
 // ValidateIndexerClusterSpec checks validity and makes default updates to a IndexerClusterSpec, and returns error if something is wrong.
 func ValidateIndexerClusterSpec(spec *enterprisev1.IndexerClusterSpec) error {
+	if spec.ClusterMasterRef.Name == "" {
+		return fmt.Errorf("clusterMasterRef is required")
+	}
+	if spec.Replicas < 0 {
+		return fmt.Errorf("replicas must not be negative; value=%d", spec.Replicas)
+	}
+	if spec.RollingRestartPercentage < 0 || spec.RollingRestartPercentage > 100 {
+		return fmt.Errorf("rollingRestartPercentage must be between 0 and 100; value=%d", spec.RollingRestartPercentage)
+	}
+	if spec.RebalanceOnScaleUp.BucketSkewThreshold < 0 || spec.RebalanceOnScaleUp.BucketSkewThreshold > 100 {
+		return fmt.Errorf("rebalanceOnScaleUp.bucketSkewThreshold must be between 0 and 100; value=%d", spec.RebalanceOnScaleUp.BucketSkewThreshold)
+	}
+	if spec.RebalanceOnScaleUp.BucketSkewThreshold == 0 {
+		spec.RebalanceOnScaleUp.BucketSkewThreshold = 20
+	}
+	if err := validateMultisiteSpec(&spec.Multisite); err != nil {
+		return err
+	}
+
+	// apps are installed on indexer cluster peers using the cluster master bundle
+	if len(spec.AppRepo.Volumes) > 0 || len(spec.AppRepo.Apps) > 0 {
+		return fmt.Errorf("appRepo is not supported for indexer clusters; use the cluster master to install apps")
+	}
+
+	// total number of indexers is the sum of all sites for multisite clusters
+	if len(spec.Multisite.Sites) > 0 {
+		spec.Replicas = 0
+		for _, site := range spec.Multisite.Sites {
+			spec.Replicas += site.Replicas
+		}
+	}
+
 	if spec.Replicas == 0 {
 		spec.Replicas = 1
 	}
 	return validateCommonSplunkSpec(&spec.CommonSplunkSpec)
 }
 
+// ValidateIndexerClusterMultisite checks that the sites of an indexer cluster match the multisite configuration of its cluster master,
+// and returns error if something is wrong. Both specs should already be validated.
+func ValidateIndexerClusterMultisite(spec *enterprisev1.IndexerClusterSpec, clusterMasterSpec *enterprisev1.ClusterMasterSpec) error {
+	if len(clusterMasterSpec.Multisite.Sites) == 0 {
+		if len(spec.Multisite.Sites) > 0 {
+			return fmt.Errorf("multisite sites are defined, but cluster master \"%s\" does not use multisite clustering", spec.ClusterMasterRef.Name)
+		}
+		return nil
+	}
+	if len(spec.Multisite.Sites) == 0 {
+		return fmt.Errorf("cluster master \"%s\" uses multisite clustering, but no sites are defined", spec.ClusterMasterRef.Name)
+	}
+
+	sites := make(map[string]bool)
+	for _, site := range clusterMasterSpec.Multisite.Sites {
+		sites[site] = true
+	}
+	for _, site := range spec.Multisite.Sites {
+		if !sites[site.Name] {
+			return fmt.Errorf("site \"%s\" is not defined by cluster master \"%s\"", site.Name, spec.ClusterMasterRef.Name)
+		}
+	}
+	return nil
+}
+
+// ValidateClusterMasterSpec checks validity and makes default updates to a ClusterMasterSpec, and returns error if something is wrong.
+func ValidateClusterMasterSpec(spec *enterprisev1.ClusterMasterSpec) error {
+	if err := validateClusterMasterMultisiteSpec(&spec.Multisite); err != nil {
+		return err
+	}
+	if err := validateSmartStoreSpec(&spec.SmartStore); err != nil {
+		return err
+	}
+	if err := validateAppRepoSpec(&spec.AppRepo, SplunkClusterMaster); err != nil {
+		return err
+	}
+	return validateCommonSplunkSpec(&spec.CommonSplunkSpec)
+}
+
 // ValidateSearchHeadClusterSpec checks validity and makes default updates to a SearchHeadClusterSpec, and returns error if something is wrong.
 func ValidateSearchHeadClusterSpec(spec *enterprisev1.SearchHeadClusterSpec) error {
+	if err := validateAppRepoSpec(&spec.AppRepo, SplunkDeployer); err != nil {
+		return err
+	}
+	if spec.Replicas < 0 {
+		return fmt.Errorf("replicas must not be negative; value=%d", spec.Replicas)
+	}
 	if spec.Replicas < 3 {
 		spec.Replicas = 3
 	}
-	spec.SparkImage = spark.GetSparkImage(spec.SparkImage)
+	spec.DFS.SparkImage = spark.GetSparkImage(spec.DFS.SparkImage)
 	return validateCommonSplunkSpec(&spec.CommonSplunkSpec)
 }
 
 // ValidateStandaloneSpec checks validity and makes default updates to a StandaloneSpec, and returns error if something is wrong.
 func ValidateStandaloneSpec(spec *enterprisev1.StandaloneSpec) error {
+	if err := validateSmartStoreSpec(&spec.SmartStore); err != nil {
+		return err
+	}
+	if err := validateAppRepoSpec(&spec.AppRepo, SplunkStandalone); err != nil {
+		return err
+	}
+	if spec.Replicas < 0 {
+		return fmt.Errorf("replicas must not be negative; value=%d", spec.Replicas)
+	}
 	if spec.Replicas == 0 {
 		spec.Replicas = 1
 	}
-	spec.SparkImage = spark.GetSparkImage(spec.SparkImage)
+	spec.DFS.SparkImage = spark.GetSparkImage(spec.DFS.SparkImage)
 	return validateCommonSplunkSpec(&spec.CommonSplunkSpec)
 }
 
 // ValidateLicenseMasterSpec checks validity and makes default updates to a LicenseMasterSpec, and returns error if something is wrong.
 func ValidateLicenseMasterSpec(spec *enterprisev1.LicenseMasterSpec) error {
+	if err := validateAppRepoSpec(&spec.AppRepo, SplunkLicenseMaster); err != nil {
+		return err
+	}
+	return validateCommonSplunkSpec(&spec.CommonSplunkSpec)
+}
+
+// ValidateMonitoringConsoleSpec checks validity and makes default updates to a MonitoringConsoleSpec, and returns error if something is wrong.
+func ValidateMonitoringConsoleSpec(spec *enterprisev1.MonitoringConsoleSpec) error {
+	if err := validateAppRepoSpec(&spec.AppRepo, SplunkMonitoringConsole); err != nil {
+		return err
+	}
 	return validateCommonSplunkSpec(&spec.CommonSplunkSpec)
 }
 
+// ValidateSecretRef returns error if the secretRef of a custom resource refers to a Secret in another namespace. Its
+// values are copied into a Secret in the custom resource's namespace, so allowing this would let anyone who can create
+// a custom resource read Secrets in any namespace using the operator's permissions.
+func ValidateSecretRef(cr enterprisev1.MetaObject, spec *enterprisev1.CommonSplunkSpec) error {
+	if spec.SecretRef.Namespace != "" && spec.SecretRef.Namespace != cr.GetNamespace() {
+		return fmt.Errorf("secretRef must be in the same namespace as the custom resource; namespace=\"%s\"", spec.SecretRef.Namespace)
+	}
+	return nil
+}
+
 // GetSplunkDefaults returns a Kubernetes ConfigMap containing defaults for a Splunk Enterprise resource.
 func GetSplunkDefaults(identifier, namespace string, instanceType InstanceType, defaults string) *corev1.ConfigMap {
 	return &corev1.ConfigMap{
@@ -333,7 +699,113 @@ func GetSplunkSecrets(cr enterprisev1.MetaObject, instanceType InstanceType, idx
 		"idxc_secret":  idxcSecret,
 		"shc_secret":   generateSplunkSecret(),
 	}
-	secretData["default.yml"] = []byte(fmt.Sprintf(`
+	secretData["default.yml"] = getSplunkSecretsDefaults(secretData)
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetSplunkSecretsName(cr.GetIdentifier(), instanceType),
+			Namespace: cr.GetNamespace(),
+		},
+		Data: secretData,
+	}
+}
+
+// splunkSecretKeys are the keys of a Secret returned by GetSplunkSecrets, other than default.yml
+var splunkSecretKeys = []string{"hec_token", "password", "pass4SymmKey", "idxc_secret", "shc_secret"}
+
+// GetSplunkSecretsFromRef returns a Kubernetes Secret like GetSplunkSecrets, using values from a user-managed Secret
+// referenced by secretRef instead of generating them. Non-empty values for idxcSecret and pass4SymmKey are shared with
+// a referenced resource and take precedence; all other keys are required in source.
+func GetSplunkSecretsFromRef(cr enterprisev1.MetaObject, instanceType InstanceType, source *corev1.Secret, idxcSecret []byte, pass4SymmKey []byte) (*corev1.Secret, error) {
+	secretData := map[string][]byte{
+		"pass4SymmKey": pass4SymmKey,
+		"idxc_secret":  idxcSecret,
+	}
+	missing := []string{}
+	for _, key := range splunkSecretKeys {
+		if len(secretData[key]) == 0 {
+			secretData[key] = source.Data[key]
+			if len(secretData[key]) == 0 {
+				missing = append(missing, key)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Secret %s/%s referenced by secretRef is missing required keys: %s",
+			source.GetNamespace(), source.GetName(), strings.Join(missing, ", "))
+	}
+	secretData["default.yml"] = getSplunkSecretsDefaults(secretData)
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetSplunkSecretsName(cr.GetIdentifier(), instanceType),
+			Namespace: cr.GetNamespace(),
+		},
+		Data: secretData,
+	}, nil
+}
+
+// GetSplunkRotatedSecrets returns a Kubernetes Secret containing a new randomly generated admin password for a Splunk
+// Enterprise resource, and a new pass4SymmKey if requested, since it may be shared with other resources. idxc_secret
+// and shc_secret are never rotated, since every member of a cluster would have to switch to the new value at once.
+func GetSplunkRotatedSecrets(cr enterprisev1.MetaObject, instanceType InstanceType, rotatePass4SymmKey bool) *corev1.Secret {
+	secretData := map[string][]byte{
+		"password": generateSplunkSecret(),
+	}
+	if rotatePass4SymmKey {
+		secretData["pass4SymmKey"] = generateSplunkSecret()
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetSplunkRotatedSecretsName(cr.GetIdentifier(), instanceType),
+			Namespace: cr.GetNamespace(),
+		},
+		Data: secretData,
+	}
+}
+
+// ServiceAccountUsername is the name of the low-privilege Splunk user that the operator uses to poll the status of
+// Splunk instances, instead of the admin user
+const ServiceAccountUsername = "splunk-operator"
+
+// GetSplunkServiceAccount returns a Kubernetes Secret containing the username and a randomly generated password for
+// the service account of a Splunk Enterprise resource.
+func GetSplunkServiceAccount(cr enterprisev1.MetaObject, instanceType InstanceType) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetSplunkServiceAccountName(cr.GetIdentifier(), instanceType),
+			Namespace: cr.GetNamespace(),
+		},
+		Data: map[string][]byte{
+			"username": []byte(ServiceAccountUsername),
+			"password": generateSplunkSecret(),
+		},
+	}
+}
+
+// UpdateSplunkSecrets updates a Secret returned by GetSplunkSecrets with new values, which are ignored if empty, and
+// returns true if anything has changed.
+func UpdateSplunkSecrets(secrets *corev1.Secret, values map[string][]byte) bool {
+	if secrets.Data == nil {
+		secrets.Data = make(map[string][]byte)
+	}
+	changed := false
+	for k, v := range values {
+		if len(v) > 0 && !bytes.Equal(secrets.Data[k], v) {
+			secrets.Data[k] = v
+			changed = true
+		}
+	}
+	if changed {
+		secrets.Data["default.yml"] = getSplunkSecretsDefaults(secrets.Data)
+	}
+	return changed
+}
+
+// getSplunkSecretsDefaults returns the contents of the default.yml file included in a Secret returned by GetSplunkSecrets
+func getSplunkSecretsDefaults(secretData map[string][]byte) []byte {
+	return []byte(fmt.Sprintf(`
 splunk:
     hec_disabled: 0
     hec_enableSSL: 0
@@ -350,14 +822,6 @@ splunk:
 		secretData["pass4SymmKey"],
 		secretData["idxc_secret"],
 		secretData["shc_secret"]))
-
-	return &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      GetSplunkSecretsName(cr.GetIdentifier(), instanceType),
-			Namespace: cr.GetNamespace(),
-		},
-		Data: secretData,
-	}
 }
 
 // generateSplunkSecret returns a randomly generated Splunk secret.
@@ -635,6 +1099,16 @@ func updateSplunkPodTemplateWithConfig(podTemplateSpec *corev1.PodTemplateSpec,
 		},
 	})
 
+	// add TLS certificates to all splunk containers
+	if spec.TLS.Enabled {
+		addSplunkVolumeToTemplate(podTemplateSpec, "tls", corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  GetSplunkTLSName(cr.GetIdentifier(), instanceType),
+				DefaultMode: &secretVolDefaultMode,
+			},
+		})
+	}
+
 	// Explicitly set the default value here so we can compare for changes correctly with current statefulset.
 	configMapVolDefaultMode := int32(corev1.ConfigMapVolumeSourceDefaultMode)
 
@@ -691,6 +1165,9 @@ func updateSplunkPodTemplateWithConfig(podTemplateSpec *corev1.PodTemplateSpec,
 
 	// prepare defaults variable
 	splunkDefaults := "/mnt/splunk-secrets/default.yml"
+	if spec.TLS.Enabled {
+		splunkDefaults = fmt.Sprintf("%s,%s", splunkDefaults, tlsMountPath+"/default.yml")
+	}
 	if spec.DefaultsURL != "" {
 		splunkDefaults = fmt.Sprintf("%s,%s", splunkDefaults, spec.DefaultsURL)
 	}
@@ -727,13 +1204,8 @@ func updateSplunkPodTemplateWithConfig(podTemplateSpec *corev1.PodTemplateSpec,
 
 	// append URL for cluster master, if configured
 	var clusterMasterURL string
-	if instanceType == SplunkIndexer {
-		clusterMasterURL = GetSplunkServiceName(SplunkClusterMaster, cr.GetIdentifier(), false)
-	} else if instanceType != SplunkClusterMaster && spec.IndexerClusterRef.Name != "" {
-		clusterMasterURL = GetSplunkServiceName(SplunkClusterMaster, spec.IndexerClusterRef.Name, false)
-		if spec.IndexerClusterRef.Namespace != "" {
-			clusterMasterURL = resources.GetServiceFQDN(spec.IndexerClusterRef.Namespace, clusterMasterURL)
-		}
+	if instanceType != SplunkClusterMaster {
+		clusterMasterURL = getClusterMasterURL(spec)
 	}
 	if clusterMasterURL != "" {
 		extraEnv = append(extraEnv, corev1.EnvVar{
@@ -767,12 +1239,56 @@ func getSearchHeadExtraEnv(cr enterprisev1.MetaObject, replicas int32) []corev1.
 	}
 }
 
-// getIndexerExtraEnv returns extra environment variables used by search head clusters
-func getIndexerExtraEnv(cr enterprisev1.MetaObject, replicas int32) []corev1.EnvVar {
+// getIndexerExtraEnv returns extra environment variables used by indexer clusters
+func getIndexerExtraEnv(cr *enterprisev1.IndexerCluster) []corev1.EnvVar {
 	return []corev1.EnvVar{
 		{
 			Name:  "SPLUNK_INDEXER_URL",
-			Value: GetSplunkStatefulsetUrls(cr.GetNamespace(), SplunkIndexer, cr.GetIdentifier(), replicas, false),
+			Value: getIndexerUrls(cr),
 		},
 	}
 }
+
+// getIndexerUrls returns a list of fully qualified domain names for all indexers in an indexer cluster, including those in every site
+func getIndexerUrls(cr *enterprisev1.IndexerCluster) string {
+	if len(cr.Spec.Multisite.Sites) == 0 {
+		return GetSplunkStatefulsetUrls(cr.GetNamespace(), SplunkIndexer, cr.GetIdentifier(), cr.Spec.Replicas, false)
+	}
+	siteURLs := make([]string, len(cr.Spec.Multisite.Sites))
+	for idx, site := range cr.Spec.Multisite.Sites {
+		siteURLs[idx] = GetSplunkStatefulsetUrls(cr.GetNamespace(), SplunkIndexer, GetSplunkSiteIdentifier(cr.GetIdentifier(), site.Name), site.Replicas, false)
+	}
+	return strings.Join(siteURLs, ",")
+}
+
+// getClusterMasterURL returns the URL of the cluster master referenced by a CommonSplunkSpec, or an empty string if there is none
+func getClusterMasterURL(spec *enterprisev1.CommonSplunkSpec) string {
+	ref := spec.ClusterMasterRef
+	if ref.Name == "" {
+		return ""
+	}
+	clusterMasterURL := GetSplunkServiceName(SplunkClusterMaster, ref.Name, false)
+	if ref.Namespace != "" {
+		clusterMasterURL = resources.GetServiceFQDN(ref.Namespace, clusterMasterURL)
+	}
+	return clusterMasterURL
+}
+
+// getClusterMasterMultisiteExtraEnv returns extra environment variables used by cluster masters of multisite indexer clusters
+func getClusterMasterMultisiteExtraEnv(cr *enterprisev1.ClusterMaster) []corev1.EnvVar {
+	multisite := &cr.Spec.Multisite
+	if len(multisite.Sites) == 0 {
+		return []corev1.EnvVar{}
+	}
+
+	// cluster master is assigned to the first site
+	return []corev1.EnvVar{
+		{Name: "SPLUNK_SITE", Value: multisite.Sites[0]},
+		{Name: "SPLUNK_ALL_SITES", Value: strings.Join(multisite.Sites, ",")},
+		{Name: "SPLUNK_MULTISITE_MASTER", Value: GetSplunkServiceName(SplunkClusterMaster, cr.GetIdentifier(), false)},
+		{Name: "SPLUNK_MULTISITE_REPLICATION_FACTOR_ORIGIN", Value: fmt.Sprintf("%d", multisite.SiteReplicationFactorOrigin)},
+		{Name: "SPLUNK_MULTISITE_REPLICATION_FACTOR_TOTAL", Value: fmt.Sprintf("%d", multisite.SiteReplicationFactorTotal)},
+		{Name: "SPLUNK_MULTISITE_SEARCH_FACTOR_ORIGIN", Value: fmt.Sprintf("%d", multisite.SiteSearchFactorOrigin)},
+		{Name: "SPLUNK_MULTISITE_SEARCH_FACTOR_TOTAL", Value: fmt.Sprintf("%d", multisite.SiteSearchFactorTotal)},
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"fmt"
+	"strings"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+// GetMonitoringConsolePeers returns the host and management port of every Splunk Enterprise instance managed by a custom resource,
+// which are used as search peers by monitoring consoles. The spec of the custom resource should already be validated, so that
+// defaults have been applied to the number of replicas.
+func GetMonitoringConsolePeers(cr enterprisev1.MetaObject) []string {
+	var urls []string
+	switch cr := cr.(type) {
+	case *enterprisev1.Standalone:
+		urls = append(urls, GetSplunkStatefulsetUrls(cr.GetNamespace(), SplunkStandalone, cr.GetIdentifier(), cr.Spec.Replicas, false))
+	case *enterprisev1.LicenseMaster:
+		urls = append(urls, GetSplunkStatefulsetURL(cr.GetNamespace(), SplunkLicenseMaster, cr.GetIdentifier(), 0, false))
+	case *enterprisev1.SearchHeadCluster:
+		urls = append(urls, GetSplunkStatefulsetURL(cr.GetNamespace(), SplunkDeployer, cr.GetIdentifier(), 0, false))
+		urls = append(urls, GetSplunkStatefulsetUrls(cr.GetNamespace(), SplunkSearchHead, cr.GetIdentifier(), cr.Spec.Replicas, false))
+	case *enterprisev1.ClusterMaster:
+		urls = append(urls, GetSplunkStatefulsetURL(cr.GetNamespace(), SplunkClusterMaster, cr.GetIdentifier(), 0, false))
+	case *enterprisev1.IndexerCluster:
+		urls = append(urls, getIndexerUrls(cr))
+	}
+
+	peers := []string{}
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+		for _, host := range strings.Split(url, ",") {
+			peers = append(peers, fmt.Sprintf("%s:8089", host))
+		}
+	}
+	return peers
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"reflect"
+	"testing"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetMonitoringConsolePeers(t *testing.T) {
+	meta := metav1.ObjectMeta{
+		Name:      "stack1",
+		Namespace: "test",
+	}
+	test := func(cr enterprisev1.MetaObject, want []string) {
+		got := GetMonitoringConsolePeers(cr)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetMonitoringConsolePeers(%T) = %v; want %v", cr, got, want)
+		}
+	}
+
+	standalone := enterprisev1.Standalone{ObjectMeta: meta}
+	standalone.Spec.Replicas = 2
+	test(&standalone, []string{
+		"splunk-stack1-standalone-0.splunk-stack1-standalone-headless.test.svc.cluster.local:8089",
+		"splunk-stack1-standalone-1.splunk-stack1-standalone-headless.test.svc.cluster.local:8089",
+	})
+
+	licenseMaster := enterprisev1.LicenseMaster{ObjectMeta: meta}
+	test(&licenseMaster, []string{
+		"splunk-stack1-license-master-0.splunk-stack1-license-master-headless.test.svc.cluster.local:8089",
+	})
+
+	searchHeadCluster := enterprisev1.SearchHeadCluster{ObjectMeta: meta}
+	searchHeadCluster.Spec.Replicas = 3
+	test(&searchHeadCluster, []string{
+		"splunk-stack1-deployer-0.splunk-stack1-deployer-headless.test.svc.cluster.local:8089",
+		"splunk-stack1-search-head-0.splunk-stack1-search-head-headless.test.svc.cluster.local:8089",
+		"splunk-stack1-search-head-1.splunk-stack1-search-head-headless.test.svc.cluster.local:8089",
+		"splunk-stack1-search-head-2.splunk-stack1-search-head-headless.test.svc.cluster.local:8089",
+	})
+
+	clusterMaster := enterprisev1.ClusterMaster{ObjectMeta: meta}
+	test(&clusterMaster, []string{
+		"splunk-stack1-cluster-master-0.splunk-stack1-cluster-master-headless.test.svc.cluster.local:8089",
+	})
+
+	indexerCluster := enterprisev1.IndexerCluster{ObjectMeta: meta}
+	indexerCluster.Spec.Replicas = 1
+	test(&indexerCluster, []string{
+		"splunk-stack1-indexer-0.splunk-stack1-indexer-headless.test.svc.cluster.local:8089",
+	})
+
+	// multisite indexer clusters include indexers from every site
+	indexerCluster.Spec.Multisite.Sites = []enterprisev1.IndexerClusterSiteSpec{
+		{Name: "site1", Replicas: 1},
+		{Name: "site2", Replicas: 1},
+	}
+	test(&indexerCluster, []string{
+		"splunk-stack1-site1-indexer-0.splunk-stack1-site1-indexer-headless.test.svc.cluster.local:8089",
+		"splunk-stack1-site2-indexer-0.splunk-stack1-site2-indexer-headless.test.svc.cluster.local:8089",
+	})
+
+	// no peers for instances that have not been scaled up
+	standalone.Spec.Replicas = 0
+	test(&standalone, []string{})
+}
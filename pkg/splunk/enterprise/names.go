@@ -38,9 +38,33 @@ const (
 	// identifier
 	secretsTemplateStr = "splunk-%s-%s-secrets"
 
+	// identifier
+	rotatedSecretsTemplateStr = "splunk-%s-%s-rotated-secrets"
+
 	// identifier
 	defaultsTemplateStr = "splunk-%s-%s-defaults"
 
+	// identifier
+	smartStoreTemplateStr = "splunk-%s-%s-smartstore"
+
+	// identifier
+	appsTemplateStr = "splunk-%s-%s-apps"
+
+	// identifier
+	tlsTemplateStr = "splunk-%s-%s-tls"
+
+	// identifier
+	serviceAccountTemplateStr = "splunk-%s-%s-service-account"
+
+	// name of the Secret containing the certificate and private key of the operator's internal CA within each namespace
+	operatorCAName = "splunk-operator-ca"
+
+	// identifier, site name (ex: site1, site2, ...)
+	siteIdentifierTemplateStr = "%s-%s"
+
+	// label used to select pods belonging to a specific site of a multisite indexer cluster
+	siteLabel = "enterprise.splunk.com/site"
+
 	// default docker image used for Splunk instances
 	defaultSplunkImage = "splunk/splunk"
 
@@ -84,11 +108,46 @@ func GetSplunkSecretsName(identifier string, instanceType InstanceType) string {
 	return fmt.Sprintf(secretsTemplateStr, identifier, instanceType.ToKind())
 }
 
+// GetSplunkRotatedSecretsName uses a template to name a Kubernetes Secret containing new values for a SplunkEnterprise resource's secrets while they are being rotated.
+func GetSplunkRotatedSecretsName(identifier string, instanceType InstanceType) string {
+	return fmt.Sprintf(rotatedSecretsTemplateStr, identifier, instanceType.ToKind())
+}
+
 // GetSplunkDefaultsName uses a template to name a Kubernetes ConfigMap for a SplunkEnterprise resource.
 func GetSplunkDefaultsName(identifier string, instanceType InstanceType) string {
 	return fmt.Sprintf(defaultsTemplateStr, identifier, instanceType.ToKind())
 }
 
+// GetSplunkSmartStoreName uses a template to name a Kubernetes Secret containing SmartStore configuration for a SplunkEnterprise resource.
+func GetSplunkSmartStoreName(identifier string, instanceType InstanceType) string {
+	return fmt.Sprintf(smartStoreTemplateStr, identifier, instanceType.ToKind())
+}
+
+// GetSplunkAppsName uses a template to name a Kubernetes Secret containing app repository configuration for a SplunkEnterprise resource.
+func GetSplunkAppsName(identifier string, instanceType InstanceType) string {
+	return fmt.Sprintf(appsTemplateStr, identifier, instanceType.ToKind())
+}
+
+// GetSplunkTLSName uses a template to name a Kubernetes Secret containing TLS certificates for a SplunkEnterprise resource.
+func GetSplunkTLSName(identifier string, instanceType InstanceType) string {
+	return fmt.Sprintf(tlsTemplateStr, identifier, instanceType.ToKind())
+}
+
+// GetSplunkServiceAccountName uses a template to name a Kubernetes Secret containing the credentials of the service account used to poll the status of Splunk instances.
+func GetSplunkServiceAccountName(identifier string, instanceType InstanceType) string {
+	return fmt.Sprintf(serviceAccountTemplateStr, identifier, instanceType.ToKind())
+}
+
+// GetSplunkOperatorCAName returns the name of the Kubernetes Secret used by the operator's internal CA.
+func GetSplunkOperatorCAName() string {
+	return operatorCAName
+}
+
+// GetSplunkSiteIdentifier uses a template to name the resources for a specific site of a multisite indexer cluster.
+func GetSplunkSiteIdentifier(identifier string, site string) string {
+	return fmt.Sprintf(siteIdentifierTemplateStr, identifier, site)
+}
+
 // GetSplunkStatefulsetUrls returns a list of fully qualified domain names for all pods within a Splunk StatefulSet.
 func GetSplunkStatefulsetUrls(namespace string, instanceType InstanceType, identifier string, replicas int32, hostnameOnly bool) string {
 	urls := make([]string, replicas)
@@ -64,6 +64,14 @@ func TestGetSplunkSecretsName(t *testing.T) {
 	}
 }
 
+func TestGetSplunkRotatedSecretsName(t *testing.T) {
+	got := GetSplunkRotatedSecretsName("pw", SplunkIndexer)
+	want := "splunk-pw-indexer-rotated-secrets"
+	if got != want {
+		t.Errorf("GetSplunkRotatedSecretsName(\"%s\",\"%s\") = %s; want %s", "pw", SplunkIndexer, got, want)
+	}
+}
+
 func TestGetSplunkDefaultsName(t *testing.T) {
 	got := GetSplunkDefaultsName("t1", SplunkSearchHead)
 	want := "splunk-t1-search-head-defaults"
@@ -72,6 +80,46 @@ func TestGetSplunkDefaultsName(t *testing.T) {
 	}
 }
 
+func TestGetSplunkSmartStoreName(t *testing.T) {
+	got := GetSplunkSmartStoreName("t1", SplunkClusterMaster)
+	want := "splunk-t1-cluster-master-smartstore"
+	if got != want {
+		t.Errorf("GetSplunkSmartStoreName(\"%s\",\"%s\") = %s; want %s", "t1", SplunkClusterMaster, got, want)
+	}
+}
+
+func TestGetSplunkTLSName(t *testing.T) {
+	got := GetSplunkTLSName("t1", SplunkDeployer)
+	want := "splunk-t1-search-head-tls"
+	if got != want {
+		t.Errorf("GetSplunkTLSName(\"%s\",\"%s\") = %s; want %s", "t1", SplunkDeployer, got, want)
+	}
+}
+
+func TestGetSplunkServiceAccountName(t *testing.T) {
+	got := GetSplunkServiceAccountName("t1", SplunkClusterMaster)
+	want := "splunk-t1-cluster-master-service-account"
+	if got != want {
+		t.Errorf("GetSplunkServiceAccountName(\"%s\",\"%s\") = %s; want %s", "t1", SplunkClusterMaster, got, want)
+	}
+}
+
+func TestGetSplunkAppsName(t *testing.T) {
+	got := GetSplunkAppsName("t1", SplunkDeployer)
+	want := "splunk-t1-search-head-apps"
+	if got != want {
+		t.Errorf("GetSplunkAppsName(\"%s\",\"%s\") = %s; want %s", "t1", SplunkDeployer, got, want)
+	}
+}
+
+func TestGetSplunkSiteIdentifier(t *testing.T) {
+	got := GetSplunkSiteIdentifier("t1", "site2")
+	want := "t1-site2"
+	if got != want {
+		t.Errorf("GetSplunkSiteIdentifier(\"%s\",\"%s\") = %s; want %s", "t1", "site2", got, want)
+	}
+}
+
 func TestGetSplunkStatefulsetUrls(t *testing.T) {
 	test := func(want string, namespace string, instanceType InstanceType, identifier string, replicas int32, hostnameOnly bool) {
 		got := GetSplunkStatefulsetUrls(namespace, instanceType, identifier, replicas, hostnameOnly)
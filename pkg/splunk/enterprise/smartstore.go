@@ -0,0 +1,235 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+const (
+	// key within a remote volume's secretRef that contains the access key
+	S3AccessKey = "s3_access_key"
+
+	// key within a remote volume's secretRef that contains the secret key
+	S3SecretKey = "s3_secret_key"
+
+	// annotation used to recycle pods when SmartStore configuration changes
+	smartStoreChecksumAnnotation = "enterprise.splunk.com/smartstore-checksum"
+
+	// app directory used for SmartStore configuration on cluster masters; this is pushed to peers using the cluster bundle
+	smartStoreMasterAppDir = "/opt/splunk/etc/master-apps/splunk-operator/local"
+
+	// app directory used for SmartStore configuration on all other instances
+	smartStoreAppDir = "/opt/splunk/etc/apps/splunk-operator/local"
+)
+
+// builtinIndexes are indexes that Splunk Enterprise creates with default paths
+var builtinIndexes = map[string]bool{
+	"main":           true,
+	"history":        true,
+	"summary":        true,
+	"_internal":      true,
+	"_audit":         true,
+	"_introspection": true,
+	"_telemetry":     true,
+	"_thefishbucket": true,
+}
+
+// confStanza is used to represent a stanza within a Splunk .conf file
+type confStanza struct {
+	name   string
+	keys   []string
+	values map[string]string
+}
+
+// set adds a key and value to a confStanza, preserving the order that keys were added
+func (s *confStanza) set(key, value string) {
+	if s.values == nil {
+		s.values = make(map[string]string)
+	}
+	if _, ok := s.values[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.values[key] = value
+}
+
+// validateSmartStoreSpec checks validity and makes default updates to a SmartStoreSpec, and returns error if something is wrong.
+func validateSmartStoreSpec(spec *enterprisev1.SmartStoreSpec) error {
+	volumes, err := validateRemoteVolumes(spec.Volumes, "smartstore")
+	if err != nil {
+		return err
+	}
+
+	indexes := make(map[string]bool)
+	for idx := range spec.Indexes {
+		index := &spec.Indexes[idx]
+		if index.Name == "" {
+			return fmt.Errorf("smartstore index %d is missing a name", idx)
+		}
+		if indexes[index.Name] {
+			return fmt.Errorf("smartstore index \"%s\" is defined more than once", index.Name)
+		}
+		indexes[index.Name] = true
+		if index.VolumeName == "" {
+			if len(spec.Volumes) == 0 {
+				return fmt.Errorf("smartstore index \"%s\" requires a remote volume", index.Name)
+			}
+			index.VolumeName = spec.Volumes[0].Name
+		}
+		if !volumes[index.VolumeName] {
+			return fmt.Errorf("smartstore index \"%s\" refers to undefined volume \"%s\"", index.Name, index.VolumeName)
+		}
+		if index.RemotePath == "" {
+			index.RemotePath = index.Name
+		}
+		index.RemotePath = strings.Trim(index.RemotePath, "/")
+	}
+
+	return nil
+}
+
+// getSmartStoreStanzas returns the indexes.conf stanzas used to configure SmartStore
+func getSmartStoreStanzas(spec *enterprisev1.SmartStoreSpec, instanceType InstanceType, volumeSecrets map[string]*corev1.Secret) []confStanza {
+	stanzas := []confStanza{}
+
+	for _, volume := range spec.Volumes {
+		stanza := confStanza{name: fmt.Sprintf("volume:%s", volume.Name)}
+		stanza.set("storageType", "remote")
+		stanza.set("path", fmt.Sprintf("s3://%s", volume.Path))
+		if secret, ok := volumeSecrets[volume.Name]; ok && secret != nil {
+			stanza.set("remote.s3.access_key", string(secret.Data[S3AccessKey]))
+			stanza.set("remote.s3.secret_key", string(secret.Data[S3SecretKey]))
+		}
+		stanza.set("remote.s3.endpoint", volume.Endpoint)
+		stanzas = append(stanzas, stanza)
+	}
+
+	for _, index := range spec.Indexes {
+		stanza := confStanza{name: index.Name}
+		if !builtinIndexes[index.Name] {
+			stanza.set("homePath", fmt.Sprintf("$SPLUNK_DB/%s/db", index.Name))
+			stanza.set("coldPath", fmt.Sprintf("$SPLUNK_DB/%s/colddb", index.Name))
+			stanza.set("thawedPath", fmt.Sprintf("$SPLUNK_DB/%s/thaweddb", index.Name))
+		}
+		stanza.set("remotePath", fmt.Sprintf("volume:%s/%s", index.VolumeName, index.RemotePath))
+		if instanceType == SplunkClusterMaster {
+			stanza.set("repFactor", "auto")
+		}
+		stanzas = append(stanzas, stanza)
+	}
+
+	return stanzas
+}
+
+// getSmartStoreIndexesConf returns the contents of an indexes.conf file used to configure SmartStore
+func getSmartStoreIndexesConf(stanzas []confStanza) string {
+	var result strings.Builder
+	for idx, stanza := range stanzas {
+		if idx > 0 {
+			result.WriteString("\n")
+		}
+		fmt.Fprintf(&result, "[%s]\n", stanza.name)
+		for _, key := range stanza.keys {
+			fmt.Fprintf(&result, "%s = %s\n", key, stanza.values[key])
+		}
+	}
+	return result.String()
+}
+
+// getSmartStoreDefaults returns the contents of a default.yml file used to configure SmartStore
+func getSmartStoreDefaults(stanzas []confStanza, instanceType InstanceType) string {
+	directory := smartStoreAppDir
+	if instanceType == SplunkClusterMaster {
+		directory = smartStoreMasterAppDir
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, `
+splunk:
+    conf:
+        indexes:
+            directory: %s
+            content:
+`, directory)
+	for _, stanza := range stanzas {
+		fmt.Fprintf(&result, "                %q:\n", stanza.name)
+		for _, key := range stanza.keys {
+			fmt.Fprintf(&result, "                    %q: %q\n", key, stanza.values[key])
+		}
+	}
+	return result.String()
+}
+
+// GetSmartStoreSecret returns a Kubernetes Secret containing SmartStore configuration for a Splunk Enterprise resource.
+// The volumeSecrets map contains the Secret referenced by each remote volume, using the name of the volume as its key.
+func GetSmartStoreSecret(cr enterprisev1.MetaObject, spec *enterprisev1.SmartStoreSpec, instanceType InstanceType, volumeSecrets map[string]*corev1.Secret) *corev1.Secret {
+	stanzas := getSmartStoreStanzas(spec, instanceType, volumeSecrets)
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetSplunkSmartStoreName(cr.GetIdentifier(), instanceType),
+			Namespace: cr.GetNamespace(),
+		},
+		Data: map[string][]byte{
+			"default.yml":  []byte(getSmartStoreDefaults(stanzas, instanceType)),
+			"indexes.conf": []byte(getSmartStoreIndexesConf(stanzas)),
+		},
+	}
+}
+
+// addSmartStoreToPodTemplate modifies the podTemplateSpec object to incorporate SmartStore configuration.
+func addSmartStoreToPodTemplate(podTemplateSpec *corev1.PodTemplateSpec, cr enterprisev1.MetaObject, spec *enterprisev1.SmartStoreSpec, instanceType InstanceType) {
+	// Explicitly set the default value here so we can compare for changes correctly with current statefulset.
+	secretVolDefaultMode := int32(corev1.SecretVolumeSourceDefaultMode)
+
+	// add SmartStore secrets to all splunk containers
+	addSplunkVolumeToTemplate(podTemplateSpec, "smartstore", corev1.VolumeSource{
+		Secret: &corev1.SecretVolumeSource{
+			SecretName:  GetSplunkSmartStoreName(cr.GetIdentifier(), instanceType),
+			DefaultMode: &secretVolDefaultMode,
+		},
+	})
+
+	// append SmartStore default.yml to the defaults used by each container
+	for idx := range podTemplateSpec.Spec.Containers {
+		for envIdx := range podTemplateSpec.Spec.Containers[idx].Env {
+			env := &podTemplateSpec.Spec.Containers[idx].Env[envIdx]
+			if env.Name == "SPLUNK_DEFAULTS_URL" {
+				env.Value = fmt.Sprintf("%s,%s", env.Value, "/mnt/splunk-smartstore/default.yml")
+			}
+		}
+	}
+
+	// recycle pods whenever the SmartStore configuration changes
+	if podTemplateSpec.ObjectMeta.Annotations == nil {
+		podTemplateSpec.ObjectMeta.Annotations = make(map[string]string)
+	}
+	podTemplateSpec.ObjectMeta.Annotations[smartStoreChecksumAnnotation] = getSmartStoreChecksum(spec)
+}
+
+// getSmartStoreChecksum returns a checksum of a SmartStoreSpec
+func getSmartStoreChecksum(spec *enterprisev1.SmartStoreSpec) string {
+	data, _ := json.Marshal(spec)
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
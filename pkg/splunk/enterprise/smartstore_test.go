@@ -0,0 +1,203 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"strings"
+	"testing"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func getSmartStoreTestSpec() enterprisev1.SmartStoreSpec {
+	return enterprisev1.SmartStoreSpec{
+		Volumes: []enterprisev1.RemoteVolumeSpec{
+			{Name: "vol1", Endpoint: "https://s3-us-west-2.amazonaws.com", Path: "s3://bucket1/smartstore/", SecretRef: "s3-keys"},
+			{Name: "vol2", Endpoint: "http://minio:9000", Path: "bucket2"},
+		},
+		Indexes: []enterprisev1.RemoteIndexSpec{
+			{Name: "main"},
+			{Name: "web", VolumeName: "vol2", RemotePath: "/web-logs/"},
+		},
+	}
+}
+
+func TestValidateSmartStoreSpec(t *testing.T) {
+	spec := getSmartStoreTestSpec()
+	if err := validateSmartStoreSpec(&spec); err != nil {
+		t.Errorf("validateSmartStoreSpec() returned error: %v", err)
+	}
+	if spec.Volumes[0].Path != "bucket1/smartstore" {
+		t.Errorf("validateSmartStoreSpec() volume path = %s; want %s", spec.Volumes[0].Path, "bucket1/smartstore")
+	}
+	if spec.Indexes[0].VolumeName != "vol1" || spec.Indexes[0].RemotePath != "main" {
+		t.Errorf("validateSmartStoreSpec() index defaults = %s,%s; want %s,%s", spec.Indexes[0].VolumeName, spec.Indexes[0].RemotePath, "vol1", "main")
+	}
+	if spec.Indexes[1].VolumeName != "vol2" || spec.Indexes[1].RemotePath != "web-logs" {
+		t.Errorf("validateSmartStoreSpec() index = %s,%s; want %s,%s", spec.Indexes[1].VolumeName, spec.Indexes[1].RemotePath, "vol2", "web-logs")
+	}
+
+	test := func(update func(spec *enterprisev1.SmartStoreSpec), want string) {
+		spec := getSmartStoreTestSpec()
+		update(&spec)
+		err := validateSmartStoreSpec(&spec)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validateSmartStoreSpec() returned %v; want %s", err, want)
+		}
+	}
+
+	test(func(spec *enterprisev1.SmartStoreSpec) { spec.Volumes[1].Name = "" }, "missing a name")
+	test(func(spec *enterprisev1.SmartStoreSpec) { spec.Volumes[1].Name = "vol1" }, "defined more than once")
+	test(func(spec *enterprisev1.SmartStoreSpec) { spec.Volumes[0].Endpoint = "" }, "missing an endpoint")
+	test(func(spec *enterprisev1.SmartStoreSpec) { spec.Volumes[0].Path = "s3://" }, "missing a path")
+	test(func(spec *enterprisev1.SmartStoreSpec) { spec.Indexes[1].Name = "main" }, "defined more than once")
+	test(func(spec *enterprisev1.SmartStoreSpec) { spec.Indexes[1].VolumeName = "vol3" }, "undefined volume")
+	test(func(spec *enterprisev1.SmartStoreSpec) { spec.Volumes = nil }, "requires a remote volume")
+}
+
+func TestGetSmartStoreSecret(t *testing.T) {
+	cr := enterprisev1.ClusterMaster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cr.Spec.SmartStore = getSmartStoreTestSpec()
+	if err := ValidateClusterMasterSpec(&cr.Spec); err != nil {
+		t.Errorf("ValidateClusterMasterSpec() returned error: %v", err)
+	}
+	volumeSecrets := map[string]*corev1.Secret{
+		"vol1": {Data: map[string][]byte{S3AccessKey: []byte("access"), S3SecretKey: []byte("secret")}},
+	}
+
+	secret := GetSmartStoreSecret(&cr, &cr.Spec.SmartStore, SplunkClusterMaster, volumeSecrets)
+	if secret.GetName() != "splunk-stack1-cluster-master-smartstore" || secret.GetNamespace() != "test" {
+		t.Errorf("GetSmartStoreSecret() name = %s/%s; want %s", secret.GetNamespace(), secret.GetName(), "test/splunk-stack1-cluster-master-smartstore")
+	}
+
+	wantIndexesConf := `[volume:vol1]
+storageType = remote
+path = s3://bucket1/smartstore
+remote.s3.access_key = access
+remote.s3.secret_key = secret
+remote.s3.endpoint = https://s3-us-west-2.amazonaws.com
+
+[volume:vol2]
+storageType = remote
+path = s3://bucket2
+remote.s3.endpoint = http://minio:9000
+
+[main]
+remotePath = volume:vol1/main
+repFactor = auto
+
+[web]
+homePath = $SPLUNK_DB/web/db
+coldPath = $SPLUNK_DB/web/colddb
+thawedPath = $SPLUNK_DB/web/thaweddb
+remotePath = volume:vol2/web-logs
+repFactor = auto
+`
+	if got := string(secret.Data["indexes.conf"]); got != wantIndexesConf {
+		t.Errorf("GetSmartStoreSecret() indexes.conf = %s;\nwant %s", got, wantIndexesConf)
+	}
+
+	wantDefaults := `
+splunk:
+    conf:
+        indexes:
+            directory: /opt/splunk/etc/master-apps/splunk-operator/local
+            content:
+                "volume:vol1":
+                    "storageType": "remote"
+                    "path": "s3://bucket1/smartstore"
+                    "remote.s3.access_key": "access"
+                    "remote.s3.secret_key": "secret"
+                    "remote.s3.endpoint": "https://s3-us-west-2.amazonaws.com"
+                "volume:vol2":
+                    "storageType": "remote"
+                    "path": "s3://bucket2"
+                    "remote.s3.endpoint": "http://minio:9000"
+                "main":
+                    "remotePath": "volume:vol1/main"
+                    "repFactor": "auto"
+                "web":
+                    "homePath": "$SPLUNK_DB/web/db"
+                    "coldPath": "$SPLUNK_DB/web/colddb"
+                    "thawedPath": "$SPLUNK_DB/web/thaweddb"
+                    "remotePath": "volume:vol2/web-logs"
+                    "repFactor": "auto"
+`
+	if got := string(secret.Data["default.yml"]); got != wantDefaults {
+		t.Errorf("GetSmartStoreSecret() default.yml = %s;\nwant %s", got, wantDefaults)
+	}
+
+	// standalone instances use a local app without repFactor
+	secret = GetSmartStoreSecret(&cr, &cr.Spec.SmartStore, SplunkStandalone, volumeSecrets)
+	if got := string(secret.Data["default.yml"]); !strings.Contains(got, "directory: /opt/splunk/etc/apps/splunk-operator/local") || strings.Contains(got, "repFactor") {
+		t.Errorf("GetSmartStoreSecret(standalone) default.yml = %s", got)
+	}
+}
+
+func TestAddSmartStoreToPodTemplate(t *testing.T) {
+	cr := enterprisev1.Standalone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cr.Spec.SmartStore = getSmartStoreTestSpec()
+	if err := ValidateStandaloneSpec(&cr.Spec); err != nil {
+		t.Errorf("ValidateStandaloneSpec() returned error: %v", err)
+	}
+	ss, err := GetStandaloneStatefulSet(&cr)
+	if err != nil {
+		t.Errorf("GetStandaloneStatefulSet() returned error: %v", err)
+	}
+
+	foundVolume := false
+	for _, volume := range ss.Spec.Template.Spec.Volumes {
+		if volume.Name == "mnt-splunk-smartstore" && volume.Secret != nil && volume.Secret.SecretName == "splunk-stack1-standalone-smartstore" {
+			foundVolume = true
+		}
+	}
+	if !foundVolume {
+		t.Errorf("GetStandaloneStatefulSet() missing smartstore volume: %v", ss.Spec.Template.Spec.Volumes)
+	}
+
+	foundEnv := false
+	for _, env := range ss.Spec.Template.Spec.Containers[0].Env {
+		if env.Name == "SPLUNK_DEFAULTS_URL" && strings.HasSuffix(env.Value, ",/mnt/splunk-smartstore/default.yml") {
+			foundEnv = true
+		}
+	}
+	if !foundEnv {
+		t.Errorf("GetStandaloneStatefulSet() SPLUNK_DEFAULTS_URL missing smartstore defaults: %v", ss.Spec.Template.Spec.Containers[0].Env)
+	}
+
+	checksum := ss.Spec.Template.ObjectMeta.Annotations[smartStoreChecksumAnnotation]
+	if checksum == "" {
+		t.Errorf("GetStandaloneStatefulSet() missing %s annotation", smartStoreChecksumAnnotation)
+	}
+
+	// changes to the spec should change the checksum
+	cr.Spec.SmartStore.Indexes[0].RemotePath = "main2"
+	ss, _ = GetStandaloneStatefulSet(&cr)
+	if ss.Spec.Template.ObjectMeta.Annotations[smartStoreChecksumAnnotation] == checksum {
+		t.Errorf("GetStandaloneStatefulSet() %s annotation did not change", smartStoreChecksumAnnotation)
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	"github.com/splunk/splunk-operator/pkg/splunk/resources"
+)
+
+const (
+	// key within a TLS Secret that contains the CA certificate
+	TLSCACertKey = "ca.crt"
+
+	// key within the operator's CA Secret that contains the private key of the CA
+	tlsCAKeyKey = "ca.key"
+
+	// annotation used to record when the certificates within a TLS Secret expire
+	TLSExpirationAnnotation = "enterprise.splunk.com/tls-expiration"
+
+	// certificates issued by the operator are renewed when they are due to expire within this duration
+	TLSRenewBefore = 30 * 24 * time.Hour
+
+	// validity of the operator's internal CA
+	tlsCAValidity = 10 * 365 * 24 * time.Hour
+
+	// validity of certificates issued by the operator's internal CA
+	tlsCertValidity = 365 * 24 * time.Hour
+
+	// size of RSA keys generated by the operator
+	tlsKeyBits = 2048
+
+	// directory where the TLS Secret is mounted in pods
+	tlsMountPath = "/mnt/splunk-tls"
+
+	// name of the certificate files used when certificates are provided by a user-managed Secret
+	tlsUserCertName = "server"
+
+	// name of the certificate files used for each pod when certificates are issued by the operator's internal CA;
+	// this is expanded by splunk-ansible using the hostname of the pod
+	tlsPodCertName = "{{ lookup('env', 'HOSTNAME') }}"
+)
+
+// validateTLSSpec checks validity of a TLSSpec, and returns error if something is wrong.
+func validateTLSSpec(spec *enterprisev1.TLSSpec) error {
+	if spec.SecretName != "" && !spec.Enabled {
+		return fmt.Errorf("tls.secretName requires tls.enabled to be true")
+	}
+	return nil
+}
+
+// GetSplunkTLSHosts returns the DNS names used by each pod of a Splunk Enterprise statefulset, using the name of the
+// pod as its key. Site is the name of the site for multisite indexer clusters, or empty for all other statefulsets.
+func GetSplunkTLSHosts(namespace string, instanceType InstanceType, identifier string, site string, replicas int32) map[string][]string {
+	podIdentifier := identifier
+	if site != "" {
+		podIdentifier = GetSplunkSiteIdentifier(identifier, site)
+	}
+	serviceName := GetSplunkServiceName(instanceType, identifier, false)
+	headlessName := GetSplunkServiceName(instanceType, podIdentifier, true)
+
+	hosts := make(map[string][]string)
+	for n := int32(0); n < replicas; n++ {
+		podName := GetSplunkStatefulsetPodName(instanceType, podIdentifier, n)
+		hosts[podName] = []string{
+			podName,
+			fmt.Sprintf("%s.%s", podName, headlessName),
+			GetSplunkStatefulsetURL(namespace, instanceType, podIdentifier, n, false),
+			serviceName,
+			resources.GetServiceFQDN(namespace, serviceName),
+			resources.GetServiceFQDN(namespace, headlessName),
+		}
+	}
+	return hosts
+}
+
+// GetSplunkOperatorCA returns a Kubernetes Secret containing a new certificate and private key for the operator's
+// internal CA within a namespace.
+func GetSplunkOperatorCA(namespace string, now time.Time) (*corev1.Secret, error) {
+	key, err := rsa.GenerateKey(rand.Reader, tlsKeyBits)
+	if err != nil {
+		return nil, err
+	}
+	serialNumber, err := getTLSSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("Splunk Operator CA (%s)", namespace), Organization: []string{"Splunk"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(tlsCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetSplunkOperatorCAName(),
+			Namespace: namespace,
+		},
+		Data: map[string][]byte{
+			TLSCACertKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			tlsCAKeyKey:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+	}, nil
+}
+
+// GetSplunkTLSSecret returns a Kubernetes Secret containing the TLS certificates and default.yml used by a Splunk
+// Enterprise resource. When spec.SecretName is used, source is the user-managed Secret and its certificate is used by
+// all pods. Otherwise, source is the operator's CA Secret, which is used to issue a certificate for each pod in hosts.
+// Certificates in current (if not nil) are reused until they are due to be renewed, or the CA changes.
+func GetSplunkTLSSecret(cr enterprisev1.MetaObject, spec *enterprisev1.TLSSpec, instanceType InstanceType, source *corev1.Secret, current *corev1.Secret, hosts map[string][]string, now time.Time) (*corev1.Secret, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        GetSplunkTLSName(cr.GetIdentifier(), instanceType),
+			Namespace:   cr.GetNamespace(),
+			Annotations: make(map[string]string),
+		},
+		Data: make(map[string][]byte),
+	}
+	var err error
+	if spec.SecretName != "" {
+		err = addUserCertificate(secret, source)
+	} else {
+		err = addPodCertificates(secret, source, current, hosts, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// addUserCertificate adds the certificate and private key from a user-managed Secret to a TLS Secret
+func addUserCertificate(secret *corev1.Secret, source *corev1.Secret) error {
+	missing := []string{}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey, TLSCACertKey} {
+		if len(source.Data[key]) == 0 {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Secret %s/%s referenced by tls.secretName is missing required keys: %s",
+			source.GetNamespace(), source.GetName(), strings.Join(missing, ","))
+	}
+
+	certPEM, keyPEM := source.Data[corev1.TLSCertKey], source.Data[corev1.TLSPrivateKeyKey]
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return fmt.Errorf("Secret %s/%s referenced by tls.secretName has an invalid certificate: %v", source.GetNamespace(), source.GetName(), err)
+	}
+	cert, err := parseTLSCertificate(certPEM)
+	if err != nil {
+		return err
+	}
+
+	caPEM := source.Data[TLSCACertKey]
+	secret.Data[TLSCACertKey] = caPEM
+	addCertificateFiles(secret, tlsUserCertName, certPEM, keyPEM, caPEM)
+	secret.Data["default.yml"] = []byte(getTLSDefaults(tlsUserCertName))
+	secret.ObjectMeta.Annotations[TLSExpirationAnnotation] = cert.NotAfter.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// addPodCertificates adds certificates issued by the operator's CA for each pod in hosts to a TLS Secret. All of the
+// certificates expire at the same time, so that they are renewed together.
+func addPodCertificates(secret *corev1.Secret, ca *corev1.Secret, current *corev1.Secret, hosts map[string][]string, now time.Time) error {
+	caPEM := ca.Data[TLSCACertKey]
+	caCert, err := parseTLSCertificate(caPEM)
+	if err != nil {
+		return fmt.Errorf("Unable to parse certificate of CA %s: %v", ca.GetName(), err)
+	}
+	caKey, err := parseTLSPrivateKey(ca.Data[tlsCAKeyKey])
+	if err != nil {
+		return fmt.Errorf("Unable to parse private key of CA %s: %v", ca.GetName(), err)
+	}
+
+	// reuse current certificates unless the CA has changed, or they are due to be renewed
+	expiration := now.Add(tlsCertValidity).UTC().Truncate(time.Second)
+	reuse := false
+	if current != nil && bytes.Equal(current.Data[TLSCACertKey], caPEM) {
+		if t, err := time.Parse(time.RFC3339, current.GetAnnotations()[TLSExpirationAnnotation]); err == nil && now.Before(t.Add(-TLSRenewBefore)) {
+			expiration = t
+			reuse = true
+		}
+	}
+
+	podNames := make([]string, 0, len(hosts))
+	for podName := range hosts {
+		podNames = append(podNames, podName)
+	}
+	sort.Strings(podNames)
+	for _, podName := range podNames {
+		dnsNames := hosts[podName]
+		if reuse && isPodCertificateCurrent(current, podName, dnsNames) {
+			for _, ext := range []string{".crt", ".key", ".pem"} {
+				secret.Data[podName+ext] = current.Data[podName+ext]
+			}
+			continue
+		}
+		certPEM, keyPEM, err := issueTLSCertificate(caCert, caKey, podName, dnsNames, now, expiration)
+		if err != nil {
+			return err
+		}
+		addCertificateFiles(secret, podName, certPEM, keyPEM, caPEM)
+	}
+
+	secret.Data[TLSCACertKey] = caPEM
+	secret.Data["default.yml"] = []byte(getTLSDefaults(tlsPodCertName))
+	secret.ObjectMeta.Annotations[TLSExpirationAnnotation] = expiration.Format(time.RFC3339)
+	return nil
+}
+
+// isPodCertificateCurrent returns true if a TLS Secret contains a certificate for a pod that is valid for dnsNames
+func isPodCertificateCurrent(secret *corev1.Secret, podName string, dnsNames []string) bool {
+	if len(secret.Data[podName+".key"]) == 0 || len(secret.Data[podName+".pem"]) == 0 {
+		return false
+	}
+	cert, err := parseTLSCertificate(secret.Data[podName+".crt"])
+	return err == nil && reflect.DeepEqual(cert.DNSNames, dnsNames)
+}
+
+// addCertificateFiles adds files named name.crt (certificate), name.key (private key) and name.pem (certificate,
+// private key and CA certificate, as used by splunkd) to a TLS Secret
+func addCertificateFiles(secret *corev1.Secret, name string, certPEM, keyPEM, caPEM []byte) {
+	secret.Data[name+".crt"] = certPEM
+	secret.Data[name+".key"] = keyPEM
+	secret.Data[name+".pem"] = bytes.Join([][]byte{certPEM, keyPEM, caPEM}, nil)
+}
+
+// issueTLSCertificate returns a new certificate and private key for a server, signed by a CA
+func issueTLSCertificate(caCert *x509.Certificate, caKey *rsa.PrivateKey, commonName string, dnsNames []string, now, notAfter time.Time) ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, tlsKeyBits)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := getTLSSerialNumber()
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Splunk"}},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, nil
+}
+
+// getTLSSerialNumber returns a random serial number for a new certificate
+func getTLSSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// parseTLSCertificate returns the first certificate in PEM encoded data
+func parseTLSCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// parseTLSPrivateKey returns the RSA private key in PEM encoded data
+func parseTLSPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "RSA PRIVATE KEY" {
+		return nil, fmt.Errorf("no PEM encoded RSA private key found")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// getTLSDefaults returns the contents of a default.yml file that enables TLS for splunkd, Splunk Web, HTTP event
+// collector and forwarding (S2S), using the certificate files named certName
+func getTLSDefaults(certName string) string {
+	return fmt.Sprintf(`
+splunk:
+    http_enableSSL: 1
+    http_enableSSL_cert: "%[1]s/%[2]s.crt"
+    http_enableSSL_privKey: "%[1]s/%[2]s.key"
+    hec_enableSSL: 1
+    hec:
+        ssl: true
+        cert: "%[1]s/%[2]s.pem"
+    s2s:
+        ssl: true
+        cert: "%[1]s/%[2]s.pem"
+        ca: "%[1]s/%[3]s"
+    conf:
+        server:
+            directory: /opt/splunk/etc/system/local
+            content:
+                "sslConfig":
+                    "serverCert": "%[1]s/%[2]s.pem"
+                    "sslRootCAPath": "%[1]s/%[3]s"
+`, tlsMountPath, certName, TLSCACertKey)
+}
+
+// GetTLSExpiration returns the time when the certificates in a TLS Secret expire, or nil if this is unknown
+func GetTLSExpiration(secret *corev1.Secret) *metav1.Time {
+	t, err := time.Parse(time.RFC3339, secret.GetAnnotations()[TLSExpirationAnnotation])
+	if err != nil {
+		return nil
+	}
+	result := metav1.NewTime(t)
+	return &result
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enterprise
+
+import (
+	"bytes"
+	"crypto/x509"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+func TestGetSplunkTLSHosts(t *testing.T) {
+	hosts := GetSplunkTLSHosts("test", SplunkSearchHead, "stack1", "", 2)
+	want := map[string][]string{
+		"splunk-stack1-search-head-0": {
+			"splunk-stack1-search-head-0",
+			"splunk-stack1-search-head-0.splunk-stack1-search-head-headless",
+			"splunk-stack1-search-head-0.splunk-stack1-search-head-headless.test.svc.cluster.local",
+			"splunk-stack1-search-head-service",
+			"splunk-stack1-search-head-service.test.svc.cluster.local",
+			"splunk-stack1-search-head-headless.test.svc.cluster.local",
+		},
+		"splunk-stack1-search-head-1": {
+			"splunk-stack1-search-head-1",
+			"splunk-stack1-search-head-1.splunk-stack1-search-head-headless",
+			"splunk-stack1-search-head-1.splunk-stack1-search-head-headless.test.svc.cluster.local",
+			"splunk-stack1-search-head-service",
+			"splunk-stack1-search-head-service.test.svc.cluster.local",
+			"splunk-stack1-search-head-headless.test.svc.cluster.local",
+		},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("GetSplunkTLSHosts() = %v; want %v", hosts, want)
+	}
+
+	// pods in each site of a multisite indexer cluster use the site's headless service
+	hosts = GetSplunkTLSHosts("test", SplunkIndexer, "idxc", "east", 1)
+	want = map[string][]string{
+		"splunk-idxc-east-indexer-0": {
+			"splunk-idxc-east-indexer-0",
+			"splunk-idxc-east-indexer-0.splunk-idxc-east-indexer-headless",
+			"splunk-idxc-east-indexer-0.splunk-idxc-east-indexer-headless.test.svc.cluster.local",
+			"splunk-idxc-indexer-service",
+			"splunk-idxc-indexer-service.test.svc.cluster.local",
+			"splunk-idxc-east-indexer-headless.test.svc.cluster.local",
+		},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("GetSplunkTLSHosts() = %v; want %v", hosts, want)
+	}
+}
+
+func TestGetSplunkTLSSecret(t *testing.T) {
+	cr := enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	spec := enterprisev1.TLSSpec{Enabled: true}
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	ca, err := GetSplunkOperatorCA("test", now)
+	if err != nil {
+		t.Fatalf("GetSplunkOperatorCA() returned error: %v", err)
+	}
+	if ca.GetName() != "splunk-operator-ca" || len(ca.Data[TLSCACertKey]) == 0 || len(ca.Data[tlsCAKeyKey]) == 0 {
+		t.Errorf("GetSplunkOperatorCA() = %s %v; want splunk-operator-ca with ca.crt and ca.key", ca.GetName(), ca.Data)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.Data[TLSCACertKey])
+
+	// verify returns the certificate issued for a pod, after checking that it was signed by the CA for dnsName
+	verify := func(secret *corev1.Secret, podName, dnsName string, now time.Time) *x509.Certificate {
+		cert, err := parseTLSCertificate(secret.Data[podName+".crt"])
+		if err != nil {
+			t.Fatalf("GetSplunkTLSSecret() %s.crt is invalid: %v", podName, err)
+		}
+		_, err = cert.Verify(x509.VerifyOptions{DNSName: dnsName, Roots: roots, CurrentTime: now})
+		if err != nil {
+			t.Errorf("GetSplunkTLSSecret() %s.crt failed verification for %s: %v", podName, dnsName, err)
+		}
+		if !bytes.HasPrefix(secret.Data[podName+".pem"], secret.Data[podName+".crt"]) {
+			t.Errorf("GetSplunkTLSSecret() %s.pem does not start with certificate", podName)
+		}
+		return cert
+	}
+
+	hosts := GetSplunkTLSHosts("test", SplunkStandalone, "stack1", "", 1)
+	secret, err := GetSplunkTLSSecret(&cr, &spec, SplunkStandalone, ca, nil, hosts, now)
+	if err != nil {
+		t.Fatalf("GetSplunkTLSSecret() returned error: %v", err)
+	}
+	if secret.GetName() != "splunk-stack1-standalone-tls" {
+		t.Errorf("GetSplunkTLSSecret() name = %s; want %s", secret.GetName(), "splunk-stack1-standalone-tls")
+	}
+	cert := verify(secret, "splunk-stack1-standalone-0", "splunk-stack1-standalone-0.splunk-stack1-standalone-headless.test.svc.cluster.local", now)
+	if !cert.NotAfter.Equal(now.Add(tlsCertValidity)) {
+		t.Errorf("GetSplunkTLSSecret() expires at %s; want %s", cert.NotAfter, now.Add(tlsCertValidity))
+	}
+	if got := secret.GetAnnotations()[TLSExpirationAnnotation]; got != "2021-06-01T00:00:00Z" {
+		t.Errorf("GetSplunkTLSSecret() %s = %s; want %s", TLSExpirationAnnotation, got, "2021-06-01T00:00:00Z")
+	}
+	if !strings.Contains(string(secret.Data["default.yml"]), `serverCert": "/mnt/splunk-tls/{{ lookup('env', 'HOSTNAME') }}.pem"`) {
+		t.Errorf("GetSplunkTLSSecret() default.yml missing per-pod serverCert: %s", secret.Data["default.yml"])
+	}
+
+	// scaling up reuses existing certificates, and issues new ones with the same expiration
+	later := now.Add(24 * time.Hour)
+	hosts = GetSplunkTLSHosts("test", SplunkStandalone, "stack1", "", 2)
+	scaled, err := GetSplunkTLSSecret(&cr, &spec, SplunkStandalone, ca, secret, hosts, later)
+	if err != nil {
+		t.Fatalf("GetSplunkTLSSecret() returned error: %v", err)
+	}
+	if !bytes.Equal(scaled.Data["splunk-stack1-standalone-0.key"], secret.Data["splunk-stack1-standalone-0.key"]) {
+		t.Errorf("GetSplunkTLSSecret() re-issued certificate for existing pod")
+	}
+	cert = verify(scaled, "splunk-stack1-standalone-1", "splunk-stack1-standalone-1", later)
+	if !cert.NotAfter.Equal(now.Add(tlsCertValidity)) {
+		t.Errorf("GetSplunkTLSSecret() new pod expires at %s; want %s", cert.NotAfter, now.Add(tlsCertValidity))
+	}
+
+	// scaling down removes certificates
+	hosts = GetSplunkTLSHosts("test", SplunkStandalone, "stack1", "", 1)
+	scaledDown, _ := GetSplunkTLSSecret(&cr, &spec, SplunkStandalone, ca, scaled, hosts, later)
+	if !reflect.DeepEqual(scaledDown.Data, secret.Data) {
+		t.Errorf("GetSplunkTLSSecret() after scaling down = %v; want %v", scaledDown.Data, secret.Data)
+	}
+
+	// certificates are renewed 30 days before they expire
+	renewal := now.Add(tlsCertValidity - TLSRenewBefore)
+	renewed, _ := GetSplunkTLSSecret(&cr, &spec, SplunkStandalone, ca, secret, hosts, renewal)
+	if bytes.Equal(renewed.Data["splunk-stack1-standalone-0.key"], secret.Data["splunk-stack1-standalone-0.key"]) {
+		t.Errorf("GetSplunkTLSSecret() did not renew certificate")
+	}
+	if got := renewed.GetAnnotations()[TLSExpirationAnnotation]; got != "2022-05-02T00:00:00Z" {
+		t.Errorf("GetSplunkTLSSecret() %s = %s; want %s", TLSExpirationAnnotation, got, "2022-05-02T00:00:00Z")
+	}
+
+	// certificates are re-issued when the CA changes
+	newCA, _ := GetSplunkOperatorCA("test", now)
+	reissued, _ := GetSplunkTLSSecret(&cr, &spec, SplunkStandalone, newCA, secret, hosts, later)
+	if bytes.Equal(reissued.Data["splunk-stack1-standalone-0.key"], secret.Data["splunk-stack1-standalone-0.key"]) ||
+		!bytes.Equal(reissued.Data[TLSCACertKey], newCA.Data[TLSCACertKey]) {
+		t.Errorf("GetSplunkTLSSecret() did not re-issue certificate for new CA")
+	}
+
+	// user-managed certificates are used by all pods
+	spec.SecretName = "my-certs"
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-certs", Namespace: "test"},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       secret.Data["splunk-stack1-standalone-0.crt"],
+			corev1.TLSPrivateKeyKey: secret.Data["splunk-stack1-standalone-0.key"],
+			TLSCACertKey:            ca.Data[TLSCACertKey],
+		},
+	}
+	user, err := GetSplunkTLSSecret(&cr, &spec, SplunkStandalone, source, nil, hosts, now)
+	if err != nil {
+		t.Fatalf("GetSplunkTLSSecret() returned error: %v", err)
+	}
+	for _, key := range []string{"ca.crt", "server.crt", "server.key", "server.pem", "default.yml"} {
+		if len(user.Data[key]) == 0 {
+			t.Errorf("GetSplunkTLSSecret() missing %s", key)
+		}
+	}
+	if len(user.Data) != 5 {
+		t.Errorf("GetSplunkTLSSecret() data has %d keys; want 5", len(user.Data))
+	}
+	if !strings.Contains(string(user.Data["default.yml"]), `serverCert": "/mnt/splunk-tls/server.pem"`) {
+		t.Errorf("GetSplunkTLSSecret() default.yml missing serverCert: %s", user.Data["default.yml"])
+	}
+	if got := user.GetAnnotations()[TLSExpirationAnnotation]; got != "2021-06-01T00:00:00Z" {
+		t.Errorf("GetSplunkTLSSecret() %s = %s; want %s", TLSExpirationAnnotation, got, "2021-06-01T00:00:00Z")
+	}
+
+	source.Data[corev1.TLSPrivateKeyKey] = newCA.Data[tlsCAKeyKey]
+	_, err = GetSplunkTLSSecret(&cr, &spec, SplunkStandalone, source, nil, hosts, now)
+	if err == nil || !strings.Contains(err.Error(), "Secret test/my-certs referenced by tls.secretName has an invalid certificate") {
+		t.Errorf("GetSplunkTLSSecret() with mismatched key returned %v; want error", err)
+	}
+
+	delete(source.Data, corev1.TLSPrivateKeyKey)
+	delete(source.Data, TLSCACertKey)
+	_, err = GetSplunkTLSSecret(&cr, &spec, SplunkStandalone, source, nil, hosts, now)
+	want := "Secret test/my-certs referenced by tls.secretName is missing required keys: tls.key,ca.crt"
+	if err == nil || err.Error() != want {
+		t.Errorf("GetSplunkTLSSecret() returned %v; want %s", err, want)
+	}
+}
+
+func TestGetTLSExpiration(t *testing.T) {
+	secret := corev1.Secret{}
+	if got := GetTLSExpiration(&secret); got != nil {
+		t.Errorf("GetTLSExpiration() = %v; want nil", got)
+	}
+	secret.SetAnnotations(map[string]string{TLSExpirationAnnotation: "2021-06-01T00:00:00Z"})
+	want := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	if got := GetTLSExpiration(&secret); got == nil || !got.Time.Equal(want) {
+		t.Errorf("GetTLSExpiration() = %v; want %s", got, want)
+	}
+}
+
+func TestAddTLSToPodTemplate(t *testing.T) {
+	cr := enterprisev1.Standalone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cr.Spec.TLS.Enabled = true
+	cr.Spec.DefaultsURL = "/mnt/defaults/default.yml"
+	if err := ValidateStandaloneSpec(&cr.Spec); err != nil {
+		t.Errorf("ValidateStandaloneSpec() returned error: %v", err)
+	}
+	ss, err := GetStandaloneStatefulSet(&cr)
+	if err != nil {
+		t.Errorf("GetStandaloneStatefulSet() returned error: %v", err)
+	}
+
+	foundVolume := false
+	for _, volume := range ss.Spec.Template.Spec.Volumes {
+		if volume.Name == "mnt-splunk-tls" && volume.Secret != nil && volume.Secret.SecretName == "splunk-stack1-standalone-tls" {
+			foundVolume = true
+		}
+	}
+	if !foundVolume {
+		t.Errorf("GetStandaloneStatefulSet() missing tls volume: %v", ss.Spec.Template.Spec.Volumes)
+	}
+
+	// TLS defaults are applied before any provided by the user, so that they may be overridden
+	want := "/mnt/splunk-secrets/default.yml,/mnt/splunk-tls/default.yml,/mnt/defaults/default.yml"
+	for _, env := range ss.Spec.Template.Spec.Containers[0].Env {
+		if env.Name == "SPLUNK_DEFAULTS_URL" && env.Value != want {
+			t.Errorf("GetStandaloneStatefulSet() SPLUNK_DEFAULTS_URL = %s; want %s", env.Value, want)
+		}
+	}
+}
@@ -35,6 +35,9 @@ const (
 
 	// SplunkLicenseMaster controls one or more license slaves
 	SplunkLicenseMaster InstanceType = "license-master"
+
+	// SplunkMonitoringConsole is a search head used to monitor the health of other Splunk Enterprise instances
+	SplunkMonitoringConsole InstanceType = "monitoring-console"
 )
 
 // ToString returns a string for a given InstanceType
@@ -58,6 +61,8 @@ func (instanceType InstanceType) ToRole() string {
 		role = "splunk_deployer"
 	case SplunkLicenseMaster:
 		role = "splunk_license_master"
+	case SplunkMonitoringConsole:
+		role = "splunk_monitor"
 	}
 	return role
 }
@@ -69,7 +74,7 @@ func (instanceType InstanceType) ToKind() string {
 	case SplunkStandalone:
 		kind = "standalone"
 	case SplunkClusterMaster:
-		kind = "indexer"
+		kind = "cluster-master"
 	case SplunkIndexer:
 		kind = "indexer"
 	case SplunkSearchHead:
@@ -78,6 +83,8 @@ func (instanceType InstanceType) ToKind() string {
 		kind = "search-head"
 	case SplunkLicenseMaster:
 		kind = "license-master"
+	case SplunkMonitoringConsole:
+		kind = "monitoring-console"
 	}
 	return kind
 }
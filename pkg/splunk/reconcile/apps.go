@@ -0,0 +1,219 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+	"github.com/splunk/splunk-operator/pkg/splunk/resources"
+)
+
+// presigned URLs for app packages remain valid for 7 days, which is the maximum supported by S3;
+// they are refreshed every time the app repository is polled
+const appPackageURLExpiration = 7 * 24 * time.Hour
+
+// ApplyAppRepoConfig checks an app repository for new or changed app packages, reconciles the Kubernetes Secret used
+// to install them on Splunk Enterprise instances, and updates status with the latest version of each app package.
+// The app repository is checked when the spec changes, or when its poll interval has elapsed since the last check.
+func ApplyAppRepoConfig(client ControllerClient, cr enterprisev1.MetaObject, spec *enterprisev1.AppRepoSpec, instanceType enterprise.InstanceType, status *enterprisev1.AppRepoStatus) error {
+	scopedLog := log.WithName("ApplyAppRepoConfig").WithValues("name", cr.GetIdentifier(), "namespace", cr.GetNamespace())
+
+	if len(spec.Apps) == 0 {
+		status.Apps = []enterprisev1.AppStatus{}
+		status.LastPollTime = 0
+		return nil
+	}
+
+	now := time.Now().Unix()
+	if !isAppRepoChanged(spec, status) && now < status.LastPollTime+spec.PollInterval {
+		return nil
+	}
+	scopedLog.Info("Checking app repository for new or changed app packages")
+
+	// retrieve keys for each remote volume
+	volumeSecrets := make(map[string]*corev1.Secret)
+	for _, volume := range spec.Volumes {
+		if volume.SecretRef == "" {
+			continue
+		}
+		secret, err := getRemoteVolumeSecret(client, cr, &volume)
+		if err != nil {
+			return err
+		}
+		volumeSecrets[volume.Name] = secret
+	}
+
+	// get the latest version of each app package
+	previous := make(map[string]enterprisev1.AppStatus)
+	for _, appStatus := range status.Apps {
+		previous[appStatus.Name] = appStatus
+	}
+	packageURLs := make(map[string]string)
+	result := make([]enterprisev1.AppStatus, len(spec.Apps))
+	for idx, app := range spec.Apps {
+		appStatus := enterprisev1.AppStatus{
+			Name:     app.Name,
+			Scope:    app.Scope,
+			Location: enterprise.GetAppLocation(spec, &app),
+		}
+		if prev, ok := previous[app.Name]; ok && prev.Scope == appStatus.Scope && prev.Location == appStatus.Location {
+			appStatus.Version = prev.Version
+			appStatus.Phase = prev.Phase
+		}
+
+		version, packageURL, err := getAppPackage(client, cr, spec, &app, volumeSecrets)
+		if err != nil {
+			scopedLog.Error(err, "Unable to get app package", "app", app.Name)
+			appStatus.Phase = enterprisev1.PhaseError
+			appStatus.Message = err.Error()
+			// keep installing the previous version, so that the app is not dropped from instances that restart
+			if appStatus.Version != "" {
+				if packageURL, err = getAppPackageURL(spec, &app, volumeSecrets); err == nil {
+					packageURLs[app.Name] = packageURL
+				}
+			}
+		} else {
+			if version != appStatus.Version || appStatus.Phase != enterprisev1.PhaseReady {
+				scopedLog.Info("Found new or changed app package", "app", app.Name, "version", version)
+				appStatus.Phase = enterprisev1.PhasePending
+			}
+			appStatus.Version = version
+			packageURLs[app.Name] = packageURL
+		}
+		result[idx] = appStatus
+	}
+
+	// create or update secret used to install app packages
+	appsSecret := enterprise.GetAppRepoSecret(cr, spec, instanceType, packageURLs)
+	appsSecret.SetOwnerReferences(append(appsSecret.GetOwnerReferences(), resources.AsOwner(cr)))
+	if err := ApplyConfigSecret(client, appsSecret); err != nil {
+		return err
+	}
+
+	status.Apps = result
+	status.LastPollTime = now
+	return nil
+}
+
+// UpdateAppRepoStatus marks pending app packages as installed once the instances they are installed on are ready.
+func UpdateAppRepoStatus(status *enterprisev1.AppRepoStatus, phase enterprisev1.ResourcePhase) {
+	if phase != enterprisev1.PhaseReady {
+		return
+	}
+	for idx := range status.Apps {
+		if status.Apps[idx].Phase == enterprisev1.PhasePending {
+			status.Apps[idx].Phase = enterprisev1.PhaseReady
+		}
+	}
+}
+
+// setAppRepoPollRequeue updates a reconcile result to requeue after the poll interval of an app repository, if it has any apps
+func setAppRepoPollRequeue(result *reconcile.Result, spec *enterprisev1.AppRepoSpec) {
+	if len(spec.Apps) > 0 {
+		result.Requeue = true
+		result.RequeueAfter = time.Second * time.Duration(spec.PollInterval)
+	}
+}
+
+// isAppRepoChanged returns true if the apps in an app repository spec do not match those in its status
+func isAppRepoChanged(spec *enterprisev1.AppRepoSpec, status *enterprisev1.AppRepoStatus) bool {
+	if len(spec.Apps) != len(status.Apps) {
+		return true
+	}
+	for idx, app := range spec.Apps {
+		appStatus := status.Apps[idx]
+		if app.Name != appStatus.Name || app.Scope != appStatus.Scope || enterprise.GetAppLocation(spec, &app) != appStatus.Location {
+			return true
+		}
+	}
+	return false
+}
+
+// getAppPackage returns the version of an app package, and the URL or path used to install it
+func getAppPackage(client ControllerClient, cr enterprisev1.MetaObject, spec *enterprisev1.AppRepoSpec, app *enterprisev1.AppSourceSpec, volumeSecrets map[string]*corev1.Secret) (string, string, error) {
+	// use checksum of ConfigMap data
+	if app.ConfigMapRef != "" {
+		namespacedName := types.NamespacedName{Namespace: cr.GetNamespace(), Name: app.ConfigMapRef}
+		var configMap corev1.ConfigMap
+		err := client.Get(context.TODO(), namespacedName, &configMap)
+		if err != nil {
+			return "", "", fmt.Errorf("Unable to get ConfigMap \"%s\" for app \"%s\": %v", app.ConfigMapRef, app.Name, err)
+		}
+		data, ok := configMap.BinaryData[app.Path]
+		if !ok {
+			data = []byte(configMap.Data[app.Path])
+		}
+		if len(data) == 0 {
+			return "", "", fmt.Errorf("ConfigMap \"%s\" for app \"%s\" is missing %s", app.ConfigMapRef, app.Name, app.Path)
+		}
+		hash := sha256.Sum256(data)
+		return hex.EncodeToString(hash[:]), enterprise.GetAppPackagePath(app), nil
+	}
+
+	// use ETag of object in remote storage volume
+	s3Client, err := getAppS3Client(spec, app, volumeSecrets)
+	if err != nil {
+		return "", "", err
+	}
+	objectPath := enterprise.GetAppObjectPath(spec, app)
+	version, err := s3Client.GetObjectChecksum(objectPath)
+	if err != nil {
+		return "", "", err
+	}
+	packageURL, err := s3Client.GetPresignedURL(objectPath, appPackageURLExpiration)
+	if err != nil {
+		return "", "", err
+	}
+	return version, packageURL, nil
+}
+
+// getAppPackageURL returns the URL or path used to install an app package, without checking that it exists
+func getAppPackageURL(spec *enterprisev1.AppRepoSpec, app *enterprisev1.AppSourceSpec, volumeSecrets map[string]*corev1.Secret) (string, error) {
+	if app.ConfigMapRef != "" {
+		return enterprise.GetAppPackagePath(app), nil
+	}
+	s3Client, err := getAppS3Client(spec, app, volumeSecrets)
+	if err != nil {
+		return "", err
+	}
+	return s3Client.GetPresignedURL(enterprise.GetAppObjectPath(spec, app), appPackageURLExpiration)
+}
+
+// getAppS3Client returns a client for the remote storage volume containing an app package
+func getAppS3Client(spec *enterprisev1.AppRepoSpec, app *enterprisev1.AppSourceSpec, volumeSecrets map[string]*corev1.Secret) (*splclient.S3Client, error) {
+	for _, volume := range spec.Volumes {
+		if volume.Name != app.VolumeName {
+			continue
+		}
+		var accessKey, secretKey string
+		if secret, ok := volumeSecrets[volume.Name]; ok {
+			accessKey = string(secret.Data[enterprise.S3AccessKey])
+			secretKey = string(secret.Data[enterprise.S3SecretKey])
+		}
+		return splclient.NewS3Client(volume.Endpoint, accessKey, secretKey), nil
+	}
+	return nil, fmt.Errorf("Unable to find volume \"%s\" for app \"%s\"", app.VolumeName, app.Name)
+}
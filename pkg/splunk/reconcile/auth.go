@@ -0,0 +1,172 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+	"github.com/splunk/splunk-operator/pkg/splunk/resources"
+)
+
+// serviceAccountRole is the name of the Splunk role assigned to service accounts
+const serviceAccountRole = "splunk_operator"
+
+// serviceAccountCapabilities are the only capabilities granted to service accounts, which allow them to read the
+// status of indexer clusters and search head clusters
+var serviceAccountCapabilities = []string{"list_indexer_cluster", "list_search_head_clustering"}
+
+// ApplySplunkServiceAccount creates the Secret containing the credentials of the service account for a Splunk
+// Enterprise custom resource, if it does not exist yet, and returns it.
+func ApplySplunkServiceAccount(client ControllerClient, cr enterprisev1.MetaObject, instanceType enterprise.InstanceType) (*corev1.Secret, error) {
+	account := enterprise.GetSplunkServiceAccount(cr, instanceType)
+	namespacedName := types.NamespacedName{Namespace: account.GetNamespace(), Name: account.GetName()}
+	var current corev1.Secret
+	err := client.Get(context.TODO(), namespacedName, &current)
+	if err == nil {
+		return &current, nil
+	}
+
+	account.SetOwnerReferences(append(account.GetOwnerReferences(), resources.AsOwner(cr)))
+	if err = CreateResource(client, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// GetSplunkServiceAccount is used to retrieve the Secret containing the credentials of the service account for
+// another custom resource.
+func GetSplunkServiceAccount(client ControllerClient, cr enterprisev1.MetaObject, ref corev1.ObjectReference, instanceType enterprise.InstanceType) (*corev1.Secret, error) {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = cr.GetNamespace()
+	}
+	namespacedName := types.NamespacedName{Namespace: namespace, Name: enterprise.GetSplunkServiceAccountName(ref.Name, instanceType)}
+	var secret corev1.Secret
+	err := client.Get(context.TODO(), namespacedName, &secret)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get service account: %v", err)
+	}
+	return &secret, nil
+}
+
+// ServiceAccount is used to create clients that authenticate as the low-privilege service account of a Splunk
+// Enterprise custom resource, which is used for routine status polling instead of the admin user. The account is
+// created on each Splunk instance the first time it is used.
+type ServiceAccount struct {
+	log logr.Logger
+
+	// secret contains the username and password of the service account
+	secret *corev1.Secret
+
+	// adminPassword is used to create the service account on instances where it does not exist yet
+	adminPassword []byte
+
+	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+
+	// events is used to publish events for the custom resource (optional)
+	events *eventPublisher
+
+	// sessions is used to reuse session keys between reconciles (optional, defaults to a cache shared by all
+	// custom resources)
+	sessions *sessionCache
+}
+
+// getClient for ServiceAccount returns a SplunkClient for the instance at managementURI that authenticates as the
+// service account using a session key, creating the service account first if necessary. Requests made while logging
+// in and creating the service account are cancelled when ctx is done.
+func (sa *ServiceAccount) getClient(ctx context.Context, managementURI string) (*splclient.SplunkClient, error) {
+	username := string(sa.secret.Data["username"])
+	password := string(sa.secret.Data["password"])
+	c := sa.newSplunkClient(managementURI, username, password)
+	sessions := sa.sessions
+	if sessions == nil {
+		sessions = defaultSessionCache
+	}
+	session := sessions.get(managementURI, username, password)
+	c.Authenticator = session
+
+	err := session.LoginContext(ctx, c)
+	if err == splclient.ErrInvalidCredentials {
+		if err = sa.create(ctx, managementURI); err != nil {
+			return nil, err
+		}
+		err = session.LoginContext(ctx, c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// create for ServiceAccount uses the admin user to create, or reset, the service account and its role on the instance
+// at managementURI
+func (sa *ServiceAccount) create(ctx context.Context, managementURI string) error {
+	username := string(sa.secret.Data["username"])
+	sa.log.Info("Creating service account", "uri", managementURI, "username", username)
+	c := sa.newSplunkClient(managementURI, "admin", string(sa.adminPassword))
+
+	// the role or user may already exist, for example if the account's Secret was replaced
+	if err := c.CreateRoleContext(ctx, serviceAccountRole, serviceAccountCapabilities); err != nil {
+		if err = c.UpdateRoleContext(ctx, serviceAccountRole, serviceAccountCapabilities); err != nil {
+			sa.events.Warning("RESTAPIFailed", "Unable to create role %s on %s: %v", serviceAccountRole, managementURI, err)
+			return err
+		}
+	}
+	password := string(sa.secret.Data["password"])
+	roles := []string{serviceAccountRole}
+	if err := c.CreateUserContext(ctx, username, password, roles); err != nil {
+		if err = c.UpdateUserContext(ctx, username, password, roles); err != nil {
+			sa.events.Warning("RESTAPIFailed", "Unable to create service account %s on %s: %v", username, managementURI, err)
+			return err
+		}
+	}
+	sa.events.Normal("CreatedServiceAccount", "Created service account %s on %s", username, managementURI)
+	return nil
+}
+
+// sessionCache is used to share session keys for each Splunk instance between reconciles
+type sessionCache struct {
+	mutex    sync.Mutex
+	sessions map[string]*splclient.SessionKeyAuthenticator
+}
+
+// defaultSessionCache is the sessionCache used by service accounts, unless they are given another one
+var defaultSessionCache = &sessionCache{}
+
+// get for sessionCache returns the SessionKeyAuthenticator for a user of the instance at managementURI, replacing it
+// if the password has changed
+func (cache *sessionCache) get(managementURI, username, password string) *splclient.SessionKeyAuthenticator {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.sessions == nil {
+		cache.sessions = make(map[string]*splclient.SessionKeyAuthenticator)
+	}
+	key := fmt.Sprintf("%s %s", managementURI, username)
+	session, ok := cache.sessions[key]
+	if !ok || session.Password != password {
+		session = &splclient.SessionKeyAuthenticator{Username: username, Password: password}
+		cache.sessions[key] = session
+	}
+	return session
+}
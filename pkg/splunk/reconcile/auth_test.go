@@ -0,0 +1,210 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+)
+
+func TestApplySplunkServiceAccount(t *testing.T) {
+	cr := enterprisev1.ClusterMaster{
+		TypeMeta:   metav1.TypeMeta{Kind: "ClusterMaster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "master1", Namespace: "test"},
+	}
+	c := newMockClient()
+	account, err := ApplySplunkServiceAccount(c, &cr, enterprise.SplunkClusterMaster)
+	if err != nil {
+		t.Fatalf("ApplySplunkServiceAccount() returned %v; want nil", err)
+	}
+	if len(account.GetOwnerReferences()) != 1 || len(c.calls["Create"]) != 1 {
+		t.Errorf("ApplySplunkServiceAccount() did not create owned Secret %s", account.GetName())
+	}
+
+	// existing credentials are reused
+	again, err := ApplySplunkServiceAccount(c, &cr, enterprise.SplunkClusterMaster)
+	if err != nil || string(again.Data["password"]) != string(account.Data["password"]) || len(c.calls["Create"]) != 1 {
+		t.Errorf("ApplySplunkServiceAccount() = %v, %v; want existing Secret", again.Data, err)
+	}
+
+	// the service account of a referenced resource can be retrieved
+	idxc := enterprisev1.IndexerCluster{ObjectMeta: metav1.ObjectMeta{Name: "idxc1", Namespace: "test"}}
+	got, err := GetSplunkServiceAccount(c, &idxc, corev1.ObjectReference{Name: "master1"}, enterprise.SplunkClusterMaster)
+	if err != nil || string(got.Data["password"]) != string(account.Data["password"]) {
+		t.Errorf("GetSplunkServiceAccount() = %v, %v; want %v", got.Data, err, account.Data)
+	}
+	_, err = GetSplunkServiceAccount(c, &idxc, corev1.ObjectReference{Name: "master2"}, enterprise.SplunkClusterMaster)
+	if err == nil || err.Error() != "Unable to get service account: NotFound" {
+		t.Errorf("GetSplunkServiceAccount() returned %v; want Unable to get service account: NotFound", err)
+	}
+}
+
+// fakeSplunkAuth is used to emulate the authentication and access control endpoints of a Splunk instance
+type fakeSplunkAuth struct {
+	users    map[string]string
+	roles    map[string]bool
+	logins   int
+	requests []string
+}
+
+// ServeHTTP for fakeSplunkAuth handles a REST API request
+func (f *fakeSplunkAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	if r.URL.Path == "/services/auth/login" {
+		username := r.Form.Get("username")
+		if password, ok := f.users[username]; !ok || password != r.Form.Get("password") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		f.logins++
+		fmt.Fprintf(w, `{"sessionKey":"%d:%s"}`, f.logins, username)
+		return
+	}
+
+	// only the admin user may manage roles and users
+	user, password, ok := r.BasicAuth()
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Splunk ") {
+		user = strings.SplitN(r.Header.Get("Authorization"), ":", 2)[1]
+	} else if !ok || f.users[user] != password {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.requests = append(f.requests, fmt.Sprintf("%s %s %s", user, r.Method, r.URL.Path))
+	if r.URL.Path != "/services/cluster/master/info" && user != "admin" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	switch r.URL.Path {
+	case "/services/cluster/master/info":
+		fmt.Fprint(w, `{"entry":[{"name":"master","content":{"initialized_flag":true}}]}`)
+	case "/services/authorization/roles":
+		if f.roles[r.Form.Get("name")] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.roles[r.Form.Get("name")] = true
+		w.WriteHeader(http.StatusCreated)
+	case "/services/authentication/users":
+		if _, ok := f.users[r.Form.Get("name")]; ok {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		f.users[r.Form.Get("name")] = r.Form.Get("password")
+		w.WriteHeader(http.StatusCreated)
+	case "/services/authentication/users/splunk-operator":
+		f.users["splunk-operator"] = r.Form.Get("password")
+	}
+}
+
+func TestServiceAccount(t *testing.T) {
+	cr := enterprisev1.ClusterMaster{
+		TypeMeta:   metav1.TypeMeta{Kind: "ClusterMaster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "master1", Namespace: "test"},
+	}
+	fake := &fakeSplunkAuth{users: map[string]string{"admin": "123"}, roles: map[string]bool{}}
+	server := httptest.NewTLSServer(fake)
+	defer server.Close()
+	c := newMockClient()
+	account := enterprise.GetSplunkServiceAccount(&cr, enterprise.SplunkClusterMaster)
+	sa := ServiceAccount{
+		log:           log.WithName("TestServiceAccount"),
+		secret:        account,
+		adminPassword: []byte("123"),
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			return splclient.NewSplunkClient(server.URL, username, password)
+		},
+		events:   newEventPublisher(c, &cr),
+		sessions: &sessionCache{},
+	}
+	test := func(want []string) {
+		fake.requests = []string{}
+		client, err := sa.getClient(context.Background(), "https://splunk-master1-cluster-master-service.test.svc.cluster.local:8089")
+		if err != nil {
+			t.Fatalf("ServiceAccount.getClient() returned %v; want nil", err)
+		}
+		if _, err = client.GetClusterMasterInfo(); err != nil {
+			t.Errorf("GetClusterMasterInfo() returned %v; want nil", err)
+		}
+		if strings.Join(fake.requests, ", ") != strings.Join(want, ", ") {
+			t.Errorf("ServiceAccount.getClient() requests = %v; want %v", fake.requests, want)
+		}
+	}
+
+	// service account and role are created using the admin user, which is not used for polling
+	test([]string{
+		"admin POST /services/authorization/roles",
+		"admin POST /services/authentication/users",
+		"splunk-operator GET /services/cluster/master/info",
+	})
+	if fake.users["splunk-operator"] != string(account.Data["password"]) {
+		t.Errorf("ServiceAccount.getClient() did not create user splunk-operator")
+	}
+	if got := (*c.events)[0]; got.reason != "CreatedServiceAccount" {
+		t.Errorf("ServiceAccount.getClient() published %s; want CreatedServiceAccount", got.reason)
+	}
+
+	// session key is reused
+	test([]string{"splunk-operator GET /services/cluster/master/info"})
+	if fake.logins != 1 {
+		t.Errorf("ServiceAccount.getClient() logged in %d times; want 1", fake.logins)
+	}
+
+	// existing role and user are updated when the credentials change
+	account.Data["password"] = []byte("456")
+	test([]string{
+		"admin POST /services/authorization/roles",
+		"admin POST /services/authorization/roles/splunk_operator",
+		"admin POST /services/authentication/users",
+		"admin POST /services/authentication/users/splunk-operator",
+		"splunk-operator GET /services/cluster/master/info",
+	})
+	if fake.users["splunk-operator"] != "456" {
+		t.Errorf("ServiceAccount.getClient() did not update password for splunk-operator")
+	}
+
+	// the service account cannot be created without the admin password
+	account.Data["password"] = []byte("789")
+	sa.adminPassword = []byte("wrong")
+	if _, err := sa.getClient(context.Background(), server.URL); err == nil {
+		t.Errorf("ServiceAccount.getClient() returned nil; want error")
+	}
+	if got := (*c.events)[len(*c.events)-1]; got.reason != "RESTAPIFailed" {
+		t.Errorf("ServiceAccount.getClient() published %s; want RESTAPIFailed", got.reason)
+	}
+
+	// nothing is requested once the context is done
+	sa.adminPassword = []byte("123")
+	fake.requests = []string{}
+	logins := fake.logins
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := sa.getClient(ctx, server.URL); err == nil {
+		t.Errorf("ServiceAccount.getClient() returned nil; want error")
+	}
+	if len(fake.requests) != 0 || fake.logins != logins {
+		t.Errorf("ServiceAccount.getClient() requests = %v after %d logins; want none", fake.requests, fake.logins-logins)
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+	"github.com/splunk/splunk-operator/pkg/splunk/resources"
+)
+
+// ApplyClusterMaster reconciles the state of a Splunk Enterprise indexer cluster master.
+func ApplyClusterMaster(client ControllerClient, cr *enterprisev1.ClusterMaster) (result reconcile.Result, err error) {
+
+	// unless modified, reconcile for this object will be requeued after 5 seconds
+	result = reconcile.Result{
+		Requeue:      true,
+		RequeueAfter: time.Second * 5,
+	}
+	scopedLog := log.WithName("ApplyClusterMaster").WithValues("name", cr.GetIdentifier(), "namespace", cr.GetNamespace())
+
+	// updates status after function completes
+	tracker := newStatusTracker(client, cr, &cr.Status.Phase, &cr.Status.CommonStatus,
+		stepValidate, stepTLS, stepSecrets, stepSmartStore, stepApps, stepServices, stepPods, stepCluster, stepBundle)
+	defer func() {
+		tracker.finish(err)
+		if updateErr := client.Status().Update(context.TODO(), cr); updateErr != nil {
+			scopedLog.Error(updateErr, "Status update failed")
+		}
+	}()
+
+	// validate and updates defaults for CR
+	tracker.begin(stepValidate)
+	err = enterprise.ValidateClusterMasterSpec(&cr.Spec)
+	if err != nil {
+		return result, err
+	}
+	if cr.Status.RemoteVolumes == nil {
+		cr.Status.RemoteVolumes = []enterprisev1.RemoteVolumeStatus{}
+	}
+	if cr.Status.AppRepo.Apps == nil {
+		cr.Status.AppRepo.Apps = []enterprisev1.AppStatus{}
+	}
+	if cr.Status.BundlePush.ValidationErrors == nil {
+		cr.Status.BundlePush.ValidationErrors = []string{}
+	}
+
+	// check if deletion has been requested
+	if cr.ObjectMeta.DeletionTimestamp != nil {
+		tracker.begin(stepDeletion)
+		terminating, err := CheckSplunkDeletion(cr, client)
+		if terminating && err != nil { // don't bother if no error, since it will just be removed immmediately after
+			cr.Status.Phase = enterprisev1.PhaseTerminating
+		} else {
+			result.Requeue = false
+		}
+		return result, err
+	}
+
+	// only refresh status while reconcile is paused
+	if tracker.paused {
+		tracker.begin(stepPods)
+		statefulSet, err := enterprise.GetClusterMasterStatefulSet(cr)
+		if err != nil {
+			return result, err
+		}
+		cr.Status.Phase = GetStatefulSetPhase(client, statefulSet)
+		tracker.setPhase(cr.Status.Phase)
+		result.Requeue = false
+		return result, nil
+	}
+
+	// create or update TLS certificates
+	tracker.begin(stepTLS)
+	tlsManager := TLSManager{log: scopedLog, cr: cr, spec: &cr.Spec.TLS, status: &cr.Status.TLS, instanceType: enterprise.SplunkClusterMaster,
+		hosts: enterprise.GetSplunkTLSHosts(cr.GetNamespace(), enterprise.SplunkClusterMaster, cr.GetIdentifier(), "", 1), events: newEventPublisher(client, cr)}
+	err = tlsManager.Apply(client)
+	if err != nil {
+		return result, err
+	}
+
+	// create or update general config resources
+	tracker.begin(stepSecrets)
+	secrets, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkClusterMaster, &cr.Status.Secrets)
+	if err != nil {
+		return result, err
+	}
+
+	// the service account is used to poll the cluster master, by this resource and by its indexer clusters
+	serviceAccount, err := ApplySplunkServiceAccount(client, cr, enterprise.SplunkClusterMaster)
+	if err != nil {
+		return result, err
+	}
+
+	// rotate secrets when requested, before they are used to manage any instances
+	secretsManager := SecretRotationManager{log: scopedLog, cr: cr, spec: &cr.Spec.CommonSplunkSpec, status: &cr.Status.Secrets, instanceType: enterprise.SplunkClusterMaster,
+		secrets: secrets, instanceURIs: getSplunkInstanceURIs(cr.GetNamespace(), enterprise.SplunkClusterMaster, cr.GetIdentifier(), 1), newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}
+	err = secretsManager.Update(client, cr.Status.Phase == enterprisev1.PhaseReady)
+	if err != nil {
+		return result, err
+	}
+
+	// create or update SmartStore configuration, which is pushed to peers using the cluster master bundle
+	tracker.begin(stepSmartStore)
+	err = ApplySmartStoreConfig(client, cr, &cr.Spec.SmartStore, enterprise.SplunkClusterMaster, &cr.Status.RemoteVolumes)
+	if err != nil {
+		return result, err
+	}
+
+	// check app repository for new or changed app packages
+	tracker.begin(stepApps)
+	err = ApplyAppRepoConfig(client, cr, &cr.Spec.AppRepo, enterprise.SplunkClusterMaster, &cr.Status.AppRepo)
+	if err != nil {
+		return result, err
+	}
+
+	// create or update a headless service for the cluster master
+	tracker.begin(stepServices)
+	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkClusterMaster, true))
+	if err != nil {
+		return result, err
+	}
+
+	// create or update a regular service for the cluster master
+	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkClusterMaster, false))
+	if err != nil {
+		return result, err
+	}
+
+	// create or update statefulset
+	tracker.begin(stepPods)
+	statefulSet, err := enterprise.GetClusterMasterStatefulSet(cr)
+	if err != nil {
+		return result, err
+	}
+	err = updateConfigChecksums(client, cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkClusterMaster, &statefulSet.Spec.Template)
+	if err != nil {
+		return result, err
+	}
+	err = updateSmartStoreChecksum(client, cr, &cr.Spec.SmartStore, enterprise.SplunkClusterMaster, &statefulSet.Spec.Template)
+	if err != nil {
+		return result, err
+	}
+	mgr := DefaultStatefulSetPodManager{UpdateStrategy: cr.Spec.UpdateStrategy}
+	phase, err := mgr.Update(client, statefulSet, 1)
+	if err != nil {
+		return result, err
+	}
+	cr.Status.Phase = phase
+	tracker.setPhase(phase)
+	UpdateAppRepoStatus(&cr.Status.AppRepo, phase)
+	if cr.Status.Phase != enterprisev1.PhaseReady {
+		return result, nil
+	}
+	tlsManager.setReady()
+
+	// update status of the indexer cluster using the cluster master's REST API
+	tracker.begin(stepCluster)
+	statusManager := ClusterMasterStatusManager{log: scopedLog, cr: cr, secrets: secrets, newSplunkClient: tlsManager.newSplunkClient,
+		serviceAccount: &ServiceAccount{log: scopedLog, secret: serviceAccount, adminPassword: secrets.Data["password"], newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}}
+	err = statusManager.Update()
+	if splclient.IsNotReady(err) {
+		// the cluster master may still be starting up, or restarting to apply a bundle
+		tracker.setCondition(corev1.ConditionFalse, "ServiceNotReady", fmt.Sprintf("cluster master is not ready yet: %v", err))
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	switch {
+	case !cr.Status.ServiceReady:
+		tracker.setCondition(corev1.ConditionFalse, "ServiceNotReady", "indexer cluster is not ready for service")
+		return result, nil
+	case !cr.Status.ReplicationFactorMet:
+		tracker.setCondition(corev1.ConditionFalse, "ReplicationFactorNotMet", "indexer cluster does not meet its replication factor")
+		return result, nil
+	case !cr.Status.SearchFactorMet:
+		tracker.setCondition(corev1.ConditionFalse, "SearchFactorNotMet", "indexer cluster does not meet its search factor")
+		return result, nil
+	}
+
+	// validate and apply changes to the bundle ConfigMap
+	tracker.begin(stepBundle)
+	bundleManager := ClusterBundleManager{log: scopedLog, cr: cr, secrets: secrets, newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}
+	applied, err := bundleManager.Update(client)
+	if err != nil {
+		return result, err
+	}
+	if !applied {
+		tracker.setCondition(corev1.ConditionFalse, "Validating", "validating changes to the cluster bundle")
+		return result, nil
+	}
+
+	// no need to requeue if the cluster is healthy, other than to poll the app repository, rotate secrets and renew certificates
+	result.Requeue = false
+	setAppRepoPollRequeue(&result, &cr.Spec.AppRepo)
+	secretsManager.setRequeue(&result)
+	tlsManager.setRequeue(&result)
+	return result, nil
+}
+
+// ClusterMasterStatusManager is used to update the status of an indexer cluster master
+type ClusterMasterStatusManager struct {
+	log             logr.Logger
+	cr              *enterprisev1.ClusterMaster
+	secrets         *corev1.Secret
+	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+
+	// serviceAccount is used to poll the cluster master (optional, defaults to the admin user)
+	serviceAccount *ServiceAccount
+}
+
+// Update for ClusterMasterStatusManager uses the REST API to update the status of a ClusterMaster custom resource
+func (mgr *ClusterMasterStatusManager) Update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	c, err := mgr.getClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	clusterInfo, err := c.GetClusterMasterInfoContext(ctx)
+	if err != nil {
+		return err
+	}
+	mgr.cr.Status.Initialized = clusterInfo.Initialized
+	mgr.cr.Status.IndexingReady = clusterInfo.IndexingReady
+	mgr.cr.Status.ServiceReady = clusterInfo.ServiceReady
+	mgr.cr.Status.MaintenanceMode = clusterInfo.MaintenanceMode
+	mgr.cr.Status.RollingRestart = clusterInfo.RollingRestart
+	mgr.cr.Status.ActiveBundle = enterprisev1.ClusterMasterBundleStatus{
+		Checksum:  clusterInfo.ActiveBundle.Checksum,
+		Timestamp: clusterInfo.ActiveBundle.Timestamp,
+	}
+	mgr.cr.Status.LatestBundle = enterprisev1.ClusterMasterBundleStatus{
+		Checksum:  clusterInfo.LatestBundle.Checksum,
+		Timestamp: clusterInfo.LatestBundle.Timestamp,
+	}
+	if mgr.cr.Status.ActiveBundle != mgr.cr.Status.LatestBundle {
+		mgr.log.Info("Cluster bundle push is in progress", "activeBundle", mgr.cr.Status.ActiveBundle.Checksum, "latestBundle", mgr.cr.Status.LatestBundle.Checksum)
+	}
+
+	generation, err := c.GetClusterMasterGenerationContext(ctx)
+	if err != nil {
+		return err
+	}
+	mgr.cr.Status.ReplicationFactorMet = generation.ReplicationFactorMet
+	mgr.cr.Status.SearchFactorMet = generation.SearchFactorMet
+
+	return nil
+}
+
+// getClient for ClusterMasterStatusManager returns a SplunkClient for the cluster master; logging in as the service
+// account is cancelled when ctx is done
+func (mgr *ClusterMasterStatusManager) getClient(ctx context.Context) (*splclient.SplunkClient, error) {
+	fqdnName := resources.GetServiceFQDN(mgr.cr.GetNamespace(), enterprise.GetSplunkServiceName(enterprise.SplunkClusterMaster, mgr.cr.GetIdentifier(), false))
+	managementURI := fmt.Sprintf("https://%s:8089", fqdnName)
+	if mgr.serviceAccount != nil {
+		return mgr.serviceAccount.getClient(ctx, managementURI)
+	}
+	return mgr.newSplunkClient(managementURI, "admin", string(mgr.secrets.Data["password"])), nil
+}
+
+// bundleSyncTimeout is the maximum time to wait for the kubelet to refresh a bundle ConfigMap that is mounted by a
+// cluster master or deployer. If the cluster master still validates a bundle identical to the active one after this
+// time, the changes are assumed to have no effect on the bundle.
+const bundleSyncTimeout = 2 * time.Minute
+
+// ClusterBundleManager is used to validate and distribute the contents of a cluster master's bundle ConfigMap to its peers
+type ClusterBundleManager struct {
+	log             logr.Logger
+	cr              *enterprisev1.ClusterMaster
+	secrets         *corev1.Secret
+	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+
+	// events is used to publish events for the cluster master (optional)
+	events *eventPublisher
+}
+
+// Update for ClusterBundleManager validates and applies the cluster bundle whenever the contents of the bundle ConfigMap
+// change; it returns true when the current contents have been applied
+func (mgr *ClusterBundleManager) Update(client ControllerClient) (bool, error) {
+	status := &mgr.cr.Status.BundlePush
+	ref := mgr.cr.Spec.BundleConfigMapRef
+	if ref == "" {
+		*status = enterprisev1.ClusterMasterBundlePushStatus{ValidationErrors: []string{}}
+		return true, nil
+	}
+
+	// nothing to do if the current contents have already been applied
+	namespacedName := types.NamespacedName{Namespace: mgr.cr.GetNamespace(), Name: ref}
+	var configMap corev1.ConfigMap
+	err := client.Get(context.TODO(), namespacedName, &configMap)
+	if err != nil {
+		return false, fmt.Errorf("Unable to get bundle ConfigMap %s: %v", namespacedName, err)
+	}
+	checksum := enterprise.GetClusterBundleChecksum(&configMap)
+	if checksum == status.ConfigMapChecksum {
+		status.PendingConfigMapChecksum = ""
+		status.ValidationStartTime = nil
+		status.ValidationErrors = []string{}
+		return true, nil
+	}
+
+	// start validating the bundle whenever the contents change
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	c := mgr.getClient()
+	if checksum != status.PendingConfigMapChecksum || status.ValidationStartTime == nil {
+		mgr.log.Info("Validating cluster bundle", "configMap", ref, "configMapChecksum", checksum)
+		err = c.ValidateClusterBundleContext(ctx)
+		if err != nil {
+			mgr.events.Warning("RESTAPIFailed", "Unable to validate cluster bundle: %v", err)
+			return false, err
+		}
+		mgr.events.Normal("ValidatingBundle", "Validating cluster bundle from ConfigMap %s", ref)
+		now := metav1.Now()
+		status.PendingConfigMapChecksum = checksum
+		status.ValidationStartTime = &now
+		status.ValidationErrors = []string{}
+		return false, nil
+	}
+
+	// check the results of validation
+	clusterInfo, err := c.GetClusterMasterInfoContext(ctx)
+	if err != nil {
+		mgr.events.Warning("RESTAPIFailed", "Unable to retrieve cluster info from cluster master: %v", err)
+		return false, err
+	}
+	validated := clusterInfo.LastValidatedBundle
+	invalid := clusterInfo.ApplyBundleStatus.InvalidBundle
+	started := status.ValidationStartTime.Unix()
+	switch {
+	case invalid.Checksum != "" && invalid.Timestamp >= started:
+		status.ValidationErrors = invalid.BundleValidationErrors
+		if status.ValidationErrors == nil {
+			status.ValidationErrors = []string{}
+		}
+		return false, fmt.Errorf("Cluster bundle from ConfigMap %s is invalid: %s", ref, strings.Join(invalid.BundleValidationErrors, "; "))
+
+	case !validated.IsValidBundle || validated.Timestamp < started:
+		mgr.log.Info("Waiting for cluster bundle validation to complete", "status", clusterInfo.ApplyBundleStatus.Status)
+		return false, nil
+
+	case validated.Checksum == clusterInfo.ActiveBundle.Checksum && time.Since(status.ValidationStartTime.Time) < bundleSyncTimeout:
+		// the kubelet may not have refreshed the mounted ConfigMap yet, so validate it again
+		mgr.log.Info("Waiting for bundle ConfigMap to be refreshed", "configMap", ref)
+		return false, c.ValidateClusterBundleContext(ctx)
+	}
+
+	// distribute the validated bundle to all peers
+	mgr.log.Info("Applying cluster bundle", "checksum", validated.Checksum)
+	err = c.ApplyClusterBundleContext(ctx)
+	if err != nil {
+		mgr.events.Warning("RESTAPIFailed", "Unable to apply cluster bundle: %v", err)
+		return false, err
+	}
+	mgr.events.Normal("AppliedBundle", "Applied cluster bundle %s from ConfigMap %s", validated.Checksum, ref)
+	status.ConfigMapChecksum = checksum
+	status.PendingConfigMapChecksum = ""
+	status.ValidationStartTime = nil
+	status.ValidationErrors = []string{}
+	status.AppliedChecksum = validated.Checksum
+	return true, nil
+}
+
+// getClient for ClusterBundleManager returns a SplunkClient for the cluster master
+func (mgr *ClusterBundleManager) getClient() *splclient.SplunkClient {
+	fqdnName := resources.GetServiceFQDN(mgr.cr.GetNamespace(), enterprise.GetSplunkServiceName(enterprise.SplunkClusterMaster, mgr.cr.GetIdentifier(), false))
+	return mgr.newSplunkClient(fmt.Sprintf("https://%s:8089", fqdnName), "admin", string(mgr.secrets.Data["password"]))
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+func TestApplyClusterMaster(t *testing.T) {
+	funcCalls := []mockFuncCall{
+		{metaName: "*v1.Secret-test-splunk-stack1-cluster-master-secrets"},
+		{metaName: "*v1.Secret-test-splunk-stack1-cluster-master-service-account"},
+		{metaName: "*v1.Service-test-splunk-stack1-cluster-master-headless"},
+		{metaName: "*v1.Service-test-splunk-stack1-cluster-master-service"},
+		{metaName: "*v1.StatefulSet-test-splunk-stack1-cluster-master"},
+	}
+	getCalls := []mockFuncCall{funcCalls[0], funcCalls[1], funcCalls[2], funcCalls[3], funcCalls[0], funcCalls[4]}
+	createCalls := map[string][]mockFuncCall{"Get": getCalls, "Create": funcCalls}
+	updateCalls := map[string][]mockFuncCall{"Get": getCalls, "Update": []mockFuncCall{funcCalls[4]}}
+	current := enterprisev1.ClusterMaster{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "ClusterMaster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	revised := current.DeepCopy()
+	revised.Spec.Image = "splunk/test"
+	reconcile := func(c *mockClient, cr interface{}) error {
+		_, err := ApplyClusterMaster(c, cr.(*enterprisev1.ClusterMaster))
+		return err
+	}
+	reconcileTester(t, "TestApplyClusterMaster", &current, revised, createCalls, updateCalls, reconcile)
+
+	// test deletion
+	currentTime := metav1.NewTime(time.Now())
+	revised.ObjectMeta.DeletionTimestamp = &currentTime
+	revised.ObjectMeta.Finalizers = []string{"enterprise.splunk.com/delete-pvc"}
+	deleteFunc := func(cr enterprisev1.MetaObject, c ControllerClient) (bool, error) {
+		_, err := ApplyClusterMaster(c, cr.(*enterprisev1.ClusterMaster))
+		return true, err
+	}
+	splunkDeletionTester(t, revised, deleteFunc)
+}
+
+func TestClusterMasterStatusManager(t *testing.T) {
+	cr := enterprisev1.ClusterMaster{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "ClusterMaster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "master1",
+			Namespace: "test",
+		},
+	}
+	secrets := &corev1.Secret{
+		Data: map[string][]byte{
+			"password": []byte{'1', '2', '3'},
+		},
+	}
+	masterURL := "https://splunk-master1-cluster-master-service.test.svc.cluster.local:8089"
+	mockSplunkClient := &spltest.MockHTTPClient{}
+	mockSplunkClient.AddHandlers(
+		spltest.MockHTTPHandler{
+			Method: "GET",
+			URL:    masterURL + "/services/cluster/master/info?count=0&output_mode=json",
+			Status: 200,
+			Body:   `{"entry":[{"name":"master","content":{"initialized_flag":true,"indexing_ready_flag":true,"service_ready_flag":true,"maintenance_mode":true,"rolling_restart_flag":false,"active_bundle":{"checksum":"14310A4AABD23E85BBD4559C4A3B59F8","timestamp":1583870198},"latest_bundle":{"checksum":"4F3AB2D2EBB1E5AB4C3B2C70FE6A7D9C","timestamp":1583948640}}}]}`,
+		},
+		spltest.MockHTTPHandler{
+			Method: "GET",
+			URL:    masterURL + "/services/cluster/master/generation?count=0&output_mode=json",
+			Status: 200,
+			Body:   `{"entry":[{"name":"master","content":{"generation_id":26,"replication_factor_met":true,"search_factor_met":false,"was_forced_searchable":false}}]}`,
+		},
+	)
+	var gotPasswords []string
+	mgr := ClusterMasterStatusManager{
+		log:     log.WithName("TestClusterMasterStatusManager"),
+		cr:      &cr,
+		secrets: secrets,
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			gotPasswords = append(gotPasswords, password)
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+	}
+	err := mgr.Update()
+	if err != nil {
+		t.Errorf("ClusterMasterStatusManager.Update() returned %v; want nil", err)
+	}
+	mockSplunkClient.CheckRequests(t, "ClusterMasterStatusManager.Update()")
+	if len(gotPasswords) != 1 || gotPasswords[0] != "123" {
+		t.Errorf("ClusterMasterStatusManager.Update() client passwords = %v; want [123]", gotPasswords)
+	}
+
+	want := enterprisev1.ClusterMasterStatus{
+		Initialized:          true,
+		IndexingReady:        true,
+		ServiceReady:         true,
+		MaintenanceMode:      true,
+		ReplicationFactorMet: true,
+		SearchFactorMet:      false,
+		ActiveBundle:         enterprisev1.ClusterMasterBundleStatus{Checksum: "14310A4AABD23E85BBD4559C4A3B59F8", Timestamp: 1583870198},
+		LatestBundle:         enterprisev1.ClusterMasterBundleStatus{Checksum: "4F3AB2D2EBB1E5AB4C3B2C70FE6A7D9C", Timestamp: 1583948640},
+	}
+	got := cr.Status
+	if got.Initialized != want.Initialized || got.IndexingReady != want.IndexingReady || got.ServiceReady != want.ServiceReady ||
+		got.MaintenanceMode != want.MaintenanceMode || got.RollingRestart != want.RollingRestart ||
+		got.ReplicationFactorMet != want.ReplicationFactorMet || got.SearchFactorMet != want.SearchFactorMet ||
+		got.ActiveBundle != want.ActiveBundle || got.LatestBundle != want.LatestBundle {
+		t.Errorf("ClusterMasterStatusManager.Update() status = %+v; want %+v", got, want)
+	}
+}
+
+func TestClusterBundleManager(t *testing.T) {
+	method := "ClusterBundleManager.Update()"
+	cr := enterprisev1.ClusterMaster{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "ClusterMaster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "master1",
+			Namespace: "test",
+		},
+	}
+	secrets := &corev1.Secret{
+		Data: map[string][]byte{
+			"password": []byte{'1', '2', '3'},
+		},
+	}
+	masterURL := "https://splunk-master1-cluster-master-service.test.svc.cluster.local:8089"
+	validateHandler := spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    masterURL + "/services/cluster/master/control/default/validate_bundle?check-restart=true",
+		Status: 200,
+	}
+	applyHandler := spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    masterURL + "/services/cluster/master/control/default/apply?ignore_identical_bundle=true",
+		Status: 200,
+	}
+	infoHandler := func(validated string, timestamp int64, errors string) spltest.MockHTTPHandler {
+		invalidChecksum := ""
+		if errors != "" {
+			invalidChecksum = validated
+		}
+		return spltest.MockHTTPHandler{
+			Method: "GET",
+			URL:    masterURL + "/services/cluster/master/info?count=0&output_mode=json",
+			Status: 200,
+			Body: fmt.Sprintf(`{"entry":[{"name":"master","content":{"active_bundle":{"checksum":"14310A4AABD23E85BBD4559C4A3B59F8","timestamp":1583870198},`+
+				`"last_validated_bundle":{"checksum":"%s","is_valid_bundle":%t,"timestamp":%d},`+
+				`"apply_bundle_status":{"invalid_bundle":{"checksum":"%s","timestamp":%d,"bundle_validation_errors_on_master":[%s]},"status":"None"}}}]}`,
+				validated, errors == "", timestamp, invalidChecksum, timestamp, errors),
+		}
+	}
+
+	c := newMockClient()
+	var mockSplunkClient *spltest.MockHTTPClient
+	mgr := ClusterBundleManager{
+		log:     log.WithName(method),
+		cr:      &cr,
+		secrets: secrets,
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+	}
+	test := func(want bool, wantErr string, handlers ...spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(handlers...)
+		got, err := mgr.Update(c)
+		if got != want || (err == nil && wantErr != "") || (err != nil && err.Error() != wantErr) {
+			t.Errorf("%s = %t,%v; want %t,%s", method, got, err, want, wantErr)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+	}
+
+	// nothing to do without a bundle ConfigMap
+	test(true, "")
+
+	// bundle ConfigMap must exist
+	cr.Spec.BundleConfigMapRef = "bundle1"
+	test(false, "Unable to get bundle ConfigMap test/bundle1: NotFound")
+
+	// changes to the bundle ConfigMap are validated
+	configMap := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle1", Namespace: "test"},
+		Data:       map[string]string{"indexes.conf": "[web]\n"},
+	}
+	c.Create(context.TODO(), &configMap)
+	test(false, "", validateHandler)
+	if cr.Status.BundlePush.PendingConfigMapChecksum == "" || cr.Status.BundlePush.ValidationStartTime == nil {
+		t.Errorf("%s status = %+v; want pending checksum and validation start time", method, cr.Status.BundlePush)
+	}
+	started := cr.Status.BundlePush.ValidationStartTime.Unix()
+
+	// wait for validation to complete
+	test(false, "", infoHandler("14310A4AABD23E85BBD4559C4A3B59F8", started-60, ""))
+
+	// validate again if the kubelet has not refreshed the ConfigMap yet
+	test(false, "", infoHandler("14310A4AABD23E85BBD4559C4A3B59F8", started, ""), validateHandler)
+
+	// validation errors are reported
+	test(false, "Cluster bundle from ConfigMap bundle1 is invalid: bad indexes.conf",
+		infoHandler("5F3AB2D2EBB1E5AB4C3B2C70FE6A7D9C", started, `"bad indexes.conf"`))
+	if len(cr.Status.BundlePush.ValidationErrors) != 1 || cr.Status.BundlePush.ValidationErrors[0] != "bad indexes.conf" {
+		t.Errorf("%s ValidationErrors = %v; want [bad indexes.conf]", method, cr.Status.BundlePush.ValidationErrors)
+	}
+
+	// valid bundles are applied
+	test(true, "", infoHandler("4F3AB2D2EBB1E5AB4C3B2C70FE6A7D9C", started, ""), applyHandler)
+	want := enterprisev1.ClusterMasterBundlePushStatus{
+		ConfigMapChecksum: enterprise.GetClusterBundleChecksum(&configMap),
+		AppliedChecksum:   "4F3AB2D2EBB1E5AB4C3B2C70FE6A7D9C",
+		ValidationErrors:  []string{},
+	}
+	if got := cr.Status.BundlePush; got.ConfigMapChecksum != want.ConfigMapChecksum || got.AppliedChecksum != want.AppliedChecksum ||
+		got.PendingConfigMapChecksum != "" || got.ValidationStartTime != nil || len(got.ValidationErrors) != 0 {
+		t.Errorf("%s status = %+v; want %+v", method, got, want)
+	}
+	wantReasons := []string{"ValidatingBundle", "AppliedBundle"}
+	if len(*c.events) != len(wantReasons) {
+		t.Fatalf("%s published %d events; want %d", method, len(*c.events), len(wantReasons))
+	}
+	for i, reason := range wantReasons {
+		if (*c.events)[i].reason != reason {
+			t.Errorf("%s event %d reason = %s; want %s", method, i, (*c.events)[i].reason, reason)
+		}
+	}
+
+	// nothing to do until the bundle ConfigMap changes again
+	test(true, "")
+}
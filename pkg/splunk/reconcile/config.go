@@ -16,26 +16,43 @@ package reconcile
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"reflect"
 
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
 	"github.com/splunk/splunk-operator/pkg/splunk/resources"
 )
 
+const (
+	// annotations used to recycle pods whenever the contents of the Secrets and ConfigMaps they mount change
+	secretsChecksumAnnotation    = "enterprise.splunk.com/secrets-checksum"
+	defaultsChecksumAnnotation   = "enterprise.splunk.com/defaults-checksum"
+	volumesChecksumAnnotation    = "enterprise.splunk.com/volumes-checksum"
+	tlsChecksumAnnotation        = "enterprise.splunk.com/tls-checksum"
+	smartStoreChecksumAnnotation = "enterprise.splunk.com/smartstore-secret-checksum"
+)
+
 // ApplySplunkConfig reconciles the state of Kubernetes Secrets, ConfigMaps and other general settings for Splunk Enterprise instances.
-func ApplySplunkConfig(client ControllerClient, cr enterprisev1.MetaObject, spec enterprisev1.CommonSplunkSpec, instanceType enterprise.InstanceType) (*corev1.Secret, error) {
+func ApplySplunkConfig(client ControllerClient, cr enterprisev1.MetaObject, spec enterprisev1.CommonSplunkSpec, instanceType enterprise.InstanceType, status *enterprisev1.SecretsStatus) (*corev1.Secret, error) {
 	var err error
 
-	// if reference to indexer cluster, extract and re-use idxc.secret
-	// IndexerRef is not relevant for Indexer, and Indexer will use value from LicenseMaster to prevent cyclical dependency
+	// if reference to cluster master, extract and re-use idxc.secret
+	// ClusterMasterRef is not relevant for ClusterMaster, and ClusterMaster will use value from LicenseMaster to prevent cyclical dependency
 	var idxcSecret []byte
-	if instanceType.ToKind() != "indexer" && instanceType.ToKind() != "license-master" && spec.IndexerClusterRef.Name != "" {
-		idxcSecret, err = GetSplunkSecret(client, cr, spec.IndexerClusterRef, enterprise.SplunkIndexer, "idxc_secret")
+	if isIdxcSecretBorrowed(spec, instanceType) && instanceType.ToKind() != "cluster-master" {
+		idxcSecret, err = GetSplunkSecret(client, cr, spec.ClusterMasterRef, enterprise.SplunkClusterMaster, "idxc_secret")
 		if err != nil {
 			return nil, err
 		}
@@ -43,13 +60,13 @@ func ApplySplunkConfig(client ControllerClient, cr enterprisev1.MetaObject, spec
 
 	// if reference to license master, extract and re-use pass4SymmKey
 	var pass4SymmKey []byte
-	if instanceType.ToKind() != "license-master" && spec.LicenseMasterRef.Name != "" {
+	if isPass4SymmKeyBorrowed(spec, instanceType) {
 		pass4SymmKey, err = GetSplunkSecret(client, cr, spec.LicenseMasterRef, enterprise.SplunkLicenseMaster, "pass4SymmKey")
 		if err != nil {
 			return nil, err
 		}
-		if instanceType.ToKind() == "indexer" {
-			// get pass4SymmKey from LicenseMaster to avoid cyclical dependency
+		if instanceType.ToKind() == "cluster-master" {
+			// get idxc_secret from LicenseMaster to avoid cyclical dependency
 			idxcSecret, err = GetSplunkSecret(client, cr, spec.LicenseMasterRef, enterprise.SplunkLicenseMaster, "idxc_secret")
 			if err != nil {
 				return nil, err
@@ -57,13 +74,32 @@ func ApplySplunkConfig(client ControllerClient, cr enterprisev1.MetaObject, spec
 		}
 	}
 
-	// create or retrieve splunk secrets
+	// create or retrieve splunk secrets, using values from a user-managed Secret if there is one
 	secrets := enterprise.GetSplunkSecrets(cr, instanceType, idxcSecret, pass4SymmKey)
+	if spec.SecretRef.Name != "" {
+		source, err := getSecretRef(client, cr, &spec)
+		if err != nil {
+			return nil, err
+		}
+		secrets, err = enterprise.GetSplunkSecretsFromRef(cr, instanceType, source, idxcSecret, pass4SymmKey)
+		if err != nil {
+			return nil, err
+		}
+	}
 	secrets.SetOwnerReferences(append(secrets.GetOwnerReferences(), resources.AsOwner(cr)))
 	if secrets, err = ApplySecret(client, secrets); err != nil {
 		return nil, err
 	}
 
+	// propagate new values for any secrets that have been rotated by the resource they are borrowed from
+	if enterprise.UpdateSplunkSecrets(secrets, map[string][]byte{"idxc_secret": idxcSecret, "pass4SymmKey": pass4SymmKey}) {
+		if err = UpdateResource(client, secrets); err != nil {
+			return nil, err
+		}
+		status.Version++
+		newEventPublisher(client, cr).Normal("UpdatedSecrets", "Updated secrets %s with values from referenced resources", secrets.GetName())
+	}
+
 	// create splunk defaults (for inline config)
 	if spec.Defaults != "" {
 		defaultsMap := enterprise.GetSplunkDefaults(cr.GetIdentifier(), cr.GetNamespace(), instanceType, spec.Defaults)
@@ -76,6 +112,38 @@ func ApplySplunkConfig(client ControllerClient, cr enterprisev1.MetaObject, spec
 	return secrets, nil
 }
 
+// getSecretRef returns the user-managed Secret referenced by the secretRef of a custom resource, which must be in the
+// same namespace
+func getSecretRef(client ControllerClient, cr enterprisev1.MetaObject, spec *enterprisev1.CommonSplunkSpec) (*corev1.Secret, error) {
+	if err := enterprise.ValidateSecretRef(cr, spec); err != nil {
+		return nil, err
+	}
+	namespacedName := types.NamespacedName{Namespace: cr.GetNamespace(), Name: spec.SecretRef.Name}
+	var secret corev1.Secret
+	err := client.Get(context.TODO(), namespacedName, &secret)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get Secret %s referenced by secretRef: %v", namespacedName, err)
+	}
+	return &secret, nil
+}
+
+// isIdxcSecretBorrowed returns true if a resource uses the idxc_secret of the cluster master it references,
+// or of the license master it references if it is a cluster master
+func isIdxcSecretBorrowed(spec enterprisev1.CommonSplunkSpec, instanceType enterprise.InstanceType) bool {
+	switch instanceType.ToKind() {
+	case "license-master":
+		return false
+	case "cluster-master":
+		return spec.LicenseMasterRef.Name != ""
+	}
+	return spec.ClusterMasterRef.Name != ""
+}
+
+// isPass4SymmKeyBorrowed returns true if a resource uses the pass4SymmKey of the license master it references
+func isPass4SymmKeyBorrowed(spec enterprisev1.CommonSplunkSpec, instanceType enterprise.InstanceType) bool {
+	return instanceType.ToKind() != "license-master" && spec.LicenseMasterRef.Name != ""
+}
+
 // ApplyConfigMap creates or updates a Kubernetes ConfigMap
 func ApplyConfigMap(client ControllerClient, configMap *corev1.ConfigMap) error {
 	scopedLog := log.WithName("ApplyConfigMap").WithValues(
@@ -118,11 +186,39 @@ func ApplySecret(client ControllerClient, secret *corev1.Secret) (*corev1.Secret
 	} else {
 		err = CreateResource(client, secret)
 		result = secret
+		if err == nil {
+			newOwnerEventPublisher(client, secret).Normal("CreatedSecret", "Created secret %s", secret.GetName())
+		}
 	}
 
 	return result, err
 }
 
+// ApplyConfigSecret creates or updates a Kubernetes Secret that contains generated configuration
+func ApplyConfigSecret(client ControllerClient, secret *corev1.Secret) error {
+	scopedLog := log.WithName("ApplyConfigSecret").WithValues(
+		"name", secret.GetObjectMeta().GetName(),
+		"namespace", secret.GetObjectMeta().GetNamespace())
+
+	namespacedName := types.NamespacedName{Namespace: secret.GetNamespace(), Name: secret.GetName()}
+	var current corev1.Secret
+
+	err := client.Get(context.TODO(), namespacedName, &current)
+	if err == nil {
+		if !reflect.DeepEqual(secret.Data, current.Data) {
+			scopedLog.Info("Updating existing Secret")
+			current.Data = secret.Data
+			err = UpdateResource(client, &current)
+		} else {
+			scopedLog.Info("No changes for Secret")
+		}
+	} else {
+		err = CreateResource(client, secret)
+	}
+
+	return err
+}
+
 // GetSplunkSecret is used to retrieve a secret from another custom resource.
 func GetSplunkSecret(client ControllerClient, cr enterprisev1.MetaObject, ref corev1.ObjectReference, instanceType enterprise.InstanceType, secretName string) ([]byte, error) {
 	namespace := ref.Namespace
@@ -151,3 +247,190 @@ func GetSplunkSecret(client ControllerClient, cr enterprisev1.MetaObject, ref co
 	scopedLog.Info("Re-using secret")
 	return result, nil
 }
+
+// updateConfigChecksums annotates a pod template with checksums of the contents of the splunk secrets Secret, the inline
+// defaults ConfigMap, the TLS Secret (if enabled) and any Secret or ConfigMap volumes in spec.Volumes. Since the pod template only refers to these by name, this ensures that pods are recycled whenever their
+// contents change.
+func updateConfigChecksums(client ControllerClient, cr enterprisev1.MetaObject, spec *enterprisev1.CommonSplunkSpec, instanceType enterprise.InstanceType, podTemplateSpec *corev1.PodTemplateSpec) error {
+	checksums := make(map[string]string)
+
+	// splunk secrets
+	hash := sha256.New()
+	err := addConfigChecksum(client, hash, cr.GetNamespace(), enterprise.GetSplunkSecretsName(cr.GetIdentifier(), instanceType), &corev1.Secret{})
+	if err != nil {
+		return err
+	}
+	checksums[secretsChecksumAnnotation] = hex.EncodeToString(hash.Sum(nil))
+
+	// inline defaults
+	if spec.Defaults != "" {
+		hash = sha256.New()
+		err = addConfigChecksum(client, hash, cr.GetNamespace(), enterprise.GetSplunkDefaultsName(cr.GetIdentifier(), instanceType), &corev1.ConfigMap{})
+		if err != nil {
+			return err
+		}
+		checksums[defaultsChecksumAnnotation] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	// TLS certificates
+	if spec.TLS.Enabled {
+		hash = sha256.New()
+		err = addTLSChecksum(client, hash, cr.GetNamespace(), enterprise.GetSplunkTLSName(cr.GetIdentifier(), instanceType))
+		if err != nil {
+			return err
+		}
+		checksums[tlsChecksumAnnotation] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	// Secret and ConfigMap volumes
+	hash = sha256.New()
+	found := false
+	for _, volume := range spec.Volumes {
+		if volume.Secret != nil {
+			err = addConfigChecksum(client, hash, cr.GetNamespace(), volume.Secret.SecretName, &corev1.Secret{})
+		} else if volume.ConfigMap != nil {
+			err = addConfigChecksum(client, hash, cr.GetNamespace(), volume.ConfigMap.Name, &corev1.ConfigMap{})
+		} else {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+	if found {
+		checksums[volumesChecksumAnnotation] = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	if podTemplateSpec.ObjectMeta.Annotations == nil {
+		podTemplateSpec.ObjectMeta.Annotations = make(map[string]string)
+	}
+	for k, v := range checksums {
+		podTemplateSpec.ObjectMeta.Annotations[k] = v
+	}
+	return nil
+}
+
+// addConfigChecksum adds the name and contents of a Secret or ConfigMap to hash. Objects that do not exist (yet) are
+// treated as empty, since pods will not start until they are created.
+func addConfigChecksum(client ControllerClient, hash hash.Hash, namespace, name string, obj runtime.Object) error {
+	namespacedName := types.NamespacedName{Namespace: namespace, Name: name}
+	err := client.Get(context.TODO(), namespacedName, obj)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("Unable to get %s for checksum: %v", name, err)
+	}
+
+	var data []byte
+	switch v := obj.(type) {
+	case *corev1.Secret:
+		data, _ = json.Marshal([]interface{}{"Secret", name, v.Data})
+	case *corev1.ConfigMap:
+		data, _ = json.Marshal([]interface{}{"ConfigMap", name, v.Data, v.BinaryData})
+	}
+	hash.Write(data)
+	return nil
+}
+
+// addTLSChecksum adds the name, CA certificate, default.yml and expiration of a TLS Secret to hash. Certificates issued
+// for individual pods are excluded, so that adding pods does not recycle existing ones; these are all replaced when
+// the CA changes or they are renewed, which changes the expiration.
+func addTLSChecksum(client ControllerClient, hash hash.Hash, namespace, name string) error {
+	var secret corev1.Secret
+	namespacedName := types.NamespacedName{Namespace: namespace, Name: name}
+	err := client.Get(context.TODO(), namespacedName, &secret)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("Unable to get %s for checksum: %v", name, err)
+	}
+	data, _ := json.Marshal([]interface{}{"Secret", name, secret.GetAnnotations()[enterprise.TLSExpirationAnnotation],
+		secret.Data[enterprise.TLSCACertKey], secret.Data["default.yml"], secret.Data["server.pem"]})
+	hash.Write(data)
+	return nil
+}
+
+// IsSecretRef returns true if obj is a Secret named name in namespace, which is the user-managed Secret referenced by
+// the secretRef of a custom resource
+func IsSecretRef(cr enterprisev1.MetaObject, spec *enterprisev1.CommonSplunkSpec, obj runtime.Object, namespace, name string) bool {
+	if _, ok := obj.(*corev1.Secret); !ok {
+		return false
+	}
+	return spec.SecretRef.Name != "" && spec.SecretRef.Name == name && cr.GetNamespace() == namespace &&
+		enterprise.ValidateSecretRef(cr, spec) == nil
+}
+
+// IsSecretsSource returns true if a changed LicenseMaster or ClusterMaster is referenced by spec.LicenseMasterRef or
+// spec.ClusterMasterRef, and may therefore be the source of the idxc_secret or pass4SymmKey used by cr
+func IsSecretsSource(cr enterprisev1.MetaObject, spec *enterprisev1.CommonSplunkSpec, obj runtime.Object, namespace, name string) bool {
+	var ref corev1.ObjectReference
+	switch obj.(type) {
+	case *enterprisev1.LicenseMaster:
+		ref = spec.LicenseMasterRef
+	case *enterprisev1.ClusterMaster:
+		ref = spec.ClusterMasterRef
+	default:
+		return false
+	}
+	refNamespace := ref.Namespace
+	if refNamespace == "" {
+		refNamespace = cr.GetNamespace()
+	}
+	return ref.Name != "" && ref.Name == name && refNamespace == namespace
+}
+
+// SecretsVersionChanged filters out updates to LicenseMasters and ClusterMasters that do not change the version of their
+// secrets, so that resources borrowing them are only requeued when the secrets are rotated or updated
+var SecretsVersionChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldStatus, newStatus := getSecretsStatus(e.ObjectOld), getSecretsStatus(e.ObjectNew)
+		return oldStatus == nil || newStatus == nil || oldStatus.Version != newStatus.Version
+	},
+}
+
+// ConfigDataChanged filters out updates to Secrets and ConfigMaps that do not change their contents, such as the
+// frequent renewals of leader election ConfigMaps, so that they do not requeue resources using them as volumes
+var ConfigDataChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		switch oldObj := e.ObjectOld.(type) {
+		case *corev1.Secret:
+			newObj, ok := e.ObjectNew.(*corev1.Secret)
+			return !ok || !reflect.DeepEqual(oldObj.Data, newObj.Data) || !reflect.DeepEqual(oldObj.StringData, newObj.StringData)
+		case *corev1.ConfigMap:
+			newObj, ok := e.ObjectNew.(*corev1.ConfigMap)
+			return !ok || !reflect.DeepEqual(oldObj.Data, newObj.Data) || !reflect.DeepEqual(oldObj.BinaryData, newObj.BinaryData)
+		}
+		return true
+	},
+}
+
+// getSecretsStatus returns the status of the secrets of a LicenseMaster or ClusterMaster, or nil for other objects
+func getSecretsStatus(obj runtime.Object) *enterprisev1.SecretsStatus {
+	switch cr := obj.(type) {
+	case *enterprisev1.LicenseMaster:
+		return &cr.Status.Secrets
+	case *enterprisev1.ClusterMaster:
+		return &cr.Status.Secrets
+	}
+	return nil
+}
+
+// IsVolumeSource returns true if a Secret or ConfigMap is used by any of the volumes in spec.Volumes, or if it is a
+// Secret used as the source of TLS certificates
+func IsVolumeSource(spec *enterprisev1.CommonSplunkSpec, obj runtime.Object, name string) bool {
+	if _, ok := obj.(*corev1.Secret); ok && spec.TLS.Enabled {
+		if name == spec.TLS.SecretName || (spec.TLS.SecretName == "" && name == enterprise.GetSplunkOperatorCAName()) {
+			return true
+		}
+	}
+	for _, volume := range spec.Volumes {
+		switch obj.(type) {
+		case *corev1.Secret:
+			if volume.Secret != nil && volume.Secret.SecretName == name {
+				return true
+			}
+		case *corev1.ConfigMap:
+			if volume.ConfigMap != nil && volume.ConfigMap.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -15,12 +15,16 @@
 package reconcile
 
 import (
+	"errors"
 	"testing"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
 func TestApplySplunkConfig(t *testing.T) {
@@ -44,30 +48,32 @@ func TestApplySplunkConfig(t *testing.T) {
 	searchHeadRevised.Spec.Image = "splunk/test"
 	reconcile := func(c *mockClient, cr interface{}) error {
 		obj := cr.(*enterprisev1.SearchHeadCluster)
-		_, err := ApplySplunkConfig(c, obj, obj.Spec.CommonSplunkSpec, enterprise.SplunkSearchHead)
+		_, err := ApplySplunkConfig(c, obj, obj.Spec.CommonSplunkSpec, enterprise.SplunkSearchHead, &obj.Status.Secrets)
 		return err
 	}
 	reconcileTester(t, "TestApplySplunkConfig", &searchHeadCR, searchHeadRevised, createCalls, updateCalls, reconcile)
 
-	// test search head with indexer reference
+	// test search head with cluster master reference
 	secret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "splunk-stack2-indexer-secrets",
+			Name:      "splunk-stack2-cluster-master-secrets",
 			Namespace: "test",
 		},
 		Data: map[string][]byte{
 			"idxc_secret": []byte{'a', 'b'},
 		},
 	}
-	searchHeadRevised.Spec.IndexerClusterRef.Name = "stack2"
+	// adding a reference updates existing secrets with the values it shares
+	searchHeadRevised.Spec.ClusterMasterRef.Name = "stack2"
 	updateCalls["Get"] = []mockFuncCall{
-		{metaName: "*v1.Secret-test-splunk-stack2-indexer-secrets"},
+		{metaName: "*v1.Secret-test-splunk-stack2-cluster-master-secrets"},
 		{metaName: "*v1.Secret-test-splunk-stack1-search-head-secrets"},
 		{metaName: "*v1.ConfigMap-test-splunk-stack1-search-head-defaults"},
 	}
+	updateCalls["Update"] = []mockFuncCall{{metaName: "*v1.Secret-test-splunk-stack1-search-head-secrets"}}
 	reconcileTester(t, "TestApplySplunkConfig", &searchHeadCR, searchHeadRevised, createCalls, updateCalls, reconcile, &secret)
 
-	// test indexer with license master
+	// test indexer with cluster master reference
 	indexerCR := enterprisev1.IndexerCluster{
 		TypeMeta: metav1.TypeMeta{
 			Kind: "IndexerCluster",
@@ -77,6 +83,32 @@ func TestApplySplunkConfig(t *testing.T) {
 			Namespace: "test",
 		},
 	}
+	indexerRevised := indexerCR.DeepCopy()
+	indexerRevised.Spec.Image = "splunk/test"
+	indexerRevised.Spec.ClusterMasterRef.Name = "stack2"
+	reconcile = func(c *mockClient, cr interface{}) error {
+		obj := cr.(*enterprisev1.IndexerCluster)
+		_, err := ApplySplunkConfig(c, obj, obj.Spec.CommonSplunkSpec, enterprise.SplunkIndexer, &obj.Status.Secrets)
+		return err
+	}
+	funcCalls = []mockFuncCall{
+		{metaName: "*v1.Secret-test-splunk-stack2-cluster-master-secrets"},
+		{metaName: "*v1.Secret-test-splunk-stack1-indexer-secrets"},
+	}
+	createCalls = map[string][]mockFuncCall{"Get": {funcCalls[1]}, "Create": {funcCalls[1]}}
+	updateCalls = map[string][]mockFuncCall{"Get": funcCalls, "Update": {funcCalls[1]}}
+	reconcileTester(t, "TestApplySplunkConfig", &indexerCR, indexerRevised, createCalls, updateCalls, reconcile, &secret)
+
+	// test cluster master with license master
+	clusterMasterCR := enterprisev1.ClusterMaster{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "ClusterMaster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
 	secret = corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "splunk-stack2-license-master-secrets",
@@ -87,22 +119,22 @@ func TestApplySplunkConfig(t *testing.T) {
 			"idxc_secret":  []byte{'a', 'b'},
 		},
 	}
-	indexerRevised := indexerCR.DeepCopy()
-	indexerRevised.Spec.Image = "splunk/test"
-	indexerRevised.Spec.LicenseMasterRef.Name = "stack2"
+	clusterMasterRevised := clusterMasterCR.DeepCopy()
+	clusterMasterRevised.Spec.Image = "splunk/test"
+	clusterMasterRevised.Spec.LicenseMasterRef.Name = "stack2"
 	reconcile = func(c *mockClient, cr interface{}) error {
-		obj := cr.(*enterprisev1.IndexerCluster)
-		_, err := ApplySplunkConfig(c, obj, obj.Spec.CommonSplunkSpec, enterprise.SplunkIndexer)
+		obj := cr.(*enterprisev1.ClusterMaster)
+		_, err := ApplySplunkConfig(c, obj, obj.Spec.CommonSplunkSpec, enterprise.SplunkClusterMaster, &obj.Status.Secrets)
 		return err
 	}
 	funcCalls = []mockFuncCall{
 		{metaName: "*v1.Secret-test-splunk-stack2-license-master-secrets"},
 		{metaName: "*v1.Secret-test-splunk-stack2-license-master-secrets"},
-		{metaName: "*v1.Secret-test-splunk-stack1-indexer-secrets"},
+		{metaName: "*v1.Secret-test-splunk-stack1-cluster-master-secrets"},
 	}
 	createCalls = map[string][]mockFuncCall{"Get": {funcCalls[2]}, "Create": {funcCalls[2]}}
-	updateCalls = map[string][]mockFuncCall{"Get": funcCalls}
-	reconcileTester(t, "TestApplySplunkConfig", &indexerCR, indexerRevised, createCalls, updateCalls, reconcile, &secret)
+	updateCalls = map[string][]mockFuncCall{"Get": funcCalls, "Update": {funcCalls[2]}}
+	reconcileTester(t, "TestApplySplunkConfig", &clusterMasterCR, clusterMasterRevised, createCalls, updateCalls, reconcile, &secret)
 }
 
 func TestApplyConfigMap(t *testing.T) {
@@ -141,3 +173,225 @@ func TestApplySecret(t *testing.T) {
 	}
 	reconcileTester(t, "TestApplySecret", &current, revised, createCalls, updateCalls, reconcile)
 }
+
+func TestApplyConfigSecret(t *testing.T) {
+	funcCalls := []mockFuncCall{{metaName: "*v1.Secret-test-smartstore"}}
+	createCalls := map[string][]mockFuncCall{"Get": funcCalls, "Create": funcCalls}
+	updateCalls := map[string][]mockFuncCall{"Get": funcCalls, "Update": funcCalls}
+	current := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "smartstore",
+			Namespace: "test",
+		},
+	}
+	revised := current.DeepCopy()
+	revised.Data = map[string][]byte{"a": []byte{'1', '2'}}
+	reconcile := func(c *mockClient, cr interface{}) error {
+		return ApplyConfigSecret(c, cr.(*corev1.Secret))
+	}
+	reconcileTester(t, "TestApplyConfigSecret", &current, revised, createCalls, updateCalls, reconcile)
+}
+
+func TestUpdateConfigChecksums(t *testing.T) {
+	cr := enterprisev1.Standalone{
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	cr.Spec.Defaults = "defaults-yaml"
+	cr.Spec.Volumes = []corev1.Volume{
+		{Name: "licenses", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "splunk-licenses"}}}},
+		{Name: "certs", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "splunk-certs"}}},
+		{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+	}
+	secrets := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-standalone-secrets", Namespace: "test"},
+		Data:       map[string][]byte{"password": []byte("abc")},
+	}
+	defaults := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-standalone-defaults", Namespace: "test"},
+		Data:       map[string]string{"default.yml": "defaults-yaml"},
+	}
+	licenses := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-licenses", Namespace: "test"},
+		Data:       map[string]string{"enterprise.lic": "license"},
+	}
+	certs := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-certs", Namespace: "test"},
+		Data:       map[string][]byte{"server.pem": []byte("cert")},
+	}
+	c := newMockClient()
+	for _, obj := range []runtime.Object{secrets, defaults, licenses, certs} {
+		c.state[getStateKey(obj)] = obj
+	}
+
+	getChecksums := func() map[string]string {
+		var podTemplateSpec corev1.PodTemplateSpec
+		err := updateConfigChecksums(c, &cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone, &podTemplateSpec)
+		if err != nil {
+			t.Errorf("updateConfigChecksums() returned %v; want nil", err)
+		}
+		return podTemplateSpec.ObjectMeta.Annotations
+	}
+	test := func(name string, before, after map[string]string, changed ...string) {
+		for _, annotation := range []string{secretsChecksumAnnotation, defaultsChecksumAnnotation, volumesChecksumAnnotation} {
+			want := false
+			for _, c := range changed {
+				if c == annotation {
+					want = true
+				}
+			}
+			if got := before[annotation] != after[annotation]; got != want {
+				t.Errorf("updateConfigChecksums(%s) changed %s = %t; want %t", name, annotation, got, want)
+			}
+		}
+	}
+
+	checksums := getChecksums()
+	for _, annotation := range []string{secretsChecksumAnnotation, defaultsChecksumAnnotation, volumesChecksumAnnotation} {
+		if checksums[annotation] == "" {
+			t.Errorf("updateConfigChecksums() missing %s annotation", annotation)
+		}
+	}
+	test("no changes", checksums, getChecksums())
+
+	secrets.Data["password"] = []byte("def")
+	updated := getChecksums()
+	test("secrets", checksums, updated, secretsChecksumAnnotation)
+
+	checksums = updated
+	defaults.Data["default.yml"] = "revised-yaml"
+	updated = getChecksums()
+	test("defaults", checksums, updated, defaultsChecksumAnnotation)
+
+	checksums = updated
+	certs.Data["server.pem"] = []byte("revised-cert")
+	updated = getChecksums()
+	test("secret volume", checksums, updated, volumesChecksumAnnotation)
+
+	checksums = updated
+	licenses.Data["enterprise.lic"] = "revised-license"
+	updated = getChecksums()
+	test("configmap volume", checksums, updated, volumesChecksumAnnotation)
+
+	// objects that do not exist are treated as empty, but other errors are returned
+	cr.Spec.Volumes[0].ConfigMap.Name = "missing"
+	c.notFoundError = k8serrors.NewNotFound(corev1.Resource("configmaps"), "missing")
+	var podTemplateSpec corev1.PodTemplateSpec
+	if err := updateConfigChecksums(c, &cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone, &podTemplateSpec); err != nil {
+		t.Errorf("updateConfigChecksums() returned %v for missing ConfigMap; want nil", err)
+	}
+	c.notFoundError = errors.New("boom")
+	if err := updateConfigChecksums(c, &cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone, &podTemplateSpec); err == nil {
+		t.Errorf("updateConfigChecksums() returned nil for get error; want error")
+	}
+}
+
+func TestIsVolumeSource(t *testing.T) {
+	spec := enterprisev1.CommonSplunkSpec{
+		Volumes: []corev1.Volume{
+			{Name: "licenses", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "splunk-licenses"}}}},
+			{Name: "certs", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "splunk-certs"}}},
+		},
+	}
+	test := func(obj runtime.Object, name string, want bool) {
+		if got := IsVolumeSource(&spec, obj, name); got != want {
+			t.Errorf("IsVolumeSource(%T, %s) = %t; want %t", obj, name, got, want)
+		}
+	}
+	test(&corev1.ConfigMap{}, "splunk-licenses", true)
+	test(&corev1.Secret{}, "splunk-certs", true)
+	test(&corev1.Secret{}, "splunk-licenses", false)
+	test(&corev1.ConfigMap{}, "splunk-certs", false)
+	test(&corev1.ConfigMap{}, "other", false)
+	test(&corev1.Secret{}, "splunk-operator-ca", false)
+
+	spec.TLS.Enabled = true
+	test(&corev1.Secret{}, "splunk-operator-ca", true)
+	test(&corev1.ConfigMap{}, "splunk-operator-ca", false)
+
+	spec.TLS.SecretName = "my-certs"
+	test(&corev1.Secret{}, "my-certs", true)
+	test(&corev1.Secret{}, "splunk-operator-ca", false)
+}
+
+func TestIsSecretRef(t *testing.T) {
+	cr := enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	test := func(obj runtime.Object, namespace, name string, want bool) {
+		if got := IsSecretRef(&cr, &cr.Spec.CommonSplunkSpec, obj, namespace, name); got != want {
+			t.Errorf("IsSecretRef(%T, %s, %s) = %t; want %t", obj, namespace, name, got, want)
+		}
+	}
+	test(&corev1.Secret{}, "test", "", false)
+
+	cr.Spec.SecretRef.Name = "my-secrets"
+	test(&corev1.Secret{}, "test", "my-secrets", true)
+	test(&corev1.ConfigMap{}, "test", "my-secrets", false)
+	test(&corev1.Secret{}, "vault", "my-secrets", false)
+
+	cr.Spec.SecretRef.Namespace = "test"
+	test(&corev1.Secret{}, "test", "my-secrets", true)
+
+	// Secrets in other namespaces are never used
+	cr.Spec.SecretRef.Namespace = "vault"
+	test(&corev1.Secret{}, "vault", "my-secrets", false)
+	test(&corev1.Secret{}, "test", "my-secrets", false)
+}
+
+func TestIsSecretsSource(t *testing.T) {
+	cr := enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	test := func(obj runtime.Object, namespace, name string, want bool) {
+		if got := IsSecretsSource(&cr, &cr.Spec.CommonSplunkSpec, obj, namespace, name); got != want {
+			t.Errorf("IsSecretsSource(%T, %s, %s) = %t; want %t", obj, namespace, name, got, want)
+		}
+	}
+	test(&enterprisev1.LicenseMaster{}, "test", "", false)
+
+	cr.Spec.LicenseMasterRef.Name = "lm"
+	cr.Spec.ClusterMasterRef = corev1.ObjectReference{Name: "cm", Namespace: "idxc"}
+	test(&enterprisev1.LicenseMaster{}, "test", "lm", true)
+	test(&enterprisev1.LicenseMaster{}, "idxc", "lm", false)
+	test(&enterprisev1.ClusterMaster{}, "test", "lm", false)
+	test(&enterprisev1.ClusterMaster{}, "idxc", "cm", true)
+	test(&enterprisev1.ClusterMaster{}, "test", "cm", false)
+	test(&corev1.Secret{}, "idxc", "cm", false)
+}
+
+func TestSecretsVersionChanged(t *testing.T) {
+	test := func(oldObj, newObj runtime.Object, want bool) {
+		if got := SecretsVersionChanged.Update(event.UpdateEvent{ObjectOld: oldObj, ObjectNew: newObj}); got != want {
+			t.Errorf("SecretsVersionChanged.Update(%T) = %t; want %t", newObj, got, want)
+		}
+	}
+	lm := enterprisev1.LicenseMaster{}
+	updated := lm.DeepCopy()
+	updated.Status.Phase = enterprisev1.PhaseReady
+	test(&lm, updated, false)
+	updated.Status.Secrets.Version++
+	test(&lm, updated, true)
+
+	cm := enterprisev1.ClusterMaster{}
+	test(&cm, cm.DeepCopy(), false)
+	test(&corev1.Secret{}, &corev1.Secret{}, true)
+}
+
+func TestConfigDataChanged(t *testing.T) {
+	test := func(oldObj, newObj runtime.Object, want bool) {
+		if got := ConfigDataChanged.Update(event.UpdateEvent{ObjectOld: oldObj, ObjectNew: newObj}); got != want {
+			t.Errorf("ConfigDataChanged.Update(%T) = %t; want %t", newObj, got, want)
+		}
+	}
+	secret := corev1.Secret{Data: map[string][]byte{"password": []byte("abc")}}
+	updated := secret.DeepCopy()
+	updated.Annotations = map[string]string{"renewed": "true"}
+	test(&secret, updated, false)
+	updated.Data["password"] = []byte("def")
+	test(&secret, updated, true)
+
+	configMap := corev1.ConfigMap{Data: map[string]string{"default.yml": "defaults"}}
+	leader := configMap.DeepCopy()
+	leader.Annotations = map[string]string{"control-plane.alpha.kubernetes.io/leader": "renewed"}
+	test(&configMap, leader, false)
+	leader.BinaryData = map[string][]byte{"app.tgz": []byte("app")}
+	test(&configMap, leader, true)
+
+	test(&enterprisev1.ClusterMaster{}, &enterprisev1.ClusterMaster{}, true)
+}
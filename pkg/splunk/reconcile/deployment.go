@@ -18,7 +18,7 @@ import (
 	"context"
 	"fmt"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -48,10 +48,12 @@ func ApplyDeployment(c ControllerClient, revised *appsv1.Deployment) (enterprise
 	if revised.Spec.Replicas != nil {
 		if *revised.Spec.Replicas < desiredReplicas {
 			scopedLog.Info(fmt.Sprintf("Scaling replicas up to %d", desiredReplicas))
+			newOwnerEventPublisher(c, revised).Normal("ScalingUp", "Scaling up %s from %d to %d replicas", revised.GetName(), *revised.Spec.Replicas, desiredReplicas)
 			*revised.Spec.Replicas = desiredReplicas
 			return enterprisev1.PhaseScalingUp, UpdateResource(c, revised)
 		} else if *revised.Spec.Replicas > desiredReplicas {
 			scopedLog.Info(fmt.Sprintf("Scaling replicas down to %d", desiredReplicas))
+			newOwnerEventPublisher(c, revised).Normal("ScalingDown", "Scaling down %s from %d to %d replicas", revised.GetName(), *revised.Spec.Replicas, desiredReplicas)
 			*revised.Spec.Replicas = desiredReplicas
 			return enterprisev1.PhaseScalingDown, UpdateResource(c, revised)
 		}
@@ -81,3 +83,34 @@ func ApplyDeployment(c ControllerClient, revised *appsv1.Deployment) (enterprise
 	scopedLog.Info("All pods are ready")
 	return enterprisev1.PhaseReady, nil
 }
+
+// GetDeploymentPhase returns the phase of the pods managed by an existing Deployment, without modifying anything.
+// This is used to refresh status while reconcile is paused.
+func GetDeploymentPhase(c ControllerClient, deployment *appsv1.Deployment) enterprisev1.ResourcePhase {
+	namespacedName := types.NamespacedName{Namespace: deployment.GetNamespace(), Name: deployment.GetName()}
+	var current appsv1.Deployment
+
+	err := c.Get(context.TODO(), namespacedName, &current)
+	if err != nil {
+		// no Deployment exists, and it won't be created until reconcile is resumed
+		deployment.Status = appsv1.DeploymentStatus{}
+		return enterprisev1.PhasePending
+	}
+	*deployment = current // caller expects that object passed represents latest state
+
+	replicas := int32(1)
+	if current.Spec.Replicas != nil {
+		replicas = *current.Spec.Replicas
+	}
+	switch {
+	case current.Status.UpdatedReplicas < current.Status.Replicas:
+		return enterprisev1.PhaseUpdating
+	case current.Status.ReadyReplicas < replicas && current.Status.ReadyReplicas > 0:
+		return enterprisev1.PhaseScalingUp
+	case current.Status.ReadyReplicas < replicas:
+		return enterprisev1.PhasePending
+	case current.Status.Replicas > replicas:
+		return enterprisev1.PhaseScalingDown
+	}
+	return enterprisev1.PhaseReady
+}
@@ -17,7 +17,7 @@ package reconcile
 import (
 	"testing"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -118,3 +118,30 @@ func TestApplyDeployment(t *testing.T) {
 		t.Errorf("TestApplyDeployment() returned error = %v; want nil", err)
 	}
 }
+
+func TestGetDeploymentPhase(t *testing.T) {
+	c := newMockClient()
+	var replicas int32 = 2
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-spark-worker", Namespace: "test"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+	}
+
+	test := func(status appsv1.DeploymentStatus, want enterprisev1.ResourcePhase) {
+		current := deployment.DeepCopy()
+		current.Status = status
+		c.state[getStateKey(current)] = current
+		if got := GetDeploymentPhase(c, deployment.DeepCopy()); got != want {
+			t.Errorf("GetDeploymentPhase(%v) = %s; want %s", status, got, want)
+		}
+	}
+
+	if got := GetDeploymentPhase(c, deployment.DeepCopy()); got != enterprisev1.PhasePending {
+		t.Errorf("GetDeploymentPhase() = %s; want Pending when it does not exist", got)
+	}
+	test(appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2}, enterprisev1.PhasePending)
+	test(appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 1}, enterprisev1.PhaseScalingUp)
+	test(appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 1, ReadyReplicas: 2}, enterprisev1.PhaseUpdating)
+	test(appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, ReadyReplicas: 3}, enterprisev1.PhaseScalingDown)
+	test(appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2}, enterprisev1.PhaseReady)
+}
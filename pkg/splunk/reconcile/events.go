@@ -0,0 +1,163 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+)
+
+// eventInterval is the minimum time between publishing identical events for the same object
+const eventInterval = 5 * time.Minute
+
+// controllerClient is a ControllerClient that publishes events using a Kubernetes EventRecorder
+type controllerClient struct {
+	client.Client
+	recorder record.EventRecorder
+	limiter  *eventRateLimiter
+}
+
+// NewControllerClient returns a ControllerClient that uses c to manage resources, and recorder to publish events.
+// Identical events for the same object are only published once every 5 minutes, to prevent event spam.
+func NewControllerClient(c client.Client, recorder record.EventRecorder) ControllerClient {
+	return &controllerClient{
+		Client:   c,
+		recorder: recorder,
+		limiter:  newEventRateLimiter(eventInterval),
+	}
+}
+
+// Eventf for controllerClient publishes an event, unless an identical one was recently published
+func (c *controllerClient) Eventf(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if c.limiter.allow(obj, eventType, reason, message) {
+		c.recorder.Event(obj, eventType, reason, message)
+	}
+}
+
+// eventRateLimiter is used to prevent identical events from being published too often
+type eventRateLimiter struct {
+	interval  time.Duration
+	now       func() time.Time
+	mutex     sync.Mutex
+	published map[string]time.Time
+}
+
+// newEventRateLimiter returns an eventRateLimiter that allows identical events once per interval
+func newEventRateLimiter(interval time.Duration) *eventRateLimiter {
+	return &eventRateLimiter{
+		interval:  interval,
+		now:       time.Now,
+		published: make(map[string]time.Time),
+	}
+}
+
+// allow returns true if an event may be published, and records that it was
+func (l *eventRateLimiter) allow(obj runtime.Object, eventType, reason, message string) bool {
+	key := fmt.Sprintf("%s/%s/%s/%s", getEventObjectKey(obj), eventType, reason, message)
+	now := l.now()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if last, ok := l.published[key]; ok && now.Sub(last) < l.interval {
+		return false
+	}
+
+	// forget events that may be published again, so that this doesn't grow forever
+	for k, last := range l.published {
+		if now.Sub(last) >= l.interval {
+			delete(l.published, k)
+		}
+	}
+	l.published[key] = now
+	return true
+}
+
+// getEventObjectKey returns a string that identifies the object an event is published for
+func getEventObjectKey(obj runtime.Object) string {
+	if ref, ok := obj.(*corev1.ObjectReference); ok {
+		return fmt.Sprintf("%s/%s/%s", ref.Kind, ref.Namespace, ref.Name)
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return fmt.Sprintf("%T", obj)
+	}
+	return fmt.Sprintf("%T/%s/%s", obj, accessor.GetNamespace(), accessor.GetName())
+}
+
+// eventPublisher is used to publish events for a custom resource
+type eventPublisher struct {
+	client ControllerClient
+	obj    runtime.Object
+}
+
+// newEventPublisher returns an eventPublisher for a custom resource
+func newEventPublisher(client ControllerClient, cr runtime.Object) *eventPublisher {
+	return &eventPublisher{client: client, obj: cr}
+}
+
+// newOwnerEventPublisher returns an eventPublisher for the custom resource that controls a Kubernetes resource,
+// or for the resource itself if it has no controller
+func newOwnerEventPublisher(client ControllerClient, obj ResourceObject) *eventPublisher {
+	objMeta := obj.GetObjectMeta()
+	owner := metav1.GetControllerOf(objMeta)
+	if owner == nil {
+		return newEventPublisher(client, obj)
+	}
+	return newEventPublisher(client, &corev1.ObjectReference{
+		APIVersion: owner.APIVersion,
+		Kind:       owner.Kind,
+		Name:       owner.Name,
+		Namespace:  objMeta.GetNamespace(),
+		UID:        owner.UID,
+	})
+}
+
+// Normal publishes an event of type Normal; this does nothing if p is nil
+func (p *eventPublisher) Normal(reason, messageFmt string, args ...interface{}) {
+	p.publish(corev1.EventTypeNormal, reason, messageFmt, args...)
+}
+
+// Warning publishes an event of type Warning; this does nothing if p is nil
+func (p *eventPublisher) Warning(reason, messageFmt string, args ...interface{}) {
+	p.publish(corev1.EventTypeWarning, reason, messageFmt, args...)
+}
+
+// RESTAPIFailed publishes a RESTAPIFailed warning for a Splunk REST API request that returned err, unless err only
+// indicates that the Splunk instance is not ready yet; this does nothing if p is nil
+func (p *eventPublisher) RESTAPIFailed(err error, messageFmt string, args ...interface{}) {
+	if !splclient.IsNotReady(err) {
+		p.Warning("RESTAPIFailed", messageFmt, args...)
+	}
+}
+
+// publish publishes an event of the given type
+func (p *eventPublisher) publish(eventType, reason, messageFmt string, args ...interface{}) {
+	if p == nil || p.client == nil {
+		return
+	}
+	p.client.Eventf(p.obj, eventType, reason, messageFmt, args...)
+}
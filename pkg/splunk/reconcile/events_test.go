@@ -0,0 +1,108 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	"github.com/splunk/splunk-operator/pkg/splunk/resources"
+)
+
+func TestEventRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := newEventRateLimiter(time.Minute)
+	l.now = func() time.Time { return now }
+	cr := &enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	other := &enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack2", Namespace: "test"}}
+
+	test := func(obj *enterprisev1.Standalone, message string, want bool) {
+		if got := l.allow(obj, corev1.EventTypeWarning, "PodsFailed", message); got != want {
+			t.Errorf("allow(%s, %s) = %t; want %t", obj.GetName(), message, got, want)
+		}
+	}
+
+	test(cr, "unable to create statefulset", true)
+	test(cr, "unable to create statefulset", false)
+	test(cr, "unable to update statefulset", true)
+	test(other, "unable to create statefulset", true)
+
+	now = now.Add(30 * time.Second)
+	test(cr, "unable to create statefulset", false)
+
+	now = now.Add(30 * time.Second)
+	test(cr, "unable to create statefulset", true)
+	if len(l.published) != 1 {
+		t.Errorf("len(published) = %d; want 1", len(l.published))
+	}
+}
+
+func TestNewControllerClient(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	c := NewControllerClient(newMockClient(), recorder)
+	cr := &enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+
+	c.Eventf(cr, corev1.EventTypeNormal, "ScalingUp", "Scaling up %s from %d to %d replicas", "splunk-stack1-standalone", 1, 2)
+	c.Eventf(cr, corev1.EventTypeNormal, "ScalingUp", "Scaling up %s from %d to %d replicas", "splunk-stack1-standalone", 1, 2)
+	if len(recorder.Events) != 1 {
+		t.Fatalf("published %d events; want 1", len(recorder.Events))
+	}
+	want := "Normal ScalingUp Scaling up splunk-stack1-standalone from 1 to 2 replicas"
+	if got := <-recorder.Events; got != want {
+		t.Errorf("published %s; want %s", got, want)
+	}
+}
+
+func TestOwnerEventPublisher(t *testing.T) {
+	c := newMockClient()
+	cr := enterprisev1.Standalone{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "enterprise.splunk.com/v1alpha3", Kind: "Standalone"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test", UID: "abc"},
+	}
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-standalone", Namespace: "test"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: new(int32)},
+		Status:     appsv1.StatefulSetStatus{Replicas: 1, ReadyReplicas: 1},
+	}
+	*statefulSet.Spec.Replicas = 1
+
+	// events are published for the resource itself if it has no controller
+	newOwnerEventPublisher(c, statefulSet).Warning("Test", "test")
+	if got := (*c.events)[0].obj; got != statefulSet {
+		t.Errorf("event published for %v; want %v", got, statefulSet)
+	}
+
+	// otherwise, events are published for the custom resource that controls it
+	statefulSet.SetOwnerReferences([]metav1.OwnerReference{resources.AsOwner(&cr)})
+	mgr := DefaultStatefulSetPodManager{}
+	phase, err := UpdateStatefulSetPods(c, statefulSet, &mgr, 2)
+	if phase != enterprisev1.PhaseScalingUp || err != nil {
+		t.Errorf("UpdateStatefulSetPods() = %s,%v; want ScalingUp,nil", phase, err)
+	}
+	got := (*c.events)[1]
+	want := corev1.ObjectReference{APIVersion: "enterprise.splunk.com/v1alpha3", Kind: "Standalone", Name: "stack1", Namespace: "test", UID: "abc"}
+	if ref, ok := got.obj.(*corev1.ObjectReference); !ok || *ref != want {
+		t.Errorf("event published for %v; want %v", got.obj, want)
+	}
+	if got.eventType != corev1.EventTypeNormal || got.reason != "ScalingUp" || got.message != "Scaling up splunk-stack1-standalone from 1 to 2 replicas" {
+		t.Errorf("published %s %s %s; want Normal ScalingUp \"Scaling up splunk-stack1-standalone from 1 to 2 replicas\"", got.eventType, got.reason, got.message)
+	}
+}
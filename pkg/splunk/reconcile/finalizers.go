@@ -22,7 +22,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 )
 
 const (
@@ -66,6 +66,10 @@ func CheckSplunkDeletion(cr enterprisev1.MetaObject, c ControllerClient) (bool,
 		}
 	}
 
+	// forget any status that was cached, and any bundle push that was tracked, for the custom resource
+	defaultStatusPollers.remove(cr)
+	defaultBundlePushes.remove(cr)
+
 	scopedLog.Info("Deletion complete")
 
 	return true, nil
@@ -85,6 +89,10 @@ func DeleteSplunkPvc(cr enterprisev1.MetaObject, c ControllerClient) error {
 		component = "search-head"
 	case "IndexerCluster":
 		component = "indexer"
+	case "ClusterMaster":
+		component = "cluster-master"
+	case "MonitoringConsole":
+		component = "monitoring-console"
 	default:
 		scopedLog.Info("Skipping PVC removal")
 		return nil
@@ -114,6 +122,7 @@ func DeleteSplunkPvc(cr enterprisev1.MetaObject, c ControllerClient) error {
 		if err := c.Delete(context.Background(), &pvc); err != nil {
 			return err
 		}
+		newEventPublisher(c, cr).Normal("DeletedPVC", "Deleted persistent volume claim %s", pvc.ObjectMeta.Name)
 	}
 
 	return nil
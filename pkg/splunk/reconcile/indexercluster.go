@@ -17,146 +17,503 @@ package reconcile
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/go-logr/logr"
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
 	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
 	"github.com/splunk/splunk-operator/pkg/splunk/resources"
 )
 
 // ApplyIndexerCluster reconciles the state of a Splunk Enterprise indexer cluster.
-func ApplyIndexerCluster(client ControllerClient, cr *enterprisev1.IndexerCluster) (reconcile.Result, error) {
+func ApplyIndexerCluster(client ControllerClient, cr *enterprisev1.IndexerCluster) (result reconcile.Result, err error) {
 
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 	scopedLog := log.WithName("ApplyIndexerCluster").WithValues("name", cr.GetIdentifier(), "namespace", cr.GetNamespace())
 
+	// updates status after function completes
+	tracker := newStatusTracker(client, cr, &cr.Status.Phase, &cr.Status.CommonStatus,
+		stepValidate, stepClusterMaster, stepTLS, stepSecrets, stepServices, stepPods, stepCluster)
+	defer func() {
+		tracker.finish(err)
+		if updateErr := client.Status().Update(context.TODO(), cr); updateErr != nil {
+			scopedLog.Error(updateErr, "Status update failed")
+		}
+	}()
+
 	// validate and updates defaults for CR
-	err := enterprise.ValidateIndexerClusterSpec(&cr.Spec)
+	tracker.begin(stepValidate)
+	err = enterprise.ValidateIndexerClusterSpec(&cr.Spec)
 	if err != nil {
 		return result, err
 	}
-
-	// updates status after function completes
-	cr.Status.Phase = enterprisev1.PhaseError
-	cr.Status.ClusterMasterPhase = enterprisev1.PhaseError
 	cr.Status.Replicas = cr.Spec.Replicas
 	cr.Status.Selector = fmt.Sprintf("app.kubernetes.io/instance=splunk-%s-indexer", cr.GetIdentifier())
 	if cr.Status.Peers == nil {
 		cr.Status.Peers = []enterprisev1.IndexerClusterMemberStatus{}
 	}
-	defer func() {
-		err = client.Status().Update(context.TODO(), cr)
-		if err != nil {
-			scopedLog.Error(err, "Status update failed")
-		}
-	}()
+	if cr.Status.Sites == nil {
+		cr.Status.Sites = []enterprisev1.IndexerClusterSiteStatus{}
+	}
+	if cr.Status.Bundle.ValidationErrors == nil {
+		cr.Status.Bundle.ValidationErrors = []string{}
+	}
 
 	// check if deletion has been requested
 	if cr.ObjectMeta.DeletionTimestamp != nil {
+		tracker.begin(stepDeletion)
 		terminating, err := CheckSplunkDeletion(cr, client)
 		if terminating && err != nil { // don't bother if no error, since it will just be removed immmediately after
 			cr.Status.Phase = enterprisev1.PhaseTerminating
-			cr.Status.ClusterMasterPhase = enterprisev1.PhaseTerminating
 		} else {
 			result.Requeue = false
 		}
 		return result, err
 	}
 
-	// create or update general config resources
-	secrets, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkIndexer)
+	// only refresh status while reconcile is paused
+	if tracker.paused {
+		result.Requeue = false
+		return result, refreshPausedIndexerClusterStatus(client, cr, tracker)
+	}
+
+	// get the cluster master that this indexer cluster is a peer group of
+	tracker.begin(stepClusterMaster)
+	clusterMaster, err := getClusterMaster(client, cr)
 	if err != nil {
+		cr.Status.ClusterMasterPhase = enterprisev1.PhaseError
 		return result, err
 	}
+	cr.Status.ClusterMasterPhase = clusterMaster.Status.Phase
 
-	// create or update a headless service for indexer cluster
-	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkIndexer, true))
+	// validate the indexer cluster against the cluster master's configuration
+	err = enterprise.ValidateClusterMasterSpec(&clusterMaster.Spec)
 	if err != nil {
 		return result, err
 	}
+	err = enterprise.ValidateIndexerClusterMultisite(&cr.Spec, &clusterMaster.Spec)
+	if err != nil {
+		return result, err
+	}
+	if cr.Status.ClusterMasterPhase != enterprisev1.PhaseReady {
+		tracker.setCondition(corev1.ConditionFalse, string(cr.Status.ClusterMasterPhase),
+			fmt.Sprintf("cluster master \"%s\" is %s", clusterMaster.GetName(), cr.Status.ClusterMasterPhase))
+	}
 
-	// create or update a regular service for indexer cluster (ingestion)
-	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkIndexer, false))
+	// create or update TLS certificates for the indexers in all sites
+	tracker.begin(stepTLS)
+	if cr.Spec.TLS.Enabled && !clusterMaster.Spec.TLS.Enabled {
+		return result, fmt.Errorf("tls must also be enabled for cluster master \"%s\"", clusterMaster.GetName())
+	}
+	tlsHosts := enterprise.GetSplunkTLSHosts(cr.GetNamespace(), enterprise.SplunkIndexer, cr.GetIdentifier(), "", cr.Spec.Replicas)
+	if len(cr.Spec.Multisite.Sites) > 0 {
+		tlsHosts = make(map[string][]string)
+		for _, site := range cr.Spec.Multisite.Sites {
+			for podName, dnsNames := range enterprise.GetSplunkTLSHosts(cr.GetNamespace(), enterprise.SplunkIndexer, cr.GetIdentifier(), site.Name, site.Replicas) {
+				tlsHosts[podName] = dnsNames
+			}
+		}
+	}
+	tlsManager := TLSManager{log: scopedLog, cr: cr, spec: &cr.Spec.TLS, status: &cr.Status.TLS, instanceType: enterprise.SplunkIndexer,
+		hosts: tlsHosts, events: newEventPublisher(client, cr)}
+	err = tlsManager.Apply(client)
 	if err != nil {
 		return result, err
 	}
 
-	// create or update a regular service for the cluster master
-	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkClusterMaster, false))
+	// the cluster master's certificates are verified using its own CA, which may differ from the indexers' CA if either
+	// uses tls.secretName or the cluster master is in another namespace
+	clusterMasterTLS := TLSManager{log: scopedLog, cr: clusterMaster, spec: &clusterMaster.Spec.TLS, status: &clusterMaster.Status.TLS,
+		instanceType: enterprise.SplunkClusterMaster}
+	err = clusterMasterTLS.Load(client)
 	if err != nil {
 		return result, err
 	}
 
-	// create or update statefulset for the cluster master
-	statefulSet, err := enterprise.GetClusterMasterStatefulSet(cr)
+	// create or update general config resources
+	tracker.begin(stepSecrets)
+	secrets, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkIndexer, &cr.Status.Secrets)
 	if err != nil {
 		return result, err
 	}
-	clusterMasterManager := DefaultStatefulSetPodManager{}
-	phase, err := clusterMasterManager.Update(client, statefulSet, 1)
+
+	// rotate secrets when requested, before they are used to manage any peers
+	instanceURIs := getSplunkInstanceURIs(cr.GetNamespace(), enterprise.SplunkIndexer, cr.GetIdentifier(), cr.Spec.Replicas)
+	if len(cr.Spec.Multisite.Sites) > 0 {
+		instanceURIs = []string{}
+		for _, site := range cr.Spec.Multisite.Sites {
+			siteIdentifier := enterprise.GetSplunkSiteIdentifier(cr.GetIdentifier(), site.Name)
+			instanceURIs = append(instanceURIs, getSplunkInstanceURIs(cr.GetNamespace(), enterprise.SplunkIndexer, siteIdentifier, site.Replicas)...)
+		}
+	}
+	secretsManager := SecretRotationManager{log: scopedLog, cr: cr, spec: &cr.Spec.CommonSplunkSpec, status: &cr.Status.Secrets, instanceType: enterprise.SplunkIndexer,
+		secrets: secrets, instanceURIs: instanceURIs, newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}
+	err = secretsManager.Update(client, cr.Status.Phase == enterprisev1.PhaseReady)
 	if err != nil {
 		return result, err
 	}
-	cr.Status.ClusterMasterPhase = phase
 
-	// create or update statefulset for the indexers
-	statefulSet, err = enterprise.GetIndexerStatefulSet(cr)
+	// get the admin password used to manage peers through the cluster master, and the service account used to poll it
+	clusterMasterPassword, err := GetSplunkSecret(client, cr, cr.Spec.ClusterMasterRef, enterprise.SplunkClusterMaster, "password")
 	if err != nil {
 		return result, err
 	}
-	mgr := IndexerClusterPodManager{log: scopedLog, cr: cr, secrets: secrets, newSplunkClient: splclient.NewSplunkClient}
-	phase, err = mgr.Update(client, statefulSet, cr.Spec.Replicas)
+	clusterMasterAccount, err := GetSplunkServiceAccount(client, cr, cr.Spec.ClusterMasterRef, enterprise.SplunkClusterMaster)
+	if err != nil {
+		return result, err
+	}
+	serviceAccount := &ServiceAccount{log: scopedLog, secret: clusterMasterAccount, adminPassword: clusterMasterPassword, newSplunkClient: clusterMasterTLS.newSplunkClient, events: newEventPublisher(client, cr)}
+
+	// create or update a headless service for indexer cluster
+	tracker.begin(stepServices)
+	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkIndexer, true))
+	if err != nil {
+		return result, err
+	}
+
+	// create or update a regular service for indexer cluster (ingestion)
+	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkIndexer, false))
+	if err != nil {
+		return result, err
+	}
+
+	// create or update statefulsets for the indexers
+	tracker.begin(stepPods)
+	var phase enterprisev1.ResourcePhase
+	if len(cr.Spec.Multisite.Sites) > 0 {
+		phase, err = applyIndexerClusterSites(client, cr, secrets, clusterMasterPassword, serviceAccount, tlsManager.newSplunkClient, clusterMasterTLS.newSplunkClient, scopedLog)
+	} else {
+		var statefulSet *appsv1.StatefulSet
+		statefulSet, err = enterprise.GetIndexerStatefulSet(cr)
+		if err != nil {
+			return result, err
+		}
+		err = updateConfigChecksums(client, cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkIndexer, &statefulSet.Spec.Template)
+		if err != nil {
+			return result, err
+		}
+		mgr := IndexerClusterPodManager{log: scopedLog, cr: cr, secrets: secrets, clusterMasterPassword: clusterMasterPassword, serviceAccount: serviceAccount, newSplunkClient: tlsManager.newSplunkClient,
+			newClusterMasterClient: clusterMasterTLS.newSplunkClient, events: newEventPublisher(client, cr), client: client}
+		phase, err = mgr.Update(client, statefulSet, cr.Spec.Replicas)
+	}
 	if err != nil {
 		return result, err
 	}
 	cr.Status.Phase = phase
+	tracker.setPhase(phase)
+	updateIndexerClusterBundleStatus(cr, clusterMaster)
 
 	// no need to requeue if everything is ready
 	if cr.Status.Phase == enterprisev1.PhaseReady {
+		tlsManager.setReady()
+
+		// disable maintenance mode after all peers have been recycled and are up again
+		mgr := IndexerClusterPodManager{log: scopedLog, cr: cr, clusterMasterPassword: clusterMasterPassword, newSplunkClient: tlsManager.newSplunkClient,
+			newClusterMasterClient: clusterMasterTLS.newSplunkClient, events: newEventPublisher(client, cr), client: client}
+		err = mgr.setMaintenanceMode(false)
+		if err != nil {
+			return result, err
+		}
+
+		// use a searchable rolling restart to apply changes to the cluster bundle
+		cr.Status.Phase, err = mgr.updateRollingRestart()
+		if err != nil {
+			return result, err
+		}
+
+		// rebalance data across peers after scaling up
+		if cr.Status.Phase == enterprisev1.PhaseReady {
+			cr.Status.Phase, err = mgr.updateRebalance()
+			if err != nil {
+				return result, err
+			}
+		}
+		tracker.setPhase(cr.Status.Phase)
+	}
+
+	// report whether the indexer cluster is in a safe state, which is required before peers are decommissioned
+	tracker.begin(stepCluster)
+	healthy := setIndexerClusterHealthCondition(cr, tracker)
+	if cr.Status.Phase == enterprisev1.PhaseReady && healthy {
 		result.Requeue = false
+		secretsManager.setRequeue(&result)
+		tlsManager.setRequeue(&result)
 	}
 	return result, nil
 }
 
-// IndexerClusterPodManager is used to manage the pods within a search head cluster
+// getIndexerClusterHealth returns the reason and message why an indexer cluster is not in a safe state, based on the
+// health most recently polled from its cluster master, or an empty reason if it is
+func getIndexerClusterHealth(cr *enterprisev1.IndexerCluster) (string, string) {
+	switch {
+	case cr.Status.ReplicationFactorMet == nil || cr.Status.SearchFactorMet == nil:
+		return "HealthUnknown", "health of the indexer cluster could not be polled from the cluster master"
+	case !*cr.Status.ReplicationFactorMet:
+		return "ReplicationFactorNotMet", "indexer cluster does not meet its replication factor"
+	case !*cr.Status.SearchFactorMet:
+		return "SearchFactorNotMet", "indexer cluster does not meet its search factor"
+	}
+	return "", ""
+}
+
+// setIndexerClusterHealthCondition reports the health of an indexer cluster using the condition for the current
+// step; it returns true if the indexer cluster is in a safe state
+func setIndexerClusterHealthCondition(cr *enterprisev1.IndexerCluster, tracker *statusTracker) bool {
+	reason, message := getIndexerClusterHealth(cr)
+	switch reason {
+	case "":
+		tracker.setCondition(corev1.ConditionTrue, stepCluster.successReason, "")
+		return true
+	case "HealthUnknown":
+		tracker.setCondition(corev1.ConditionUnknown, reason, message)
+	default:
+		tracker.setCondition(corev1.ConditionFalse, reason, message)
+	}
+	return false
+}
+
+// updateIndexerClusterBundleStatus updates the status of the cluster master bundle, including how many peers have
+// activated it
+func updateIndexerClusterBundleStatus(cr *enterprisev1.IndexerCluster, clusterMaster *enterprisev1.ClusterMaster) {
+	status := &cr.Status.Bundle
+	status.AppliedChecksum = clusterMaster.Status.ActiveBundle.Checksum
+	status.ValidationErrors = clusterMaster.Status.BundlePush.ValidationErrors
+	if status.ValidationErrors == nil {
+		status.ValidationErrors = []string{}
+	}
+
+	status.UpdatedPeers = 0
+	countPeers := func(peers []enterprisev1.IndexerClusterMemberStatus) {
+		for _, peer := range peers {
+			if peer.ActiveBundleID != "" && peer.ActiveBundleID == status.AppliedChecksum {
+				status.UpdatedPeers++
+			}
+		}
+	}
+	countPeers(cr.Status.Peers)
+	for _, site := range cr.Status.Sites {
+		countPeers(site.Peers)
+	}
+}
+
+// getIndexerClusterConfigHash returns a checksum of the configuration for an indexer cluster that is applied by
+// restarting splunkd, without needing to modify the pod template: the cluster bundle in master-apps that is active on
+// its cluster master. It is empty until the active bundle is known.
+func getIndexerClusterConfigHash(cr *enterprisev1.IndexerCluster) string {
+	return cr.Status.Bundle.AppliedChecksum
+}
+
+// getClusterMasterName returns the namespace and name of the ClusterMaster custom resource referenced by an indexer cluster
+func getClusterMasterName(cr *enterprisev1.IndexerCluster) types.NamespacedName {
+	ref := cr.Spec.ClusterMasterRef
+	namespacedName := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	if namespacedName.Namespace == "" {
+		namespacedName.Namespace = cr.GetNamespace()
+	}
+	return namespacedName
+}
+
+// getClusterMaster returns the ClusterMaster custom resource referenced by an indexer cluster
+func getClusterMaster(client ControllerClient, cr *enterprisev1.IndexerCluster) (*enterprisev1.ClusterMaster, error) {
+	namespacedName := getClusterMasterName(cr)
+	var clusterMaster enterprisev1.ClusterMaster
+	err := client.Get(context.TODO(), namespacedName, &clusterMaster)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get cluster master %s: %v", namespacedName, err)
+	}
+	return &clusterMaster, nil
+}
+
+// applyIndexerClusterSites creates or updates a headless service and statefulset of indexers for each site of a multisite indexer cluster
+func applyIndexerClusterSites(client ControllerClient, cr *enterprisev1.IndexerCluster, secrets *corev1.Secret, clusterMasterPassword []byte, serviceAccount *ServiceAccount, newSplunkClient, newClusterMasterClient func(managementURI, username, password string) *splclient.SplunkClient, scopedLog logr.Logger) (enterprisev1.ResourcePhase, error) {
+
+	// prepare status for each site, keeping peer status from previous updates
+	siteStatus := make([]enterprisev1.IndexerClusterSiteStatus, len(cr.Spec.Multisite.Sites))
+	for idx, site := range cr.Spec.Multisite.Sites {
+		siteStatus[idx] = enterprisev1.IndexerClusterSiteStatus{
+			Name:     site.Name,
+			Phase:    enterprisev1.PhaseError,
+			Replicas: site.Replicas,
+			Peers:    []enterprisev1.IndexerClusterMemberStatus{},
+		}
+		for _, prev := range cr.Status.Sites {
+			if prev.Name == site.Name {
+				siteStatus[idx].ReadyReplicas = prev.ReadyReplicas
+				siteStatus[idx].Peers = prev.Peers
+				break
+			}
+		}
+	}
+	cr.Status.Sites = siteStatus
+
+	// overall phase is ready only when all sites are ready
+	phase := enterprisev1.PhaseReady
+	for idx := range cr.Spec.Multisite.Sites {
+		site := &cr.Spec.Multisite.Sites[idx]
+		status := &cr.Status.Sites[idx]
+
+		// create or update a headless service for indexers in this site
+		err := ApplyService(client, enterprise.GetIndexerSiteService(cr, site))
+		if err != nil {
+			return enterprisev1.PhaseError, err
+		}
+
+		// create or update statefulset for indexers in this site
+		statefulSet, err := enterprise.GetIndexerSiteStatefulSet(cr, site)
+		if err != nil {
+			return enterprisev1.PhaseError, err
+		}
+		err = updateConfigChecksums(client, cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkIndexer, &statefulSet.Spec.Template)
+		if err != nil {
+			return enterprisev1.PhaseError, err
+		}
+		mgr := IndexerClusterPodManager{log: scopedLog.WithValues("site", site.Name), cr: cr, secrets: secrets, clusterMasterPassword: clusterMasterPassword, serviceAccount: serviceAccount, newSplunkClient: newSplunkClient,
+			newClusterMasterClient: newClusterMasterClient, site: status, events: newEventPublisher(client, cr), client: client}
+		status.Phase, err = mgr.Update(client, statefulSet, site.Replicas)
+		if err != nil {
+			return enterprisev1.PhaseError, err
+		}
+		if phase == enterprisev1.PhaseReady {
+			phase = status.Phase
+		}
+	}
+
+	// total number of ready indexers across all sites
+	cr.Status.ReadyReplicas = 0
+	for _, status := range cr.Status.Sites {
+		cr.Status.ReadyReplicas += status.ReadyReplicas
+	}
+
+	return phase, nil
+}
+
+const (
+	// clusterMasterInfoKey is used to cache the cluster info polled from the cluster master
+	clusterMasterInfoKey = "cluster/master/info"
+
+	// clusterMasterPeersKey is used to cache the peers polled from the cluster master
+	clusterMasterPeersKey = "cluster/master/peers"
+
+	// clusterMasterHealthKey is used to cache the cluster health polled from the cluster master
+	clusterMasterHealthKey = "cluster/master/health"
+
+	// clusterMasterReplicationFixupKey is used to cache the replication factor fixup tasks polled from the cluster master
+	clusterMasterReplicationFixupKey = "cluster/master/fixup?level=" + splclient.FixupLevelReplicationFactor
+
+	// clusterMasterSearchFixupKey is used to cache the search factor fixup tasks polled from the cluster master
+	clusterMasterSearchFixupKey = "cluster/master/fixup?level=" + splclient.FixupLevelSearchFactor
+
+	// clusterMasterIndexesKey is used to cache the indexes polled from the cluster master
+	clusterMasterIndexesKey = "cluster/master/indexes"
+)
+
+// clusterMasterStatusKeys are used to poll the status of an indexer cluster from its cluster master
+var clusterMasterStatusKeys = []string{
+	clusterMasterInfoKey,
+	clusterMasterPeersKey,
+	clusterMasterHealthKey,
+	clusterMasterReplicationFixupKey,
+	clusterMasterSearchFixupKey,
+	clusterMasterIndexesKey,
+}
+
+// IndexerClusterPodManager is used to manage the pods within an indexer cluster
 type IndexerClusterPodManager struct {
 	log             logr.Logger
 	cr              *enterprisev1.IndexerCluster
 	secrets         *corev1.Secret
 	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+
+	// newClusterMasterClient is used to create clients for the cluster master, which may use a different CA than the
+	// indexers (optional, defaults to newSplunkClient)
+	newClusterMasterClient func(managementURI, username, password string) *splclient.SplunkClient
+
+	// admin password for the cluster master that manages this indexer cluster
+	clusterMasterPassword []byte
+
+	// serviceAccount is used to poll the cluster master (optional, defaults to the admin user)
+	serviceAccount *ServiceAccount
+
+	// status of the site being managed, for multisite indexer clusters (nil otherwise)
+	site *enterprisev1.IndexerClusterSiteStatus
+
+	// events is used to publish events for the indexer cluster (optional)
+	events *eventPublisher
+
+	// client is used to find other indexer clusters that share maintenance mode on the cluster master (optional;
+	// without it, maintenance mode is never shared)
+	client ControllerClient
+
+	// true if maintenance mode is enabled on the cluster master, as of the most recent status update
+	maintenanceMode bool
+
+	// poller is used to poll the cluster master, caching its status for the sites of a multisite indexer cluster
+	// (optional, defaults to a poller shared by all reconciles of the indexer cluster)
+	poller *statusPoller
 }
 
 // Update for IndexerClusterPodManager handles all updates for a statefulset of indexers
 func (mgr *IndexerClusterPodManager) Update(c ControllerClient, statefulSet *appsv1.StatefulSet, desiredReplicas int32) (enterprisev1.ResourcePhase, error) {
 	// update statefulset, if necessary
-	_, err := ApplyStatefulSet(c, statefulSet)
+	phase, err := ApplyStatefulSet(c, statefulSet)
 	if err != nil {
 		return enterprisev1.PhaseError, err
 	}
 
+	// recycling pods also applies any configuration changes, so no rolling restart will be needed
+	if phase != enterprisev1.PhaseReady || statefulSet.Status.UpdatedReplicas < statefulSet.Status.Replicas {
+		mgr.setAppliedConfigHash()
+	}
+
 	// update CR status with SHC information
 	err = mgr.updateStatus(statefulSet)
-	if err != nil || mgr.cr.Status.ReadyReplicas == 0 || !mgr.cr.Status.Initialized || !mgr.cr.Status.IndexingReady || !mgr.cr.Status.ServiceReady {
+	if err != nil && !splclient.IsNotReady(err) && mgr.cr.Status.ClusterMasterPhase == enterprisev1.PhaseReady {
+		// cluster master is up, but is failing requests for some other reason
+		return enterprisev1.PhaseError, err
+	}
+	if err != nil || statefulSet.Status.ReadyReplicas == 0 || !mgr.cr.Status.Initialized || !mgr.cr.Status.IndexingReady || !mgr.cr.Status.ServiceReady {
 		mgr.log.Error(err, "Indexer cluster is not ready")
 		return enterprisev1.PhasePending, nil
 	}
 
 	// manage scaling and updates
-	return UpdateStatefulSetPods(c, statefulSet, mgr, desiredReplicas)
+	phase, err = UpdateStatefulSetPods(c, statefulSet, mgr, desiredReplicas)
+	if phase == enterprisev1.PhaseScalingUp && mgr.cr.Spec.RebalanceOnScaleUp.Enabled {
+		// new peers start out without any buckets, so consider rebalancing data once they are up
+		mgr.cr.Status.Rebalance.Pending = true
+	}
+	return phase, err
 }
 
 // PrepareScaleDown for IndexerClusterPodManager prepares indexer pod to be removed via scale down event; it returns true when ready
 func (mgr *IndexerClusterPodManager) PrepareScaleDown(n int32) (bool, error) {
+	// bucket fixup is required to decommission with enforceCounts=true, so maintenance mode must be disabled
+	err := mgr.setMaintenanceMode(false)
+	if err != nil {
+		return false, err
+	}
+
+	// wait for the cluster to be in a safe state before decommissioning another peer, since removing a peer while
+	// the replication or search factor is not met (or not known to be met) may leave some buckets without enough copies
+	peer := (*mgr.getPeers())[n]
+	if reason, message := getIndexerClusterHealth(mgr.cr); peer.Status == "Up" && reason != "" {
+		mgr.log.Info("Waiting for indexer cluster to be in a safe state before decommissioning peer", "peerName", peer.Name,
+			"reason", reason, "message", message)
+		return false, nil
+	}
+
 	// first, decommission indexer peer with enforceCounts=true; this will rebalance buckets across other peers
 	complete, err := mgr.decommission(n, true)
 	if err != nil {
@@ -167,28 +524,301 @@ func (mgr *IndexerClusterPodManager) PrepareScaleDown(n int32) (bool, error) {
 	}
 
 	// next, remove the peer
+	peerName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkIndexer, mgr.getIdentifier(), n)
+	mgr.events.Normal("RemovingPeer", "Removing peer %s from indexer cluster", peerName)
 	c := mgr.getClusterMasterClient()
-	return true, c.RemoveIndexerClusterPeer(mgr.cr.Status.Peers[n].ID)
+	mgr.getPoller().invalidate(clusterMasterPeersKey)
+	return true, c.RemoveIndexerClusterPeer((*mgr.getPeers())[n].ID)
 }
 
 // PrepareRecycle for IndexerClusterPodManager prepares indexer pod to be recycled for updates; it returns true when ready
 func (mgr *IndexerClusterPodManager) PrepareRecycle(n int32) (bool, error) {
+	if (*mgr.getPeers())[n].Stale {
+		mgr.log.Info("Waiting for an up to date status", "peerName", (*mgr.getPeers())[n].Name)
+		return false, nil
+	}
+
+	// enable maintenance mode before taking a peer offline, so that the cluster master doesn't start bucket fixup
+	// while it restarts
+	if (*mgr.getPeers())[n].Status == "Up" && !mgr.cr.Status.MaintenanceMode {
+		err := mgr.setMaintenanceMode(true)
+		if err != nil {
+			return false, err
+		}
+	}
 	return mgr.decommission(n, false)
 }
 
 // FinishRecycle for IndexerClusterPodManager completes recycle event for indexer pod; it returns true when complete
 func (mgr *IndexerClusterPodManager) FinishRecycle(n int32) (bool, error) {
-	return mgr.cr.Status.Peers[n].Status == "Up", nil
+	peer := (*mgr.getPeers())[n]
+	return peer.Status == "Up" && !peer.Stale, nil
+}
+
+// GetUpdateStrategy for IndexerClusterPodManager returns the strategy used to recycle indexer pods
+func (mgr *IndexerClusterPodManager) GetUpdateStrategy() enterprisev1.UpdateStrategySpec {
+	return mgr.cr.Spec.UpdateStrategy
+}
+
+// setMaintenanceMode for IndexerClusterPodManager enables or disables maintenance mode on the cluster master.
+// The MaintenanceMode status field is used to track whether maintenance mode was enabled by the operator, so
+// that it will only disable maintenance mode that it enabled itself. Since maintenance mode applies to every peer
+// of the cluster master, it is shared by all indexer clusters that reference the same cluster master: an indexer
+// cluster joins maintenance mode enabled by another one, and it is only disabled once none of them still need it.
+func (mgr *IndexerClusterPodManager) setMaintenanceMode(enable bool) error {
+	if mgr.cr.Status.MaintenanceMode == enable {
+		return nil
+	}
+	holders, err := mgr.getMaintenanceModeHolders()
+	if err != nil {
+		return err
+	}
+	if enable && mgr.maintenanceMode {
+		if len(holders) == 0 {
+			// enabled by someone else, who is responsible for disabling it again
+			return nil
+		}
+		mgr.log.Info("Sharing indexer cluster maintenance mode", "indexerClusters", holders)
+		mgr.cr.Status.MaintenanceMode = true
+		return nil
+	}
+	if !enable && len(holders) > 0 {
+		mgr.log.Info("Leaving indexer cluster maintenance mode enabled for other indexer clusters", "indexerClusters", holders)
+		mgr.cr.Status.MaintenanceMode = false
+		return nil
+	}
+
+	mgr.log.Info("Setting indexer cluster maintenance mode", "enable", enable)
+	c := mgr.getClusterMasterClient()
+	err = c.SetClusterMaintenanceMode(enable)
+	if err != nil {
+		mgr.events.Warning("RESTAPIFailed", "Unable to set maintenance mode on cluster master: %v", err)
+		return err
+	}
+	if enable {
+		mgr.events.Normal("EnabledMaintenanceMode", "Enabled indexer cluster maintenance mode to recycle peers")
+	} else {
+		mgr.events.Normal("DisabledMaintenanceMode", "Disabled indexer cluster maintenance mode")
+	}
+	mgr.cr.Status.MaintenanceMode = enable
+	mgr.maintenanceMode = enable
+	mgr.getPoller().invalidate(clusterMasterInfoKey)
+	return nil
+}
+
+// getMaintenanceModeHolders for IndexerClusterPodManager returns the namespace and name of every other indexer cluster
+// that references the same cluster master and has enabled maintenance mode on it
+func (mgr *IndexerClusterPodManager) getMaintenanceModeHolders() ([]string, error) {
+	holders := []string{}
+	if mgr.client == nil {
+		return holders, nil
+	}
+	var list enterprisev1.IndexerClusterList
+	err := mgr.client.List(context.TODO(), &list)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list indexer clusters sharing cluster master %s: %v", getClusterMasterName(mgr.cr), err)
+	}
+	for idx := range list.Items {
+		item := &list.Items[idx]
+		if item.GetNamespace() == mgr.cr.GetNamespace() && item.GetName() == mgr.cr.GetName() {
+			continue
+		}
+		if item.Status.MaintenanceMode && getClusterMasterName(item) == getClusterMasterName(mgr.cr) {
+			holders = append(holders, fmt.Sprintf("%s/%s", item.GetNamespace(), item.GetName()))
+		}
+	}
+	return holders, nil
+}
+
+// setAppliedConfigHash for IndexerClusterPodManager records that the current configuration has been applied to
+// the peers being managed, because they are being recycled
+func (mgr *IndexerClusterPodManager) setAppliedConfigHash() {
+	status := &mgr.cr.Status.RollingRestart
+	status.AppliedConfigHash = getIndexerClusterConfigHash(mgr.cr)
+	if status.InProgress {
+		// peers restarted by the cluster master will also pick up the latest configuration
+		status.PendingConfigHash = status.AppliedConfigHash
+	}
+}
+
+// updateRollingRestart for IndexerClusterPodManager uses the cluster master to initiate a searchable rolling restart
+// of its peers whenever the active cluster bundle changes and the percentage of peers to restart is not zero. The
+// cluster master restarts all of its peers, including those of any other indexer clusters that share it. It returns
+// PhaseUpdating until the rolling restart has completed.
+func (mgr *IndexerClusterPodManager) updateRollingRestart() (enterprisev1.ResourcePhase, error) {
+	status := &mgr.cr.Status.RollingRestart
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	c := mgr.getClusterMasterClient()
+
+	// check if a rolling restart has completed
+	if status.InProgress {
+		clusterInfo, err := c.GetClusterMasterInfoContext(ctx)
+		if err != nil {
+			mgr.events.Warning("RESTAPIFailed", "Unable to retrieve cluster info from cluster master: %v", err)
+			return enterprisev1.PhaseError, err
+		}
+		if clusterInfo.RollingRestart {
+			mgr.log.Info("Waiting for rolling restart to complete")
+			return enterprisev1.PhaseUpdating, nil
+		}
+		mgr.log.Info("Rolling restart complete", "configHash", status.PendingConfigHash)
+		mgr.events.Normal("RollingRestartComplete", "Rolling restart of indexer cluster peers is complete")
+		status.AppliedConfigHash = status.PendingConfigHash
+		status.PendingConfigHash = ""
+		status.InProgress = false
+	}
+
+	// without rolling restarts, changes to the cluster bundle only take effect when peers are restarted for other reasons
+	configHash := getIndexerClusterConfigHash(mgr.cr)
+	if configHash == "" {
+		return enterprisev1.PhaseReady, nil
+	}
+	if status.AppliedConfigHash == "" || mgr.cr.Spec.RollingRestartPercentage == 0 {
+		status.AppliedConfigHash = configHash
+	}
+	if status.AppliedConfigHash == configHash {
+		return enterprisev1.PhaseReady, nil
+	}
+
+	// a rolling restart initiated by the cluster master when the bundle was applied will also pick it up
+	clusterInfo, err := c.GetClusterMasterInfoContext(ctx)
+	if err != nil {
+		mgr.events.Warning("RESTAPIFailed", "Unable to retrieve cluster info from cluster master: %v", err)
+		return enterprisev1.PhaseError, err
+	}
+	now := metav1.Now()
+	if clusterInfo.RollingRestart {
+		mgr.log.Info("Waiting for rolling restart initiated by cluster master", "configHash", configHash)
+		status.PendingConfigHash = configHash
+		status.InProgress = true
+		status.LastRestartTime = &now
+		return enterprisev1.PhaseUpdating, nil
+	}
+
+	// cluster bundle has changed; restart peers
+	mgr.log.Info("Initiating rolling restart of indexer cluster peers", "configHash", configHash,
+		"percentPeersToRestart", mgr.cr.Spec.RollingRestartPercentage)
+	err = c.RollingRestartIndexerClusterPeersContext(ctx, mgr.cr.Spec.RollingRestartPercentage)
+	if err != nil {
+		mgr.events.Warning("RESTAPIFailed", "Unable to initiate rolling restart on cluster master: %v", err)
+		return enterprisev1.PhaseError, err
+	}
+	mgr.events.Normal("RollingRestart", "Initiated rolling restart of all peers of cluster master %s to apply bundle %s (%d%% at a time)",
+		mgr.cr.Spec.ClusterMasterRef.Name, configHash, mgr.cr.Spec.RollingRestartPercentage)
+	status.PendingConfigHash = configHash
+	status.InProgress = true
+	status.LastRestartTime = &now
+	return enterprisev1.PhaseUpdating, nil
+}
+
+// updateRebalance for IndexerClusterPodManager uses the cluster master to start a data rebalance after the indexer
+// cluster has been scaled up, once all peers are up and the skew in their bucket counts reaches the threshold of the
+// rebalanceOnScaleUp policy. It returns PhaseUpdating until the data rebalance has completed.
+func (mgr *IndexerClusterPodManager) updateRebalance() (enterprisev1.ResourcePhase, error) {
+	status := &mgr.cr.Status.Rebalance
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	c := mgr.getClusterMasterClient()
+
+	// check if a data rebalance has completed
+	if status.InProgress {
+		rebalanceStatus, err := c.GetClusterRebalanceStatusContext(ctx)
+		if err != nil {
+			mgr.events.Warning("RESTAPIFailed", "Unable to retrieve data rebalance status from cluster master: %v", err)
+			return enterprisev1.PhaseError, err
+		}
+		if rebalanceStatus.InProgress {
+			status.PercentComplete = int32(rebalanceStatus.PercentComplete)
+			mgr.log.Info("Waiting for data rebalance to complete", "percentComplete", status.PercentComplete)
+			return enterprisev1.PhaseUpdating, nil
+		}
+		mgr.log.Info("Data rebalance complete")
+		mgr.events.Normal("RebalanceComplete", "Data rebalance of indexer cluster peers is complete")
+		now := metav1.Now()
+		status.InProgress = false
+		status.PercentComplete = 100
+		status.LastCompletionTime = &now
+	}
+
+	if !status.Pending {
+		return enterprisev1.PhaseReady, nil
+	}
+	policy := mgr.cr.Spec.RebalanceOnScaleUp
+	if !policy.Enabled {
+		status.Pending = false
+		return enterprisev1.PhaseReady, nil
+	}
+
+	// wait for all peers to be up, including any that were just added
+	skew, allUp := getIndexerClusterBucketSkew(mgr.cr)
+	if !allUp {
+		mgr.log.Info("Waiting for all peers to be up before rebalancing data")
+		return enterprisev1.PhaseUpdating, nil
+	}
+	status.BucketSkew = skew
+	if skew < policy.BucketSkewThreshold {
+		mgr.log.Info("Data rebalance is not needed", "bucketSkew", skew, "bucketSkewThreshold", policy.BucketSkewThreshold)
+		status.Pending = false
+		return enterprisev1.PhaseReady, nil
+	}
+
+	// buckets are unevenly distributed; start a data rebalance
+	mgr.log.Info("Starting data rebalance of indexer cluster peers", "bucketSkew", skew)
+	err := c.StartClusterRebalanceContext(ctx)
+	if err != nil {
+		mgr.events.Warning("RESTAPIFailed", "Unable to start data rebalance on cluster master: %v", err)
+		return enterprisev1.PhaseError, err
+	}
+	mgr.events.Normal("Rebalancing", "Started data rebalance of indexer cluster peers (bucket count skew is %d%%)", skew)
+	now := metav1.Now()
+	status.Pending = false
+	status.InProgress = true
+	status.PercentComplete = 0
+	status.LastStartTime = &now
+	return enterprisev1.PhaseUpdating, nil
+}
+
+// getIndexerClusterBucketSkew returns the difference between the largest and smallest bucket count of any indexer
+// cluster peer, as a percentage of the largest, and whether all peers are up
+func getIndexerClusterBucketSkew(cr *enterprisev1.IndexerCluster) (int32, bool) {
+	var minBuckets, maxBuckets int64 = -1, 0
+	allUp := true
+	countBuckets := func(peers []enterprisev1.IndexerClusterMemberStatus) {
+		for _, peer := range peers {
+			if peer.Status != "Up" {
+				allUp = false
+			}
+			if minBuckets < 0 || peer.BucketCount < minBuckets {
+				minBuckets = peer.BucketCount
+			}
+			if peer.BucketCount > maxBuckets {
+				maxBuckets = peer.BucketCount
+			}
+		}
+	}
+	countBuckets(cr.Status.Peers)
+	for _, site := range cr.Status.Sites {
+		countBuckets(site.Peers)
+	}
+	if maxBuckets == 0 {
+		return 0, allUp
+	}
+	return int32((maxBuckets - minBuckets) * 100 / maxBuckets), allUp
 }
 
 // decommission for IndexerClusterPodManager decommissions an indexer pod; it returns true when ready
 func (mgr *IndexerClusterPodManager) decommission(n int32, enforceCounts bool) (bool, error) {
-	peerName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkIndexer, mgr.cr.GetIdentifier(), n)
+	peerName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkIndexer, mgr.getIdentifier(), n)
+	peers := *mgr.getPeers()
 
-	switch mgr.cr.Status.Peers[n].Status {
+	switch peers[n].Status {
 	case "Up":
 		mgr.log.Info("Decommissioning indexer cluster peer", "peerName", peerName, "enforceCounts", enforceCounts)
+		mgr.events.Normal("DecommissioningPeer", "Decommissioning indexer cluster peer %s (enforceCounts=%t)", peerName, enforceCounts)
 		c := mgr.getClient(n)
+		mgr.getPoller().invalidate(clusterMasterPeersKey)
+		mgr.getPoller().invalidate(clusterMasterHealthKey)
 		return false, c.DecommissionIndexerClusterPeer(enforceCounts)
 
 	case "Decommissioning":
@@ -200,11 +830,11 @@ func (mgr *IndexerClusterPodManager) decommission(n int32, enforceCounts bool) (
 		return false, nil
 
 	case "GracefulShutdown":
-		mgr.log.Info("Decommission complete", "peerName", peerName, "Status", mgr.cr.Status.Peers[n].Status)
+		mgr.log.Info("Decommission complete", "peerName", peerName, "Status", peers[n].Status)
 		return true, nil
 
 	case "Down":
-		mgr.log.Info("Decommission complete", "peerName", peerName, "Status", mgr.cr.Status.Peers[n].Status)
+		mgr.log.Info("Decommission complete", "peerName", peerName, "Status", peers[n].Status)
 		return true, nil
 
 	case "": // this can happen after the peer has been removed from the indexer cluster
@@ -213,54 +843,135 @@ func (mgr *IndexerClusterPodManager) decommission(n int32, enforceCounts bool) (
 	}
 
 	// unhandled status
-	return false, fmt.Errorf("Status=%s", mgr.cr.Status.Peers[n].Status)
+	return false, fmt.Errorf("Status=%s", peers[n].Status)
+}
+
+// getPoller for IndexerClusterPodManager returns the statusPoller used to poll the cluster master
+func (mgr *IndexerClusterPodManager) getPoller() *statusPoller {
+	if mgr.poller == nil {
+		mgr.poller = defaultStatusPollers.get(mgr.cr, indexerClusterPollerSettings)
+	}
+	return mgr.poller
+}
+
+// getIdentifier for IndexerClusterPodManager returns the identifier used to name indexer statefulsets and pods
+func (mgr *IndexerClusterPodManager) getIdentifier() string {
+	if mgr.site == nil {
+		return mgr.cr.GetIdentifier()
+	}
+	return enterprise.GetSplunkSiteIdentifier(mgr.cr.GetIdentifier(), mgr.site.Name)
+}
+
+// getPeers for IndexerClusterPodManager returns the status of indexer peers being managed
+func (mgr *IndexerClusterPodManager) getPeers() *[]enterprisev1.IndexerClusterMemberStatus {
+	if mgr.site == nil {
+		return &mgr.cr.Status.Peers
+	}
+	return &mgr.site.Peers
 }
 
 // getClient for IndexerClusterPodManager returns a SplunkClient for the member n
 func (mgr *IndexerClusterPodManager) getClient(n int32) *splclient.SplunkClient {
-	memberName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkIndexer, mgr.cr.GetIdentifier(), n)
+	memberName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkIndexer, mgr.getIdentifier(), n)
 	fqdnName := resources.GetServiceFQDN(mgr.cr.GetNamespace(),
-		fmt.Sprintf("%s.%s", memberName, enterprise.GetSplunkServiceName(enterprise.SplunkIndexer, mgr.cr.GetIdentifier(), true)))
+		fmt.Sprintf("%s.%s", memberName, enterprise.GetSplunkServiceName(enterprise.SplunkIndexer, mgr.getIdentifier(), true)))
 	return mgr.newSplunkClient(fmt.Sprintf("https://%s:8089", fqdnName), "admin", string(mgr.secrets.Data["password"]))
 }
 
 // getClusterMasterClient for IndexerClusterPodManager returns a SplunkClient for cluster master
 func (mgr *IndexerClusterPodManager) getClusterMasterClient() *splclient.SplunkClient {
-	fqdnName := resources.GetServiceFQDN(mgr.cr.GetNamespace(), enterprise.GetSplunkServiceName(enterprise.SplunkClusterMaster, mgr.cr.GetIdentifier(), false))
-	return mgr.newSplunkClient(fmt.Sprintf("https://%s:8089", fqdnName), "admin", string(mgr.secrets.Data["password"]))
+	if mgr.newClusterMasterClient != nil {
+		return mgr.newClusterMasterClient(mgr.getClusterMasterURI(), "admin", string(mgr.clusterMasterPassword))
+	}
+	return mgr.newSplunkClient(mgr.getClusterMasterURI(), "admin", string(mgr.clusterMasterPassword))
+}
+
+// getClusterMasterStatusClient for IndexerClusterPodManager returns a SplunkClient used to poll the cluster master;
+// logging in as the service account is cancelled when ctx is done
+func (mgr *IndexerClusterPodManager) getClusterMasterStatusClient(ctx context.Context) (*splclient.SplunkClient, error) {
+	if mgr.serviceAccount != nil {
+		return mgr.serviceAccount.getClient(ctx, mgr.getClusterMasterURI())
+	}
+	return mgr.getClusterMasterClient(), nil
+}
+
+// getClusterMasterURI for IndexerClusterPodManager returns the management URI for the cluster master
+func (mgr *IndexerClusterPodManager) getClusterMasterURI() string {
+	ref := mgr.cr.Spec.ClusterMasterRef
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = mgr.cr.GetNamespace()
+	}
+	fqdnName := resources.GetServiceFQDN(namespace, enterprise.GetSplunkServiceName(enterprise.SplunkClusterMaster, ref.Name, false))
+	return fmt.Sprintf("https://%s:8089", fqdnName)
 }
 
 // updateStatus for IndexerClusterPodManager uses the REST API to update the status for a SearcHead custom resource
 func (mgr *IndexerClusterPodManager) updateStatus(statefulSet *appsv1.StatefulSet) error {
-	mgr.cr.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
+	if mgr.site == nil {
+		mgr.cr.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
+	} else {
+		mgr.site.ReadyReplicas = statefulSet.Status.ReadyReplicas
+	}
 
 	if mgr.cr.Status.ClusterMasterPhase != enterprisev1.PhaseReady {
 		mgr.cr.Status.Initialized = false
 		mgr.cr.Status.IndexingReady = false
 		mgr.cr.Status.ServiceReady = false
-		mgr.cr.Status.MaintenanceMode = false
+		mgr.cr.Status.ReplicationFactorMet = nil
+		mgr.cr.Status.SearchFactorMet = nil
 		return fmt.Errorf("Waiting for cluster master to become ready")
 	}
 
-	// get indexer cluster info from cluster master if it's ready
-	c := mgr.getClusterMasterClient()
-	clusterInfo, err := c.GetClusterMasterInfo()
+	// get indexer cluster info from cluster master if it's ready; logging in is subject to the same timeout as polling
+	poller := mgr.getPoller()
+	ctx, cancel := context.WithTimeout(context.Background(), poller.settings.timeout)
+	c, err := mgr.getClusterMasterStatusClient(ctx)
+	cancel()
 	if err != nil {
+		mgr.events.RESTAPIFailed(err, "Unable to authenticate with cluster master: %v", err)
 		return err
 	}
+	results := poller.poll(clusterMasterStatusKeys, func(ctx context.Context, i int) (interface{}, error) {
+		switch clusterMasterStatusKeys[i] {
+		case clusterMasterInfoKey:
+			return c.GetClusterMasterInfoContext(ctx)
+		case clusterMasterPeersKey:
+			return c.GetClusterMasterPeersContext(ctx)
+		case clusterMasterHealthKey:
+			return c.GetClusterMasterHealthContext(ctx)
+		case clusterMasterReplicationFixupKey:
+			return c.GetClusterMasterFixupTasksContext(ctx, splclient.FixupLevelReplicationFactor)
+		case clusterMasterSearchFixupKey:
+			return c.GetClusterMasterFixupTasksContext(ctx, splclient.FixupLevelSearchFactor)
+		}
+		return c.GetClusterMasterIndexesContext(ctx)
+	})
+	info, peerResult := results[0], results[1]
+	mgr.updateHealthStatus(results[2], results[3], results[4], results[5])
+	if info.err != nil {
+		mgr.events.RESTAPIFailed(info.err, "Unable to retrieve cluster info from cluster master: %v", info.err)
+		return info.err
+	}
+	clusterInfo := info.value.(*splclient.ClusterMasterInfo)
 	mgr.cr.Status.Initialized = clusterInfo.Initialized
 	mgr.cr.Status.IndexingReady = clusterInfo.IndexingReady
 	mgr.cr.Status.ServiceReady = clusterInfo.ServiceReady
-	mgr.cr.Status.MaintenanceMode = clusterInfo.MaintenanceMode
+	mgr.maintenanceMode = clusterInfo.MaintenanceMode
 
-	// get peer information from cluster master
-	peers, err := c.GetClusterMasterPeers()
-	if err != nil {
-		return err
+	// get peer information from cluster master, which may be stale if it could not be retrieved this time
+	if peerResult.err != nil {
+		mgr.log.Error(peerResult.err, "Unable to retrieve peers from cluster master")
+		mgr.events.RESTAPIFailed(peerResult.err, "Unable to retrieve peers from cluster master: %v", peerResult.err)
+		if peerResult.value == nil {
+			return peerResult.err
+		}
 	}
+	peers := peerResult.value.(map[string]splclient.ClusterMasterPeerInfo)
+	peerStatuses := mgr.getPeers()
 	for n := int32(0); n < statefulSet.Status.Replicas; n++ {
-		peerName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkIndexer, mgr.cr.GetIdentifier(), n)
-		peerStatus := enterprisev1.IndexerClusterMemberStatus{Name: peerName}
+		peerName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkIndexer, mgr.getIdentifier(), n)
+		peerStatus := enterprisev1.IndexerClusterMemberStatus{Name: peerName, Stale: peerResult.stale}
 		peerInfo, ok := peers[peerName]
 		if ok {
 			peerStatus.ID = peerInfo.ID
@@ -268,20 +979,132 @@ func (mgr *IndexerClusterPodManager) updateStatus(statefulSet *appsv1.StatefulSe
 			peerStatus.ActiveBundleID = peerInfo.ActiveBundleID
 			peerStatus.BucketCount = peerInfo.BucketCount
 			peerStatus.Searchable = peerInfo.Searchable
+			peerStatus.Site = peerInfo.Site
 		} else {
 			mgr.log.Info("Peer is not known by cluster master", "peerName", peerName)
 		}
-		if n < int32(len(mgr.cr.Status.Peers)) {
-			mgr.cr.Status.Peers[n] = peerStatus
+		if n < int32(len(*peerStatuses)) {
+			(*peerStatuses)[n] = peerStatus
 		} else {
-			mgr.cr.Status.Peers = append(mgr.cr.Status.Peers, peerStatus)
+			*peerStatuses = append(*peerStatuses, peerStatus)
 		}
 	}
 
 	// truncate any extra peers that we didn't check (leftover from scale down)
-	if statefulSet.Status.Replicas < int32(len(mgr.cr.Status.Peers)) {
-		mgr.cr.Status.Peers = mgr.cr.Status.Peers[:statefulSet.Status.Replicas]
+	if statefulSet.Status.Replicas < int32(len(*peerStatuses)) {
+		*peerStatuses = (*peerStatuses)[:statefulSet.Status.Replicas]
+	}
+
+	return nil
+}
+
+// updateHealthStatus for IndexerClusterPodManager updates the health of the indexer cluster and its indexes, using
+// the results polled from the cluster master. Failures are not fatal, but the replication and search factors are
+// reported as unknown until the cluster master can be polled again; the previous fixup tasks and excess buckets of
+// each index are kept.
+func (mgr *IndexerClusterPodManager) updateHealthStatus(health, replicationFixup, searchFixup, indexes polledStatus) {
+	for _, result := range []struct {
+		polledStatus
+		name string
+	}{{health, "health"}, {replicationFixup, "replication factor fixup tasks"}, {searchFixup, "search factor fixup tasks"}, {indexes, "indexes"}} {
+		if result.err != nil {
+			mgr.log.Error(result.err, "Unable to retrieve cluster health from cluster master", "request", result.name)
+			mgr.events.RESTAPIFailed(result.err, "Unable to retrieve %s from cluster master: %v", result.name, result.err)
+		}
+	}
+
+	if health.value == nil || health.stale {
+		mgr.cr.Status.ReplicationFactorMet = nil
+		mgr.cr.Status.SearchFactorMet = nil
+	} else {
+		clusterHealth := health.value.(*splclient.ClusterMasterHealth)
+		replicationFactorMet := clusterHealth.ReplicationFactorMet && (!clusterHealth.Multisite || clusterHealth.SiteReplicationFactorMet)
+		searchFactorMet := clusterHealth.SearchFactorMet && (!clusterHealth.Multisite || clusterHealth.SiteSearchFactorMet)
+		mgr.cr.Status.ReplicationFactorMet = &replicationFactorMet
+		mgr.cr.Status.SearchFactorMet = &searchFactorMet
+	}
+
+	if replicationFixup.value == nil || searchFixup.value == nil || indexes.value == nil {
+		return
+	}
+	indexStatuses := make(map[string]*enterprisev1.IndexerClusterIndexStatus)
+	getIndexStatus := func(name string) *enterprisev1.IndexerClusterIndexStatus {
+		status, ok := indexStatuses[name]
+		if !ok {
+			status = &enterprisev1.IndexerClusterIndexStatus{Name: name}
+			indexStatuses[name] = status
+		}
+		return status
+	}
+	for name, index := range indexes.value.(map[string]splclient.ClusterMasterIndexInfo) {
+		status := getIndexStatus(name)
+		status.ExcessBuckets = index.BucketsWithExcessCopies
+		status.ExcessSearchableBuckets = index.BucketsWithExcessSearchableCopies
+	}
+	for _, task := range replicationFixup.value.([]splclient.ClusterMasterFixupTask) {
+		getIndexStatus(task.Index).ReplicationFixupTasks++
+	}
+	for _, task := range searchFixup.value.([]splclient.ClusterMasterFixupTask) {
+		getIndexStatus(task.Index).SearchFixupTasks++
 	}
+	mgr.cr.Status.Indexes = make([]enterprisev1.IndexerClusterIndexStatus, 0, len(indexStatuses))
+	for _, status := range indexStatuses {
+		mgr.cr.Status.Indexes = append(mgr.cr.Status.Indexes, *status)
+	}
+	sort.Slice(mgr.cr.Status.Indexes, func(i, j int) bool {
+		return mgr.cr.Status.Indexes[i].Name < mgr.cr.Status.Indexes[j].Name
+	})
+}
 
+// refreshPausedIndexerClusterStatus refreshes the status of an indexer cluster while reconcile is paused,
+// without modifying anything
+func refreshPausedIndexerClusterStatus(client ControllerClient, cr *enterprisev1.IndexerCluster, tracker *statusTracker) error {
+	tracker.begin(stepClusterMaster)
+	clusterMaster, err := getClusterMaster(client, cr)
+	if err != nil {
+		cr.Status.ClusterMasterPhase = enterprisev1.PhaseError
+		return err
+	}
+	cr.Status.ClusterMasterPhase = clusterMaster.Status.Phase
+	if cr.Status.ClusterMasterPhase != enterprisev1.PhaseReady {
+		tracker.setCondition(corev1.ConditionFalse, string(cr.Status.ClusterMasterPhase),
+			fmt.Sprintf("cluster master \"%s\" is %s", clusterMaster.GetName(), cr.Status.ClusterMasterPhase))
+	}
+
+	tracker.begin(stepPods)
+	if len(cr.Spec.Multisite.Sites) == 0 {
+		statefulSet, err := enterprise.GetIndexerStatefulSet(cr)
+		if err != nil {
+			return err
+		}
+		cr.Status.Phase = GetStatefulSetPhase(client, statefulSet)
+		cr.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
+		tracker.setPhase(cr.Status.Phase)
+		return nil
+	}
+
+	// overall phase is ready only when all sites are ready
+	phase := enterprisev1.PhaseReady
+	cr.Status.ReadyReplicas = 0
+	for idx := range cr.Spec.Multisite.Sites {
+		site := &cr.Spec.Multisite.Sites[idx]
+		statefulSet, err := enterprise.GetIndexerSiteStatefulSet(cr, site)
+		if err != nil {
+			return err
+		}
+		sitePhase := GetStatefulSetPhase(client, statefulSet)
+		for i := range cr.Status.Sites {
+			if cr.Status.Sites[i].Name == site.Name {
+				cr.Status.Sites[i].Phase = sitePhase
+				cr.Status.Sites[i].ReadyReplicas = statefulSet.Status.ReadyReplicas
+			}
+		}
+		cr.Status.ReadyReplicas += statefulSet.Status.ReadyReplicas
+		if phase == enterprisev1.PhaseReady {
+			phase = sitePhase
+		}
+	}
+	cr.Status.Phase = phase
+	tracker.setPhase(cr.Status.Phase)
 	return nil
 }
@@ -15,6 +15,8 @@
 package reconcile
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -24,22 +26,69 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
 	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
 )
 
+// newIndexerClusterMasterObjects returns the ClusterMaster custom resource and secrets referenced by indexer cluster tests
+func newIndexerClusterMasterObjects(sites ...string) []runtime.Object {
+	clusterMaster := &enterprisev1.ClusterMaster{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "ClusterMaster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "master1",
+			Namespace: "test",
+		},
+		Spec: enterprisev1.ClusterMasterSpec{
+			Multisite: enterprisev1.ClusterMasterMultisiteSpec{
+				Sites: sites,
+			},
+		},
+		Status: enterprisev1.ClusterMasterStatus{
+			Phase: enterprisev1.PhaseReady,
+		},
+	}
+	secrets := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "splunk-master1-cluster-master-secrets",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			"idxc_secret": []byte{'a', 'b', 'c'},
+			"password":    []byte{'1', '2', '3'},
+		},
+	}
+	account := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "splunk-master1-cluster-master-service-account",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			"username": []byte("splunk-operator"),
+			"password": []byte{'4', '5', '6'},
+		},
+	}
+	return []runtime.Object{clusterMaster, secrets, account}
+}
+
 func TestApplyIndexerCluster(t *testing.T) {
+	clusterMasterCalls := []mockFuncCall{
+		{metaName: "*v1alpha3.ClusterMaster-test-master1"},
+		{metaName: "*v1.Secret-test-splunk-master1-cluster-master-secrets"},
+	}
 	funcCalls := []mockFuncCall{
 		{metaName: "*v1.Secret-test-splunk-stack1-indexer-secrets"},
+		{metaName: "*v1.Secret-test-splunk-master1-cluster-master-secrets"},
 		{metaName: "*v1.Service-test-splunk-stack1-indexer-headless"},
 		{metaName: "*v1.Service-test-splunk-stack1-indexer-service"},
-		{metaName: "*v1.Service-test-splunk-stack1-cluster-master-service"},
-		{metaName: "*v1.StatefulSet-test-splunk-stack1-cluster-master"},
 		{metaName: "*v1.StatefulSet-test-splunk-stack1-indexer"},
 	}
-	createCalls := map[string][]mockFuncCall{"Get": funcCalls, "Create": funcCalls}
-	updateCalls := map[string][]mockFuncCall{"Get": funcCalls, "Update": []mockFuncCall{funcCalls[4], funcCalls[5]}}
+	accountCall := mockFuncCall{metaName: "*v1.Secret-test-splunk-master1-cluster-master-service-account"}
+	getCalls := append(clusterMasterCalls, funcCalls[0], funcCalls[1], accountCall, funcCalls[2], funcCalls[3], funcCalls[0], funcCalls[4])
+	createCalls := map[string][]mockFuncCall{"Get": getCalls, "Create": []mockFuncCall{funcCalls[0], funcCalls[2], funcCalls[3], funcCalls[4]}}
+	updateCalls := map[string][]mockFuncCall{"Get": getCalls, "Update": []mockFuncCall{funcCalls[4]}}
 
 	current := enterprisev1.IndexerCluster{
 		TypeMeta: metav1.TypeMeta{
@@ -49,6 +98,13 @@ func TestApplyIndexerCluster(t *testing.T) {
 			Name:      "stack1",
 			Namespace: "test",
 		},
+		Spec: enterprisev1.IndexerClusterSpec{
+			CommonSplunkSpec: enterprisev1.CommonSplunkSpec{
+				ClusterMasterRef: corev1.ObjectReference{
+					Name: "master1",
+				},
+			},
+		},
 	}
 	revised := current.DeepCopy()
 	revised.Spec.Image = "splunk/test"
@@ -56,7 +112,19 @@ func TestApplyIndexerCluster(t *testing.T) {
 		_, err := ApplyIndexerCluster(c, cr.(*enterprisev1.IndexerCluster))
 		return err
 	}
-	reconcileTester(t, "TestApplyIndexerCluster", &current, revised, createCalls, updateCalls, reconcile)
+	reconcileTester(t, "TestApplyIndexerCluster", &current, revised, createCalls, updateCalls, reconcile, newIndexerClusterMasterObjects()...)
+
+	// cluster master phase is copied from the ClusterMaster custom resource
+	if revised.Status.ClusterMasterPhase != enterprisev1.PhaseReady {
+		t.Errorf("TestApplyIndexerCluster() Status.ClusterMasterPhase = %s; want %s", revised.Status.ClusterMasterPhase, enterprisev1.PhaseReady)
+	}
+
+	// indexer clusters cannot be created without a cluster master
+	c := newMockClient()
+	_, err := ApplyIndexerCluster(c, current.DeepCopy())
+	if err == nil {
+		t.Errorf("TestApplyIndexerCluster() returned nil for missing cluster master; want error")
+	}
 
 	// test deletion
 	currentTime := metav1.NewTime(time.Now())
@@ -69,6 +137,95 @@ func TestApplyIndexerCluster(t *testing.T) {
 	splunkDeletionTester(t, revised, deleteFunc)
 }
 
+func TestApplyIndexerClusterMultisite(t *testing.T) {
+	clusterMasterCalls := []mockFuncCall{
+		{metaName: "*v1alpha3.ClusterMaster-test-master1"},
+		{metaName: "*v1.Secret-test-splunk-master1-cluster-master-secrets"},
+	}
+	funcCalls := []mockFuncCall{
+		{metaName: "*v1.Secret-test-splunk-stack1-indexer-secrets"},
+		{metaName: "*v1.Secret-test-splunk-master1-cluster-master-secrets"},
+		{metaName: "*v1.Service-test-splunk-stack1-indexer-headless"},
+		{metaName: "*v1.Service-test-splunk-stack1-indexer-service"},
+		{metaName: "*v1.Service-test-splunk-stack1-site1-indexer-headless"},
+		{metaName: "*v1.StatefulSet-test-splunk-stack1-site1-indexer"},
+		{metaName: "*v1.Service-test-splunk-stack1-site2-indexer-headless"},
+		{metaName: "*v1.StatefulSet-test-splunk-stack1-site2-indexer"},
+	}
+	accountCall := mockFuncCall{metaName: "*v1.Secret-test-splunk-master1-cluster-master-service-account"}
+	getCalls := append(clusterMasterCalls, funcCalls[0], funcCalls[1], accountCall, funcCalls[2], funcCalls[3],
+		funcCalls[4], funcCalls[0], funcCalls[5], funcCalls[6], funcCalls[0], funcCalls[7])
+	createCalls := map[string][]mockFuncCall{"Get": getCalls, "Create": append([]mockFuncCall{funcCalls[0]}, funcCalls[2:]...)}
+	updateCalls := map[string][]mockFuncCall{"Get": getCalls, "Update": []mockFuncCall{funcCalls[5], funcCalls[7]}}
+
+	current := enterprisev1.IndexerCluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "IndexerCluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+		Spec: enterprisev1.IndexerClusterSpec{
+			CommonSplunkSpec: enterprisev1.CommonSplunkSpec{
+				ClusterMasterRef: corev1.ObjectReference{
+					Name: "master1",
+				},
+			},
+			Multisite: enterprisev1.MultisiteSpec{
+				Sites: []enterprisev1.IndexerClusterSiteSpec{
+					{Name: "site1", Replicas: 2},
+					{Name: "site2", Replicas: 1},
+				},
+			},
+		},
+	}
+	revised := current.DeepCopy()
+	revised.Spec.Image = "splunk/test"
+	reconcile := func(c *mockClient, cr interface{}) error {
+		_, err := ApplyIndexerCluster(c, cr.(*enterprisev1.IndexerCluster))
+		return err
+	}
+	reconcileTester(t, "TestApplyIndexerClusterMultisite", &current, revised, createCalls, updateCalls, reconcile, newIndexerClusterMasterObjects("site1", "site2")...)
+
+	// check status of each site
+	if len(revised.Status.Sites) != 2 {
+		t.Fatalf("TestApplyIndexerClusterMultisite() len(Status.Sites) = %d; want %d", len(revised.Status.Sites), 2)
+	}
+	for idx, want := range []enterprisev1.IndexerClusterSiteStatus{
+		{Name: "site1", Phase: enterprisev1.PhasePending, Replicas: 2},
+		{Name: "site2", Phase: enterprisev1.PhasePending, Replicas: 1},
+	} {
+		got := revised.Status.Sites[idx]
+		if got.Name != want.Name || got.Phase != want.Phase || got.Replicas != want.Replicas {
+			t.Errorf("TestApplyIndexerClusterMultisite() Status.Sites[%d] = %v; want %v", idx, got, want)
+		}
+	}
+	if revised.Status.Replicas != 3 {
+		t.Errorf("TestApplyIndexerClusterMultisite() Status.Replicas = %d; want %d", revised.Status.Replicas, 3)
+	}
+}
+
+// newClusterMasterHealthHandlers returns handlers for the requests used to poll the health of an indexer cluster from
+// its cluster master, in the order they are made
+func newClusterMasterHealthHandlers(healthBody, replicationFixupBody, searchFixupBody, indexesBody string) []spltest.MockHTTPHandler {
+	masterURL := "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master"
+	return []spltest.MockHTTPHandler{
+		{Method: "GET", URL: masterURL + "/health?count=0&output_mode=json", Status: 200, Body: healthBody},
+		{Method: "GET", URL: masterURL + "/fixup?level=replication_factor&count=0&output_mode=json", Status: 200, Body: replicationFixupBody},
+		{Method: "GET", URL: masterURL + "/fixup?level=search_factor&count=0&output_mode=json", Status: 200, Body: searchFixupBody},
+		{Method: "GET", URL: masterURL + "/indexes?count=0&output_mode=json", Status: 200, Body: indexesBody},
+	}
+}
+
+// newHealthyClusterMasterHandlers returns handlers that report a healthy indexer cluster, with no fixup tasks
+func newHealthyClusterMasterHandlers() []spltest.MockHTTPHandler {
+	return newClusterMasterHealthHandlers(
+		`{"entry":[{"name":"master","content":{"multisite":"0","replication_factor_met":"1","search_factor_met":"1"}}]}`,
+		`{"entry":[]}`, `{"entry":[]}`,
+		`{"entry":[{"name":"main","content":{"buckets_with_excess_copies":0,"buckets_with_excess_searchable_copies":0}}]}`)
+}
+
 func indexerClusterPodManagerTester(t *testing.T, method string, mockHandlers []spltest.MockHTTPHandler,
 	desiredReplicas int32, wantPhase enterprisev1.ResourcePhase, statefulSet *appsv1.StatefulSet,
 	wantCalls map[string][]mockFuncCall, wantError error, initObjects ...runtime.Object) {
@@ -83,6 +240,13 @@ func indexerClusterPodManagerTester(t *testing.T, method string, mockHandlers []
 			Name:      "stack1",
 			Namespace: "test",
 		},
+		Spec: enterprisev1.IndexerClusterSpec{
+			CommonSplunkSpec: enterprisev1.CommonSplunkSpec{
+				ClusterMasterRef: corev1.ObjectReference{
+					Name: "stack1",
+				},
+			},
+		},
 		Status: enterprisev1.IndexerClusterStatus{
 			ClusterMasterPhase: enterprisev1.PhaseReady,
 		},
@@ -99,14 +263,18 @@ func indexerClusterPodManagerTester(t *testing.T, method string, mockHandlers []
 	mockSplunkClient := &spltest.MockHTTPClient{}
 	mockSplunkClient.AddHandlers(mockHandlers...)
 	mgr := &IndexerClusterPodManager{
-		log:     scopedLog,
-		cr:      &cr,
-		secrets: secrets,
+		log:                   scopedLog,
+		cr:                    &cr,
+		secrets:               secrets,
+		clusterMasterPassword: []byte{'1', '2', '3'},
 		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
 			c := splclient.NewSplunkClient(managementURI, username, password)
 			c.Client = mockSplunkClient
+			c.Retry = splclient.RetryPolicy{}
 			return c
 		},
+		// poll one endpoint at a time, so that requests are made in order
+		poller: newStatusPoller(statusPollerSettings{workers: 1, ttl: time.Minute, timeout: time.Second}),
 	}
 	podManagerUpdateTester(t, method, mgr, desiredReplicas, wantPhase, statefulSet, wantCalls, wantError, initObjects...)
 	mockSplunkClient.CheckRequests(t, method)
@@ -152,6 +320,8 @@ func TestIndexerClusterPodManager(t *testing.T) {
 			Body:   `{"links":{"create":"/services/cluster/master/peers/_new"},"origin":"https://localhost:8089/services/cluster/master/peers","updated":"2020-03-18T01:08:53+00:00","generator":{"build":"a7f645ddaf91","version":"8.0.2"},"entry":[{"name":"D39B1729-E2C5-4273-B9B2-534DA7C2F866","id":"https://localhost:8089/services/cluster/master/peers/D39B1729-E2C5-4273-B9B2-534DA7C2F866","updated":"1970-01-01T00:00:00+00:00","links":{"alternate":"/services/cluster/master/peers/D39B1729-E2C5-4273-B9B2-534DA7C2F866","list":"/services/cluster/master/peers/D39B1729-E2C5-4273-B9B2-534DA7C2F866","edit":"/services/cluster/master/peers/D39B1729-E2C5-4273-B9B2-534DA7C2F866"},"author":"system","acl":{"app":"","can_list":true,"can_write":true,"modifiable":false,"owner":"system","perms":{"read":["admin","splunk-system-role"],"write":["admin","splunk-system-role"]},"removable":false,"sharing":"system"},"content":{"active_bundle_id":"14310A4AABD23E85BBD4559C4A3B59F8","apply_bundle_status":{"invalid_bundle":{"bundle_validation_errors":[],"invalid_bundle_id":""},"reasons_for_restart":[],"restart_required_for_apply_bundle":false,"status":"None"},"base_generation_id":26,"bucket_count":73,"bucket_count_by_index":{"_audit":24,"_internal":45,"_telemetry":4},"buckets_rf_by_origin_site":{"default":73},"buckets_sf_by_origin_site":{"default":73},"delayed_buckets_to_discard":[],"eai:acl":null,"fixup_set":[],"heartbeat_started":true,"host_port_pair":"10.36.0.6:8089","indexing_disk_space":210707374080,"is_searchable":true,"is_valid_bundle":true,"label":"splunk-stack1-indexer-0","last_dry_run_bundle":"","last_heartbeat":1584493732,"last_validated_bundle":"14310A4AABD23E85BBD4559C4A3B59F8","latest_bundle_id":"14310A4AABD23E85BBD4559C4A3B59F8","peer_registered_summaries":true,"pending_builds":[],"pending_job_count":0,"primary_count":73,"primary_count_remote":0,"register_search_address":"10.36.0.6:8089","replication_count":0,"replication_port":9887,"replication_use_ssl":false,"restart_required_for_applying_dry_run_bundle":false,"search_state_counter":{"PendingSearchable":0,"Searchable":73,"SearchablePendingMask":0,"Unsearchable":0},"site":"default","splunk_version":"8.0.2","status":"Up","status_counter":{"Complete":69,"NonStreamingTarget":0,"StreamingSource":4,"StreamingTarget":0},"summary_replication_count":0}}],"paging":{"total":1,"perPage":30,"offset":0},"messages":[]}`,
 		},
 	}
+	mockHandlers = append(mockHandlers, newHealthyClusterMasterHandlers()...)
+	statusHandlers := len(mockHandlers)
 	wantCalls = map[string][]mockFuncCall{"Get": funcCalls}
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -171,20 +341,47 @@ func TestIndexerClusterPodManager(t *testing.T) {
 	method := "IndexerClusterPodManager.Update(All pods ready)"
 	indexerClusterPodManagerTester(t, method, mockHandlers, 1, enterprisev1.PhaseReady, statefulSet, wantCalls, nil, statefulSet, pod)
 
-	// test pod needs update => decommission
-	mockHandlers = append(mockHandlers, spltest.MockHTTPHandler{
+	// test cluster master not ready yet => pending
+	notReadyHandlers := append([]spltest.MockHTTPHandler{}, mockHandlers...)
+	notReadyHandlers[0].Status = 503
+	notReadyHandlers[0].Body = `{"messages":[{"type":"ERROR","text":"Service Unavailable"}]}`
+	method = "IndexerClusterPodManager.Update(Cluster Master Not Ready)"
+	indexerClusterPodManagerTester(t, method, notReadyHandlers, 1, enterprisev1.PhasePending, statefulSet, map[string][]mockFuncCall{"Get": {funcCalls[0]}}, nil, statefulSet, pod)
+
+	// test cluster master failing requests => error
+	notReadyHandlers[0].Status = 500
+	notReadyHandlers[0].Body = `{"messages":[{"type":"ERROR","text":"Internal Server Error"}]}`
+	method = "IndexerClusterPodManager.Update(Cluster Master Failed)"
+	wantErr := fmt.Errorf("Response code=500 from %s; want 200: Internal Server Error", notReadyHandlers[0].URL)
+	indexerClusterPodManagerTester(t, method, notReadyHandlers, 1, enterprisev1.PhaseError, statefulSet, map[string][]mockFuncCall{"Get": {funcCalls[0]}}, wantErr, statefulSet, pod)
+
+	// test pod needs update => enable maintenance mode and decommission
+	decommissionHandler := spltest.MockHTTPHandler{
 		Method: "POST",
 		URL:    "https://splunk-stack1-indexer-0.splunk-stack1-indexer-headless.test.svc.cluster.local:8089/services/cluster/slave/control/control/decommission?enforce_counts=0",
 		Status: 200,
 		Err:    nil,
 		Body:   ``,
-	})
+	}
+	mockHandlers = append(mockHandlers, spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/control/default/maintenance?mode=true",
+		Status: 200,
+		Err:    nil,
+		Body:   ``,
+	}, decommissionHandler)
 	pod.ObjectMeta.Labels["controller-revision-hash"] = "v0"
 	method = "IndexerClusterPodManager.Update(Decommission Pod)"
 	indexerClusterPodManagerTester(t, method, mockHandlers, 1, enterprisev1.PhaseUpdating, statefulSet, wantCalls, nil, statefulSet, pod)
 
+	// test pod needs update => maintenance mode is left alone if it was already enabled
+	maintenanceHandlers := append(append([]spltest.MockHTTPHandler{}, mockHandlers[:statusHandlers]...), decommissionHandler)
+	maintenanceHandlers[0].Body = strings.Replace(maintenanceHandlers[0].Body, `"maintenance_mode":false`, `"maintenance_mode":true`, 1)
+	method = "IndexerClusterPodManager.Update(Decommission Pod in Maintenance Mode)"
+	indexerClusterPodManagerTester(t, method, maintenanceHandlers, 1, enterprisev1.PhaseUpdating, statefulSet, wantCalls, nil, statefulSet, pod)
+
 	// test pod needs update => wait for decommission to complete
-	mockHandlers = []spltest.MockHTTPHandler{mockHandlers[0], mockHandlers[1]}
+	mockHandlers = append([]spltest.MockHTTPHandler{}, mockHandlers[:statusHandlers]...)
 	mockHandlers[1].Body = strings.Replace(mockHandlers[1].Body, `"status":"Up"`, `"status":"ReassigningPrimaries"`, 1)
 	method = "IndexerClusterPodManager.Update(ReassigningPrimaries)"
 	indexerClusterPodManagerTester(t, method, mockHandlers, 1, enterprisev1.PhaseUpdating, statefulSet, wantCalls, nil, statefulSet, pod)
@@ -233,3 +430,715 @@ func TestIndexerClusterPodManager(t *testing.T) {
 	method = "IndexerClusterPodManager.Update(Decommission)"
 	indexerClusterPodManagerTester(t, method, mockHandlers, 1, enterprisev1.PhaseScalingDown, statefulSet, wantCalls, nil, statefulSet, pod, pvcList[0], pvcList[1])
 }
+
+func TestIndexerClusterPodManagerMultisite(t *testing.T) {
+	method := "IndexerClusterPodManager.Update(Multisite Decommission Pod)"
+	var replicas int32 = 1
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "splunk-stack1-site1-indexer",
+			Namespace: "test",
+		},
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: &replicas,
+		},
+		Status: appsv1.StatefulSetStatus{
+			Replicas:        replicas,
+			ReadyReplicas:   replicas,
+			UpdatedReplicas: replicas,
+			UpdateRevision:  "v1",
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "splunk-stack1-site1-indexer-0",
+			Namespace: "test",
+			Labels: map[string]string{
+				"controller-revision-hash": "v0",
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Ready: true},
+			},
+		},
+	}
+	funcCalls := []mockFuncCall{
+		{metaName: "*v1.StatefulSet-test-splunk-stack1-site1-indexer"},
+		{metaName: "*v1.Pod-test-splunk-stack1-site1-indexer-0"},
+	}
+	wantCalls := map[string][]mockFuncCall{"Get": funcCalls}
+
+	// pod needs update => decommission using the site's headless service
+	mockHandlers := []spltest.MockHTTPHandler{
+		{
+			Method: "GET",
+			URL:    "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/info?count=0&output_mode=json",
+			Status: 200,
+			Err:    nil,
+			Body:   `{"entry":[{"name":"master","content":{"initialized_flag":true,"indexing_ready_flag":true,"service_ready_flag":true}}]}`,
+		},
+		{
+			Method: "GET",
+			URL:    "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/peers?count=0&output_mode=json",
+			Status: 200,
+			Err:    nil,
+			Body:   `{"entry":[{"name":"aa45bf46-7f46-47af-a760-590d5c606d10","content":{"status":"Up","label":"splunk-stack1-site1-indexer-0","site":"site1"}}]}`,
+		},
+	}
+	mockHandlers = append(mockHandlers, newClusterMasterHealthHandlers(
+		`{"entry":[{"name":"master","content":{"multisite":"1","replication_factor_met":"1","search_factor_met":"1","site_replication_factor_met":"1","site_search_factor_met":"0"}}]}`,
+		`{"entry":[]}`, `{"entry":[]}`, `{"entry":[]}`)...)
+	mockHandlers = append(mockHandlers, []spltest.MockHTTPHandler{
+		{
+			Method: "POST",
+			URL:    "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/control/default/maintenance?mode=true",
+			Status: 200,
+			Err:    nil,
+			Body:   ``,
+		},
+		{
+			Method: "POST",
+			URL:    "https://splunk-stack1-site1-indexer-0.splunk-stack1-site1-indexer-headless.test.svc.cluster.local:8089/services/cluster/slave/control/control/decommission?enforce_counts=0",
+			Status: 200,
+			Err:    nil,
+			Body:   ``,
+		},
+	}...)
+	cr := enterprisev1.IndexerCluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "IndexerCluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+		Spec: enterprisev1.IndexerClusterSpec{
+			CommonSplunkSpec: enterprisev1.CommonSplunkSpec{
+				ClusterMasterRef: corev1.ObjectReference{
+					Name: "stack1",
+				},
+			},
+		},
+		Status: enterprisev1.IndexerClusterStatus{
+			ClusterMasterPhase: enterprisev1.PhaseReady,
+			Sites:              []enterprisev1.IndexerClusterSiteStatus{{Name: "site1"}},
+		},
+	}
+	secrets := &corev1.Secret{
+		Data: map[string][]byte{
+			"password": []byte{'1', '2', '3'},
+		},
+	}
+	mockSplunkClient := &spltest.MockHTTPClient{}
+	mockSplunkClient.AddHandlers(mockHandlers...)
+	mgr := &IndexerClusterPodManager{
+		log:                   log.WithName(method),
+		cr:                    &cr,
+		secrets:               secrets,
+		clusterMasterPassword: []byte{'1', '2', '3'},
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+		site:   &cr.Status.Sites[0],
+		poller: newStatusPoller(statusPollerSettings{workers: 1, ttl: time.Minute, timeout: time.Second}),
+	}
+	podManagerUpdateTester(t, method, mgr, 1, enterprisev1.PhaseUpdating, statefulSet, wantCalls, nil, statefulSet, pod)
+	mockSplunkClient.CheckRequests(t, method)
+
+	// peer status should be tracked for the site, not the cluster
+	if len(cr.Status.Sites[0].Peers) != 1 || cr.Status.Sites[0].Peers[0].Name != "splunk-stack1-site1-indexer-0" || cr.Status.Sites[0].Peers[0].Status != "Up" || cr.Status.Sites[0].Peers[0].Site != "site1" {
+		t.Errorf("%s Status.Sites[0].Peers = %v; want 1 peer splunk-stack1-site1-indexer-0 Up in site1", method, cr.Status.Sites[0].Peers)
+	}
+	if reason, _ := getIndexerClusterHealth(&cr); reason != "SearchFactorNotMet" {
+		t.Errorf("%s health = %s; want SearchFactorNotMet since the site search factor is not met", method, reason)
+	}
+	if cr.Status.Sites[0].ReadyReplicas != 1 {
+		t.Errorf("%s Status.Sites[0].ReadyReplicas = %d; want %d", method, cr.Status.Sites[0].ReadyReplicas, 1)
+	}
+	if len(cr.Status.Peers) != 0 {
+		t.Errorf("%s len(Status.Peers) = %d; want %d", method, len(cr.Status.Peers), 0)
+	}
+}
+
+func TestIndexerClusterPodManagerMaintenanceMode(t *testing.T) {
+	method := "IndexerClusterPodManager.PrepareScaleDown(Maintenance Mode)"
+	factorMet, factorNotMet := true, false
+	cr := enterprisev1.IndexerCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+		Spec: enterprisev1.IndexerClusterSpec{
+			CommonSplunkSpec: enterprisev1.CommonSplunkSpec{
+				ClusterMasterRef: corev1.ObjectReference{
+					Name: "stack1",
+				},
+			},
+		},
+		Status: enterprisev1.IndexerClusterStatus{
+			MaintenanceMode:      true,
+			ReplicationFactorMet: &factorMet,
+			SearchFactorMet:      &factorMet,
+			Peers:                []enterprisev1.IndexerClusterMemberStatus{{Name: "splunk-stack1-indexer-0", Status: "Up"}},
+		},
+	}
+	secrets := &corev1.Secret{
+		Data: map[string][]byte{
+			"password": []byte{'1', '2', '3'},
+		},
+	}
+
+	// maintenance mode enabled by the operator is disabled before decommissioning with enforceCounts=true
+	mockSplunkClient := &spltest.MockHTTPClient{}
+	mockSplunkClient.AddHandlers(
+		spltest.MockHTTPHandler{
+			Method: "POST",
+			URL:    "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/control/default/maintenance?mode=false",
+			Status: 200,
+		},
+		spltest.MockHTTPHandler{
+			Method: "POST",
+			URL:    "https://splunk-stack1-indexer-0.splunk-stack1-indexer-headless.test.svc.cluster.local:8089/services/cluster/slave/control/control/decommission?enforce_counts=1",
+			Status: 200,
+		},
+	)
+	c := newMockClient()
+	mgr := &IndexerClusterPodManager{
+		log:                   log.WithName(method),
+		cr:                    &cr,
+		secrets:               secrets,
+		clusterMasterPassword: []byte{'1', '2', '3'},
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+	}
+	ready, err := mgr.PrepareScaleDown(0)
+	if ready || err != nil {
+		t.Errorf("%s = %t,%v; want false,nil", method, ready, err)
+	}
+	if cr.Status.MaintenanceMode {
+		t.Errorf("%s MaintenanceMode = true; want false", method)
+	}
+	mockSplunkClient.CheckRequests(t, method)
+	if len(*c.events) < 1 || (*c.events)[0].reason != "DisabledMaintenanceMode" {
+		t.Errorf("%s published events %v; want DisabledMaintenanceMode first", method, *c.events)
+	}
+
+	// maintenance mode is not disabled again if the operator didn't enable it
+	mockSplunkClient = &spltest.MockHTTPClient{}
+	mockSplunkClient.AddHandlers(spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    "https://splunk-stack1-indexer-0.splunk-stack1-indexer-headless.test.svc.cluster.local:8089/services/cluster/slave/control/control/decommission?enforce_counts=1",
+		Status: 200,
+	})
+	_, err = mgr.PrepareScaleDown(0)
+	if err != nil {
+		t.Errorf("%s returned %v; want nil", method, err)
+	}
+	mockSplunkClient.CheckRequests(t, method)
+
+	// peers are not decommissioned until the replication and search factors are met
+	method = "IndexerClusterPodManager.PrepareScaleDown(Search Factor Not Met)"
+	cr.Status.SearchFactorMet = &factorNotMet
+	mockSplunkClient = &spltest.MockHTTPClient{}
+	ready, err = mgr.PrepareScaleDown(0)
+	if ready || err != nil {
+		t.Errorf("%s = %t,%v; want false,nil", method, ready, err)
+	}
+	mockSplunkClient.CheckRequests(t, method)
+
+	// or while the health of the indexer cluster is unknown
+	method = "IndexerClusterPodManager.PrepareScaleDown(Health Unknown)"
+	cr.Status.SearchFactorMet = nil
+	ready, err = mgr.PrepareScaleDown(0)
+	if ready || err != nil {
+		t.Errorf("%s = %t,%v; want false,nil", method, ready, err)
+	}
+	mockSplunkClient.CheckRequests(t, method)
+
+	// but peers that are already being decommissioned are still removed once they are down
+	cr.Status.Peers[0].Status = "Down"
+	cr.Status.Peers[0].ID = "aa45bf46-7f46-47af-a760-590d5c606d10"
+	mockSplunkClient.AddHandlers(spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/control/control/remove_peers?peers=aa45bf46-7f46-47af-a760-590d5c606d10",
+		Status: 200,
+	})
+	ready, err = mgr.PrepareScaleDown(0)
+	if !ready || err != nil {
+		t.Errorf("%s = %t,%v; want true,nil", method, ready, err)
+	}
+	mockSplunkClient.CheckRequests(t, method)
+}
+
+func TestIndexerClusterPodManagerSharedMaintenanceMode(t *testing.T) {
+	method := "IndexerClusterPodManager.setMaintenanceMode()"
+	newIndexerCluster := func(namespace, name string, maintenanceMode bool) enterprisev1.IndexerCluster {
+		cr := enterprisev1.IndexerCluster{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		cr.Spec.ClusterMasterRef = corev1.ObjectReference{Name: "stack1", Namespace: "test"}
+		cr.Status.MaintenanceMode = maintenanceMode
+		return cr
+	}
+	cr := newIndexerCluster("test", "idxc1", false)
+	list := enterprisev1.IndexerClusterList{Items: []enterprisev1.IndexerCluster{
+		newIndexerCluster("test", "idxc1", true),
+		newIndexerCluster("other", "idxc2", false),
+		newIndexerCluster("test", "idxc3", true),
+	}}
+	list.Items[2].Spec.ClusterMasterRef.Name = "stack2"
+	c := newMockClient()
+	c.state[getListStateKey(&list)] = &list
+	var mockSplunkClient *spltest.MockHTTPClient
+	mgr := &IndexerClusterPodManager{
+		log:                   log.WithName(method),
+		cr:                    &cr,
+		clusterMasterPassword: []byte{'1', '2', '3'},
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+		client: c,
+	}
+	test := func(enable, maintenanceMode, want bool, handlers ...spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(handlers...)
+		mgr.maintenanceMode = maintenanceMode
+		if err := mgr.setMaintenanceMode(enable); err != nil {
+			t.Errorf("%s returned %v; want nil", method, err)
+		}
+		if cr.Status.MaintenanceMode != want {
+			t.Errorf("%s MaintenanceMode = %t; want %t", method, cr.Status.MaintenanceMode, want)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+	}
+	maintenanceHandler := func(enable bool) spltest.MockHTTPHandler {
+		return spltest.MockHTTPHandler{
+			Method: "POST",
+			URL:    fmt.Sprintf("https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/control/default/maintenance?mode=%t", enable),
+			Status: 200,
+		}
+	}
+
+	// maintenance mode enabled by someone else is left alone, even if enabled for another cluster master
+	test(true, true, false)
+
+	// maintenance mode enabled by another indexer cluster sharing the cluster master is joined
+	list.Items[1].Status.MaintenanceMode = true
+	test(true, true, true)
+
+	// and left enabled while the other indexer cluster still needs it
+	test(false, true, false)
+
+	// it is enabled again if it was disabled by someone else
+	test(true, false, true, maintenanceHandler(true))
+
+	// and disabled once no other indexer cluster needs it
+	list.Items[1].Status.MaintenanceMode = false
+	test(false, true, false, maintenanceHandler(false))
+
+	// failures to list other indexer clusters are returned
+	delete(c.state, getListStateKey(&list))
+	if err := mgr.setMaintenanceMode(true); err == nil {
+		t.Errorf("%s returned nil for list error; want error", method)
+	}
+}
+
+func TestIndexerClusterPodManagerHealth(t *testing.T) {
+	method := "IndexerClusterPodManager.updateStatus(Health)"
+	var replicas int32 = 1
+	statefulSet := &appsv1.StatefulSet{
+		Status: appsv1.StatefulSetStatus{
+			Replicas:      replicas,
+			ReadyReplicas: replicas,
+		},
+	}
+	cr := enterprisev1.IndexerCluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "IndexerCluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+		Spec: enterprisev1.IndexerClusterSpec{
+			CommonSplunkSpec: enterprisev1.CommonSplunkSpec{
+				ClusterMasterRef: corev1.ObjectReference{
+					Name: "stack1",
+				},
+			},
+		},
+		Status: enterprisev1.IndexerClusterStatus{
+			ClusterMasterPhase: enterprisev1.PhaseReady,
+		},
+	}
+	statusHandlers := []spltest.MockHTTPHandler{
+		{
+			Method: "GET",
+			URL:    "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/info?count=0&output_mode=json",
+			Status: 200,
+			Body:   `{"entry":[{"name":"master","content":{"initialized_flag":true,"indexing_ready_flag":true,"service_ready_flag":true}}]}`,
+		},
+		{
+			Method: "GET",
+			URL:    "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/peers?count=0&output_mode=json",
+			Status: 200,
+			Body:   `{"entry":[{"name":"aa45bf46-7f46-47af-a760-590d5c606d10","content":{"status":"Up","label":"splunk-stack1-indexer-0","site":"default"}}]}`,
+		},
+	}
+	var mockSplunkClient *spltest.MockHTTPClient
+	c := newMockClient()
+	mgr := &IndexerClusterPodManager{
+		log:                   log.WithName(method),
+		cr:                    &cr,
+		clusterMasterPassword: []byte{'1', '2', '3'},
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			c.Retry = splclient.RetryPolicy{}
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+	}
+	test := func(healthHandlers []spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(append(append([]spltest.MockHTTPHandler{}, statusHandlers...), healthHandlers...)...)
+		mgr.poller = newStatusPoller(statusPollerSettings{workers: 1, ttl: time.Minute, timeout: time.Second})
+		if err := mgr.updateStatus(statefulSet); err != nil {
+			t.Errorf("%s returned %v; want nil", method, err)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+	}
+
+	// fixup tasks and excess buckets are reported for each index
+	healthHandlers := newClusterMasterHealthHandlers(
+		`{"entry":[{"name":"master","content":{"multisite":"0","replication_factor_met":"0","search_factor_met":"0"}}]}`,
+		`{"entry":[{"name":"main~3~B5E4F8E5","content":{"index":"main","latest":{"reason":"streaming failure"}}},{"name":"main~4~B5E4F8E5","content":{"index":"main"}}]}`,
+		`{"entry":[{"name":"_internal~12~B5E4F8E5","content":{"index":"_internal"}}]}`,
+		`{"entry":[{"name":"main","content":{"buckets_with_excess_copies":0,"buckets_with_excess_searchable_copies":0}},{"name":"_internal","content":{"buckets_with_excess_copies":2,"buckets_with_excess_searchable_copies":1}}]}`)
+	test(healthHandlers)
+	wantIndexes := []enterprisev1.IndexerClusterIndexStatus{
+		{Name: "_internal", SearchFixupTasks: 1, ExcessBuckets: 2, ExcessSearchableBuckets: 1},
+		{Name: "main", ReplicationFixupTasks: 2},
+	}
+	if !reflect.DeepEqual(cr.Status.Indexes, wantIndexes) {
+		t.Errorf("%s Indexes = %v; want %v", method, cr.Status.Indexes, wantIndexes)
+	}
+	if reason, _ := getIndexerClusterHealth(&cr); reason != "ReplicationFactorNotMet" {
+		t.Errorf("%s health = %s; want ReplicationFactorNotMet", method, reason)
+	}
+	if len(cr.Status.Peers) != 1 || cr.Status.Peers[0].Site != "default" {
+		t.Errorf("%s Peers = %v; want 1 peer in site default", method, cr.Status.Peers)
+	}
+
+	// failing to poll the health is not fatal, but the replication and search factors are unknown
+	healthHandlers[0].Status = 500
+	healthHandlers[0].Body = `{"messages":[{"type":"ERROR","text":"Internal Server Error"}]}`
+	healthHandlers[3].Body = `{"entry":[]}`
+	test(healthHandlers)
+	if cr.Status.ReplicationFactorMet != nil || cr.Status.SearchFactorMet != nil {
+		t.Errorf("%s ReplicationFactorMet,SearchFactorMet = %v,%v; want nil,nil", method, cr.Status.ReplicationFactorMet, cr.Status.SearchFactorMet)
+	}
+	if reason, _ := getIndexerClusterHealth(&cr); reason != "HealthUnknown" {
+		t.Errorf("%s health = %s; want HealthUnknown", method, reason)
+	}
+	if len(cr.Status.Indexes) != 2 || cr.Status.Indexes[0].ExcessBuckets != 0 || cr.Status.Indexes[0].SearchFixupTasks != 1 {
+		t.Errorf("%s Indexes = %v; want main and _internal with fixup tasks only", method, cr.Status.Indexes)
+	}
+	if len(*c.events) != 1 || (*c.events)[0].reason != "RESTAPIFailed" {
+		t.Errorf("%s published events %v; want RESTAPIFailed", method, *c.events)
+	}
+
+	// the cluster is healthy once the replication and search factors are met
+	test(newHealthyClusterMasterHandlers())
+	if reason, message := getIndexerClusterHealth(&cr); reason != "" {
+		t.Errorf("%s health = %s (%s); want healthy", method, reason, message)
+	}
+	wantIndexes = []enterprisev1.IndexerClusterIndexStatus{{Name: "main"}}
+	if !reflect.DeepEqual(cr.Status.Indexes, wantIndexes) {
+		t.Errorf("%s Indexes = %v; want %v", method, cr.Status.Indexes, wantIndexes)
+	}
+}
+
+func TestIndexerClusterPodManagerRollingRestart(t *testing.T) {
+	method := "IndexerClusterPodManager.updateRollingRestart()"
+	cr := enterprisev1.IndexerCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+		Spec: enterprisev1.IndexerClusterSpec{
+			CommonSplunkSpec: enterprisev1.CommonSplunkSpec{
+				ClusterMasterRef: corev1.ObjectReference{
+					Name: "stack1",
+				},
+			},
+			RollingRestartPercentage: 20,
+		},
+	}
+	infoURL := "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/info?count=0&output_mode=json"
+	infoBody := func(rollingRestart bool) string {
+		return fmt.Sprintf(`{"entry":[{"name":"master","content":{"initialized_flag":true,"indexing_ready_flag":true,"service_ready_flag":true,"rolling_restart_flag":%t}}]}`, rollingRestart)
+	}
+	var mockSplunkClient *spltest.MockHTTPClient
+	c := newMockClient()
+	mgr := &IndexerClusterPodManager{
+		log:                   log.WithName(method),
+		cr:                    &cr,
+		clusterMasterPassword: []byte{'1', '2', '3'},
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+	}
+	test := func(want enterprisev1.ResourcePhase, wantInProgress bool, handlers ...spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(handlers...)
+		phase, err := mgr.updateRollingRestart()
+		if phase != want || err != nil {
+			t.Errorf("%s = %s,%v; want %s,nil", method, phase, err, want)
+		}
+		if cr.Status.RollingRestart.InProgress != wantInProgress {
+			t.Errorf("%s InProgress = %t; want %t", method, cr.Status.RollingRestart.InProgress, wantInProgress)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+	}
+
+	// nothing to do until the active bundle is known
+	test(enterprisev1.PhaseReady, false)
+	if cr.Status.RollingRestart.AppliedConfigHash != "" {
+		t.Errorf("%s AppliedConfigHash = %s; want empty", method, cr.Status.RollingRestart.AppliedConfigHash)
+	}
+
+	// initial bundle is applied when pods are created
+	cr.Status.Bundle.AppliedChecksum = "bundle1"
+	test(enterprisev1.PhaseReady, false)
+	if cr.Status.RollingRestart.AppliedConfigHash != "bundle1" {
+		t.Errorf("%s AppliedConfigHash = %s; want bundle1", method, cr.Status.RollingRestart.AppliedConfigHash)
+	}
+
+	// defaults are applied by recycling pods, not by rolling restarts
+	cr.Spec.Defaults = "splunk:\n  conf: []\n"
+	test(enterprisev1.PhaseReady, false)
+
+	// bundle changes initiate a searchable rolling restart
+	cr.Status.Bundle.AppliedChecksum = "bundle2"
+	test(enterprisev1.PhaseUpdating, true,
+		spltest.MockHTTPHandler{Method: "GET", URL: infoURL, Status: 200, Body: infoBody(false)},
+		spltest.MockHTTPHandler{
+			Method: "POST",
+			URL:    "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/control/control/restart?searchable=true&percent_peers_to_restart=20",
+			Status: 200,
+		})
+	if cr.Status.RollingRestart.PendingConfigHash != "bundle2" || cr.Status.RollingRestart.LastRestartTime == nil {
+		t.Errorf("%s RollingRestart = %v; want PendingConfigHash=bundle2", method, cr.Status.RollingRestart)
+	}
+
+	// wait for rolling restart to complete
+	test(enterprisev1.PhaseUpdating, true, spltest.MockHTTPHandler{Method: "GET", URL: infoURL, Status: 200, Body: infoBody(true)})
+	test(enterprisev1.PhaseReady, false, spltest.MockHTTPHandler{Method: "GET", URL: infoURL, Status: 200, Body: infoBody(false)})
+	if cr.Status.RollingRestart.AppliedConfigHash != "bundle2" || cr.Status.RollingRestart.PendingConfigHash != "" {
+		t.Errorf("%s RollingRestart = %v; want AppliedConfigHash=bundle2", method, cr.Status.RollingRestart)
+	}
+	wantReasons := []string{"RollingRestart", "RollingRestartComplete"}
+	if len(*c.events) != len(wantReasons) {
+		t.Fatalf("%s published %d events; want %d", method, len(*c.events), len(wantReasons))
+	}
+	for i, reason := range wantReasons {
+		if (*c.events)[i].reason != reason {
+			t.Errorf("%s event %d reason = %s; want %s", method, i, (*c.events)[i].reason, reason)
+		}
+	}
+	if want := "Initiated rolling restart of all peers of cluster master stack1 to apply bundle bundle2 (20% at a time)"; (*c.events)[0].message != want {
+		t.Errorf("%s event message = %s; want %s", method, (*c.events)[0].message, want)
+	}
+
+	// a rolling restart already initiated by the cluster master is not repeated
+	cr.Status.Bundle.AppliedChecksum = "bundle3"
+	test(enterprisev1.PhaseUpdating, true, spltest.MockHTTPHandler{Method: "GET", URL: infoURL, Status: 200, Body: infoBody(true)})
+	test(enterprisev1.PhaseReady, false, spltest.MockHTTPHandler{Method: "GET", URL: infoURL, Status: 200, Body: infoBody(false)})
+	if cr.Status.RollingRestart.AppliedConfigHash != "bundle3" {
+		t.Errorf("%s AppliedConfigHash = %s; want bundle3", method, cr.Status.RollingRestart.AppliedConfigHash)
+	}
+
+	// bundle changes are not applied using rolling restarts when percentage is zero
+	cr.Spec.RollingRestartPercentage = 0
+	cr.Status.Bundle.AppliedChecksum = "bundle4"
+	test(enterprisev1.PhaseReady, false)
+
+	// recycling pods also applies bundle changes
+	cr.Spec.RollingRestartPercentage = 20
+	cr.Status.Bundle.AppliedChecksum = "bundle5"
+	mgr.setAppliedConfigHash()
+	test(enterprisev1.PhaseReady, false)
+}
+
+func TestIndexerClusterPodManagerRebalance(t *testing.T) {
+	method := "IndexerClusterPodManager.updateRebalance()"
+	cr := enterprisev1.IndexerCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+		Spec: enterprisev1.IndexerClusterSpec{
+			CommonSplunkSpec: enterprisev1.CommonSplunkSpec{
+				ClusterMasterRef: corev1.ObjectReference{
+					Name: "stack1",
+				},
+			},
+			RebalanceOnScaleUp: enterprisev1.IndexerClusterRebalancePolicy{
+				Enabled:             true,
+				BucketSkewThreshold: 20,
+			},
+		},
+	}
+	cr.Status.Peers = []enterprisev1.IndexerClusterMemberStatus{
+		{Name: "splunk-stack1-indexer-0", Status: "Up", BucketCount: 100},
+		{Name: "splunk-stack1-indexer-1", Status: "Up", BucketCount: 90},
+		{Name: "splunk-stack1-indexer-2", Status: "Down", BucketCount: 0},
+	}
+	rebalanceURL := "https://splunk-stack1-cluster-master-service.test.svc.cluster.local:8089/services/cluster/master/control/control/rebalance_buckets"
+	statusHandler := func(text string) spltest.MockHTTPHandler {
+		return spltest.MockHTTPHandler{
+			Method: "POST",
+			URL:    rebalanceURL + "?action=status&output_mode=json",
+			Status: 200,
+			Body:   fmt.Sprintf(`{"messages":[{"type":"INFO","text":"%s"}]}`, text),
+		}
+	}
+	var mockSplunkClient *spltest.MockHTTPClient
+	c := newMockClient()
+	mgr := &IndexerClusterPodManager{
+		log:                   log.WithName(method),
+		cr:                    &cr,
+		clusterMasterPassword: []byte{'1', '2', '3'},
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+	}
+	test := func(want enterprisev1.ResourcePhase, wantInProgress bool, handlers ...spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(handlers...)
+		phase, err := mgr.updateRebalance()
+		if phase != want || err != nil {
+			t.Errorf("%s = %s,%v; want %s,nil", method, phase, err, want)
+		}
+		if cr.Status.Rebalance.InProgress != wantInProgress {
+			t.Errorf("%s InProgress = %t; want %t", method, cr.Status.Rebalance.InProgress, wantInProgress)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+	}
+
+	// nothing to do unless the indexer cluster has been scaled up
+	test(enterprisev1.PhaseReady, false)
+
+	// wait for all peers to be up
+	cr.Status.Rebalance.Pending = true
+	test(enterprisev1.PhaseUpdating, false)
+
+	// data rebalance is started when buckets are unevenly distributed
+	cr.Status.Peers[2].Status = "Up"
+	test(enterprisev1.PhaseUpdating, true, spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    rebalanceURL + "?action=start&searchable=true",
+		Status: 200,
+	})
+	if cr.Status.Rebalance.Pending || cr.Status.Rebalance.BucketSkew != 100 || cr.Status.Rebalance.LastStartTime == nil {
+		t.Errorf("%s Rebalance = %+v; want BucketSkew=100 and LastStartTime", method, cr.Status.Rebalance)
+	}
+
+	// progress is reported until the data rebalance has completed
+	test(enterprisev1.PhaseUpdating, true, statusHandler("Data rebalance is running. 45.27% complete"))
+	if cr.Status.Rebalance.PercentComplete != 45 {
+		t.Errorf("%s PercentComplete = %d; want 45", method, cr.Status.Rebalance.PercentComplete)
+	}
+	test(enterprisev1.PhaseReady, false, statusHandler("Data rebalance is not running"))
+	if cr.Status.Rebalance.PercentComplete != 100 || cr.Status.Rebalance.LastCompletionTime == nil {
+		t.Errorf("%s Rebalance = %+v; want PercentComplete=100 and LastCompletionTime", method, cr.Status.Rebalance)
+	}
+	wantReasons := []string{"Rebalancing", "RebalanceComplete"}
+	if len(*c.events) != len(wantReasons) {
+		t.Fatalf("%s published %d events; want %d", method, len(*c.events), len(wantReasons))
+	}
+	for i, reason := range wantReasons {
+		if (*c.events)[i].reason != reason {
+			t.Errorf("%s event %d reason = %s; want %s", method, i, (*c.events)[i].reason, reason)
+		}
+	}
+
+	// data rebalance is not started when the skew is below the threshold
+	cr.Status.Peers[2].BucketCount = 85
+	cr.Status.Rebalance.Pending = true
+	test(enterprisev1.PhaseReady, false)
+	if cr.Status.Rebalance.Pending || cr.Status.Rebalance.BucketSkew != 15 {
+		t.Errorf("%s Rebalance = %+v; want BucketSkew=15", method, cr.Status.Rebalance)
+	}
+
+	// or when the policy is disabled
+	cr.Status.Peers[2].BucketCount = 0
+	cr.Status.Rebalance.Pending = true
+	cr.Spec.RebalanceOnScaleUp.Enabled = false
+	test(enterprisev1.PhaseReady, false)
+}
+
+func TestSetIndexerClusterHealthCondition(t *testing.T) {
+	factorMet, factorNotMet := true, false
+	for _, tc := range []struct {
+		replicationFactorMet, searchFactorMet *bool
+		wantStatus                            corev1.ConditionStatus
+		wantReason                            string
+	}{
+		{nil, nil, corev1.ConditionUnknown, "HealthUnknown"},
+		{&factorMet, nil, corev1.ConditionUnknown, "HealthUnknown"},
+		{&factorNotMet, &factorMet, corev1.ConditionFalse, "ReplicationFactorNotMet"},
+		{&factorMet, &factorNotMet, corev1.ConditionFalse, "SearchFactorNotMet"},
+		{&factorMet, &factorMet, corev1.ConditionTrue, "Ready"},
+	} {
+		cr := enterprisev1.IndexerCluster{}
+		cr.Status.ReplicationFactorMet = tc.replicationFactorMet
+		cr.Status.SearchFactorMet = tc.searchFactorMet
+		tracker := newStatusTracker(newMockClient(), &cr, &cr.Status.Phase, &cr.Status.CommonStatus, stepCluster)
+		tracker.begin(stepCluster)
+		healthy := setIndexerClusterHealthCondition(&cr, tracker)
+		condition := GetStatusCondition(cr.Status.Conditions, enterprisev1.ConditionClusterReady)
+		if condition == nil || condition.Status != tc.wantStatus || condition.Reason != tc.wantReason || healthy != (tc.wantStatus == corev1.ConditionTrue) {
+			t.Errorf("setIndexerClusterHealthCondition(%v,%v) = %t,%v; want %s %s", tc.replicationFactorMet, tc.searchFactorMet, healthy, condition, tc.wantStatus, tc.wantReason)
+		}
+	}
+}
+
+func TestUpdateIndexerClusterBundleStatus(t *testing.T) {
+	clusterMaster := enterprisev1.ClusterMaster{}
+	clusterMaster.Status.ActiveBundle.Checksum = "14310A4AABD23E85BBD4559C4A3B59F8"
+	clusterMaster.Status.BundlePush.ValidationErrors = []string{"bad indexes.conf"}
+	cr := enterprisev1.IndexerCluster{}
+	cr.Status.Sites = []enterprisev1.IndexerClusterSiteStatus{
+		{Name: "site1", Peers: []enterprisev1.IndexerClusterMemberStatus{
+			{Name: "splunk-stack1-site1-indexer-0", ActiveBundleID: "14310A4AABD23E85BBD4559C4A3B59F8"},
+			{Name: "splunk-stack1-site1-indexer-1", ActiveBundleID: "4F3AB2D2EBB1E5AB4C3B2C70FE6A7D9C"},
+		}},
+		{Name: "site2", Peers: []enterprisev1.IndexerClusterMemberStatus{
+			{Name: "splunk-stack1-site2-indexer-0", ActiveBundleID: "14310A4AABD23E85BBD4559C4A3B59F8"},
+			{Name: "splunk-stack1-site2-indexer-1"},
+		}},
+	}
+	updateIndexerClusterBundleStatus(&cr, &clusterMaster)
+	got := cr.Status.Bundle
+	if got.AppliedChecksum != "14310A4AABD23E85BBD4559C4A3B59F8" || got.UpdatedPeers != 2 ||
+		len(got.ValidationErrors) != 1 || got.ValidationErrors[0] != "bad indexes.conf" {
+		t.Errorf("updateIndexerClusterBundleStatus() = %+v; want AppliedChecksum=14310A4AABD23E85BBD4559C4A3B59F8 UpdatedPeers=2 ValidationErrors=[bad indexes.conf]", got)
+	}
+}
@@ -20,33 +20,41 @@ import (
 
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
 )
 
 // ApplyLicenseMaster reconciles the state for the Splunk Enterprise license master.
-func ApplyLicenseMaster(client ControllerClient, cr *enterprisev1.LicenseMaster) (reconcile.Result, error) {
+func ApplyLicenseMaster(client ControllerClient, cr *enterprisev1.LicenseMaster) (result reconcile.Result, err error) {
+	scopedLog := log.WithName("ApplyLicenseMaster").WithValues("name", cr.GetIdentifier(), "namespace", cr.GetNamespace())
 
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 
-	// validate and updates defaults for CR
-	err := enterprise.ValidateLicenseMasterSpec(&cr.Spec)
-	if err != nil {
-		return result, err
-	}
-
 	// updates status after function completes
-	cr.Status.Phase = enterprisev1.PhaseError
+	tracker := newStatusTracker(client, cr, &cr.Status.Phase, &cr.Status.CommonStatus,
+		stepValidate, stepTLS, stepSecrets, stepApps, stepServices, stepPods)
 	defer func() {
+		tracker.finish(err)
 		client.Status().Update(context.TODO(), cr)
 	}()
 
+	// validate and updates defaults for CR
+	tracker.begin(stepValidate)
+	err = enterprise.ValidateLicenseMasterSpec(&cr.Spec)
+	if err != nil {
+		return result, err
+	}
+	if cr.Status.AppRepo.Apps == nil {
+		cr.Status.AppRepo.Apps = []enterprisev1.AppStatus{}
+	}
+
 	// check if deletion has been requested
 	if cr.ObjectMeta.DeletionTimestamp != nil {
+		tracker.begin(stepDeletion)
 		terminating, err := CheckSplunkDeletion(cr, client)
 		if terminating && err != nil { // don't bother if no error, since it will just be removed immmediately after
 			cr.Status.Phase = enterprisev1.PhaseTerminating
@@ -56,33 +64,83 @@ func ApplyLicenseMaster(client ControllerClient, cr *enterprisev1.LicenseMaster)
 		return result, err
 	}
 
+	// only refresh status while reconcile is paused
+	if tracker.paused {
+		tracker.begin(stepPods)
+		statefulSet, err := enterprise.GetLicenseMasterStatefulSet(cr)
+		if err != nil {
+			return result, err
+		}
+		cr.Status.Phase = GetStatefulSetPhase(client, statefulSet)
+		tracker.setPhase(cr.Status.Phase)
+		result.Requeue = false
+		return result, nil
+	}
+
+	// create or update TLS certificates
+	tracker.begin(stepTLS)
+	tlsManager := TLSManager{log: scopedLog, cr: cr, spec: &cr.Spec.TLS, status: &cr.Status.TLS, instanceType: enterprise.SplunkLicenseMaster,
+		hosts: enterprise.GetSplunkTLSHosts(cr.GetNamespace(), enterprise.SplunkLicenseMaster, cr.GetIdentifier(), "", 1), events: newEventPublisher(client, cr)}
+	err = tlsManager.Apply(client)
+	if err != nil {
+		return result, err
+	}
+
 	// create or update general config resources
-	_, err = ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkLicenseMaster)
+	tracker.begin(stepSecrets)
+	secrets, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkLicenseMaster, &cr.Status.Secrets)
+	if err != nil {
+		return result, err
+	}
+
+	// rotate secrets when requested, before they are used to manage any instances
+	secretsManager := SecretRotationManager{log: scopedLog, cr: cr, spec: &cr.Spec.CommonSplunkSpec, status: &cr.Status.Secrets, instanceType: enterprise.SplunkLicenseMaster,
+		secrets: secrets, instanceURIs: getSplunkInstanceURIs(cr.GetNamespace(), enterprise.SplunkLicenseMaster, cr.GetIdentifier(), 1), newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}
+	err = secretsManager.Update(client, cr.Status.Phase == enterprisev1.PhaseReady)
+	if err != nil {
+		return result, err
+	}
+
+	// check app repository for new or changed app packages
+	tracker.begin(stepApps)
+	err = ApplyAppRepoConfig(client, cr, &cr.Spec.AppRepo, enterprise.SplunkLicenseMaster, &cr.Status.AppRepo)
 	if err != nil {
 		return result, err
 	}
 
 	// create or update a service
+	tracker.begin(stepServices)
 	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkLicenseMaster, false))
 	if err != nil {
 		return result, err
 	}
 
 	// create or update statefulset
+	tracker.begin(stepPods)
 	statefulSet, err := enterprise.GetLicenseMasterStatefulSet(cr)
 	if err != nil {
 		return result, err
 	}
-	mgr := DefaultStatefulSetPodManager{}
+	err = updateConfigChecksums(client, cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkLicenseMaster, &statefulSet.Spec.Template)
+	if err != nil {
+		return result, err
+	}
+	mgr := DefaultStatefulSetPodManager{UpdateStrategy: cr.Spec.UpdateStrategy}
 	phase, err := mgr.Update(client, statefulSet, 1)
 	if err != nil {
 		return result, err
 	}
 	cr.Status.Phase = phase
+	tracker.setPhase(phase)
+	UpdateAppRepoStatus(&cr.Status.AppRepo, phase)
 
-	// no need to requeue if everything is ready
+	// no need to requeue if everything is ready, other than to poll the app repository, rotate secrets and renew certificates
 	if cr.Status.Phase == enterprisev1.PhaseReady {
 		result.Requeue = false
+		setAppRepoPollRequeue(&result, &cr.Spec.AppRepo)
+		secretsManager.setRequeue(&result)
+		tlsManager.setReady()
+		tlsManager.setRequeue(&result)
 	}
 	return result, nil
 }
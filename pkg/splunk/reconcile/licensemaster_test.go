@@ -20,7 +20,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 )
 
 func TestApplyLicenseMaster(t *testing.T) {
@@ -29,8 +29,9 @@ func TestApplyLicenseMaster(t *testing.T) {
 		{metaName: "*v1.Service-test-splunk-stack1-license-master-service"},
 		{metaName: "*v1.StatefulSet-test-splunk-stack1-license-master"},
 	}
-	createCalls := map[string][]mockFuncCall{"Get": funcCalls, "Create": funcCalls}
-	updateCalls := map[string][]mockFuncCall{"Get": funcCalls, "Update": []mockFuncCall{funcCalls[2]}}
+	getCalls := []mockFuncCall{funcCalls[0], funcCalls[1], funcCalls[0], funcCalls[2]}
+	createCalls := map[string][]mockFuncCall{"Get": getCalls, "Create": funcCalls}
+	updateCalls := map[string][]mockFuncCall{"Get": getCalls, "Update": []mockFuncCall{funcCalls[2]}}
 	current := enterprisev1.LicenseMaster{
 		TypeMeta: metav1.TypeMeta{
 			Kind: "LicenseMaster",
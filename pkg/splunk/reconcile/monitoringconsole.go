@@ -0,0 +1,362 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+)
+
+// ApplyMonitoringConsole reconciles the state for a Splunk Enterprise monitoring console.
+func ApplyMonitoringConsole(client ControllerClient, cr *enterprisev1.MonitoringConsole) (result reconcile.Result, err error) {
+
+	// unless modified, reconcile for this object will be requeued after 5 seconds
+	result = reconcile.Result{
+		Requeue:      true,
+		RequeueAfter: time.Second * 5,
+	}
+	scopedLog := log.WithName("ApplyMonitoringConsole").WithValues("name", cr.GetIdentifier(), "namespace", cr.GetNamespace())
+
+	// updates status after function completes
+	tracker := newStatusTracker(client, cr, &cr.Status.Phase, &cr.Status.CommonStatus,
+		stepValidate, stepTLS, stepSecrets, stepApps, stepServices, stepPods, stepPeers)
+	defer func() {
+		tracker.finish(err)
+		if updateErr := client.Status().Update(context.TODO(), cr); updateErr != nil {
+			scopedLog.Error(updateErr, "Status update failed")
+		}
+	}()
+
+	// validate and updates defaults for CR
+	tracker.begin(stepValidate)
+	err = enterprise.ValidateMonitoringConsoleSpec(&cr.Spec)
+	if err != nil {
+		return result, err
+	}
+	if cr.Status.Peers == nil {
+		cr.Status.Peers = []enterprisev1.MonitoringConsolePeerStatus{}
+	}
+	if cr.Status.AppRepo.Apps == nil {
+		cr.Status.AppRepo.Apps = []enterprisev1.AppStatus{}
+	}
+
+	// check if deletion has been requested
+	if cr.ObjectMeta.DeletionTimestamp != nil {
+		tracker.begin(stepDeletion)
+		terminating, err := CheckSplunkDeletion(cr, client)
+		if terminating && err != nil { // don't bother if no error, since it will just be removed immmediately after
+			cr.Status.Phase = enterprisev1.PhaseTerminating
+		} else {
+			result.Requeue = false
+		}
+		return result, err
+	}
+
+	// only refresh status while reconcile is paused
+	if tracker.paused {
+		tracker.begin(stepPods)
+		statefulSet, err := enterprise.GetMonitoringConsoleStatefulSet(cr)
+		if err != nil {
+			return result, err
+		}
+		cr.Status.Phase = GetStatefulSetPhase(client, statefulSet)
+		tracker.setPhase(cr.Status.Phase)
+		result.Requeue = false
+		return result, nil
+	}
+
+	// create or update TLS certificates
+	tracker.begin(stepTLS)
+	tlsManager := TLSManager{log: scopedLog, cr: cr, spec: &cr.Spec.TLS, status: &cr.Status.TLS, instanceType: enterprise.SplunkMonitoringConsole,
+		hosts: enterprise.GetSplunkTLSHosts(cr.GetNamespace(), enterprise.SplunkMonitoringConsole, cr.GetIdentifier(), "", 1), events: newEventPublisher(client, cr)}
+	err = tlsManager.Apply(client)
+	if err != nil {
+		return result, err
+	}
+
+	// create or update general config resources
+	tracker.begin(stepSecrets)
+	secrets, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkMonitoringConsole, &cr.Status.Secrets)
+	if err != nil {
+		return result, err
+	}
+
+	// the service account is used to poll the search peers of the monitoring console
+	serviceAccount, err := ApplySplunkServiceAccount(client, cr, enterprise.SplunkMonitoringConsole)
+	if err != nil {
+		return result, err
+	}
+
+	// rotate secrets when requested, before they are used to manage any instances
+	secretsManager := SecretRotationManager{log: scopedLog, cr: cr, spec: &cr.Spec.CommonSplunkSpec, status: &cr.Status.Secrets, instanceType: enterprise.SplunkMonitoringConsole,
+		secrets: secrets, instanceURIs: getSplunkInstanceURIs(cr.GetNamespace(), enterprise.SplunkMonitoringConsole, cr.GetIdentifier(), 1), newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}
+	err = secretsManager.Update(client, cr.Status.Phase == enterprisev1.PhaseReady)
+	if err != nil {
+		return result, err
+	}
+
+	// check app repository for new or changed app packages
+	tracker.begin(stepApps)
+	err = ApplyAppRepoConfig(client, cr, &cr.Spec.AppRepo, enterprise.SplunkMonitoringConsole, &cr.Status.AppRepo)
+	if err != nil {
+		return result, err
+	}
+
+	// create or update a headless service for the monitoring console
+	tracker.begin(stepServices)
+	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkMonitoringConsole, true))
+	if err != nil {
+		return result, err
+	}
+
+	// create or update a regular service for the monitoring console
+	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkMonitoringConsole, false))
+	if err != nil {
+		return result, err
+	}
+
+	// create or update statefulset
+	tracker.begin(stepPods)
+	statefulSet, err := enterprise.GetMonitoringConsoleStatefulSet(cr)
+	if err != nil {
+		return result, err
+	}
+	err = updateConfigChecksums(client, cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkMonitoringConsole, &statefulSet.Spec.Template)
+	if err != nil {
+		return result, err
+	}
+	mgr := DefaultStatefulSetPodManager{UpdateStrategy: cr.Spec.UpdateStrategy}
+	phase, err := mgr.Update(client, statefulSet, 1)
+	if err != nil {
+		return result, err
+	}
+	cr.Status.Phase = phase
+	tracker.setPhase(phase)
+	UpdateAppRepoStatus(&cr.Status.AppRepo, phase)
+	if cr.Status.Phase != enterprisev1.PhaseReady {
+		return result, nil
+	}
+	tlsManager.setReady()
+
+	// keep search peers in sync with the Splunk Enterprise instances being monitored
+	tracker.begin(stepPeers)
+	peerManager := MonitoringConsolePeerManager{log: scopedLog, cr: cr, secrets: secrets, newSplunkClient: tlsManager.newSplunkClient,
+		serviceAccount: &ServiceAccount{log: scopedLog, secret: serviceAccount, adminPassword: secrets.Data["password"], newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}}
+	peersReady, err := peerManager.Update(client)
+	if err != nil {
+		return result, err
+	}
+
+	if !peersReady {
+		tracker.setCondition(corev1.ConditionFalse, "PeersNotReady", "waiting for search peers to be added and up")
+		return result, nil
+	}
+
+	// no need to requeue if all search peers are up, other than to poll the app repository, rotate secrets and renew certificates
+	result.Requeue = false
+	setAppRepoPollRequeue(&result, &cr.Spec.AppRepo)
+	secretsManager.setRequeue(&result)
+	tlsManager.setRequeue(&result)
+	return result, nil
+}
+
+// MonitoringConsolePeerManager is used to manage the search peers of a monitoring console
+type MonitoringConsolePeerManager struct {
+	log             logr.Logger
+	cr              *enterprisev1.MonitoringConsole
+	secrets         *corev1.Secret
+	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+
+	// serviceAccount is used to poll the search peers (optional, defaults to the admin user)
+	serviceAccount *ServiceAccount
+}
+
+// monitoringConsolePeer describes a Splunk Enterprise instance that should be a search peer of a monitoring console
+type monitoringConsolePeer struct {
+	// host and management port of the search peer
+	name string
+
+	// kind and name of the custom resource that manages the search peer
+	owner string
+
+	// admin password used to establish trust with the search peer
+	password string
+}
+
+// Update for MonitoringConsolePeerManager adds search peers for all Splunk Enterprise instances managed by custom resources in the
+// same namespace, and removes search peers for instances that no longer exist. This uses the REST API, and does not require a restart.
+// It returns true if all of the search peers are up.
+func (mgr *MonitoringConsolePeerManager) Update(c ControllerClient) (bool, error) {
+	peers, err := mgr.getPeers(c)
+	if err != nil {
+		return false, err
+	}
+
+	// only adding and removing search peers requires the admin user
+	ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+	defer cancel()
+	splunkClient := mgr.getClient()
+	statusClient := splunkClient
+	if mgr.serviceAccount != nil {
+		statusClient, err = mgr.serviceAccount.getClient(ctx, mgr.getURI())
+		if err != nil {
+			return false, err
+		}
+	}
+	current, err := statusClient.GetSearchPeersContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// remove search peers that were previously added, but are no longer managed
+	desired := make(map[string]bool)
+	for _, peer := range peers {
+		desired[peer.name] = true
+	}
+	for _, peerStatus := range mgr.cr.Status.Peers {
+		if _, ok := current[peerStatus.Name]; ok && !desired[peerStatus.Name] {
+			mgr.log.Info("Removing search peer", "peer", peerStatus.Name)
+			if err := splunkClient.RemoveSearchPeer(peerStatus.Name); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// add missing search peers
+	allReady := true
+	result := make([]enterprisev1.MonitoringConsolePeerStatus, len(peers))
+	for idx, peer := range peers {
+		result[idx] = enterprisev1.MonitoringConsolePeerStatus{Name: peer.name, Owner: peer.owner, Status: "Pending"}
+		if info, ok := current[peer.name]; ok {
+			result[idx].Status = info.Status
+		} else {
+			// failures are expected until the instance is ready, so just log them and try again later
+			mgr.log.Info("Adding search peer", "peer", peer.name, "owner", peer.owner)
+			if err := splunkClient.AddSearchPeer(peer.name, "admin", peer.password); err != nil {
+				mgr.log.Error(err, "Unable to add search peer", "peer", peer.name)
+			}
+		}
+		if result[idx].Status != "Up" {
+			allReady = false
+		}
+	}
+	mgr.cr.Status.Peers = result
+
+	return allReady, nil
+}
+
+// getClient for MonitoringConsolePeerManager returns a SplunkClient for the monitoring console
+func (mgr *MonitoringConsolePeerManager) getClient() *splclient.SplunkClient {
+	return mgr.newSplunkClient(mgr.getURI(), "admin", string(mgr.secrets.Data["password"]))
+}
+
+// getURI for MonitoringConsolePeerManager returns the management URI for the monitoring console
+func (mgr *MonitoringConsolePeerManager) getURI() string {
+	fqdnName := enterprise.GetSplunkStatefulsetURL(mgr.cr.GetNamespace(), enterprise.SplunkMonitoringConsole, mgr.cr.GetIdentifier(), 0, false)
+	return fmt.Sprintf("https://%s:8089", fqdnName)
+}
+
+// getPeers for MonitoringConsolePeerManager returns the search peers for all Splunk Enterprise custom resources in the same namespace
+func (mgr *MonitoringConsolePeerManager) getPeers(c ControllerClient) ([]monitoringConsolePeer, error) {
+	listOpts := []client.ListOption{client.InNamespace(mgr.cr.GetNamespace())}
+	type owner struct {
+		cr           enterprisev1.MetaObject
+		kind         string
+		instanceType enterprise.InstanceType
+	}
+	owners := []owner{}
+
+	var standalones enterprisev1.StandaloneList
+	if err := c.List(context.TODO(), &standalones, listOpts...); err != nil {
+		return nil, err
+	}
+	for idx := range standalones.Items {
+		if enterprise.ValidateStandaloneSpec(&standalones.Items[idx].Spec) == nil {
+			owners = append(owners, owner{&standalones.Items[idx], "Standalone", enterprise.SplunkStandalone})
+		}
+	}
+
+	var licenseMasters enterprisev1.LicenseMasterList
+	if err := c.List(context.TODO(), &licenseMasters, listOpts...); err != nil {
+		return nil, err
+	}
+	for idx := range licenseMasters.Items {
+		if enterprise.ValidateLicenseMasterSpec(&licenseMasters.Items[idx].Spec) == nil {
+			owners = append(owners, owner{&licenseMasters.Items[idx], "LicenseMaster", enterprise.SplunkLicenseMaster})
+		}
+	}
+
+	var searchHeadClusters enterprisev1.SearchHeadClusterList
+	if err := c.List(context.TODO(), &searchHeadClusters, listOpts...); err != nil {
+		return nil, err
+	}
+	for idx := range searchHeadClusters.Items {
+		if enterprise.ValidateSearchHeadClusterSpec(&searchHeadClusters.Items[idx].Spec) == nil {
+			owners = append(owners, owner{&searchHeadClusters.Items[idx], "SearchHeadCluster", enterprise.SplunkSearchHead})
+		}
+	}
+
+	var clusterMasters enterprisev1.ClusterMasterList
+	if err := c.List(context.TODO(), &clusterMasters, listOpts...); err != nil {
+		return nil, err
+	}
+	for idx := range clusterMasters.Items {
+		if enterprise.ValidateClusterMasterSpec(&clusterMasters.Items[idx].Spec) == nil {
+			owners = append(owners, owner{&clusterMasters.Items[idx], "ClusterMaster", enterprise.SplunkClusterMaster})
+		}
+	}
+
+	var indexerClusters enterprisev1.IndexerClusterList
+	if err := c.List(context.TODO(), &indexerClusters, listOpts...); err != nil {
+		return nil, err
+	}
+	for idx := range indexerClusters.Items {
+		if enterprise.ValidateIndexerClusterSpec(&indexerClusters.Items[idx].Spec) == nil {
+			owners = append(owners, owner{&indexerClusters.Items[idx], "IndexerCluster", enterprise.SplunkIndexer})
+		}
+	}
+
+	peers := []monitoringConsolePeer{}
+	for _, o := range owners {
+		if o.cr.GetObjectMeta().GetDeletionTimestamp() != nil {
+			continue
+		}
+		ownerName := fmt.Sprintf("%s/%s", o.kind, o.cr.GetIdentifier())
+
+		// secrets are created by the custom resource's own reconcile; skip it until they exist
+		ref := corev1.ObjectReference{Name: o.cr.GetIdentifier(), Namespace: o.cr.GetNamespace()}
+		password, err := GetSplunkSecret(c, mgr.cr, ref, o.instanceType, "password")
+		if err != nil {
+			mgr.log.Info("Skipping search peers without secrets", "owner", ownerName, "error", err.Error())
+			continue
+		}
+
+		for _, name := range enterprise.GetMonitoringConsolePeers(o.cr) {
+			peers = append(peers, monitoringConsolePeer{name: name, owner: ownerName, password: string(password)})
+		}
+	}
+
+	return peers, nil
+}
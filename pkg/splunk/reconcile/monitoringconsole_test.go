@@ -0,0 +1,243 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"io/ioutil"
+	"net/url"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+func TestApplyMonitoringConsole(t *testing.T) {
+	funcCalls := []mockFuncCall{
+		{metaName: "*v1.Secret-test-splunk-stack1-monitoring-console-secrets"},
+		{metaName: "*v1.Secret-test-splunk-stack1-monitoring-console-service-account"},
+		{metaName: "*v1.Service-test-splunk-stack1-monitoring-console-headless"},
+		{metaName: "*v1.Service-test-splunk-stack1-monitoring-console-service"},
+		{metaName: "*v1.StatefulSet-test-splunk-stack1-monitoring-console"},
+	}
+	getCalls := []mockFuncCall{funcCalls[0], funcCalls[1], funcCalls[2], funcCalls[3], funcCalls[0], funcCalls[4]}
+	createCalls := map[string][]mockFuncCall{"Get": getCalls, "Create": funcCalls}
+	updateCalls := map[string][]mockFuncCall{"Get": getCalls, "Update": []mockFuncCall{funcCalls[4]}}
+	current := enterprisev1.MonitoringConsole{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "MonitoringConsole",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	revised := current.DeepCopy()
+	revised.Spec.Image = "splunk/test"
+	reconcile := func(c *mockClient, cr interface{}) error {
+		_, err := ApplyMonitoringConsole(c, cr.(*enterprisev1.MonitoringConsole))
+		return err
+	}
+	reconcileTester(t, "TestApplyMonitoringConsole", &current, revised, createCalls, updateCalls, reconcile)
+
+	// test deletion
+	currentTime := metav1.NewTime(time.Now())
+	revised.ObjectMeta.DeletionTimestamp = &currentTime
+	revised.ObjectMeta.Finalizers = []string{"enterprise.splunk.com/delete-pvc"}
+	deleteFunc := func(cr enterprisev1.MetaObject, c ControllerClient) (bool, error) {
+		_, err := ApplyMonitoringConsole(c, cr.(*enterprisev1.MonitoringConsole))
+		return true, err
+	}
+	splunkDeletionTester(t, revised, deleteFunc)
+}
+
+func TestMonitoringConsolePeerManager(t *testing.T) {
+	cr := enterprisev1.MonitoringConsole{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "MonitoringConsole",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mc1",
+			Namespace: "test",
+		},
+	}
+	secrets := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "splunk-mc1-monitoring-console-secrets",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{"password": []byte("mc-password")},
+	}
+	standaloneSecrets := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "splunk-stack1-standalone-secrets",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{"password": []byte("stack1-password")},
+	}
+	currentTime := metav1.NewTime(time.Now())
+	standalones := enterprisev1.StandaloneList{
+		Items: []enterprisev1.Standalone{
+			{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}, Spec: enterprisev1.StandaloneSpec{Replicas: 2}},
+		},
+	}
+	licenseMasters := enterprisev1.LicenseMasterList{
+		Items: []enterprisev1.LicenseMaster{
+			{ObjectMeta: metav1.ObjectMeta{Name: "lm1", Namespace: "test", DeletionTimestamp: &currentTime}},
+		},
+	}
+	indexerClusters := enterprisev1.IndexerClusterList{
+		Items: []enterprisev1.IndexerCluster{
+			{ObjectMeta: metav1.ObjectMeta{Name: "idxc1", Namespace: "test"}, Spec: enterprisev1.IndexerClusterSpec{CommonSplunkSpec: enterprisev1.CommonSplunkSpec{ClusterMasterRef: corev1.ObjectReference{Name: "cm1"}}}},
+		},
+	}
+
+	c := newMockClient()
+	c.state[getStateKey(standaloneSecrets)] = standaloneSecrets
+	c.state[getListStateKey(&standalones)] = &standalones
+	c.state[getListStateKey(&licenseMasters)] = &licenseMasters
+	c.state[getListStateKey(&enterprisev1.SearchHeadClusterList{})] = &enterprisev1.SearchHeadClusterList{}
+	c.state[getListStateKey(&enterprisev1.ClusterMasterList{})] = &enterprisev1.ClusterMasterList{}
+	c.state[getListStateKey(&indexerClusters)] = &indexerClusters
+
+	// standalone-0 is already a peer, standalone-1 needs to be added, and stack2 is no longer managed
+	mcURL := "https://splunk-mc1-monitoring-console-0.splunk-mc1-monitoring-console-headless.test.svc.cluster.local:8089"
+	peer0 := "splunk-stack1-standalone-0.splunk-stack1-standalone-headless.test.svc.cluster.local:8089"
+	peer1 := "splunk-stack1-standalone-1.splunk-stack1-standalone-headless.test.svc.cluster.local:8089"
+	stalePeer := "splunk-stack2-standalone-0.splunk-stack2-standalone-headless.test.svc.cluster.local:8089"
+	cr.Status.Peers = []enterprisev1.MonitoringConsolePeerStatus{
+		{Name: peer0, Owner: "Standalone/stack1", Status: "Up"},
+		{Name: stalePeer, Owner: "Standalone/stack2", Status: "Up"},
+	}
+	mockSplunkClient := &spltest.MockHTTPClient{}
+	mockSplunkClient.AddHandlers(
+		spltest.MockHTTPHandler{
+			Method: "GET",
+			URL:    mcURL + "/services/search/distributed/peers?count=0&output_mode=json",
+			Status: 200,
+			Body:   `{"entry":[{"name":"` + peer0 + `","content":{"status":"Up"}},{"name":"` + stalePeer + `","content":{"status":"Up"}}]}`,
+		},
+		spltest.MockHTTPHandler{
+			Method: "DELETE",
+			URL:    mcURL + "/services/search/distributed/peers/" + stalePeer,
+			Status: 200,
+		},
+		spltest.MockHTTPHandler{
+			Method: "POST",
+			URL:    mcURL + "/services/search/distributed/peers",
+			Status: 201,
+		},
+	)
+	var gotPasswords []string
+	mgr := MonitoringConsolePeerManager{
+		log:     log.WithName("TestMonitoringConsolePeerManager"),
+		cr:      &cr,
+		secrets: secrets,
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			gotPasswords = append(gotPasswords, password)
+			sc := splclient.NewSplunkClient(managementURI, username, password)
+			sc.Client = mockSplunkClient
+			return sc
+		},
+	}
+
+	ready, err := mgr.Update(c)
+	if err != nil {
+		t.Errorf("MonitoringConsolePeerManager.Update() returned %v; want nil", err)
+	}
+	if ready {
+		t.Errorf("MonitoringConsolePeerManager.Update() returned ready; want not ready")
+	}
+	mockSplunkClient.CheckRequests(t, "MonitoringConsolePeerManager.Update()")
+	if len(gotPasswords) != 1 || gotPasswords[0] != "mc-password" {
+		t.Errorf("MonitoringConsolePeerManager.Update() client passwords = %v; want [mc-password]", gotPasswords)
+	}
+	body, _ := ioutil.ReadAll(mockSplunkClient.GotRequests[2].Body)
+	wantBody := "name=" + url.QueryEscape(peer1) + "&remotePassword=stack1-password&remoteUsername=admin"
+	if string(body) != wantBody {
+		t.Errorf("MonitoringConsolePeerManager.Update() add peer body = %s; want %s", body, wantBody)
+	}
+
+	// the indexer cluster is skipped because its secrets do not exist yet
+	want := []enterprisev1.MonitoringConsolePeerStatus{
+		{Name: peer0, Owner: "Standalone/stack1", Status: "Up"},
+		{Name: peer1, Owner: "Standalone/stack1", Status: "Pending"},
+	}
+	if len(cr.Status.Peers) != len(want) {
+		t.Fatalf("MonitoringConsolePeerManager.Update() peers = %v; want %v", cr.Status.Peers, want)
+	}
+	for idx := range want {
+		if cr.Status.Peers[idx] != want[idx] {
+			t.Errorf("MonitoringConsolePeerManager.Update() peer %d = %v; want %v", idx, cr.Status.Peers[idx], want[idx])
+		}
+	}
+
+	// all peers up
+	mockSplunkClient = &spltest.MockHTTPClient{}
+	mockSplunkClient.AddHandlers(spltest.MockHTTPHandler{
+		Method: "GET",
+		URL:    mcURL + "/services/search/distributed/peers?count=0&output_mode=json",
+		Status: 200,
+		Body:   `{"entry":[{"name":"` + peer0 + `","content":{"status":"Up"}},{"name":"` + peer1 + `","content":{"status":"Up"}}]}`,
+	})
+	ready, err = mgr.Update(c)
+	if err != nil || !ready {
+		t.Errorf("MonitoringConsolePeerManager.Update() returned %t,%v; want true,nil", ready, err)
+	}
+	mockSplunkClient.CheckRequests(t, "MonitoringConsolePeerManager.Update(ready)")
+
+	// search peers are polled using the service account, when there is one
+	mockSplunkClient = &spltest.MockHTTPClient{}
+	mockSplunkClient.AddHandlers(
+		spltest.MockHTTPHandler{
+			Method: "POST",
+			URL:    mcURL + "/services/auth/login",
+			Status: 200,
+			Body:   `{"sessionKey":"key1"}`,
+		},
+		spltest.MockHTTPHandler{
+			Method: "GET",
+			URL:    mcURL + "/services/search/distributed/peers?count=0&output_mode=json",
+			Status: 200,
+			Body:   `{"entry":[{"name":"` + peer0 + `","content":{"status":"Up"}},{"name":"` + peer1 + `","content":{"status":"Up"}}]}`,
+		},
+	)
+	account := &corev1.Secret{Data: map[string][]byte{"username": []byte("splunk-operator"), "password": []byte("sa-password")}}
+	mgr.serviceAccount = &ServiceAccount{log: mgr.log, secret: account, adminPassword: secrets.Data["password"], newSplunkClient: mgr.newSplunkClient, sessions: &sessionCache{}}
+	ready, err = mgr.Update(c)
+	if err != nil || !ready {
+		t.Errorf("MonitoringConsolePeerManager.Update() returned %t,%v; want true,nil", ready, err)
+	}
+	mockSplunkClient.CheckRequests(t, "MonitoringConsolePeerManager.Update(serviceAccount)")
+	if got := mockSplunkClient.GotRequests[1].Header.Get("Authorization"); got != "Splunk key1" {
+		t.Errorf("MonitoringConsolePeerManager.Update() polled search peers with %s; want Splunk key1", got)
+	}
+	mgr.serviceAccount = nil
+
+	// test API failure
+	mockSplunkClient = &spltest.MockHTTPClient{}
+	mockSplunkClient.AddHandlers(spltest.MockHTTPHandler{
+		Method: "GET",
+		URL:    mcURL + "/services/search/distributed/peers?count=0&output_mode=json",
+		Status: 500,
+	})
+	if _, err = mgr.Update(c); err == nil {
+		t.Errorf("MonitoringConsolePeerManager.Update() returned nil; want error")
+	}
+}
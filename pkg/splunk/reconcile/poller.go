@@ -0,0 +1,203 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+// statusPollerSettings configure how a statusPoller polls the Splunk instances in a cluster
+type statusPollerSettings struct {
+	// workers is the maximum number of requests that are in flight at the same time
+	workers int
+
+	// budget is the maximum number of instances that are polled each time (0 for no limit); the instances with the
+	// oldest results are polled first, so that every instance is eventually polled in large clusters
+	budget int
+
+	// ttl is the time that results are cached; instances with results younger than this are not polled again
+	ttl time.Duration
+
+	// timeout is the maximum time spent polling each instance, including any retries
+	timeout time.Duration
+}
+
+// searchHeadClusterPollerSettings are used to poll the members of search head clusters
+var searchHeadClusterPollerSettings = statusPollerSettings{
+	workers: 5,
+	budget:  10,
+	ttl:     15 * time.Second,
+	timeout: 5 * time.Second,
+}
+
+// indexerClusterPollerSettings are used to poll the cluster master of indexer clusters. Results expire before the
+// next reconcile, so that they are only shared by the sites of a multisite indexer cluster.
+var indexerClusterPollerSettings = statusPollerSettings{
+	workers: 2,
+	ttl:     4 * time.Second,
+	timeout: 5 * time.Second,
+}
+
+// statusTimeout is the maximum time spent polling the status of a Splunk instance without a statusPoller, including
+// logging in and any retries
+const statusTimeout = 10 * time.Second
+
+// polledStatus is the most recent result of polling a Splunk instance
+type polledStatus struct {
+	// value returned by the instance, or nil if it has never responded
+	value interface{}
+
+	// updated is the time that value was returned
+	updated time.Time
+
+	// err is the reason that polling the instance failed, if it was polled and failed
+	err error
+
+	// stale is true if value was not refreshed when the instance was most recently polled, because the request
+	// failed or was skipped to stay within the budget
+	stale bool
+}
+
+// statusPoller is used to poll the status of the Splunk instances in a cluster concurrently, caching the results
+// between reconciles so that large clusters can be polled without blocking on each instance in turn
+type statusPoller struct {
+	settings statusPollerSettings
+
+	mutex sync.Mutex
+	cache map[string]polledStatus
+}
+
+// newStatusPoller returns a new statusPoller using the given settings
+func newStatusPoller(settings statusPollerSettings) *statusPoller {
+	return &statusPoller{settings: settings, cache: make(map[string]polledStatus)}
+}
+
+// poll for statusPoller returns the status of the instances identified by keys, in the same order. The cached status
+// is used for instances that were polled within the TTL; fetch is called to poll the others, up to the budget.
+// Instances that are not polled successfully keep their previous value, but are marked stale.
+func (p *statusPoller) poll(keys []string, fetch func(ctx context.Context, i int) (interface{}, error)) []polledStatus {
+	results := make([]polledStatus, len(keys))
+	due := []int{}
+	now := time.Now()
+	p.mutex.Lock()
+	for i, key := range keys {
+		cached, ok := p.cache[key]
+		results[i] = cached
+		if !ok || cached.stale || now.Sub(cached.updated) >= p.settings.ttl {
+			results[i].stale = true
+			results[i].err = nil
+			due = append(due, i)
+		}
+	}
+	p.mutex.Unlock()
+
+	// poll the instances with the oldest results first
+	sort.SliceStable(due, func(a, b int) bool {
+		return results[due[a]].updated.Before(results[due[b]].updated)
+	})
+	if p.settings.budget > 0 && len(due) > p.settings.budget {
+		due = due[:p.settings.budget]
+	}
+
+	// each worker writes results only for the instances it polls, so no locking is needed
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < p.settings.workers && w < len(due); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ctx, cancel := context.WithTimeout(context.Background(), p.settings.timeout)
+				value, err := fetch(ctx, i)
+				cancel()
+				if err != nil {
+					results[i].err = err
+				} else {
+					results[i] = polledStatus{value: value, updated: time.Now()}
+				}
+			}
+		}()
+	}
+	for _, i := range due {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	// update the cache, forgetting any instances that no longer exist
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	cache := make(map[string]polledStatus, len(keys))
+	for i, key := range keys {
+		cache[key] = results[i]
+	}
+	p.cache = cache
+	return results
+}
+
+// invalidate for statusPoller marks the cached status of an instance stale, so that it is polled again next time.
+// This is used after making changes to an instance, which will usually change its status.
+func (p *statusPoller) invalidate(key string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if cached, ok := p.cache[key]; ok {
+		cached.stale = true
+		p.cache[key] = cached
+	}
+}
+
+// statusPollerCache is used to keep a statusPoller for each cluster between reconciles
+type statusPollerCache struct {
+	mutex   sync.Mutex
+	pollers map[string]*statusPoller
+}
+
+// defaultStatusPollers is the statusPollerCache used by pod managers, unless they are given a statusPoller
+var defaultStatusPollers = &statusPollerCache{}
+
+// getStatusPollerKey returns the key used to identify the statusPoller for a custom resource
+func getStatusPollerKey(cr enterprisev1.MetaObject) string {
+	return fmt.Sprintf("%s/%s/%s", cr.GetTypeMeta().Kind, cr.GetNamespace(), cr.GetIdentifier())
+}
+
+// get for statusPollerCache returns the statusPoller for a custom resource, creating it with the given settings if
+// it doesn't exist yet
+func (cache *statusPollerCache) get(cr enterprisev1.MetaObject, settings statusPollerSettings) *statusPoller {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.pollers == nil {
+		cache.pollers = make(map[string]*statusPoller)
+	}
+	key := getStatusPollerKey(cr)
+	poller, ok := cache.pollers[key]
+	if !ok {
+		poller = newStatusPoller(settings)
+		cache.pollers[key] = poller
+	}
+	return poller
+}
+
+// remove for statusPollerCache discards the statusPoller for a custom resource that has been deleted
+func (cache *statusPollerCache) remove(cr enterprisev1.MetaObject) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	delete(cache.pollers, getStatusPollerKey(cr))
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+// fakeInstances is used to emulate polling a cluster of Splunk instances
+type fakeInstances struct {
+	mutex    sync.Mutex
+	keys     []string
+	down     map[string]bool
+	polled   []string
+	inFlight int
+	maxSeen  int
+	delay    time.Duration
+}
+
+// fetch for fakeInstances polls instance i, returning its name and the number of times it has been polled
+func (f *fakeInstances) fetch(ctx context.Context, i int) (interface{}, error) {
+	f.mutex.Lock()
+	key := f.keys[i]
+	f.polled = append(f.polled, key)
+	f.inFlight++
+	if f.inFlight > f.maxSeen {
+		f.maxSeen = f.inFlight
+	}
+	down := f.down[key]
+	f.mutex.Unlock()
+
+	defer func() {
+		f.mutex.Lock()
+		f.inFlight--
+		f.mutex.Unlock()
+	}()
+	if down {
+		// unreachable instances block until they time out
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	time.Sleep(f.delay)
+	return fmt.Sprintf("%s-ok", key), nil
+}
+
+func TestStatusPoller(t *testing.T) {
+	f := &fakeInstances{keys: []string{"sh-0", "sh-1", "sh-2", "sh-3", "sh-4"}, down: map[string]bool{}, delay: 10 * time.Millisecond}
+	p := newStatusPoller(statusPollerSettings{workers: 2, budget: 3, ttl: time.Hour, timeout: 50 * time.Millisecond})
+	test := func(wantPolled, wantStale []string) []polledStatus {
+		f.polled = []string{}
+		results := p.poll(f.keys, f.fetch)
+		if f.maxSeen > 2 {
+			t.Errorf("statusPoller.poll() sent %d requests at once; want at most 2", f.maxSeen)
+		}
+		polled := map[string]bool{}
+		for _, key := range f.polled {
+			polled[key] = true
+		}
+		for _, key := range wantPolled {
+			if !polled[key] {
+				t.Errorf("statusPoller.poll() polled %v; want %v", f.polled, wantPolled)
+				break
+			}
+		}
+		if len(f.polled) != len(wantPolled) {
+			t.Errorf("statusPoller.poll() polled %v; want %v", f.polled, wantPolled)
+		}
+		stale := []string{}
+		for i, result := range results {
+			if result.stale {
+				stale = append(stale, f.keys[i])
+			}
+		}
+		if !reflect.DeepEqual(stale, wantStale) {
+			t.Errorf("statusPoller.poll() stale = %v; want %v", stale, wantStale)
+		}
+		return results
+	}
+
+	// the budget limits the number of instances polled each time
+	test([]string{"sh-0", "sh-1", "sh-2"}, []string{"sh-3", "sh-4"})
+
+	// cached results are used until they expire, and instances that were not polled yet go first
+	results := test([]string{"sh-3", "sh-4"}, []string{})
+	if results[0].value != "sh-0-ok" || results[4].value != "sh-4-ok" {
+		t.Errorf("statusPoller.poll() values = %v, %v; want sh-0-ok, sh-4-ok", results[0].value, results[4].value)
+	}
+
+	// unreachable instances keep their previous value, but are marked stale without blocking the others
+	p.invalidate("sh-1")
+	p.invalidate("sh-2")
+	f.down["sh-1"] = true
+	start := time.Now()
+	results = test([]string{"sh-1", "sh-2"}, []string{"sh-1"})
+	if results[1].value != "sh-1-ok" || !errors.Is(results[1].err, context.DeadlineExceeded) {
+		t.Errorf("statusPoller.poll() = %v, %v; want sh-1-ok, %v", results[1].value, results[1].err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("statusPoller.poll() took %v; want timeout", elapsed)
+	}
+
+	// failed instances are polled again next time
+	f.down["sh-1"] = false
+	test([]string{"sh-1"}, []string{})
+
+	// instances that no longer exist are forgotten
+	f.keys = f.keys[:2]
+	test([]string{}, []string{})
+	if len(p.cache) != 2 {
+		t.Errorf("statusPoller cache has %d instances; want 2", len(p.cache))
+	}
+
+	// expired results are polled again
+	p.settings.ttl = 0
+	test([]string{"sh-0", "sh-1"}, []string{})
+}
+
+func TestStatusPollerCache(t *testing.T) {
+	cache := &statusPollerCache{}
+	cr := enterprisev1.SearchHeadCluster{
+		TypeMeta:   metav1.TypeMeta{Kind: "SearchHeadCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	p := cache.get(&cr, searchHeadClusterPollerSettings)
+	if cache.get(&cr, searchHeadClusterPollerSettings) != p {
+		t.Errorf("statusPollerCache.get() returned a new poller; want the same one")
+	}
+	idxc := enterprisev1.IndexerCluster{
+		TypeMeta:   metav1.TypeMeta{Kind: "IndexerCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	if cache.get(&idxc, indexerClusterPollerSettings) == p {
+		t.Errorf("statusPollerCache.get() returned the same poller for different resources")
+	}
+	cache.remove(&cr)
+	if cache.get(&cr, searchHeadClusterPollerSettings) == p {
+		t.Errorf("statusPollerCache.get() returned the poller of a deleted resource")
+	}
+}
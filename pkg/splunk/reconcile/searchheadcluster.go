@@ -17,51 +17,59 @@ package reconcile
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
 	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
 	"github.com/splunk/splunk-operator/pkg/splunk/resources"
 )
 
 // ApplySearchHeadCluster reconciles the state for a Splunk Enterprise search head cluster.
-func ApplySearchHeadCluster(client ControllerClient, cr *enterprisev1.SearchHeadCluster) (reconcile.Result, error) {
+func ApplySearchHeadCluster(client ControllerClient, cr *enterprisev1.SearchHeadCluster) (result reconcile.Result, err error) {
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 	scopedLog := log.WithName("ApplySearchHeadCluster").WithValues("name", cr.GetIdentifier(), "namespace", cr.GetNamespace())
 
+	// updates status after function completes
+	tracker := newStatusTracker(client, cr, &cr.Status.Phase, &cr.Status.CommonStatus,
+		stepValidate, stepTLS, stepSecrets, stepApps, stepServices, stepDeployer, stepPods, stepBundle)
+	defer func() {
+		tracker.finish(err)
+		if updateErr := client.Status().Update(context.TODO(), cr); updateErr != nil {
+			scopedLog.Error(updateErr, "Status update failed")
+		}
+	}()
+
 	// validate and updates defaults for CR
-	err := enterprise.ValidateSearchHeadClusterSpec(&cr.Spec)
+	tracker.begin(stepValidate)
+	err = enterprise.ValidateSearchHeadClusterSpec(&cr.Spec)
 	if err != nil {
 		return result, err
 	}
-
-	// updates status after function completes
-	cr.Status.Phase = enterprisev1.PhaseError
-	cr.Status.DeployerPhase = enterprisev1.PhaseError
 	cr.Status.Replicas = cr.Spec.Replicas
 	cr.Status.Selector = fmt.Sprintf("app.kubernetes.io/instance=splunk-%s-search-head", cr.GetIdentifier())
 	if cr.Status.Members == nil {
 		cr.Status.Members = []enterprisev1.SearchHeadClusterMemberStatus{}
 	}
-	defer func() {
-		err = client.Status().Update(context.TODO(), cr)
-		if err != nil {
-			scopedLog.Error(err, "Status update failed")
-		}
-	}()
+	if cr.Status.AppRepo.Apps == nil {
+		cr.Status.AppRepo.Apps = []enterprisev1.AppStatus{}
+	}
 
 	// check if deletion has been requested
 	if cr.ObjectMeta.DeletionTimestamp != nil {
+		tracker.begin(stepDeletion)
 		terminating, err := CheckSplunkDeletion(cr, client)
 		if terminating && err != nil { // don't bother if no error, since it will just be removed immmediately after
 			cr.Status.Phase = enterprisev1.PhaseTerminating
@@ -72,13 +80,57 @@ func ApplySearchHeadCluster(client ControllerClient, cr *enterprisev1.SearchHead
 		return result, err
 	}
 
+	// only refresh status while reconcile is paused
+	if tracker.paused {
+		result.Requeue = false
+		return result, refreshPausedSearchHeadClusterStatus(client, cr, tracker)
+	}
+
+	// create or update TLS certificates; the deployer shares certificates with the search heads
+	tracker.begin(stepTLS)
+	tlsHosts := enterprise.GetSplunkTLSHosts(cr.GetNamespace(), enterprise.SplunkSearchHead, cr.GetIdentifier(), "", cr.Spec.Replicas)
+	for podName, dnsNames := range enterprise.GetSplunkTLSHosts(cr.GetNamespace(), enterprise.SplunkDeployer, cr.GetIdentifier(), "", 1) {
+		tlsHosts[podName] = dnsNames
+	}
+	tlsManager := TLSManager{log: scopedLog, cr: cr, spec: &cr.Spec.TLS, status: &cr.Status.TLS, instanceType: enterprise.SplunkSearchHead,
+		hosts: tlsHosts, events: newEventPublisher(client, cr)}
+	err = tlsManager.Apply(client)
+	if err != nil {
+		return result, err
+	}
+
 	// create or update general config resources
-	secrets, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkSearchHead)
+	tracker.begin(stepSecrets)
+	secrets, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkSearchHead, &cr.Status.Secrets)
+	if err != nil {
+		return result, err
+	}
+
+	// the service account is used to poll the search head cluster members
+	serviceAccount, err := ApplySplunkServiceAccount(client, cr, enterprise.SplunkSearchHead)
+	if err != nil {
+		return result, err
+	}
+
+	// rotate secrets when requested, before they are used to manage any instances; the deployer shares secrets with the search heads
+	instanceURIs := append(getSplunkInstanceURIs(cr.GetNamespace(), enterprise.SplunkDeployer, cr.GetIdentifier(), 1),
+		getSplunkInstanceURIs(cr.GetNamespace(), enterprise.SplunkSearchHead, cr.GetIdentifier(), cr.Spec.Replicas)...)
+	secretsManager := SecretRotationManager{log: scopedLog, cr: cr, spec: &cr.Spec.CommonSplunkSpec, status: &cr.Status.Secrets, instanceType: enterprise.SplunkSearchHead,
+		secrets: secrets, instanceURIs: instanceURIs, newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}
+	err = secretsManager.Update(client, cr.Status.Phase == enterprisev1.PhaseReady && cr.Status.DeployerPhase == enterprisev1.PhaseReady)
+	if err != nil {
+		return result, err
+	}
+
+	// check app repository used by the deployer for new or changed app packages
+	tracker.begin(stepApps)
+	err = ApplyAppRepoConfig(client, cr, &cr.Spec.AppRepo, enterprise.SplunkDeployer, &cr.Status.AppRepo)
 	if err != nil {
 		return result, err
 	}
 
 	// create or update a headless search head cluster service
+	tracker.begin(stepServices)
 	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkSearchHead, true))
 	if err != nil {
 		return result, err
@@ -97,32 +149,73 @@ func ApplySearchHeadCluster(client ControllerClient, cr *enterprisev1.SearchHead
 	}
 
 	// create or update statefulset for the deployer
+	tracker.begin(stepDeployer)
 	statefulSet, err := enterprise.GetDeployerStatefulSet(cr)
 	if err != nil {
 		return result, err
 	}
+	err = updateConfigChecksums(client, cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkDeployer, &statefulSet.Spec.Template)
+	if err != nil {
+		return result, err
+	}
 	deployerManager := DefaultStatefulSetPodManager{}
 	phase, err := deployerManager.Update(client, statefulSet, 1)
+	cr.Status.DeployerPhase = phase
 	if err != nil {
 		return result, err
 	}
-	cr.Status.DeployerPhase = phase
+	tracker.setPhase(phase)
+	UpdateAppRepoStatus(&cr.Status.AppRepo, phase)
 
 	// create or update statefulset for the search heads
+	tracker.begin(stepPods)
 	statefulSet, err = enterprise.GetSearchHeadStatefulSet(cr)
 	if err != nil {
 		return result, err
 	}
-	mgr := SearchHeadClusterPodManager{log: scopedLog, cr: cr, secrets: secrets, newSplunkClient: splclient.NewSplunkClient}
+	err = updateConfigChecksums(client, cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkSearchHead, &statefulSet.Spec.Template)
+	if err != nil {
+		return result, err
+	}
+	mgr := SearchHeadClusterPodManager{log: scopedLog, cr: cr, secrets: secrets, newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr),
+		serviceAccount: &ServiceAccount{log: scopedLog, secret: serviceAccount, adminPassword: secrets.Data["password"], newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}}
 	phase, err = mgr.Update(client, statefulSet, cr.Spec.Replicas)
 	if err != nil {
 		return result, err
 	}
 	cr.Status.Phase = phase
+	tracker.setPhase(phase)
+
+	if cr.Status.Phase == enterprisev1.PhaseReady && cr.Status.DeployerPhase == enterprisev1.PhaseReady {
+		tlsManager.setReady()
+	}
+
+	// push changes to the deployer bundle, but never while members are being recycled
+	if cr.Status.Phase == enterprisev1.PhaseReady || cr.Status.DeployerBundle.PushInProgress {
+		tracker.begin(stepBundle)
+		bundleManager := DeployerBundleManager{log: scopedLog, cr: cr, secrets: secrets, newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}
+		var pushed bool
+		pushed, err = bundleManager.Update(client)
+		if err != nil {
+			return result, err
+		}
+		if !pushed {
+			cr.Status.DeployerPhase = enterprisev1.PhaseUpdating
+			if cr.Status.DeployerBundle.PushInProgress {
+				tracker.setCondition(corev1.ConditionFalse, "Pushing", "pushing changes to the deployer bundle")
+			} else {
+				tracker.setCondition(corev1.ConditionFalse, "PushPending", "waiting to push changes to the deployer bundle")
+			}
+			return result, nil
+		}
+	}
 
-	// no need to requeue if everything is ready
+	// no need to requeue if everything is ready, other than to poll the app repository, rotate secrets and renew certificates
 	if cr.Status.Phase == enterprisev1.PhaseReady {
 		result.Requeue = false
+		setAppRepoPollRequeue(&result, &cr.Spec.AppRepo)
+		secretsManager.setRequeue(&result)
+		tlsManager.setRequeue(&result)
 	}
 	return result, nil
 }
@@ -133,6 +226,20 @@ type SearchHeadClusterPodManager struct {
 	cr              *enterprisev1.SearchHeadCluster
 	secrets         *corev1.Secret
 	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+
+	// events is used to publish events for the search head cluster (optional)
+	events *eventPublisher
+
+	// serviceAccount is used to poll the members (optional, defaults to the admin user)
+	serviceAccount *ServiceAccount
+
+	// poller is used to poll the members concurrently, caching their status between reconciles (optional, defaults
+	// to a poller shared by all reconciles of the search head cluster)
+	poller *statusPoller
+
+	// client and statefulSet are set by Update, and used to find the members whose pods have already been updated
+	client      ControllerClient
+	statefulSet *appsv1.StatefulSet
 }
 
 // Update for SearchHeadClusterPodManager handles all updates for a statefulset of search heads
@@ -142,6 +249,8 @@ func (mgr *SearchHeadClusterPodManager) Update(c ControllerClient, statefulSet *
 	if err != nil {
 		return enterprisev1.PhaseError, err
 	}
+	mgr.client = c
+	mgr.statefulSet = statefulSet
 
 	// update CR status with SHC information
 	err = mgr.updateStatus(statefulSet)
@@ -165,8 +274,10 @@ func (mgr *SearchHeadClusterPodManager) PrepareScaleDown(n int32) (bool, error)
 	// pod is quarantined; decommission it
 	memberName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkSearchHead, mgr.cr.GetIdentifier(), n)
 	mgr.log.Info("Removing member from search head cluster", "memberName", memberName)
+	mgr.events.Normal("RemovingMember", "Removing member %s from search head cluster", memberName)
 	c := mgr.getClient(n)
 	err = c.RemoveSearchHeadClusterMember()
+	mgr.invalidateStatus(n)
 	if err != nil {
 		return false, err
 	}
@@ -178,12 +289,32 @@ func (mgr *SearchHeadClusterPodManager) PrepareScaleDown(n int32) (bool, error)
 // PrepareRecycle for SearchHeadClusterPodManager prepares search head pod to be recycled for updates; it returns true when ready
 func (mgr *SearchHeadClusterPodManager) PrepareRecycle(n int32) (bool, error) {
 	memberName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkSearchHead, mgr.cr.GetIdentifier(), n)
+	if mgr.cr.Status.Members[n].Stale {
+		mgr.log.Info("Waiting for an up to date status", "memberName", memberName)
+		return false, nil
+	}
 
 	switch mgr.cr.Status.Members[n].Status {
 	case "Up":
+		// never detain a member while the deployer is pushing a bundle
+		if mgr.cr.Status.DeployerBundle.PushInProgress {
+			mgr.log.Info("Waiting for deployer bundle push to complete", "memberName", memberName)
+			return false, nil
+		}
+
+		// transfer captaincy before detaining the captain, to avoid an election while its searches drain
+		if memberName == mgr.cr.Status.Captain {
+			transferred, err := mgr.transferCaptaincy(n)
+			if err != nil || transferred {
+				return false, err
+			}
+		}
+
 		// Detain search head
 		mgr.log.Info("Detaining search head cluster member", "memberName", memberName)
+		mgr.events.Normal("DetainingMember", "Detaining search head cluster member %s", memberName)
 		c := mgr.getClient(n)
+		mgr.invalidateStatus(n)
 		return false, c.SetSearchHeadDetention(true)
 
 	case "ManualDetention":
@@ -208,6 +339,10 @@ func (mgr *SearchHeadClusterPodManager) PrepareRecycle(n int32) (bool, error) {
 // FinishRecycle for SearchHeadClusterPodManager completes recycle event for search head pod; it returns true when complete
 func (mgr *SearchHeadClusterPodManager) FinishRecycle(n int32) (bool, error) {
 	memberName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkSearchHead, mgr.cr.GetIdentifier(), n)
+	if mgr.cr.Status.Members[n].Stale {
+		mgr.log.Info("Waiting for an up to date status", "memberName", memberName)
+		return false, nil
+	}
 
 	switch mgr.cr.Status.Members[n].Status {
 	case "Up":
@@ -217,7 +352,9 @@ func (mgr *SearchHeadClusterPodManager) FinishRecycle(n int32) (bool, error) {
 	case "ManualDetention":
 		// release from detention
 		mgr.log.Info("Releasing search head cluster member from detention", "memberName", memberName)
+		mgr.events.Normal("ReleasingMember", "Releasing search head cluster member %s from detention", memberName)
 		c := mgr.getClient(n)
+		mgr.invalidateStatus(n)
 		return false, c.SetSearchHeadDetention(false)
 	}
 
@@ -225,15 +362,100 @@ func (mgr *SearchHeadClusterPodManager) FinishRecycle(n int32) (bool, error) {
 	return false, fmt.Errorf("Status=%s", mgr.cr.Status.Members[n].Status)
 }
 
-// getClient for SearchHeadClusterPodManager returns a SplunkClient for the member n
-func (mgr *SearchHeadClusterPodManager) getClient(n int32) *splclient.SplunkClient {
+// GetUpdateStrategy for SearchHeadClusterPodManager returns the strategy used to recycle search head pods
+func (mgr *SearchHeadClusterPodManager) GetUpdateStrategy() enterprisev1.UpdateStrategySpec {
+	return mgr.cr.Spec.UpdateStrategy
+}
+
+// GetRecycleOrder for SearchHeadClusterPodManager returns the ordinals of all search head pods in reverse order,
+// except that the current captain is always recycled last
+func (mgr *SearchHeadClusterPodManager) GetRecycleOrder(replicas int32) []int32 {
+	order := getDefaultRecycleOrder(replicas)
+	for i, n := range order {
+		if n < int32(len(mgr.cr.Status.Members)) && mgr.cr.Status.Members[n].Name == mgr.cr.Status.Captain {
+			order = append(append(order[:i:i], order[i+1:]...), n)
+			break
+		}
+	}
+	return order
+}
+
+// transferCaptaincy for SearchHeadClusterPodManager transfers captaincy from member n to another member that is up
+// and whose pod has already been updated, so that captaincy is not given to a member that is about to be recycled.
+// The captain is usually recycled last, but other members may not have been updated yet when a partition or
+// pauseAfter is used, or if a new captain was elected during the rollout. It returns false if there is no other
+// member to transfer captaincy to.
+func (mgr *SearchHeadClusterPodManager) transferCaptaincy(n int32) (bool, error) {
+	memberName := mgr.cr.Status.Members[n].Name
+	for m, member := range mgr.cr.Status.Members {
+		if int32(m) == n || member.Status != "Up" {
+			continue
+		}
+		updated, err := mgr.isMemberUpdated(int32(m))
+		if err != nil {
+			return false, err
+		}
+		if !updated {
+			continue
+		}
+		mgr.log.Info("Transferring search head cluster captaincy", "memberName", memberName, "newCaptain", member.Name)
+		c := mgr.getClient(n)
+		err = c.TransferSearchHeadCaptaincy(mgr.getMemberURI(int32(m)))
+		mgr.invalidateStatus(n)
+		mgr.invalidateStatus(int32(m))
+		if err != nil {
+			mgr.events.Warning("RESTAPIFailed", "Unable to transfer captaincy from %s to %s: %v", memberName, member.Name, err)
+			return false, err
+		}
+		mgr.events.Normal("TransferringCaptain", "Transferring search head cluster captaincy from %s to %s", memberName, member.Name)
+		return true, nil
+	}
+	mgr.log.Info("No other updated member is up to transfer captaincy to", "memberName", memberName)
+	return false, nil
+}
+
+// isMemberUpdated for SearchHeadClusterPodManager returns true if the pod for member n uses the latest revision of
+// the statefulset
+func (mgr *SearchHeadClusterPodManager) isMemberUpdated(n int32) (bool, error) {
+	if mgr.statefulSet.Status.UpdateRevision == "" {
+		return true, nil
+	}
+	namespacedName := types.NamespacedName{
+		Namespace: mgr.statefulSet.GetNamespace(),
+		Name:      fmt.Sprintf("%s-%d", mgr.statefulSet.GetName(), n),
+	}
+	var pod corev1.Pod
+	err := mgr.client.Get(context.TODO(), namespacedName, &pod)
+	if err != nil {
+		return false, err
+	}
+	return pod.GetLabels()["controller-revision-hash"] == mgr.statefulSet.Status.UpdateRevision, nil
+}
+
+// getMemberURI for SearchHeadClusterPodManager returns the management URI for the member n
+func (mgr *SearchHeadClusterPodManager) getMemberURI(n int32) string {
 	memberName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkSearchHead, mgr.cr.GetIdentifier(), n)
 	fqdnName := resources.GetServiceFQDN(mgr.cr.GetNamespace(),
 		fmt.Sprintf("%s.%s", memberName, enterprise.GetSplunkServiceName(enterprise.SplunkSearchHead, mgr.cr.GetIdentifier(), true)))
-	return mgr.newSplunkClient(fmt.Sprintf("https://%s:8089", fqdnName), "admin", string(mgr.secrets.Data["password"]))
+	return fmt.Sprintf("https://%s:8089", fqdnName)
+}
+
+// getClient for SearchHeadClusterPodManager returns a SplunkClient for the member n
+func (mgr *SearchHeadClusterPodManager) getClient(n int32) *splclient.SplunkClient {
+	return mgr.newSplunkClient(mgr.getMemberURI(n), "admin", string(mgr.secrets.Data["password"]))
+}
+
+// getStatusClient for SearchHeadClusterPodManager returns a SplunkClient used to poll the member n; logging in as
+// the service account is cancelled when ctx is done
+func (mgr *SearchHeadClusterPodManager) getStatusClient(ctx context.Context, n int32) (*splclient.SplunkClient, error) {
+	if mgr.serviceAccount != nil {
+		return mgr.serviceAccount.getClient(ctx, mgr.getMemberURI(n))
+	}
+	return mgr.getClient(n), nil
 }
 
-// updateStatus for SearchHeadClusterPodManager uses the REST API to update the status for a SearcHead custom resource
+// updateStatus for SearchHeadClusterPodManager uses the REST API to update the status for a SearcHead custom resource.
+// Members are polled concurrently, and members that cannot be polled keep their previous status but are marked stale.
 func (mgr *SearchHeadClusterPodManager) updateStatus(statefulSet *appsv1.StatefulSet) error {
 	// populate members status using REST API to get search head cluster member info
 	mgr.cr.Status.Captain = ""
@@ -242,38 +464,38 @@ func (mgr *SearchHeadClusterPodManager) updateStatus(statefulSet *appsv1.Statefu
 	if mgr.cr.Status.ReadyReplicas == 0 {
 		return nil
 	}
-	gotCaptainInfo := false
-	for n := int32(0); n < statefulSet.Status.Replicas; n++ {
-		c := mgr.getClient(n)
-		memberName := enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkSearchHead, mgr.cr.GetIdentifier(), n)
-		memberStatus := enterprisev1.SearchHeadClusterMemberStatus{Name: memberName}
-		memberInfo, err := c.GetSearchHeadClusterMemberInfo()
-		if err == nil {
+	memberNames := make([]string, statefulSet.Status.Replicas)
+	for n := range memberNames {
+		memberNames[n] = enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkSearchHead, mgr.cr.GetIdentifier(), int32(n))
+	}
+	results := mgr.getPoller().poll(memberNames, func(ctx context.Context, n int) (interface{}, error) {
+		c, err := mgr.getStatusClient(ctx, int32(n))
+		if err != nil {
+			return nil, err
+		}
+		return c.GetSearchHeadClusterMemberInfoContext(ctx)
+	})
+
+	captainMember := -1
+	for n, result := range results {
+		memberName := memberNames[n]
+		memberStatus := enterprisev1.SearchHeadClusterMemberStatus{Name: memberName, Stale: result.stale}
+		if result.err != nil {
+			mgr.log.Error(result.err, "Unable to retrieve search head cluster member info", "memberName", memberName)
+			mgr.events.RESTAPIFailed(result.err, "Unable to retrieve search head cluster member info for %s: %v", memberName, result.err)
+		}
+		if memberInfo, ok := result.value.(*splclient.SearchHeadClusterMemberInfo); ok {
 			memberStatus.Status = memberInfo.Status
 			memberStatus.Adhoc = memberInfo.Adhoc
 			memberStatus.Registered = memberInfo.Registered
 			memberStatus.ActiveHistoricalSearchCount = memberInfo.ActiveHistoricalSearchCount
 			memberStatus.ActiveRealtimeSearchCount = memberInfo.ActiveRealtimeSearchCount
-		} else {
-			mgr.log.Error(err, "Unable to retrieve search head cluster member info", "memberName", memberName)
-		}
-
-		if err == nil && !gotCaptainInfo {
-			// try querying captain api; note that this should work on any node
-			captainInfo, err := c.GetSearchHeadCaptainInfo()
-			if err == nil {
-				mgr.cr.Status.Captain = captainInfo.Label
-				mgr.cr.Status.CaptainReady = captainInfo.ServiceReady
-				mgr.cr.Status.Initialized = captainInfo.Initialized
-				mgr.cr.Status.MinPeersJoined = captainInfo.MinPeersJoined
-				mgr.cr.Status.MaintenanceMode = captainInfo.MaintenanceMode
-				gotCaptainInfo = true
-			} else {
-				mgr.log.Error(err, "Unable to retrieve captain info", "memberName", memberName)
+			if captainMember < 0 && !result.stale {
+				captainMember = n
 			}
 		}
 
-		if n < int32(len(mgr.cr.Status.Members)) {
+		if n < len(mgr.cr.Status.Members) {
 			mgr.cr.Status.Members[n] = memberStatus
 		} else {
 			mgr.cr.Status.Members = append(mgr.cr.Status.Members, memberStatus)
@@ -285,5 +507,269 @@ func (mgr *SearchHeadClusterPodManager) updateStatus(statefulSet *appsv1.Statefu
 		mgr.cr.Status.Members = mgr.cr.Status.Members[:statefulSet.Status.Replicas]
 	}
 
+	// query the captain api using a member that is up to date; note that this should work on any member
+	if captainMember >= 0 {
+		memberName := memberNames[captainMember]
+		ctx, cancel := context.WithTimeout(context.Background(), searchHeadClusterPollerSettings.timeout)
+		defer cancel()
+		c, err := mgr.getStatusClient(ctx, int32(captainMember))
+		var captainInfo *splclient.SearchHeadCaptainInfo
+		if err == nil {
+			captainInfo, err = c.GetSearchHeadCaptainInfoContext(ctx)
+		}
+		if err == nil {
+			mgr.cr.Status.Captain = captainInfo.Label
+			mgr.cr.Status.CaptainReady = captainInfo.ServiceReady
+			mgr.cr.Status.Initialized = captainInfo.Initialized
+			mgr.cr.Status.MinPeersJoined = captainInfo.MinPeersJoined
+			mgr.cr.Status.MaintenanceMode = captainInfo.MaintenanceMode
+		} else {
+			mgr.log.Error(err, "Unable to retrieve captain info", "memberName", memberName)
+			mgr.events.RESTAPIFailed(err, "Unable to retrieve captain info from %s: %v", memberName, err)
+		}
+	}
+
 	return nil
 }
+
+// getPoller for SearchHeadClusterPodManager returns the statusPoller used to poll the members
+func (mgr *SearchHeadClusterPodManager) getPoller() *statusPoller {
+	if mgr.poller == nil {
+		mgr.poller = defaultStatusPollers.get(mgr.cr, searchHeadClusterPollerSettings)
+	}
+	return mgr.poller
+}
+
+// invalidateStatus for SearchHeadClusterPodManager discards the cached status of member n, after making changes to it
+func (mgr *SearchHeadClusterPodManager) invalidateStatus(n int32) {
+	mgr.getPoller().invalidate(enterprise.GetSplunkStatefulsetPodName(enterprise.SplunkSearchHead, mgr.cr.GetIdentifier(), n))
+}
+
+// refreshPausedSearchHeadClusterStatus refreshes the status of a search head cluster while reconcile is paused,
+// without modifying anything
+func refreshPausedSearchHeadClusterStatus(client ControllerClient, cr *enterprisev1.SearchHeadCluster, tracker *statusTracker) error {
+	tracker.begin(stepDeployer)
+	statefulSet, err := enterprise.GetDeployerStatefulSet(cr)
+	if err != nil {
+		return err
+	}
+	cr.Status.DeployerPhase = GetStatefulSetPhase(client, statefulSet)
+	tracker.setPhase(cr.Status.DeployerPhase)
+
+	tracker.begin(stepPods)
+	statefulSet, err = enterprise.GetSearchHeadStatefulSet(cr)
+	if err != nil {
+		return err
+	}
+	cr.Status.Phase = GetStatefulSetPhase(client, statefulSet)
+	cr.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
+	tracker.setPhase(cr.Status.Phase)
+	return nil
+}
+
+// DeployerBundleManager is used to push the contents of a search head cluster's bundle ConfigMap from the deployer to its members
+type DeployerBundleManager struct {
+	log             logr.Logger
+	cr              *enterprisev1.SearchHeadCluster
+	secrets         *corev1.Secret
+	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+
+	// events is used to publish events for the search head cluster (optional)
+	events *eventPublisher
+
+	// pushes is used to track pushes between reconciles (optional, defaults to a cache shared by all search head
+	// clusters)
+	pushes *bundlePushCache
+}
+
+// Update for DeployerBundleManager pushes the deployer bundle to the current captain whenever the contents of the bundle
+// ConfigMap change; it returns true when the current contents have been pushed. Pushes can take a long time, so they
+// run in the background, and each reconcile checks whether the push in progress has finished.
+func (mgr *DeployerBundleManager) Update(client ControllerClient) (bool, error) {
+	status := &mgr.cr.Status.DeployerBundle
+	ref := mgr.cr.Spec.BundleConfigMapRef
+	if ref == "" {
+		mgr.getPushes().remove(mgr.cr)
+		*status = enterprisev1.SearchHeadClusterBundleStatus{}
+		return true, nil
+	}
+
+	// wait for a push in progress to finish before checking for new changes
+	if status.PushInProgress {
+		done, err := mgr.checkPush()
+		if !done || err != nil {
+			return false, err
+		}
+	}
+
+	// nothing to do if the current contents have already been pushed
+	namespacedName := types.NamespacedName{Namespace: mgr.cr.GetNamespace(), Name: ref}
+	var configMap corev1.ConfigMap
+	err := client.Get(context.TODO(), namespacedName, &configMap)
+	if err != nil {
+		return false, fmt.Errorf("Unable to get bundle ConfigMap %s: %v", namespacedName, err)
+	}
+	checksum := enterprise.GetClusterBundleChecksum(&configMap)
+	if checksum == status.AppliedChecksum {
+		status.PendingChecksum = ""
+		status.ChangeDetectedTime = nil
+		return true, nil
+	}
+
+	// give the kubelet time to refresh the mounted ConfigMap; a push that failed is retried right away
+	if checksum != status.PendingChecksum || status.ChangeDetectedTime == nil {
+		mgr.log.Info("Detected changes to bundle ConfigMap", "configMap", ref, "checksum", checksum)
+		now := metav1.Now()
+		status.PendingChecksum = checksum
+		status.ChangeDetectedTime = &now
+	}
+	if time.Since(status.ChangeDetectedTime.Time) < bundleSyncTimeout {
+		mgr.log.Info("Waiting for bundle ConfigMap to be refreshed", "configMap", ref)
+		return false, nil
+	}
+
+	// never push while a member is detained
+	for _, member := range mgr.cr.Status.Members {
+		if member.Status == "ManualDetention" {
+			mgr.log.Info("Waiting for member to be released from detention before pushing bundle", "memberName", member.Name)
+			return false, nil
+		}
+	}
+
+	// start pushing the bundle to the current captain
+	captainURI, err := mgr.getCaptainURI()
+	if err != nil {
+		return false, err
+	}
+	mgr.log.Info("Pushing deployer bundle", "captain", mgr.cr.Status.Captain, "checksum", checksum)
+	now := metav1.Now()
+	status.PushInProgress = true
+	status.LastPushTime = &now
+	c := mgr.getDeployerClient()
+	captain := mgr.cr.Status.Captain
+	mgr.getPushes().start(mgr.cr, &bundlePush{checksum: checksum, captain: captain}, func(ctx context.Context) error {
+		return c.PushSearchHeadClusterBundleContext(ctx, captainURI)
+	})
+	return false, nil
+}
+
+// checkPush for DeployerBundleManager returns true once the push in progress has finished, updating the status with
+// its result. A push that is no longer tracked, for example because the operator restarted, is started again.
+func (mgr *DeployerBundleManager) checkPush() (bool, error) {
+	status := &mgr.cr.Status.DeployerBundle
+	pushes := mgr.getPushes()
+	push := pushes.get(mgr.cr)
+	if push == nil {
+		mgr.log.Info("Lost track of deployer bundle push, pushing again", "checksum", status.PendingChecksum)
+		status.PushInProgress = false
+		return true, nil
+	}
+	select {
+	case <-push.done:
+	default:
+		mgr.log.Info("Waiting for deployer bundle push to complete", "captain", push.captain, "checksum", push.checksum)
+		return false, nil
+	}
+
+	pushes.remove(mgr.cr)
+	status.PushInProgress = false
+	if push.err != nil {
+		mgr.events.Warning("RESTAPIFailed", "Unable to push deployer bundle to captain %s: %v", push.captain, push.err)
+		return false, push.err
+	}
+	mgr.events.Normal("PushedBundle", "Pushed deployer bundle from ConfigMap %s to captain %s", mgr.cr.Spec.BundleConfigMapRef, push.captain)
+	status.AppliedChecksum = push.checksum
+	return true, nil
+}
+
+// getPushes for DeployerBundleManager returns the bundlePushCache used to track pushes
+func (mgr *DeployerBundleManager) getPushes() *bundlePushCache {
+	if mgr.pushes == nil {
+		return defaultBundlePushes
+	}
+	return mgr.pushes
+}
+
+// getCaptainURI for DeployerBundleManager returns the management URI of the current captain
+func (mgr *DeployerBundleManager) getCaptainURI() (string, error) {
+	for _, member := range mgr.cr.Status.Members {
+		if member.Name == mgr.cr.Status.Captain {
+			fqdnName := resources.GetServiceFQDN(mgr.cr.GetNamespace(),
+				fmt.Sprintf("%s.%s", member.Name, enterprise.GetSplunkServiceName(enterprise.SplunkSearchHead, mgr.cr.GetIdentifier(), true)))
+			return fmt.Sprintf("https://%s:8089", fqdnName), nil
+		}
+	}
+	return "", fmt.Errorf("Unable to find captain \"%s\" in search head cluster members", mgr.cr.Status.Captain)
+}
+
+// getDeployerClient for DeployerBundleManager returns a SplunkClient for the deployer
+func (mgr *DeployerBundleManager) getDeployerClient() *splclient.SplunkClient {
+	fqdnName := resources.GetServiceFQDN(mgr.cr.GetNamespace(), enterprise.GetSplunkServiceName(enterprise.SplunkDeployer, mgr.cr.GetIdentifier(), false))
+	return mgr.newSplunkClient(fmt.Sprintf("https://%s:8089", fqdnName), "admin", string(mgr.secrets.Data["password"]))
+}
+
+// bundlePush is a push of a deployer bundle that runs in the background
+type bundlePush struct {
+	// checksum of the bundle ConfigMap contents being pushed
+	checksum string
+
+	// name or label of the captain that the bundle is pushed to
+	captain string
+
+	// done is closed once the push has finished, after setting err
+	done chan struct{}
+	err  error
+
+	// cancel aborts the push, if it has not finished yet
+	cancel context.CancelFunc
+}
+
+// bundlePushCache is used to track the deployer bundle push in progress for each search head cluster between reconciles
+type bundlePushCache struct {
+	mutex  sync.Mutex
+	pushes map[string]*bundlePush
+}
+
+// defaultBundlePushes is the bundlePushCache used by deployer bundle managers, unless they are given another one
+var defaultBundlePushes = &bundlePushCache{}
+
+// start for bundlePushCache runs fn in the background to make push for a custom resource, unless a push is already
+// tracked for it. The context passed to fn is cancelled when the push is removed.
+func (cache *bundlePushCache) start(cr enterprisev1.MetaObject, push *bundlePush, fn func(ctx context.Context) error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if cache.pushes == nil {
+		cache.pushes = make(map[string]*bundlePush)
+	}
+	key := getStatusPollerKey(cr)
+	if _, ok := cache.pushes[key]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	push.done = make(chan struct{})
+	push.cancel = cancel
+	cache.pushes[key] = push
+	go func() {
+		defer cancel()
+		push.err = fn(ctx)
+		close(push.done)
+	}()
+}
+
+// get for bundlePushCache returns the push tracked for a custom resource, or nil if there is none
+func (cache *bundlePushCache) get(cr enterprisev1.MetaObject) *bundlePush {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.pushes[getStatusPollerKey(cr)]
+}
+
+// remove for bundlePushCache stops tracking the push for a custom resource, and aborts it if it has not finished yet
+func (cache *bundlePushCache) remove(cr enterprisev1.MetaObject) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	key := getStatusPollerKey(cr)
+	if push, ok := cache.pushes[key]; ok {
+		push.cancel()
+		delete(cache.pushes, key)
+	}
+}
@@ -15,6 +15,10 @@
 package reconcile
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -24,22 +28,25 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
 	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
 )
 
 func TestApplySearchHeadCluster(t *testing.T) {
 	funcCalls := []mockFuncCall{
 		{metaName: "*v1.Secret-test-splunk-stack1-search-head-secrets"},
+		{metaName: "*v1.Secret-test-splunk-stack1-search-head-service-account"},
 		{metaName: "*v1.Service-test-splunk-stack1-search-head-headless"},
 		{metaName: "*v1.Service-test-splunk-stack1-search-head-service"},
 		{metaName: "*v1.Service-test-splunk-stack1-deployer-service"},
 		{metaName: "*v1.StatefulSet-test-splunk-stack1-deployer"},
 		{metaName: "*v1.StatefulSet-test-splunk-stack1-search-head"},
 	}
-	createCalls := map[string][]mockFuncCall{"Get": funcCalls, "Create": funcCalls}
-	updateCalls := map[string][]mockFuncCall{"Get": funcCalls, "Update": []mockFuncCall{funcCalls[4], funcCalls[5]}}
+	getCalls := []mockFuncCall{funcCalls[0], funcCalls[1], funcCalls[2], funcCalls[3], funcCalls[4], funcCalls[0], funcCalls[5], funcCalls[0], funcCalls[6]}
+	createCalls := map[string][]mockFuncCall{"Get": getCalls, "Create": funcCalls}
+	updateCalls := map[string][]mockFuncCall{"Get": getCalls, "Update": []mockFuncCall{funcCalls[5], funcCalls[6]}}
 	statefulSet := enterprisev1.SearchHeadCluster{
 		TypeMeta: metav1.TypeMeta{
 			Kind: "SearchHeadCluster",
@@ -101,8 +108,11 @@ func searchHeadClusterPodManagerTester(t *testing.T, method string, mockHandlers
 		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
 			c := splclient.NewSplunkClient(managementURI, username, password)
 			c.Client = mockSplunkClient
+			c.Retry = splclient.RetryPolicy{}
 			return c
 		},
+		// poll one member at a time, so that requests are made in order
+		poller: newStatusPoller(statusPollerSettings{workers: 1, ttl: time.Minute, timeout: time.Second}),
 	}
 	podManagerUpdateTester(t, method, mgr, desiredReplicas, wantPhase, statefulSet, wantCalls, wantError, initObjects...)
 	mockSplunkClient.CheckRequests(t, method)
@@ -217,8 +227,9 @@ func TestSearchHeadClusterPodManager(t *testing.T) {
 	method = "SearchHeadClusterPodManager.Update(Release Quarantine)"
 	searchHeadClusterPodManagerTester(t, method, mockHandlers, 1, enterprisev1.PhaseUpdating, statefulSet, wantCalls, nil, statefulSet, pod)
 
-	// test scale down => remove member
-	mockHandlers[2] = spltest.MockHTTPHandler{
+	// test scale down => remove member; all members are polled before the captain
+	mockHandlers[2] = mockHandlers[1]
+	mockHandlers[1] = spltest.MockHTTPHandler{
 		Method: "GET",
 		URL:    "https://splunk-stack1-search-head-1.splunk-stack1-search-head-headless.test.svc.cluster.local:8089/services/shcluster/member/info?count=0&output_mode=json",
 		Status: 200,
@@ -251,3 +262,387 @@ func TestSearchHeadClusterPodManager(t *testing.T) {
 	method = "SearchHeadClusterPodManager.Update(Remove Member)"
 	searchHeadClusterPodManagerTester(t, method, mockHandlers, 1, enterprisev1.PhaseScalingDown, statefulSet, wantCalls, nil, statefulSet, pod, pvcList[0], pvcList[1])
 }
+
+func TestSearchHeadClusterPodManagerCaptainTransfer(t *testing.T) {
+	method := "SearchHeadClusterPodManager.PrepareRecycle()"
+	cr := enterprisev1.SearchHeadCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cr.Status.Captain = "splunk-stack1-search-head-1"
+	cr.Status.Members = []enterprisev1.SearchHeadClusterMemberStatus{
+		{Name: "splunk-stack1-search-head-0", Status: "Up"},
+		{Name: "splunk-stack1-search-head-1", Status: "Up"},
+		{Name: "splunk-stack1-search-head-2", Status: "Up"},
+	}
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-search-head", Namespace: "test"},
+		Status:     appsv1.StatefulSetStatus{UpdateRevision: "v1"},
+	}
+	c := newMockClient()
+	setPodRevision := func(n int, revision string) {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("splunk-stack1-search-head-%d", n),
+			Namespace: "test",
+			Labels:    map[string]string{"controller-revision-hash": revision},
+		}}
+		c.state[getStateKey(pod)] = pod
+	}
+	for n := 0; n < 3; n++ {
+		setPodRevision(n, "v1")
+	}
+	var mockSplunkClient *spltest.MockHTTPClient
+	mgr := SearchHeadClusterPodManager{
+		log:     log.WithName(method),
+		cr:      &cr,
+		secrets: &corev1.Secret{Data: map[string][]byte{"password": []byte{'1', '2', '3'}}},
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+		events:      newEventPublisher(c, &cr),
+		client:      c,
+		statefulSet: statefulSet,
+	}
+	test := func(n int32, handlers ...spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(handlers...)
+		ready, err := mgr.PrepareRecycle(n)
+		if ready || err != nil {
+			t.Errorf("%s = %t,%v; want false,nil", method, ready, err)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+	}
+
+	// the captain is recycled last
+	wantOrder := []int32{2, 0, 1}
+	if got := mgr.GetRecycleOrder(3); !reflect.DeepEqual(got, wantOrder) {
+		t.Errorf("SearchHeadClusterPodManager.GetRecycleOrder() = %v; want %v", got, wantOrder)
+	}
+	cr.Status.Captain = ""
+	wantOrder = []int32{2, 1, 0}
+	if got := mgr.GetRecycleOrder(3); !reflect.DeepEqual(got, wantOrder) {
+		t.Errorf("SearchHeadClusterPodManager.GetRecycleOrder() = %v; want %v", got, wantOrder)
+	}
+	cr.Status.Captain = "splunk-stack1-search-head-1"
+
+	// captaincy is transferred to another member before detaining the captain
+	memberURL := "https://splunk-stack1-search-head-%d.splunk-stack1-search-head-headless.test.svc.cluster.local:8089"
+	test(1, spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    fmt.Sprintf(memberURL, 1) + "/services/shcluster/member/control/control/transfer_captaincy",
+		Status: 200,
+	})
+	if len(*c.events) != 1 || (*c.events)[0].reason != "TransferringCaptain" || !strings.HasSuffix((*c.events)[0].message, "to splunk-stack1-search-head-0") {
+		t.Errorf("%s events = %v; want TransferringCaptain to splunk-stack1-search-head-0", method, *c.events)
+	}
+
+	// captaincy is only transferred to members that have already been updated
+	setPodRevision(0, "v0")
+	test(1, spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    fmt.Sprintf(memberURL, 1) + "/services/shcluster/member/control/control/transfer_captaincy",
+		Status: 200,
+	})
+	if len(*c.events) != 2 || !strings.HasSuffix((*c.events)[1].message, "to splunk-stack1-search-head-2") {
+		t.Errorf("%s events = %v; want TransferringCaptain to splunk-stack1-search-head-2", method, *c.events)
+	}
+
+	// the captain is detained without transferring captaincy if no other member has been updated
+	setPodRevision(2, "v0")
+	test(1, spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    fmt.Sprintf(memberURL, 1) + "/services/shcluster/member/control/control/set_manual_detention?manual_detention=on",
+		Status: 200,
+	})
+	if len(*c.events) != 3 || (*c.events)[2].reason != "DetainingMember" {
+		t.Errorf("%s events = %v; want DetainingMember", method, *c.events)
+	}
+	setPodRevision(0, "v1")
+	setPodRevision(2, "v1")
+
+	// other members are detained
+	cr.Status.Captain = "splunk-stack1-search-head-0"
+	test(1, spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    fmt.Sprintf(memberURL, 1) + "/services/shcluster/member/control/control/set_manual_detention?manual_detention=on",
+		Status: 200,
+	})
+
+	// the captain is detained if no other member is up
+	cr.Status.Members[1].Status = "Down"
+	cr.Status.Members[2].Status = "Down"
+	test(0, spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    fmt.Sprintf(memberURL, 0) + "/services/shcluster/member/control/control/set_manual_detention?manual_detention=on",
+		Status: 200,
+	})
+}
+
+func TestSearchHeadClusterPodManagerStaleMembers(t *testing.T) {
+	method := "SearchHeadClusterPodManager.updateStatus()"
+	cr := enterprisev1.SearchHeadCluster{
+		TypeMeta:   metav1.TypeMeta{Kind: "SearchHeadCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	var mockSplunkClient *spltest.MockHTTPClient
+	c := newMockClient()
+	mgr := SearchHeadClusterPodManager{
+		log:     log.WithName(method),
+		cr:      &cr,
+		secrets: &corev1.Secret{Data: map[string][]byte{"password": []byte{'1', '2', '3'}}},
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			c.Retry = splclient.RetryPolicy{}
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+		poller: newStatusPoller(statusPollerSettings{workers: 1, ttl: 0, timeout: time.Second}),
+	}
+	statefulSet := &appsv1.StatefulSet{Status: appsv1.StatefulSetStatus{Replicas: 2, ReadyReplicas: 2}}
+	memberURL := "https://splunk-stack1-search-head-%d.splunk-stack1-search-head-headless.test.svc.cluster.local:8089"
+	memberInfo := func(n int, status int) spltest.MockHTTPHandler {
+		return spltest.MockHTTPHandler{
+			Method: "GET",
+			URL:    fmt.Sprintf(memberURL, n) + "/services/shcluster/member/info?count=0&output_mode=json",
+			Status: status,
+			Body:   `{"entry":[{"name":"member","content":{"status":"Up","active_historical_search_count":0}}]}`,
+		}
+	}
+	captainInfo := spltest.MockHTTPHandler{
+		Method: "GET",
+		URL:    fmt.Sprintf(memberURL, 0) + "/services/shcluster/captain/info?count=0&output_mode=json",
+		Status: 200,
+		Body:   `{"entry":[{"name":"captain","content":{"label":"splunk-stack1-search-head-0","service_ready_flag":true,"initialized_flag":true}}]}`,
+	}
+	test := func(wantStale []bool, handlers ...spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(handlers...)
+		if err := mgr.updateStatus(statefulSet); err != nil {
+			t.Errorf("%s returned %v; want nil", method, err)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+		for n, want := range wantStale {
+			if got := cr.Status.Members[n]; got.Stale != want || got.Status != "Up" {
+				t.Errorf("%s Members[%d] = %s, stale=%t; want Up, stale=%t", method, n, got.Status, got.Stale, want)
+			}
+		}
+	}
+
+	// all members are up to date
+	test([]bool{false, false}, memberInfo(0, 200), memberInfo(1, 200), captainInfo)
+
+	// a member that fails keeps its previous status, but is marked stale
+	test([]bool{false, true}, memberInfo(0, 200), memberInfo(1, 503), captainInfo)
+	if !cr.Status.CaptainReady {
+		t.Errorf("%s CaptainReady = false; want true", method)
+	}
+
+	// stale members are not recycled until their status is up to date
+	mockSplunkClient = &spltest.MockHTTPClient{}
+	if ready, err := mgr.PrepareRecycle(1); ready || err != nil {
+		t.Errorf("SearchHeadClusterPodManager.PrepareRecycle() = %t,%v; want false,nil", ready, err)
+	}
+	mockSplunkClient.CheckRequests(t, "SearchHeadClusterPodManager.PrepareRecycle()")
+}
+
+func TestDeployerBundleManager(t *testing.T) {
+	method := "DeployerBundleManager.Update()"
+	cr := enterprisev1.SearchHeadCluster{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "SearchHeadCluster",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	cr.Status.Captain = "splunk-stack1-search-head-1"
+	cr.Status.Members = []enterprisev1.SearchHeadClusterMemberStatus{
+		{Name: "splunk-stack1-search-head-0", Status: "Up"},
+		{Name: "splunk-stack1-search-head-1", Status: "Up"},
+	}
+	secrets := &corev1.Secret{
+		Data: map[string][]byte{
+			"password": []byte{'1', '2', '3'},
+		},
+	}
+	pushHandler := spltest.MockHTTPHandler{
+		Method: "POST",
+		URL:    "https://splunk-stack1-deployer-service.test.svc.cluster.local:8089/services/apps/deploy",
+		Status: 200,
+	}
+
+	c := newMockClient()
+	var mockSplunkClient *spltest.MockHTTPClient
+	release := make(chan struct{})
+	close(release)
+	mgr := DeployerBundleManager{
+		log:     log.WithName(method),
+		cr:      &cr,
+		secrets: secrets,
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = &blockingHTTPClient{release: release, client: mockSplunkClient}
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+		pushes: &bundlePushCache{},
+	}
+	waitForPush := func() {
+		if push := mgr.pushes.get(&cr); push != nil {
+			<-push.done
+		}
+	}
+	test := func(want bool, wantErr string, handlers ...spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(handlers...)
+		got, err := mgr.Update(c)
+		if got != want || (err == nil && wantErr != "") || (err != nil && err.Error() != wantErr) {
+			t.Errorf("%s = %t,%v; want %t,%s", method, got, err, want, wantErr)
+		}
+		waitForPush()
+		mockSplunkClient.CheckRequests(t, method)
+	}
+
+	// nothing to do without a bundle ConfigMap
+	test(true, "")
+
+	// bundle ConfigMap must exist
+	cr.Spec.BundleConfigMapRef = "bundle1"
+	test(false, "Unable to get bundle ConfigMap test/bundle1: NotFound")
+
+	// changes are not pushed until the kubelet has had time to refresh the ConfigMap
+	configMap := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "bundle1", Namespace: "test"},
+		Data:       map[string]string{"savedsearches.conf": "[errors]\n"},
+	}
+	c.Create(context.TODO(), &configMap)
+	test(false, "")
+	checksum := enterprise.GetClusterBundleChecksum(&configMap)
+	if cr.Status.DeployerBundle.PendingChecksum != checksum || cr.Status.DeployerBundle.ChangeDetectedTime == nil {
+		t.Errorf("%s status = %+v; want pending checksum and change detected time", method, cr.Status.DeployerBundle)
+	}
+	test(false, "")
+	detected := metav1.NewTime(time.Now().Add(-bundleSyncTimeout))
+	cr.Status.DeployerBundle.ChangeDetectedTime = &detected
+
+	// never push while a member is detained
+	cr.Status.Members[0].Status = "ManualDetention"
+	test(false, "")
+	cr.Status.Members[0].Status = "Up"
+
+	// the captain must be known
+	cr.Status.Captain = ""
+	test(false, "Unable to find captain \"\" in search head cluster members")
+	cr.Status.Captain = "splunk-stack1-search-head-1"
+
+	// pushes run in the background, and failures are reported by the next reconcile
+	failedHandler := pushHandler
+	failedHandler.Status = 500
+	test(false, "", failedHandler)
+	if !cr.Status.DeployerBundle.PushInProgress || cr.Status.DeployerBundle.LastPushTime == nil {
+		t.Errorf("%s status = %+v; want push in progress", method, cr.Status.DeployerBundle)
+	}
+	test(false, "Response code=500 from https://splunk-stack1-deployer-service.test.svc.cluster.local:8089/services/apps/deploy; want 200")
+	if got := cr.Status.DeployerBundle; got.PushInProgress || got.PendingChecksum != checksum || got.AppliedChecksum == checksum {
+		t.Errorf("%s status = %+v; want pending checksum %s without push in progress", method, got, checksum)
+	}
+
+	// failed pushes are retried right away, and remain in progress until they finish
+	release = make(chan struct{})
+	mockSplunkClient = &spltest.MockHTTPClient{}
+	mockSplunkClient.AddHandlers(pushHandler)
+	for i := 0; i < 2; i++ {
+		if got, err := mgr.Update(c); got || err != nil {
+			t.Errorf("%s = %t,%v; want false,nil", method, got, err)
+		}
+		if got := cr.Status.DeployerBundle; !got.PushInProgress || got.AppliedChecksum == checksum {
+			t.Errorf("%s status = %+v; want push in progress", method, got)
+		}
+	}
+
+	// members are not detained while a push is in progress
+	podManager := SearchHeadClusterPodManager{log: mgr.log, cr: &cr, secrets: secrets, newSplunkClient: mgr.newSplunkClient}
+	if ready, err := podManager.PrepareRecycle(0); ready || err != nil {
+		t.Errorf("SearchHeadClusterPodManager.PrepareRecycle() = %t,%v; want false,nil", ready, err)
+	}
+	close(release)
+	waitForPush()
+	mockSplunkClient.CheckRequests(t, method)
+	test(true, "")
+	if got := cr.Status.DeployerBundle; got.AppliedChecksum != checksum || got.PendingChecksum != "" || got.ChangeDetectedTime != nil || got.PushInProgress {
+		t.Errorf("%s status = %+v; want applied checksum %s", method, got, checksum)
+	}
+	wantReasons := []string{"RESTAPIFailed", "PushedBundle"}
+	if len(*c.events) != len(wantReasons) {
+		t.Fatalf("%s published %d events; want %d", method, len(*c.events), len(wantReasons))
+	}
+	for i, reason := range wantReasons {
+		if (*c.events)[i].reason != reason {
+			t.Errorf("%s event %d reason = %s; want %s", method, i, (*c.events)[i].reason, reason)
+		}
+	}
+
+	// nothing to do until the bundle ConfigMap changes again
+	test(true, "")
+
+	// pushes that are no longer tracked, for example after the operator restarted, are started again
+	configMap.Data["savedsearches.conf"] = "[errors]\nsearch = error\n"
+	c.Update(context.TODO(), &configMap)
+	cr.Status.DeployerBundle.PendingChecksum = enterprise.GetClusterBundleChecksum(&configMap)
+	cr.Status.DeployerBundle.ChangeDetectedTime = &detected
+	cr.Status.DeployerBundle.PushInProgress = true
+	test(false, "", pushHandler)
+	test(true, "")
+	if got := cr.Status.DeployerBundle; got.AppliedChecksum != enterprise.GetClusterBundleChecksum(&configMap) || got.PushInProgress {
+		t.Errorf("%s status = %+v; want applied checksum %s", method, got, enterprise.GetClusterBundleChecksum(&configMap))
+	}
+}
+
+// blockingHTTPClient is used to hold requests until release is closed
+type blockingHTTPClient struct {
+	release chan struct{}
+	client  splclient.SplunkHTTPClient
+}
+
+// Do for blockingHTTPClient waits for release to be closed, and then sends the request using client
+func (c *blockingHTTPClient) Do(request *http.Request) (*http.Response, error) {
+	<-c.release
+	return c.client.Do(request)
+}
+
+func TestBundlePushCacheRemove(t *testing.T) {
+	cache := &bundlePushCache{}
+	cr := enterprisev1.SearchHeadCluster{
+		TypeMeta:   metav1.TypeMeta{Kind: "SearchHeadCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	push := &bundlePush{checksum: "abc", captain: "splunk-stack1-search-head-0"}
+	cache.start(&cr, push, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if cache.get(&cr) != push {
+		t.Errorf("bundlePushCache.get() did not return the push in progress")
+	}
+
+	// removing a push in progress aborts it
+	cache.remove(&cr)
+	select {
+	case <-push.done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("bundlePushCache.remove() did not abort the push in progress")
+	}
+	if push.err != context.Canceled {
+		t.Errorf("bundlePushCache.remove() push error = %v; want %v", push.err, context.Canceled)
+	}
+	if cache.get(&cr) != nil {
+		t.Errorf("bundlePushCache.get() returned a removed push")
+	}
+	cache.remove(&cr)
+}
@@ -0,0 +1,256 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+	"github.com/splunk/splunk-operator/pkg/splunk/resources"
+)
+
+// SecretRotationManager is used to rotate the admin password and pass4SymmKey of a Splunk Enterprise custom resource
+type SecretRotationManager struct {
+	log             logr.Logger
+	cr              enterprisev1.MetaObject
+	spec            *enterprisev1.CommonSplunkSpec
+	status          *enterprisev1.SecretsStatus
+	instanceType    enterprise.InstanceType
+	secrets         *corev1.Secret
+	instanceURIs    []string
+	newSplunkClient func(managementURI, username, password string) *splclient.SplunkClient
+
+	// events is used to publish events for the custom resource (optional)
+	events *eventPublisher
+
+	// now is used to get the current time (optional, defaults to time.Now)
+	now func() time.Time
+}
+
+// Update for SecretRotationManager rotates secrets when requested by the rotate-secrets annotation, or when the
+// rotation interval has elapsed. New values are kept in a separate Secret while the admin password and pass4SymmKey
+// are changed on each instance using the REST API, and are then saved to the resource's Secret. This recycles its
+// pods, so that values which can only be changed by restarting Splunk take effect. When secretRef is used, new values
+// are instead applied whenever the user-managed Secret changes. Rotations are only started while ready is true.
+func (mgr *SecretRotationManager) Update(client ControllerClient, ready bool) error {
+	request := mgr.cr.GetObjectMeta().GetAnnotations()[enterprisev1.RotateSecretsAnnotation]
+	var rotated *corev1.Secret
+	var err error
+	if mgr.spec.SecretRef.Name != "" {
+		rotated, err = mgr.getSecretRefChanges(client)
+		if err != nil || rotated == nil || (!mgr.status.RotationInProgress && !ready) {
+			return err
+		}
+		if !mgr.status.RotationInProgress {
+			mgr.log.Info("Applying changes to secretRef", "secrets", mgr.secrets.GetName(), "secretRef", mgr.spec.SecretRef.Name)
+			mgr.events.Normal("RotatingSecrets", "Rotating secrets %s using values from Secret %s", mgr.secrets.GetName(), mgr.spec.SecretRef.Name)
+			mgr.status.RotationInProgress = true
+		}
+	} else {
+		if !mgr.status.RotationInProgress && (!ready || !mgr.isRotationDue(request)) {
+			return nil
+		}
+		rotated, err = mgr.getRotatedSecrets(client)
+		if err != nil {
+			return err
+		}
+	}
+
+	// apply the new values to each instance
+	for _, uri := range mgr.instanceURIs {
+		if err = mgr.updateInstance(uri, rotated); err != nil {
+			mgr.events.Warning("RESTAPIFailed", "Unable to rotate secrets for %s: %v", uri, err)
+			return err
+		}
+	}
+
+	// save the new values, and clean up
+	if enterprise.UpdateSplunkSecrets(mgr.secrets, rotated.Data) {
+		if err = UpdateResource(client, mgr.secrets); err != nil {
+			return err
+		}
+	}
+	if mgr.spec.SecretRef.Name == "" {
+		if err = client.Delete(context.TODO(), rotated); err != nil {
+			return fmt.Errorf("Unable to delete Secret %s: %v", rotated.GetName(), err)
+		}
+	}
+
+	now := metav1.NewTime(mgr.getTime())
+	mgr.status.Version++
+	mgr.status.RotationInProgress = false
+	mgr.status.RotateRequest = request
+	mgr.status.LastRotationTime = &now
+	mgr.events.Normal("RotatedSecrets", "Rotated secrets %s to version %d", mgr.secrets.GetName(), mgr.status.Version)
+	return nil
+}
+
+// getRotatedSecrets for SecretRotationManager returns the Secret containing new values for a rotation in progress,
+// creating it with new random values when starting a rotation. Values borrowed from other resources are left for
+// those resources to rotate.
+func (mgr *SecretRotationManager) getRotatedSecrets(client ControllerClient) (*corev1.Secret, error) {
+	rotated := enterprise.GetSplunkRotatedSecrets(mgr.cr, mgr.instanceType, !isPass4SymmKeyBorrowed(*mgr.spec, mgr.instanceType))
+	namespacedName := types.NamespacedName{Namespace: rotated.GetNamespace(), Name: rotated.GetName()}
+	var current corev1.Secret
+	err := client.Get(context.TODO(), namespacedName, &current)
+	if err == nil {
+		return &current, nil
+	}
+
+	mgr.log.Info("Starting secret rotation", "secrets", mgr.secrets.GetName())
+	rotated.SetOwnerReferences(append(rotated.GetOwnerReferences(), resources.AsOwner(mgr.cr)))
+	if err = CreateResource(client, rotated); err != nil {
+		return nil, err
+	}
+	mgr.events.Normal("RotatingSecrets", "Rotating secrets %s", mgr.secrets.GetName())
+	mgr.status.RotationInProgress = true
+	return rotated, nil
+}
+
+// getSecretRefChanges for SecretRotationManager returns a Secret containing only the values in the user-managed
+// Secret referenced by secretRef that differ from the resource's Secret, or nil if there are none. Values borrowed
+// from other resources are ignored, since these are updated by ApplySplunkConfig. Changes to idxc_secret and
+// shc_secret are rejected, since every member of a cluster would have to switch to the new value at once.
+func (mgr *SecretRotationManager) getSecretRefChanges(client ControllerClient) (*corev1.Secret, error) {
+	source, err := getSecretRef(client, mgr.cr, mgr.spec)
+	if err != nil {
+		return nil, err
+	}
+	var idxcSecret, pass4SymmKey []byte
+	if isIdxcSecretBorrowed(*mgr.spec, mgr.instanceType) {
+		idxcSecret = mgr.secrets.Data["idxc_secret"]
+	}
+	if isPass4SymmKeyBorrowed(*mgr.spec, mgr.instanceType) {
+		pass4SymmKey = mgr.secrets.Data["pass4SymmKey"]
+	}
+	desired, err := enterprise.GetSplunkSecretsFromRef(mgr.cr, mgr.instanceType, source, idxcSecret, pass4SymmKey)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string][]byte{}
+	for k, v := range desired.Data {
+		if k != "default.yml" && !bytes.Equal(mgr.secrets.Data[k], v) {
+			changes[k] = v
+		}
+	}
+	rejected := []string{}
+	for _, k := range []string{"idxc_secret", "shc_secret"} {
+		if _, ok := changes[k]; ok {
+			rejected = append(rejected, k)
+		}
+	}
+	if len(rejected) > 0 {
+		return nil, fmt.Errorf("Secret %s/%s referenced by secretRef changes %s, which cannot be rotated; restore the previous values",
+			source.GetNamespace(), source.GetName(), strings.Join(rejected, ", "))
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	desired.Data = changes
+	return desired, nil
+}
+
+// setRequeue for SecretRotationManager updates a reconcile result to requeue when the next scheduled rotation is due,
+// unless it is already requeued sooner
+func (mgr *SecretRotationManager) setRequeue(result *reconcile.Result) {
+	if mgr.spec.SecretRotation.Interval == 0 {
+		return
+	}
+	after := mgr.getLastRotationTime().Add(time.Second * time.Duration(mgr.spec.SecretRotation.Interval)).Sub(mgr.getTime())
+	if after < time.Second {
+		after = time.Second
+	}
+	if !result.Requeue || after < result.RequeueAfter {
+		result.Requeue = true
+		result.RequeueAfter = after
+	}
+}
+
+// isRotationDue for SecretRotationManager returns true if a new rotation has been requested, or if the rotation interval has elapsed
+func (mgr *SecretRotationManager) isRotationDue(request string) bool {
+	if request != "" && request != mgr.status.RotateRequest {
+		return true
+	}
+	interval := time.Second * time.Duration(mgr.spec.SecretRotation.Interval)
+	return interval > 0 && !mgr.getTime().Before(mgr.getLastRotationTime().Add(interval))
+}
+
+// getLastRotationTime for SecretRotationManager returns the time secrets were last rotated, or created
+func (mgr *SecretRotationManager) getLastRotationTime() time.Time {
+	if mgr.status.LastRotationTime != nil {
+		return mgr.status.LastRotationTime.Time
+	}
+	return mgr.cr.GetObjectMeta().GetCreationTimestamp().Time
+}
+
+// getTime for SecretRotationManager returns the current time
+func (mgr *SecretRotationManager) getTime() time.Time {
+	if mgr.now != nil {
+		return mgr.now()
+	}
+	return time.Now()
+}
+
+// updateInstance for SecretRotationManager changes the admin password and pass4SymmKey for the instance at uri, if
+// they are included in rotated
+func (mgr *SecretRotationManager) updateInstance(uri string, rotated *corev1.Secret) error {
+	oldPassword := string(mgr.secrets.Data["password"])
+	c := mgr.newSplunkClient(uri, "admin", oldPassword)
+
+	if newPassword, ok := rotated.Data["password"]; ok {
+		// the password may have already been changed by a previous attempt, or replicated by another cluster member
+		c = mgr.newSplunkClient(uri, "admin", string(newPassword))
+		ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+		err := c.GetContext(ctx, "/services/authentication/current-context", nil)
+		cancel()
+		if err != nil {
+			mgr.log.Info("Changing admin password", "uri", uri)
+			err = mgr.newSplunkClient(uri, "admin", oldPassword).SetAdminPassword(oldPassword, string(newPassword))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if pass4SymmKey, ok := rotated.Data["pass4SymmKey"]; ok {
+		return c.SetPass4SymmKey(string(pass4SymmKey))
+	}
+	return nil
+}
+
+// getSplunkInstanceURIs returns the management URIs for each pod in a Splunk Enterprise statefulset
+func getSplunkInstanceURIs(namespace string, instanceType enterprise.InstanceType, identifier string, replicas int32) []string {
+	uris := make([]string, 0, replicas)
+	for n := int32(0); n < replicas; n++ {
+		podName := enterprise.GetSplunkStatefulsetPodName(instanceType, identifier, n)
+		fqdnName := resources.GetServiceFQDN(namespace,
+			fmt.Sprintf("%s.%s", podName, enterprise.GetSplunkServiceName(instanceType, identifier, true)))
+		uris = append(uris, fmt.Sprintf("https://%s:8089", fqdnName))
+	}
+	return uris
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+	spltest "github.com/splunk/splunk-operator/pkg/splunk/test"
+)
+
+func TestSecretRotationManager(t *testing.T) {
+	method := "SecretRotationManager.Update()"
+	now := time.Now()
+	cr := enterprisev1.Standalone{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "Standalone",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "stack1",
+			Namespace:         "test",
+			CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour)),
+		},
+	}
+	c := newMockClient()
+	secrets := enterprise.GetSplunkSecrets(&cr, enterprise.SplunkStandalone, nil, nil)
+	c.state[getStateKey(secrets)] = secrets
+	oldPassword := string(secrets.Data["password"])
+	hecToken := string(secrets.Data["hec_token"])
+	idxcSecret := string(secrets.Data["idxc_secret"])
+
+	var mockSplunkClient *spltest.MockHTTPClient
+	mgr := SecretRotationManager{
+		log:          log.WithName(method),
+		cr:           &cr,
+		spec:         &cr.Spec.CommonSplunkSpec,
+		status:       &cr.Status.Secrets,
+		instanceType: enterprise.SplunkStandalone,
+		secrets:      secrets,
+		instanceURIs: getSplunkInstanceURIs("test", enterprise.SplunkStandalone, "stack1", 2),
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+		now:    func() time.Time { return now },
+	}
+	test := func(ready bool, wantErr string, handlers ...spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(handlers...)
+		err := mgr.Update(c, ready)
+		if (err == nil && wantErr != "") || (err != nil && err.Error() != wantErr) {
+			t.Errorf("%s = %v; want %s", method, err, wantErr)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+	}
+	uri := func(n int) string {
+		return mgr.instanceURIs[n]
+	}
+	contextHandler := func(n, status int) spltest.MockHTTPHandler {
+		return spltest.MockHTTPHandler{Method: "GET", URL: uri(n) + "/services/authentication/current-context?count=0&output_mode=json", Status: status}
+	}
+	passwordHandler := func(n int) spltest.MockHTTPHandler {
+		return spltest.MockHTTPHandler{Method: "POST", URL: uri(n) + "/services/authentication/users/admin", Status: 200}
+	}
+	pass4SymmKeyHandler := func(n, status int) spltest.MockHTTPHandler {
+		return spltest.MockHTTPHandler{Method: "POST", URL: uri(n) + "/services/configs/conf-server/general", Status: status}
+	}
+
+	// nothing to do without a request or interval
+	test(true, "")
+
+	// rotations only start when ready
+	cr.ObjectMeta.Annotations = map[string]string{enterprisev1.RotateSecretsAnnotation: "1"}
+	test(false, "")
+	if cr.Status.Secrets.RotationInProgress {
+		t.Errorf("%s started rotation while not ready", method)
+	}
+
+	// failures leave the rotation in progress
+	test(true, "Response code=500 from https://splunk-stack1-standalone-1.splunk-stack1-standalone-headless.test.svc.cluster.local:8089/services/configs/conf-server/general; want 200",
+		contextHandler(0, 401), passwordHandler(0), pass4SymmKeyHandler(0, 200),
+		contextHandler(1, 401), passwordHandler(1), pass4SymmKeyHandler(1, 500))
+	rotated, ok := c.state["*v1.Secret-test-splunk-stack1-standalone-rotated-secrets"].(*corev1.Secret)
+	if !cr.Status.Secrets.RotationInProgress || !ok {
+		t.Fatalf("%s did not start rotation", method)
+	}
+	if len(rotated.Data) != 2 || string(secrets.Data["password"]) != oldPassword {
+		t.Errorf("%s rotated = %v, password = %s; want 2 new values and old password", method, rotated.Data, secrets.Data["password"])
+	}
+	if got := (*c.events)[0]; got.reason != "RotatingSecrets" {
+		t.Errorf("%s published %s; want RotatingSecrets", method, got.reason)
+	}
+	if got := (*c.events)[1]; got.eventType != corev1.EventTypeWarning || got.reason != "RESTAPIFailed" {
+		t.Errorf("%s published %s %s; want Warning RESTAPIFailed", method, got.eventType, got.reason)
+	}
+
+	// instances using the new password are not changed again, even if no longer ready
+	test(false, "",
+		contextHandler(0, 200), pass4SymmKeyHandler(0, 200),
+		contextHandler(1, 200), pass4SymmKeyHandler(1, 200))
+	if cr.Status.Secrets.RotationInProgress || cr.Status.Secrets.Version != 1 || cr.Status.Secrets.RotateRequest != "1" {
+		t.Errorf("%s status = %v; want version 1, request 1 and not in progress", method, cr.Status.Secrets)
+	}
+	if !cr.Status.Secrets.LastRotationTime.Time.Equal(now) {
+		t.Errorf("%s lastRotationTime = %v; want %v", method, cr.Status.Secrets.LastRotationTime, now)
+	}
+	if !bytes.Equal(secrets.Data["password"], rotated.Data["password"]) || !bytes.Equal(secrets.Data["pass4SymmKey"], rotated.Data["pass4SymmKey"]) || string(secrets.Data["hec_token"]) != hecToken || string(secrets.Data["idxc_secret"]) != idxcSecret {
+		t.Errorf("%s secrets = %v; want rotated values and old hec_token and idxc_secret", method, secrets.Data)
+	}
+	if c.state["*v1.Secret-test-splunk-stack1-standalone-rotated-secrets"] != nil {
+		t.Errorf("%s did not delete rotated secrets", method)
+	}
+	if got := (*c.events)[2]; got.reason != "RotatedSecrets" || got.message != "Rotated secrets splunk-stack1-standalone-secrets to version 1" {
+		t.Errorf("%s published %s %s; want RotatedSecrets", method, got.reason, got.message)
+	}
+
+	// no rotation until the request changes or the interval elapses
+	test(true, "")
+	cr.Spec.SecretRotation.Interval = 3600
+	now = now.Add(30 * time.Minute)
+	test(true, "")
+	result := reconcile.Result{}
+	mgr.setRequeue(&result)
+	if !result.Requeue || result.RequeueAfter != 30*time.Minute {
+		t.Errorf("setRequeue() = %v; want requeue after 30m", result)
+	}
+	result = reconcile.Result{Requeue: true, RequeueAfter: time.Minute}
+	mgr.setRequeue(&result)
+	if result.RequeueAfter != time.Minute {
+		t.Errorf("setRequeue() = %v; want requeue after 1m", result)
+	}
+
+	// values borrowed from a license master are not rotated
+	cr.Spec.LicenseMasterRef.Name = "stack2"
+	now = now.Add(30 * time.Minute)
+	test(true, "",
+		contextHandler(0, 401), passwordHandler(0),
+		contextHandler(1, 401), passwordHandler(1))
+	if cr.Status.Secrets.Version != 2 {
+		t.Errorf("%s version = %d; want 2", method, cr.Status.Secrets.Version)
+	}
+	if !bytes.Equal(secrets.Data["pass4SymmKey"], rotated.Data["pass4SymmKey"]) {
+		t.Errorf("%s rotated pass4SymmKey borrowed from license master", method)
+	}
+}
+
+func TestGetSplunkInstanceURIs(t *testing.T) {
+	got := getSplunkInstanceURIs("test", enterprise.SplunkIndexer, "stack1-site1", 2)
+	want := []string{
+		"https://splunk-stack1-site1-indexer-0.splunk-stack1-site1-indexer-headless.test.svc.cluster.local:8089",
+		"https://splunk-stack1-site1-indexer-1.splunk-stack1-site1-indexer-headless.test.svc.cluster.local:8089",
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("getSplunkInstanceURIs() = %v; want %v", got, want)
+	}
+}
+
+func TestApplySplunkConfigSharedSecrets(t *testing.T) {
+	c := newMockClient()
+	cr := enterprisev1.Standalone{
+		TypeMeta:   metav1.TypeMeta{Kind: "Standalone"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	cr.Spec.LicenseMasterRef.Name = "stack2"
+	licenseMasterSecrets := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack2-license-master-secrets", Namespace: "test"},
+		Data:       map[string][]byte{"pass4SymmKey": []byte("ab")},
+	}
+	c.state[getStateKey(licenseMasterSecrets)] = licenseMasterSecrets
+
+	secrets, err := ApplySplunkConfig(c, &cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone, &cr.Status.Secrets)
+	if err != nil || string(secrets.Data["pass4SymmKey"]) != "ab" || cr.Status.Secrets.Version != 0 {
+		t.Errorf("ApplySplunkConfig() = %s,%v version %d; want ab,nil version 0", secrets.Data["pass4SymmKey"], err, cr.Status.Secrets.Version)
+	}
+
+	// new values are propagated after the license master rotates them
+	licenseMasterSecrets.Data["pass4SymmKey"] = []byte("cd")
+	secrets, err = ApplySplunkConfig(c, &cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone, &cr.Status.Secrets)
+	if err != nil || string(secrets.Data["pass4SymmKey"]) != "cd" || cr.Status.Secrets.Version != 1 {
+		t.Errorf("ApplySplunkConfig() = %s,%v version %d; want cd,nil version 1", secrets.Data["pass4SymmKey"], err, cr.Status.Secrets.Version)
+	}
+	if len(c.calls["Update"]) != 1 {
+		t.Errorf("ApplySplunkConfig() updated %d resources; want 1", len(c.calls["Update"]))
+	}
+	if got := (*c.events)[len(*c.events)-1]; got.reason != "UpdatedSecrets" {
+		t.Errorf("ApplySplunkConfig() published %s; want UpdatedSecrets", got.reason)
+	}
+}
+
+func TestApplySplunkConfigSecretRef(t *testing.T) {
+	c := newMockClient()
+	cr := enterprisev1.Standalone{
+		TypeMeta:   metav1.TypeMeta{Kind: "Standalone"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	cr.Spec.SecretRef = corev1.ObjectReference{Name: "my-secrets", Namespace: "vault"}
+	test := func(wantErr string) *corev1.Secret {
+		secrets, err := ApplySplunkConfig(c, &cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone, &cr.Status.Secrets)
+		if (err == nil && wantErr != "") || (err != nil && err.Error() != wantErr) {
+			t.Errorf("ApplySplunkConfig() returned %v; want %s", err, wantErr)
+		}
+		return secrets
+	}
+
+	// Secrets in other namespaces are never read
+	vault := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-secrets", Namespace: "vault"},
+		Data:       map[string][]byte{"password": []byte("stolen")},
+	}
+	c.state[getStateKey(vault)] = vault
+	test(`secretRef must be in the same namespace as the custom resource; namespace="vault"`)
+	if len(c.calls["Get"]) != 0 {
+		t.Errorf("ApplySplunkConfig() got %v; want no calls", c.calls["Get"])
+	}
+
+	cr.Spec.SecretRef.Namespace = ""
+	test("Unable to get Secret test/my-secrets referenced by secretRef: NotFound")
+
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-secrets", Namespace: "test"},
+		Data:       map[string][]byte{"password": []byte("changeme")},
+	}
+	c.state[getStateKey(source)] = source
+	test("Secret test/my-secrets referenced by secretRef is missing required keys: hec_token, pass4SymmKey, idxc_secret, shc_secret")
+
+	for _, key := range []string{"hec_token", "pass4SymmKey", "idxc_secret", "shc_secret"} {
+		source.Data[key] = []byte(key)
+	}
+	secrets := test("")
+	if secrets == nil || secrets.GetNamespace() != "test" || string(secrets.Data["password"]) != "changeme" || len(secrets.Data["default.yml"]) == 0 {
+		t.Fatalf("ApplySplunkConfig() = %v; want copy of secretRef in namespace test", secrets)
+	}
+
+	// the user-managed Secret is never modified, and changes to it are left for SecretRotationManager to apply
+	source.Data["password"] = []byte("n3wp@ssw0rd")
+	secrets = test("")
+	if string(secrets.Data["password"]) != "changeme" {
+		t.Errorf("ApplySplunkConfig() password = %s; want changeme", secrets.Data["password"])
+	}
+	for _, call := range append(c.calls["Create"], c.calls["Update"]...) {
+		if call.obj.(*corev1.Secret).GetName() == "my-secrets" {
+			t.Errorf("ApplySplunkConfig() modified secretRef")
+		}
+	}
+}
+
+func TestSecretRotationManagerSecretRef(t *testing.T) {
+	method := "SecretRotationManager.Update()"
+	c := newMockClient()
+	cr := enterprisev1.Standalone{
+		TypeMeta:   metav1.TypeMeta{Kind: "Standalone"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	cr.Spec.SecretRef.Name = "my-secrets"
+	cr.Spec.LicenseMasterRef.Name = "stack2"
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-secrets", Namespace: "test"},
+		Data:       map[string][]byte{},
+	}
+	for _, key := range []string{"hec_token", "password", "idxc_secret", "shc_secret"} {
+		source.Data[key] = []byte(key)
+	}
+	c.state[getStateKey(source)] = source
+	secrets, err := enterprise.GetSplunkSecretsFromRef(&cr, enterprise.SplunkStandalone, source, nil, []byte("pass4"))
+	if err != nil {
+		t.Fatalf("GetSplunkSecretsFromRef() returned %v", err)
+	}
+	c.state[getStateKey(secrets)] = secrets
+
+	var mockSplunkClient *spltest.MockHTTPClient
+	mgr := SecretRotationManager{
+		log:          log.WithName(method),
+		cr:           &cr,
+		spec:         &cr.Spec.CommonSplunkSpec,
+		status:       &cr.Status.Secrets,
+		instanceType: enterprise.SplunkStandalone,
+		secrets:      secrets,
+		instanceURIs: getSplunkInstanceURIs("test", enterprise.SplunkStandalone, "stack1", 1),
+		newSplunkClient: func(managementURI, username, password string) *splclient.SplunkClient {
+			c := splclient.NewSplunkClient(managementURI, username, password)
+			c.Client = mockSplunkClient
+			return c
+		},
+		events: newEventPublisher(c, &cr),
+	}
+	test := func(ready bool, handlers ...spltest.MockHTTPHandler) {
+		mockSplunkClient = &spltest.MockHTTPClient{}
+		mockSplunkClient.AddHandlers(handlers...)
+		if err := mgr.Update(c, ready); err != nil {
+			t.Errorf("%s returned %v; want nil", method, err)
+		}
+		mockSplunkClient.CheckRequests(t, method)
+	}
+	uri := mgr.instanceURIs[0]
+
+	// nothing to do while the values match, and the rotate-secrets annotation is ignored
+	cr.ObjectMeta.Annotations = map[string]string{enterprisev1.RotateSecretsAnnotation: "1"}
+	test(true)
+
+	// cluster secrets cannot be rotated
+	source.Data["shc_secret"] = []byte("shc")
+	if err := mgr.Update(c, true); err == nil || err.Error() != "Secret test/my-secrets referenced by secretRef changes shc_secret, which cannot be rotated; restore the previous values" {
+		t.Errorf("%s returned %v; want shc_secret rejected", method, err)
+	}
+	if cr.Status.Secrets.RotationInProgress || string(secrets.Data["shc_secret"]) != "shc_secret" {
+		t.Errorf("%s applied shc_secret change", method)
+	}
+	source.Data["shc_secret"] = []byte("shc_secret")
+
+	// changes are only applied when ready
+	source.Data["password"] = []byte("n3wp@ssw0rd")
+	test(false)
+	if cr.Status.Secrets.RotationInProgress || string(secrets.Data["password"]) != "password" {
+		t.Errorf("%s applied changes while not ready", method)
+	}
+
+	// the new password is applied using the REST API, and pass4SymmKey is borrowed from the license master
+	test(true,
+		spltest.MockHTTPHandler{Method: "GET", URL: uri + "/services/authentication/current-context?count=0&output_mode=json", Status: 401},
+		spltest.MockHTTPHandler{Method: "POST", URL: uri + "/services/authentication/users/admin", Status: 200})
+	if cr.Status.Secrets.RotationInProgress || cr.Status.Secrets.Version != 1 {
+		t.Errorf("%s status = %v; want version 1 and not in progress", method, cr.Status.Secrets)
+	}
+	if string(secrets.Data["password"]) != "n3wp@ssw0rd" || string(secrets.Data["shc_secret"]) != "shc_secret" || string(secrets.Data["pass4SymmKey"]) != "pass4" {
+		t.Errorf("%s secrets = %v; want values from secretRef", method, secrets.Data)
+	}
+	if len(c.calls["Delete"]) != 0 {
+		t.Errorf("%s deleted %d resources; want 0", method, len(c.calls["Delete"]))
+	}
+
+	// changes that do not include the password only update the Secret
+	source.Data["hec_token"] = []byte("token")
+	test(true)
+	if cr.Status.Secrets.Version != 2 || string(secrets.Data["hec_token"]) != "token" {
+		t.Errorf("%s version = %d, hec_token = %s; want 2, token", method, cr.Status.Secrets.Version, secrets.Data["hec_token"])
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+	"github.com/splunk/splunk-operator/pkg/splunk/resources"
+)
+
+// ApplySmartStoreConfig reconciles the Kubernetes Secret containing SmartStore configuration for Splunk Enterprise instances,
+// and updates status with the results of connectivity checks for each remote storage volume.
+func ApplySmartStoreConfig(client ControllerClient, cr enterprisev1.MetaObject, spec *enterprisev1.SmartStoreSpec, instanceType enterprise.InstanceType, status *[]enterprisev1.RemoteVolumeStatus) error {
+	scopedLog := log.WithName("ApplySmartStoreConfig").WithValues("name", cr.GetIdentifier(), "namespace", cr.GetNamespace())
+
+	if len(spec.Volumes) == 0 {
+		*status = []enterprisev1.RemoteVolumeStatus{}
+		return nil
+	}
+
+	// retrieve keys for each remote volume
+	volumeSecrets := make(map[string]*corev1.Secret)
+	for _, volume := range spec.Volumes {
+		if volume.SecretRef == "" {
+			continue
+		}
+		secret, err := getRemoteVolumeSecret(client, cr, &volume)
+		if err != nil {
+			return err
+		}
+		volumeSecrets[volume.Name] = secret
+	}
+
+	// create or update secret containing SmartStore configuration
+	smartStoreSecret := enterprise.GetSmartStoreSecret(cr, spec, instanceType, volumeSecrets)
+	smartStoreSecret.SetOwnerReferences(append(smartStoreSecret.GetOwnerReferences(), resources.AsOwner(cr)))
+	if err := ApplyConfigSecret(client, smartStoreSecret); err != nil {
+		return err
+	}
+
+	// check connectivity for each remote volume, re-using previous results unless something changed
+	previous := make(map[string]enterprisev1.RemoteVolumeStatus)
+	for _, volumeStatus := range *status {
+		previous[volumeStatus.Name] = volumeStatus
+	}
+	result := make([]enterprisev1.RemoteVolumeStatus, len(spec.Volumes))
+	for idx, volume := range spec.Volumes {
+		volumeStatus, ok := previous[volume.Name]
+		if ok && volumeStatus.Connected && volumeStatus.Endpoint == volume.Endpoint && volumeStatus.Path == volume.Path {
+			result[idx] = volumeStatus
+			continue
+		}
+		volumeStatus = enterprisev1.RemoteVolumeStatus{
+			Name:     volume.Name,
+			Endpoint: volume.Endpoint,
+			Path:     volume.Path,
+		}
+		secret, ok := volumeSecrets[volume.Name]
+		if !ok {
+			volumeStatus.Message = "Connectivity check skipped because secretRef is empty"
+		} else {
+			s3Client := splclient.NewS3Client(volume.Endpoint, string(secret.Data[enterprise.S3AccessKey]), string(secret.Data[enterprise.S3SecretKey]))
+			err := s3Client.CheckRemoteVolume(volume.Path)
+			if err != nil {
+				scopedLog.Error(err, "Remote volume connectivity check failed", "volume", volume.Name)
+				volumeStatus.Message = err.Error()
+			} else {
+				volumeStatus.Connected = true
+			}
+		}
+		result[idx] = volumeStatus
+	}
+	*status = result
+
+	return nil
+}
+
+// getRemoteVolumeSecret is used to retrieve the Kubernetes Secret containing keys for a remote storage volume.
+func getRemoteVolumeSecret(client ControllerClient, cr enterprisev1.MetaObject, volume *enterprisev1.RemoteVolumeSpec) (*corev1.Secret, error) {
+	namespacedName := types.NamespacedName{Namespace: cr.GetNamespace(), Name: volume.SecretRef}
+	var secret corev1.Secret
+	err := client.Get(context.TODO(), namespacedName, &secret)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get secret \"%s\" for remote volume \"%s\": %v", volume.SecretRef, volume.Name, err)
+	}
+	for _, key := range []string{enterprise.S3AccessKey, enterprise.S3SecretKey} {
+		if len(secret.Data[key]) == 0 {
+			return nil, fmt.Errorf("Secret \"%s\" for remote volume \"%s\" is missing %s", volume.SecretRef, volume.Name, key)
+		}
+	}
+	return &secret, nil
+}
+
+// updateSmartStoreChecksum annotates a pod template with a checksum of the contents of the SmartStore Secret, which
+// includes the keys resolved from each remote volume's secretRef. This ensures that pods are recycled whenever those
+// keys change, and not just when the SmartStore spec changes.
+func updateSmartStoreChecksum(client ControllerClient, cr enterprisev1.MetaObject, spec *enterprisev1.SmartStoreSpec, instanceType enterprise.InstanceType, podTemplateSpec *corev1.PodTemplateSpec) error {
+	if len(spec.Volumes) == 0 {
+		return nil
+	}
+	hash := sha256.New()
+	err := addConfigChecksum(client, hash, cr.GetNamespace(), enterprise.GetSplunkSmartStoreName(cr.GetIdentifier(), instanceType), &corev1.Secret{})
+	if err != nil {
+		return err
+	}
+	if podTemplateSpec.ObjectMeta.Annotations == nil {
+		podTemplateSpec.ObjectMeta.Annotations = make(map[string]string)
+	}
+	podTemplateSpec.ObjectMeta.Annotations[smartStoreChecksumAnnotation] = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
@@ -21,35 +21,39 @@ import (
 
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	"github.com/splunk/splunk-operator/pkg/splunk/spark"
 )
 
 // ApplySpark reconciles the Deployments and Services for a Spark cluster.
-func ApplySpark(client ControllerClient, cr *enterprisev1.Spark) (reconcile.Result, error) {
+func ApplySpark(client ControllerClient, cr *enterprisev1.Spark) (result reconcile.Result, err error) {
 
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 
+	// updates status after function completes
+	tracker := newStatusTracker(client, cr, &cr.Status.Phase, &cr.Status.CommonStatus,
+		stepValidate, stepServices, stepSparkMaster, stepPods)
+	defer func() {
+		tracker.finish(err)
+		client.Status().Update(context.TODO(), cr)
+	}()
+
 	// validate and updates defaults for CR
-	err := spark.ValidateSparkSpec(&cr.Spec)
+	tracker.begin(stepValidate)
+	err = spark.ValidateSparkSpec(&cr.Spec)
 	if err != nil {
 		return result, err
 	}
-
-	// updates status after function completes
-	cr.Status.Phase = enterprisev1.PhaseError
 	cr.Status.Replicas = cr.Spec.Replicas
 	cr.Status.Selector = fmt.Sprintf("app.kubernetes.io/instance=splunk-%s-spark-worker", cr.GetIdentifier())
-	defer func() {
-		client.Status().Update(context.TODO(), cr)
-	}()
 
 	// check if deletion has been requested
 	if cr.ObjectMeta.DeletionTimestamp != nil {
+		tracker.begin(stepDeletion)
 		terminating, err := CheckSplunkDeletion(cr, client)
 		if terminating && err != nil { // don't bother if no error, since it will just be removed immmediately after
 			cr.Status.Phase = enterprisev1.PhaseTerminating
@@ -59,7 +63,14 @@ func ApplySpark(client ControllerClient, cr *enterprisev1.Spark) (reconcile.Resu
 		return result, err
 	}
 
+	// only refresh status while reconcile is paused
+	if tracker.paused {
+		result.Requeue = false
+		return result, refreshPausedSparkStatus(client, cr, tracker)
+	}
+
 	// create or update a service for spark master
+	tracker.begin(stepServices)
 	err = ApplyService(client, spark.GetSparkService(cr, spark.SparkMaster, false))
 	if err != nil {
 		return result, err
@@ -72,6 +83,7 @@ func ApplySpark(client ControllerClient, cr *enterprisev1.Spark) (reconcile.Resu
 	}
 
 	// create or update deployment for spark master
+	tracker.begin(stepSparkMaster)
 	deployment, err := spark.GetSparkDeployment(cr, spark.SparkMaster)
 	if err != nil {
 		return result, err
@@ -81,18 +93,46 @@ func ApplySpark(client ControllerClient, cr *enterprisev1.Spark) (reconcile.Resu
 		cr.Status.MasterPhase = enterprisev1.PhaseError
 		return result, err
 	}
+	tracker.setPhase(cr.Status.MasterPhase)
 
 	// create or update deployment for spark worker
+	tracker.begin(stepPods)
 	deployment, err = spark.GetSparkDeployment(cr, spark.SparkWorker)
 	if err != nil {
 		return result, err
 	}
-	cr.Status.Phase, err = ApplyDeployment(client, deployment)
+	phase, err := ApplyDeployment(client, deployment)
 	cr.Status.ReadyReplicas = deployment.Status.ReadyReplicas
 	if err != nil {
-		cr.Status.Phase = enterprisev1.PhaseError
-	} else if cr.Status.Phase == enterprisev1.PhaseReady {
+		return result, err
+	}
+	cr.Status.Phase = phase
+	tracker.setPhase(phase)
+
+	// no need to requeue if everything is ready
+	if cr.Status.Phase == enterprisev1.PhaseReady {
 		result.Requeue = false
 	}
-	return result, err
+	return result, nil
+}
+
+// refreshPausedSparkStatus refreshes the status of a spark cluster while reconcile is paused, without modifying anything
+func refreshPausedSparkStatus(client ControllerClient, cr *enterprisev1.Spark, tracker *statusTracker) error {
+	tracker.begin(stepSparkMaster)
+	deployment, err := spark.GetSparkDeployment(cr, spark.SparkMaster)
+	if err != nil {
+		return err
+	}
+	cr.Status.MasterPhase = GetDeploymentPhase(client, deployment)
+	tracker.setPhase(cr.Status.MasterPhase)
+
+	tracker.begin(stepPods)
+	deployment, err = spark.GetSparkDeployment(cr, spark.SparkWorker)
+	if err != nil {
+		return err
+	}
+	cr.Status.Phase = GetDeploymentPhase(client, deployment)
+	cr.Status.ReadyReplicas = deployment.Status.ReadyReplicas
+	tracker.setPhase(cr.Status.Phase)
+	return nil
 }
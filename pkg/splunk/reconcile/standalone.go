@@ -21,35 +21,46 @@ import (
 
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
 )
 
 // ApplyStandalone reconciles the StatefulSet for N standalone instances of Splunk Enterprise.
-func ApplyStandalone(client ControllerClient, cr *enterprisev1.Standalone) (reconcile.Result, error) {
+func ApplyStandalone(client ControllerClient, cr *enterprisev1.Standalone) (result reconcile.Result, err error) {
+	scopedLog := log.WithName("ApplyStandalone").WithValues("name", cr.GetIdentifier(), "namespace", cr.GetNamespace())
 
 	// unless modified, reconcile for this object will be requeued after 5 seconds
-	result := reconcile.Result{
+	result = reconcile.Result{
 		Requeue:      true,
 		RequeueAfter: time.Second * 5,
 	}
 
+	// updates status after function completes
+	tracker := newStatusTracker(client, cr, &cr.Status.Phase, &cr.Status.CommonStatus,
+		stepValidate, stepTLS, stepSecrets, stepSmartStore, stepApps, stepServices, stepPods)
+	defer func() {
+		tracker.finish(err)
+		client.Status().Update(context.TODO(), cr)
+	}()
+
 	// validate and updates defaults for CR
-	err := enterprise.ValidateStandaloneSpec(&cr.Spec)
+	tracker.begin(stepValidate)
+	err = enterprise.ValidateStandaloneSpec(&cr.Spec)
 	if err != nil {
 		return result, err
 	}
-
-	// updates status after function completes
-	cr.Status.Phase = enterprisev1.PhaseError
 	cr.Status.Replicas = cr.Spec.Replicas
 	cr.Status.Selector = fmt.Sprintf("app.kubernetes.io/instance=splunk-%s-standalone", cr.GetIdentifier())
-	defer func() {
-		client.Status().Update(context.TODO(), cr)
-	}()
+	if cr.Status.RemoteVolumes == nil {
+		cr.Status.RemoteVolumes = []enterprisev1.RemoteVolumeStatus{}
+	}
+	if cr.Status.AppRepo.Apps == nil {
+		cr.Status.AppRepo.Apps = []enterprisev1.AppStatus{}
+	}
 
 	// check if deletion has been requested
 	if cr.ObjectMeta.DeletionTimestamp != nil {
+		tracker.begin(stepDeletion)
 		terminating, err := CheckSplunkDeletion(cr, client)
 		if terminating && err != nil { // don't bother if no error, since it will just be removed immmediately after
 			cr.Status.Phase = enterprisev1.PhaseTerminating
@@ -59,34 +70,96 @@ func ApplyStandalone(client ControllerClient, cr *enterprisev1.Standalone) (reco
 		return result, err
 	}
 
+	// only refresh status while reconcile is paused
+	if tracker.paused {
+		tracker.begin(stepPods)
+		statefulSet, err := enterprise.GetStandaloneStatefulSet(cr)
+		if err != nil {
+			return result, err
+		}
+		cr.Status.Phase = GetStatefulSetPhase(client, statefulSet)
+		cr.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
+		tracker.setPhase(cr.Status.Phase)
+		result.Requeue = false
+		return result, nil
+	}
+
+	// create or update TLS certificates
+	tracker.begin(stepTLS)
+	tlsManager := TLSManager{log: scopedLog, cr: cr, spec: &cr.Spec.TLS, status: &cr.Status.TLS, instanceType: enterprise.SplunkStandalone,
+		hosts: enterprise.GetSplunkTLSHosts(cr.GetNamespace(), enterprise.SplunkStandalone, cr.GetIdentifier(), "", cr.Spec.Replicas), events: newEventPublisher(client, cr)}
+	err = tlsManager.Apply(client)
+	if err != nil {
+		return result, err
+	}
+
 	// create or update general config resources
-	_, err = ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone)
+	tracker.begin(stepSecrets)
+	secrets, err := ApplySplunkConfig(client, cr, cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone, &cr.Status.Secrets)
+	if err != nil {
+		return result, err
+	}
+
+	// rotate secrets when requested, before they are used to manage any instances
+	secretsManager := SecretRotationManager{log: scopedLog, cr: cr, spec: &cr.Spec.CommonSplunkSpec, status: &cr.Status.Secrets, instanceType: enterprise.SplunkStandalone,
+		secrets: secrets, instanceURIs: getSplunkInstanceURIs(cr.GetNamespace(), enterprise.SplunkStandalone, cr.GetIdentifier(), cr.Spec.Replicas), newSplunkClient: tlsManager.newSplunkClient, events: newEventPublisher(client, cr)}
+	err = secretsManager.Update(client, cr.Status.Phase == enterprisev1.PhaseReady)
+	if err != nil {
+		return result, err
+	}
+
+	// create or update SmartStore configuration
+	tracker.begin(stepSmartStore)
+	err = ApplySmartStoreConfig(client, cr, &cr.Spec.SmartStore, enterprise.SplunkStandalone, &cr.Status.RemoteVolumes)
+	if err != nil {
+		return result, err
+	}
+
+	// check app repository for new or changed app packages
+	tracker.begin(stepApps)
+	err = ApplyAppRepoConfig(client, cr, &cr.Spec.AppRepo, enterprise.SplunkStandalone, &cr.Status.AppRepo)
 	if err != nil {
 		return result, err
 	}
 
 	// create or update a headless service (this is required by DFS for Spark->standalone comms, possibly other things)
+	tracker.begin(stepServices)
 	err = ApplyService(client, enterprise.GetSplunkService(cr, cr.Spec.CommonSpec, enterprise.SplunkStandalone, true))
 	if err != nil {
 		return result, err
 	}
 
 	// create or update statefulset
+	tracker.begin(stepPods)
 	statefulSet, err := enterprise.GetStandaloneStatefulSet(cr)
 	if err != nil {
 		return result, err
 	}
-	mgr := DefaultStatefulSetPodManager{}
+	err = updateConfigChecksums(client, cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone, &statefulSet.Spec.Template)
+	if err != nil {
+		return result, err
+	}
+	err = updateSmartStoreChecksum(client, cr, &cr.Spec.SmartStore, enterprise.SplunkStandalone, &statefulSet.Spec.Template)
+	if err != nil {
+		return result, err
+	}
+	mgr := DefaultStatefulSetPodManager{UpdateStrategy: cr.Spec.UpdateStrategy}
 	phase, err := mgr.Update(client, statefulSet, cr.Spec.Replicas)
 	cr.Status.ReadyReplicas = statefulSet.Status.ReadyReplicas
 	if err != nil {
 		return result, err
 	}
 	cr.Status.Phase = phase
+	tracker.setPhase(phase)
+	UpdateAppRepoStatus(&cr.Status.AppRepo, phase)
 
-	// no need to requeue if everything is ready
+	// no need to requeue if everything is ready, other than to poll the app repository, rotate secrets and renew certificates
 	if cr.Status.Phase == enterprisev1.PhaseReady {
 		result.Requeue = false
+		setAppRepoPollRequeue(&result, &cr.Spec.AppRepo)
+		secretsManager.setRequeue(&result)
+		tlsManager.setReady()
+		tlsManager.setRequeue(&result)
 	}
 	return result, nil
 }
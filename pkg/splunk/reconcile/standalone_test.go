@@ -15,12 +15,15 @@
 package reconcile
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 )
 
 func TestApplyStandalone(t *testing.T) {
@@ -29,8 +32,9 @@ func TestApplyStandalone(t *testing.T) {
 		{metaName: "*v1.Service-test-splunk-stack1-standalone-headless"},
 		{metaName: "*v1.StatefulSet-test-splunk-stack1-standalone"},
 	}
-	createCalls := map[string][]mockFuncCall{"Get": funcCalls, "Create": funcCalls}
-	updateCalls := map[string][]mockFuncCall{"Get": funcCalls, "Update": []mockFuncCall{funcCalls[2]}}
+	getCalls := []mockFuncCall{funcCalls[0], funcCalls[1], funcCalls[0], funcCalls[2]}
+	createCalls := map[string][]mockFuncCall{"Get": getCalls, "Create": funcCalls}
+	updateCalls := map[string][]mockFuncCall{"Get": getCalls, "Update": []mockFuncCall{funcCalls[2]}}
 	current := enterprisev1.Standalone{
 		TypeMeta: metav1.TypeMeta{
 			Kind: "Standalone",
@@ -58,3 +62,106 @@ func TestApplyStandalone(t *testing.T) {
 	}
 	splunkDeletionTester(t, revised, deleteFunc)
 }
+
+func TestApplyStandaloneConditions(t *testing.T) {
+	cr := enterprisev1.Standalone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "stack1",
+			Namespace:  "test",
+			Generation: 2,
+		},
+	}
+	c := newMockClient()
+
+	// pods are not ready after the statefulset is first created
+	_, err := ApplyStandalone(c, &cr)
+	if err != nil {
+		t.Errorf("ApplyStandalone() returned %v; want nil", err)
+	}
+	if cr.Status.Phase != enterprisev1.PhasePending || cr.Status.ObservedGeneration != 2 {
+		t.Errorf("ApplyStandalone() Phase,ObservedGeneration = %s,%d; want Pending,2", cr.Status.Phase, cr.Status.ObservedGeneration)
+	}
+	for conditionType, want := range map[enterprisev1.ConditionType]corev1.ConditionStatus{
+		enterprisev1.ConditionSecretsReady: corev1.ConditionTrue,
+		enterprisev1.ConditionPodsReady:    corev1.ConditionFalse,
+		enterprisev1.ConditionProgressing:  corev1.ConditionTrue,
+		enterprisev1.ConditionDegraded:     corev1.ConditionFalse,
+		enterprisev1.ConditionReady:        corev1.ConditionFalse,
+	} {
+		if got := GetStatusCondition(cr.Status.Conditions, conditionType); got == nil || got.Status != want {
+			t.Errorf("ApplyStandalone() condition %s = %v; want %s", conditionType, got, want)
+		}
+	}
+
+	// invalid specs are reported using SpecValid
+	cr.Spec.ImagePullPolicy = "Never"
+	_, err = ApplyStandalone(c, &cr)
+	if err == nil {
+		t.Errorf("ApplyStandalone() returned nil; want error for invalid spec")
+	}
+	got := GetStatusCondition(cr.Status.Conditions, enterprisev1.ConditionSpecValid)
+	if cr.Status.Phase != enterprisev1.PhaseError || got == nil || got.Status != corev1.ConditionFalse || got.Reason != "InvalidSpec" {
+		t.Errorf("ApplyStandalone() Phase,SpecValid = %s,%v; want Error,False/InvalidSpec", cr.Status.Phase, got)
+	}
+}
+
+func TestApplyStandalonePaused(t *testing.T) {
+	cr := enterprisev1.Standalone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "stack1",
+			Namespace:   "test",
+			Annotations: map[string]string{enterprisev1.PausedAnnotation: "true"},
+		},
+	}
+	c := newMockClient()
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-standalone", Namespace: "test"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: new(int32)},
+		Status:     appsv1.StatefulSetStatus{Replicas: 1, ReadyReplicas: 1, UpdatedReplicas: 1, UpdateRevision: "v1"},
+	}
+	*statefulSet.Spec.Replicas = 1
+	c.state[getStateKey(statefulSet)] = statefulSet
+
+	// only the statefulset is read, even though more replicas are wanted
+	cr.Spec.Replicas = 3
+	result, err := ApplyStandalone(c, &cr)
+	if err != nil || result.Requeue {
+		t.Errorf("ApplyStandalone() = %v,%v; want no requeue and nil", result, err)
+	}
+	c.checkCalls(t, "TestApplyStandalonePaused", map[string][]mockFuncCall{
+		"Get": {{metaName: "*v1.StatefulSet-test-splunk-stack1-standalone"}},
+	})
+	if cr.Status.Phase != enterprisev1.PhaseReady || cr.Status.ReadyReplicas != 1 {
+		t.Errorf("ApplyStandalone() Phase,ReadyReplicas = %s,%d; want Ready,1", cr.Status.Phase, cr.Status.ReadyReplicas)
+	}
+	for conditionType, want := range map[enterprisev1.ConditionType]string{
+		enterprisev1.ConditionPaused:      "True/Paused",
+		enterprisev1.ConditionProgressing: "False/Paused",
+		enterprisev1.ConditionReady:       "True/Ready",
+	} {
+		if got := GetStatusCondition(cr.Status.Conditions, conditionType); got == nil || string(got.Status)+"/"+got.Reason != want {
+			t.Errorf("ApplyStandalone() condition %s = %v; want %s", conditionType, got, want)
+		}
+	}
+	if GetStatusCondition(cr.Status.Conditions, enterprisev1.ConditionSecretsReady) != nil {
+		t.Errorf("ApplyStandalone() set SecretsReady; want no condition while paused")
+	}
+
+	// resuming reconcile applies changes to the statefulset
+	delete(cr.ObjectMeta.Annotations, enterprisev1.PausedAnnotation)
+	c.resetCalls()
+	_, err = ApplyStandalone(c, &cr)
+	if err != nil || cr.Status.Phase != enterprisev1.PhaseUpdating || len(c.calls["Update"]) != 1 {
+		t.Errorf("ApplyStandalone() Phase,err = %s,%v; want Updating,nil and one update", cr.Status.Phase, err)
+	}
+	if got := GetStatusCondition(cr.Status.Conditions, enterprisev1.ConditionPaused); got == nil || got.Status != corev1.ConditionFalse {
+		t.Errorf("ApplyStandalone() condition Paused = %v; want False", got)
+	}
+	var reasons []string
+	for _, e := range *c.events {
+		reasons = append(reasons, e.reason)
+	}
+	if want := []string{"Ready", "Paused", "CreatedSecret", "Resumed"}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("ApplyStandalone() published events %v; want %v", reasons, want)
+	}
+}
@@ -17,13 +17,14 @@ package reconcile
 import (
 	"context"
 	"fmt"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 )
 
 // StatefulSetPodManager is used to manage the pods within a StatefulSet
@@ -39,10 +40,23 @@ type StatefulSetPodManager interface {
 
 	// FinishRecycle completes recycle event for pod and returns true, or returns false if nothing to do
 	FinishRecycle(int32) (bool, error)
+
+	// GetUpdateStrategy returns the strategy used to recycle pods for updates
+	GetUpdateStrategy() enterprisev1.UpdateStrategySpec
+}
+
+// StatefulSetPodRecycleOrderer may optionally be implemented by a StatefulSetPodManager to control the order in
+// which pods are recycled for updates
+type StatefulSetPodRecycleOrderer interface {
+	// GetRecycleOrder returns the ordinals of all pods, in the order they should be checked for updates
+	GetRecycleOrder(replicas int32) []int32
 }
 
 // DefaultStatefulSetPodManager is a simple StatefulSetPodManager that does nothing
-type DefaultStatefulSetPodManager struct{}
+type DefaultStatefulSetPodManager struct {
+	// strategy used to recycle pods for updates (optional)
+	UpdateStrategy enterprisev1.UpdateStrategySpec
+}
 
 // Update for DefaultStatefulSetPodManager handles all updates for a statefulset of standard pods
 func (mgr *DefaultStatefulSetPodManager) Update(client ControllerClient, statefulSet *appsv1.StatefulSet, desiredReplicas int32) (enterprisev1.ResourcePhase, error) {
@@ -68,6 +82,11 @@ func (mgr *DefaultStatefulSetPodManager) FinishRecycle(n int32) (bool, error) {
 	return true, nil
 }
 
+// GetUpdateStrategy for DefaultStatefulSetPodManager returns its UpdateStrategy
+func (mgr *DefaultStatefulSetPodManager) GetUpdateStrategy() enterprisev1.UpdateStrategySpec {
+	return mgr.UpdateStrategy
+}
+
 // ApplyStatefulSet creates or updates a Kubernetes StatefulSet
 func ApplyStatefulSet(c ControllerClient, revised *appsv1.StatefulSet) (enterprisev1.ResourcePhase, error) {
 	namespacedName := types.NamespacedName{Namespace: revised.GetNamespace(), Name: revised.GetName()}
@@ -98,17 +117,57 @@ func ApplyStatefulSet(c ControllerClient, revised *appsv1.StatefulSet) (enterpri
 	return enterprisev1.PhaseReady, nil
 }
 
-// UpdateStatefulSetPods manages scaling and config updates for StatefulSets
+// GetStatefulSetPhase returns the phase of the pods managed by an existing StatefulSet, without modifying anything.
+// This is used to refresh status while reconcile is paused.
+func GetStatefulSetPhase(c ControllerClient, statefulSet *appsv1.StatefulSet) enterprisev1.ResourcePhase {
+	namespacedName := types.NamespacedName{Namespace: statefulSet.GetNamespace(), Name: statefulSet.GetName()}
+	var current appsv1.StatefulSet
+
+	err := c.Get(context.TODO(), namespacedName, &current)
+	if err != nil {
+		// no StatefulSet exists, and it won't be created until reconcile is resumed
+		statefulSet.Status = appsv1.StatefulSetStatus{}
+		return enterprisev1.PhasePending
+	}
+	*statefulSet = current // caller expects that object passed represents latest state
+
+	replicas := int32(1)
+	if current.Spec.Replicas != nil {
+		replicas = *current.Spec.Replicas
+	}
+	readyReplicas := current.Status.ReadyReplicas
+	switch {
+	case readyReplicas < replicas && readyReplicas > 0:
+		return enterprisev1.PhaseScalingUp
+	case readyReplicas < replicas:
+		return enterprisev1.PhasePending
+	case readyReplicas > replicas:
+		return enterprisev1.PhaseScalingDown
+	case current.Status.UpdateRevision != "" && current.Status.UpdatedReplicas < replicas:
+		return enterprisev1.PhaseUpdating
+	}
+	return enterprisev1.PhaseReady
+}
+
+// UpdateStatefulSetPods manages scaling and config updates for StatefulSets. Pods are recycled for updates according
+// to the manager's UpdateStrategySpec.
 func UpdateStatefulSetPods(c ControllerClient, statefulSet *appsv1.StatefulSet, mgr StatefulSetPodManager, desiredReplicas int32) (enterprisev1.ResourcePhase, error) {
 
 	scopedLog := log.WithName("UpdateStatefulSetPods").WithValues(
 		"name", statefulSet.GetObjectMeta().GetName(),
 		"namespace", statefulSet.GetObjectMeta().GetNamespace())
+	events := newOwnerEventPublisher(c, statefulSet)
+	strategy := mgr.GetUpdateStrategy()
+	if strategy.MaxUnavailable < 1 {
+		strategy.MaxUnavailable = 1
+	}
 
-	// wait for all replicas ready
+	// wait for all replicas ready, unless more than one pod may be recycled at a time for updates
 	replicas := *statefulSet.Spec.Replicas
 	readyReplicas := statefulSet.Status.ReadyReplicas
-	if readyReplicas < replicas {
+	updating := statefulSet.Status.UpdateRevision != "" && statefulSet.Status.UpdatedReplicas < replicas
+	recycling := updating && readyReplicas > 0 && readyReplicas < replicas && statefulSet.Status.Replicas == replicas && replicas-readyReplicas < strategy.MaxUnavailable
+	if readyReplicas < replicas && !recycling {
 		scopedLog.Info("Waiting for pods to become ready")
 		if readyReplicas > 0 {
 			return enterprisev1.PhaseScalingUp, nil
@@ -119,18 +178,19 @@ func UpdateStatefulSetPods(c ControllerClient, statefulSet *appsv1.StatefulSet,
 		return enterprisev1.PhaseScalingDown, nil
 	}
 
-	// readyReplicas == replicas
+	// readyReplicas == replicas, unless recycling
 
 	// check for scaling up
-	if readyReplicas < desiredReplicas {
+	if !recycling && readyReplicas < desiredReplicas {
 		// scale up StatefulSet to match desiredReplicas
 		scopedLog.Info("Scaling replicas up", "replicas", desiredReplicas)
+		events.Normal("ScalingUp", "Scaling up %s from %d to %d replicas", statefulSet.GetName(), readyReplicas, desiredReplicas)
 		*statefulSet.Spec.Replicas = desiredReplicas
 		return enterprisev1.PhaseScalingUp, UpdateResource(c, statefulSet)
 	}
 
 	// check for scaling down
-	if readyReplicas > desiredReplicas {
+	if !recycling && readyReplicas > desiredReplicas {
 		// prepare pod for removal via scale down
 		n := readyReplicas - 1
 		podName := fmt.Sprintf("%s-%d", statefulSet.GetName(), n)
@@ -146,6 +206,7 @@ func UpdateStatefulSetPods(c ControllerClient, statefulSet *appsv1.StatefulSet,
 
 		// scale down statefulset to terminate pod
 		scopedLog.Info("Scaling replicas down", "replicas", n)
+		events.Normal("ScalingDown", "Scaling down %s from %d to %d replicas", statefulSet.GetName(), readyReplicas, n)
 		*statefulSet.Spec.Replicas = n
 		err = UpdateResource(c, statefulSet)
 		if err != nil {
@@ -171,16 +232,18 @@ func UpdateStatefulSetPods(c ControllerClient, statefulSet *appsv1.StatefulSet,
 				scopedLog.Error(err, "Unable to delete PVC", "pvcName", pvc.ObjectMeta.Name)
 				return enterprisev1.PhaseError, err
 			}
+			events.Normal("DeletedPVC", "Deleted persistent volume claim %s", pvc.ObjectMeta.Name)
 		}
 
 		return enterprisev1.PhaseScalingDown, nil
 	}
 
-	// ready and no StatefulSet scaling is required
-	// readyReplicas == desiredReplicas
+	// no StatefulSet scaling is required, or it will be done once recycled pods are ready
 
-	// check existing pods for desired updates
-	for n := readyReplicas - 1; n >= 0; n-- {
+	// check existing pods for desired updates; unavailable counts pods that are not ready, or are being recycled
+	unavailable := replicas - readyReplicas
+	paused := false
+	for _, n := range getRecycleOrder(mgr, replicas) {
 		// get Pod
 		podName := fmt.Sprintf("%s-%d", statefulSet.GetName(), n)
 		namespacedName := types.NamespacedName{Namespace: statefulSet.GetNamespace(), Name: podName}
@@ -191,21 +254,55 @@ func UpdateStatefulSetPods(c ControllerClient, statefulSet *appsv1.StatefulSet,
 			return enterprisev1.PhaseError, err
 		}
 		if pod.Status.Phase != corev1.PodRunning || len(pod.Status.ContainerStatuses) == 0 || pod.Status.ContainerStatuses[0].Ready != true {
+			if recycling {
+				// already counted as unavailable
+				continue
+			}
 			scopedLog.Error(err, "Waiting for Pod to become ready", "podName", podName)
 			return enterprisev1.PhaseUpdating, err
 		}
 
+		// recycled pods must be ready for minReadySeconds before moving on to other pods
+		if updating && !isPodReadyFor(&pod, strategy.MinReadySeconds) {
+			scopedLog.Info("Waiting for Pod to be ready for minReadySeconds", "podName", podName, "minReadySeconds", strategy.MinReadySeconds)
+			unavailable++
+			if unavailable >= strategy.MaxUnavailable {
+				return enterprisev1.PhaseUpdating, nil
+			}
+			continue
+		}
+
 		// terminate pod if it has pending updates; k8s will start a new one with revised template
 		if statefulSet.Status.UpdateRevision != "" && statefulSet.Status.UpdateRevision != pod.GetLabels()["controller-revision-hash"] {
+			// only pods with ordinals at or above the partition are updated
+			if n < strategy.Partition {
+				continue
+			}
+
+			// staged rollouts pause once enough pods have been updated
+			if strategy.PauseAfter > 0 && statefulSet.Status.UpdatedReplicas >= strategy.PauseAfter {
+				paused = true
+				continue
+			}
+
+			// wait if too many pods are already unavailable
+			if unavailable >= strategy.MaxUnavailable {
+				return enterprisev1.PhaseUpdating, nil
+			}
+
 			// pod needs to be updated; first, prepare it to be recycled
 			ready, err := mgr.PrepareRecycle(n)
 			if err != nil {
 				scopedLog.Error(err, "Unable to prepare Pod for recycling", "podName", podName)
 				return enterprisev1.PhaseError, err
 			}
+			unavailable++
 			if !ready {
 				// wait until pod quarantine has completed before deleting it
-				return enterprisev1.PhaseUpdating, nil
+				if unavailable >= strategy.MaxUnavailable {
+					return enterprisev1.PhaseUpdating, nil
+				}
+				continue
 			}
 
 			// deleting pod will cause StatefulSet controller to create a new one with latest template
@@ -218,9 +315,13 @@ func UpdateStatefulSetPods(c ControllerClient, statefulSet *appsv1.StatefulSet,
 				scopedLog.Error(err, "Unable to delete Pod", "podName", podName)
 				return enterprisev1.PhaseError, err
 			}
+			events.Normal("RecyclingPod", "Recycling pod %s to apply updates", podName)
 
-			// only delete one at a time
-			return enterprisev1.PhaseUpdating, nil
+			// only delete up to maxUnavailable at a time
+			if unavailable >= strategy.MaxUnavailable {
+				return enterprisev1.PhaseUpdating, nil
+			}
+			continue
 		}
 
 		// check if pod was previously prepared for recycling; if so, complete
@@ -234,8 +335,46 @@ func UpdateStatefulSetPods(c ControllerClient, statefulSet *appsv1.StatefulSet,
 			return enterprisev1.PhaseUpdating, nil
 		}
 	}
+	if unavailable > 0 {
+		return enterprisev1.PhaseUpdating, nil
+	}
+	if paused {
+		scopedLog.Info("Rollout is paused", "updatedReplicas", statefulSet.Status.UpdatedReplicas, "pauseAfter", strategy.PauseAfter)
+		events.Normal("UpdatePaused", "Paused updating %s after %d of %d pods", statefulSet.GetName(), statefulSet.Status.UpdatedReplicas, replicas)
+	}
 
 	// all is good!
 	scopedLog.Info("All pods are ready")
 	return enterprisev1.PhaseReady, nil
 }
+
+// isPodReadyFor returns true if a pod has been ready for at least the given number of seconds
+func isPodReadyFor(pod *corev1.Pod, seconds int32) bool {
+	if seconds <= 0 {
+		return true
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+			return time.Since(condition.LastTransitionTime.Time) >= time.Duration(seconds)*time.Second
+		}
+	}
+	return false
+}
+
+// getRecycleOrder returns the ordinals of the pods managed by mgr, in the order they should be checked for updates;
+// unless mgr implements StatefulSetPodRecycleOrderer, pods are recycled in reverse ordinal order
+func getRecycleOrder(mgr StatefulSetPodManager, replicas int32) []int32 {
+	if orderer, ok := mgr.(StatefulSetPodRecycleOrderer); ok {
+		return orderer.GetRecycleOrder(replicas)
+	}
+	return getDefaultRecycleOrder(replicas)
+}
+
+// getDefaultRecycleOrder returns the ordinals of replicas pods in reverse order
+func getDefaultRecycleOrder(replicas int32) []int32 {
+	order := make([]int32, 0, replicas)
+	for n := replicas - 1; n >= 0; n-- {
+		order = append(order, n)
+	}
+	return order
+}
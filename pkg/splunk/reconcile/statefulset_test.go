@@ -18,8 +18,9 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -197,3 +198,121 @@ func TestDefaultStatefulSetPodManager(t *testing.T) {
 	method := "DefaultStatefulSetPodManager.Update"
 	podManagerTester(t, method, &mgr)
 }
+
+func TestUpdateStatefulSetPodsStrategy(t *testing.T) {
+	var replicas int32 = 3
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1", Namespace: "test"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 3, UpdateRevision: "v1"},
+	}
+	newPod := func(n int, revision string, ready bool, readySince time.Time) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("splunk-stack1-%d", n),
+				Namespace: "test",
+				Labels:    map[string]string{"controller-revision-hash": revision},
+			},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Ready: ready}},
+			},
+		}
+		if ready {
+			pod.Status.Conditions = []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(readySince)},
+			}
+		}
+		return pod
+	}
+	longAgo := time.Now().Add(-time.Hour)
+	test := func(name string, strategy enterprisev1.UpdateStrategySpec, want enterprisev1.ResourcePhase, wantDeleted []string, pods ...*corev1.Pod) {
+		c := newMockClient()
+		for _, pod := range pods {
+			c.state[getStateKey(pod)] = pod
+		}
+		mgr := DefaultStatefulSetPodManager{UpdateStrategy: strategy}
+		got, err := UpdateStatefulSetPods(c, statefulSet.DeepCopy(), &mgr, 3)
+		if got != want || err != nil {
+			t.Errorf("UpdateStatefulSetPods(%s) = %s,%v; want %s,nil", name, got, err, want)
+		}
+		deleted := []string{}
+		for _, call := range c.calls["Delete"] {
+			deleted = append(deleted, call.obj.(*corev1.Pod).GetName())
+		}
+		if fmt.Sprint(deleted) != fmt.Sprint(wantDeleted) {
+			t.Errorf("UpdateStatefulSetPods(%s) deleted %v; want %v", name, deleted, wantDeleted)
+		}
+	}
+
+	// maxUnavailable recycles more than one pod at a time
+	test("maxUnavailable", enterprisev1.UpdateStrategySpec{MaxUnavailable: 2}, enterprisev1.PhaseUpdating,
+		[]string{"splunk-stack1-2", "splunk-stack1-1"},
+		newPod(0, "v0", true, longAgo), newPod(1, "v0", true, longAgo), newPod(2, "v0", true, longAgo))
+
+	// more pods are recycled while others are not ready, up to maxUnavailable
+	statefulSet.Status.ReadyReplicas = 2
+	statefulSet.Status.UpdatedReplicas = 1
+	test("maxUnavailable, 1 not ready", enterprisev1.UpdateStrategySpec{MaxUnavailable: 2}, enterprisev1.PhaseUpdating,
+		[]string{"splunk-stack1-1"},
+		newPod(0, "v0", true, longAgo), newPod(1, "v0", true, longAgo), newPod(2, "v1", false, longAgo))
+	test("maxUnavailable=1, 1 not ready", enterprisev1.UpdateStrategySpec{MaxUnavailable: 1}, enterprisev1.PhaseScalingUp,
+		[]string{},
+		newPod(0, "v0", true, longAgo), newPod(1, "v0", true, longAgo), newPod(2, "v1", false, longAgo))
+
+	// recycled pods must be ready for minReadySeconds before recycling another
+	statefulSet.Status.ReadyReplicas = 3
+	test("minReadySeconds", enterprisev1.UpdateStrategySpec{MinReadySeconds: 60}, enterprisev1.PhaseUpdating,
+		[]string{},
+		newPod(0, "v0", true, longAgo), newPod(1, "v0", true, longAgo), newPod(2, "v1", true, time.Now()))
+	test("minReadySeconds elapsed", enterprisev1.UpdateStrategySpec{MinReadySeconds: 60}, enterprisev1.PhaseUpdating,
+		[]string{"splunk-stack1-1"},
+		newPod(0, "v0", true, longAgo), newPod(1, "v0", true, longAgo), newPod(2, "v1", true, time.Now().Add(-time.Minute)))
+
+	// pods below the partition are not updated
+	test("partition", enterprisev1.UpdateStrategySpec{Partition: 2}, enterprisev1.PhaseReady,
+		[]string{},
+		newPod(0, "v0", true, longAgo), newPod(1, "v0", true, longAgo), newPod(2, "v1", true, longAgo))
+
+	// rollouts pause after pauseAfter pods have been updated
+	test("pauseAfter", enterprisev1.UpdateStrategySpec{PauseAfter: 1}, enterprisev1.PhaseReady,
+		[]string{},
+		newPod(0, "v0", true, longAgo), newPod(1, "v0", true, longAgo), newPod(2, "v1", true, longAgo))
+	test("pauseAfter increased", enterprisev1.UpdateStrategySpec{PauseAfter: 2}, enterprisev1.PhaseUpdating,
+		[]string{"splunk-stack1-1"},
+		newPod(0, "v0", true, longAgo), newPod(1, "v0", true, longAgo), newPod(2, "v1", true, longAgo))
+}
+
+func TestGetStatefulSetPhase(t *testing.T) {
+	c := newMockClient()
+	var replicas int32 = 3
+	statefulSet := appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "splunk-stack1-indexer", Namespace: "test"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+	}
+
+	test := func(status appsv1.StatefulSetStatus, want enterprisev1.ResourcePhase) {
+		current := statefulSet.DeepCopy()
+		current.Status = status
+		c.state[getStateKey(current)] = current
+		revised := statefulSet.DeepCopy()
+		if got := GetStatefulSetPhase(c, revised); got != want {
+			t.Errorf("GetStatefulSetPhase(%v) = %s; want %s", status, got, want)
+		}
+		if revised.Status.ReadyReplicas != status.ReadyReplicas {
+			t.Errorf("GetStatefulSetPhase(%v) ReadyReplicas = %d; want %d", status, revised.Status.ReadyReplicas, status.ReadyReplicas)
+		}
+	}
+
+	if got := GetStatefulSetPhase(c, statefulSet.DeepCopy()); got != enterprisev1.PhasePending {
+		t.Errorf("GetStatefulSetPhase() = %s; want Pending when it does not exist", got)
+	}
+	test(appsv1.StatefulSetStatus{Replicas: 3}, enterprisev1.PhasePending)
+	test(appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 1}, enterprisev1.PhaseScalingUp)
+	test(appsv1.StatefulSetStatus{Replicas: 4, ReadyReplicas: 4}, enterprisev1.PhaseScalingDown)
+	test(appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 3, UpdatedReplicas: 1, UpdateRevision: "v2"}, enterprisev1.PhaseUpdating)
+	test(appsv1.StatefulSetStatus{Replicas: 3, ReadyReplicas: 3, UpdatedReplicas: 3, UpdateRevision: "v2"}, enterprisev1.PhaseReady)
+	if len(c.calls["Update"]) != 0 || len(c.calls["Create"]) != 0 || len(c.calls["Delete"]) != 0 {
+		t.Errorf("GetStatefulSetPhase() modified resources: calls=%v", c.calls)
+	}
+}
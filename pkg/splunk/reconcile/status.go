@@ -0,0 +1,286 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+// GetStatusCondition returns the condition of the given type, or nil if there is none
+func GetStatusCondition(conditions []enterprisev1.Condition, conditionType enterprisev1.ConditionType) *enterprisev1.Condition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetStatusCondition adds or updates a condition. LastTransitionTime is only changed when the status of an existing
+// condition changes, or if it has not been set.
+func SetStatusCondition(conditions *[]enterprisev1.Condition, condition enterprisev1.Condition) {
+	existing := GetStatusCondition(*conditions, condition.Type)
+	if existing == nil {
+		if condition.LastTransitionTime.IsZero() {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		*conditions = append(*conditions, condition)
+		return
+	}
+	if existing.Status != condition.Status || existing.LastTransitionTime.IsZero() {
+		existing.Status = condition.Status
+		existing.LastTransitionTime = condition.LastTransitionTime
+		if existing.LastTransitionTime.IsZero() {
+			existing.LastTransitionTime = metav1.Now()
+		}
+	}
+	existing.ObservedGeneration = condition.ObservedGeneration
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+}
+
+// pausedMessage is used to report that reconcile has been paused
+var pausedMessage = fmt.Sprintf("reconcile is paused using annotation %s", enterprisev1.PausedAnnotation)
+
+// reconcileStep describes one step of reconciling a custom resource, and the condition used to report its outcome
+type reconcileStep struct {
+	// condition that reports the outcome of this step
+	condition enterprisev1.ConditionType
+
+	// reason used when this step completes successfully
+	successReason string
+
+	// reason used when this step fails with an error
+	failureReason string
+}
+
+var (
+	stepValidate      = reconcileStep{enterprisev1.ConditionSpecValid, "Valid", "InvalidSpec"}
+	stepSecrets       = reconcileStep{enterprisev1.ConditionSecretsReady, "Applied", "SecretsFailed"}
+	stepTLS           = reconcileStep{enterprisev1.ConditionTLSReady, "Applied", "TLSFailed"}
+	stepSmartStore    = reconcileStep{enterprisev1.ConditionSmartStoreReady, "Applied", "SmartStoreFailed"}
+	stepApps          = reconcileStep{enterprisev1.ConditionAppsReady, "Applied", "AppsFailed"}
+	stepServices      = reconcileStep{enterprisev1.ConditionServicesReady, "Applied", "ServicesFailed"}
+	stepClusterMaster = reconcileStep{enterprisev1.ConditionClusterMasterReady, "Ready", "ClusterMasterFailed"}
+	stepDeployer      = reconcileStep{enterprisev1.ConditionDeployerReady, "Ready", "DeployerFailed"}
+	stepSparkMaster   = reconcileStep{enterprisev1.ConditionSparkMasterReady, "Ready", "SparkMasterFailed"}
+	stepPods          = reconcileStep{enterprisev1.ConditionPodsReady, "Ready", "PodsFailed"}
+	stepCluster       = reconcileStep{enterprisev1.ConditionClusterReady, "Ready", "ClusterStatusFailed"}
+	stepPeers         = reconcileStep{enterprisev1.ConditionPeersReady, "Ready", "PeersFailed"}
+	stepBundle        = reconcileStep{enterprisev1.ConditionBundleReady, "Applied", "BundleFailed"}
+
+	// stepDeletion has no condition of its own; failures are only reported using Degraded
+	stepDeletion = reconcileStep{"", "", "DeletionFailed"}
+)
+
+// statusTracker records the outcome of each step of reconciling a custom resource using status conditions.
+// Each step is started by calling begin, which completes the previous step. Steps that only complete once
+// something becomes ready report this using setPhase or setCondition. When reconcile is finished, the summary
+// conditions (Ready, Progressing and Degraded) and observedGeneration are updated by finish.
+type statusTracker struct {
+	// generation of the custom resource being reconciled
+	generation int64
+
+	// phase of the custom resource, which is set to PhaseError if reconcile fails
+	phase *enterprisev1.ResourcePhase
+
+	// status that is updated with conditions and observedGeneration
+	status *enterprisev1.CommonStatus
+
+	// all steps used to reconcile this kind of custom resource, in order
+	steps []reconcileStep
+
+	// steps that have been started during this reconcile, in order
+	started []reconcileStep
+
+	// true if the condition for the most recently started step has already been set
+	recorded bool
+
+	// true if reconcile has been paused, in which case only status is refreshed
+	paused bool
+
+	// events is used to publish events when reconcile fails, or the custom resource becomes ready
+	events *eventPublisher
+}
+
+// newStatusTracker returns a statusTracker for a custom resource that is reconciled using the given steps
+func newStatusTracker(client ControllerClient, cr enterprisev1.MetaObject, phase *enterprisev1.ResourcePhase, status *enterprisev1.CommonStatus, steps ...reconcileStep) *statusTracker {
+	return &statusTracker{
+		generation: cr.GetObjectMeta().GetGeneration(),
+		phase:      phase,
+		status:     status,
+		steps:      steps,
+		paused:     IsPaused(cr),
+		events:     newEventPublisher(client, cr),
+	}
+}
+
+// begin completes the current step (if any), and starts the next one
+func (t *statusTracker) begin(step reconcileStep) {
+	t.complete()
+	t.started = append(t.started, step)
+	t.recorded = false
+}
+
+// setCondition sets the condition for the current step, instead of it being set to True when the step completes
+func (t *statusTracker) setCondition(status corev1.ConditionStatus, reason, message string) {
+	step := t.current()
+	if step == nil || step.condition == "" {
+		return
+	}
+	t.set(step.condition, status, reason, message)
+	t.recorded = true
+}
+
+// setPhase sets the condition for the current step using the phase of what it manages
+func (t *statusTracker) setPhase(phase enterprisev1.ResourcePhase) {
+	if phase == enterprisev1.PhaseReady {
+		t.setCondition(corev1.ConditionTrue, t.current().successReason, "")
+	} else {
+		t.setCondition(corev1.ConditionFalse, string(phase), "")
+	}
+}
+
+// finish records the outcome of reconcile, given the error (if any) that it returned
+func (t *statusTracker) finish(err error) {
+	step := t.current()
+	terminating := *t.phase == enterprisev1.PhaseTerminating
+
+	if err != nil {
+		// report failure using the condition for the step that failed
+		reason, message := "ReconcileFailed", err.Error()
+		if step != nil {
+			reason = step.failureReason
+			if step.condition != "" {
+				t.set(step.condition, corev1.ConditionFalse, reason, message)
+			}
+		}
+		if !terminating {
+			*t.phase = enterprisev1.PhaseError
+		}
+		t.events.Warning(reason, "%s", message)
+		t.set(enterprisev1.ConditionDegraded, corev1.ConditionTrue, reason, message)
+		t.set(enterprisev1.ConditionReady, corev1.ConditionFalse, reason, message)
+		t.set(enterprisev1.ConditionProgressing, corev1.ConditionFalse, reason, message)
+	} else {
+		t.complete()
+		t.set(enterprisev1.ConditionDegraded, corev1.ConditionFalse, "ReconcileSucceeded", "")
+		reason, message := t.notReady()
+		if reason == "" {
+			if ready := GetStatusCondition(t.status.Conditions, enterprisev1.ConditionReady); ready == nil || ready.Status != corev1.ConditionTrue {
+				t.events.Normal(string(enterprisev1.PhaseReady), "All resources are ready")
+			}
+			t.set(enterprisev1.ConditionReady, corev1.ConditionTrue, string(enterprisev1.PhaseReady), "")
+			t.set(enterprisev1.ConditionProgressing, corev1.ConditionFalse, string(enterprisev1.PhaseReady), "")
+		} else {
+			t.set(enterprisev1.ConditionReady, corev1.ConditionFalse, reason, message)
+			t.set(enterprisev1.ConditionProgressing, corev1.ConditionTrue, reason, message)
+		}
+		if t.paused {
+			// nothing is making progress while paused
+			t.set(enterprisev1.ConditionProgressing, corev1.ConditionFalse, "Paused", pausedMessage)
+		}
+	}
+	t.setPaused()
+
+	// steps that were not reached are unknown, unless the custom resource is being removed or reconcile is paused
+	if !terminating && !t.paused {
+		for _, s := range t.steps {
+			if !t.wasStarted(s) {
+				t.set(s.condition, corev1.ConditionUnknown, "Pending", "")
+			}
+		}
+	}
+
+	t.status.ObservedGeneration = t.generation
+}
+
+// setPaused sets the Paused condition, and publishes an event when reconcile is paused or resumed
+func (t *statusTracker) setPaused() {
+	previous := GetStatusCondition(t.status.Conditions, enterprisev1.ConditionPaused)
+	wasPaused := previous != nil && previous.Status == corev1.ConditionTrue
+	if t.paused {
+		if !wasPaused {
+			t.events.Normal("Paused", "%s", pausedMessage)
+		}
+		t.set(enterprisev1.ConditionPaused, corev1.ConditionTrue, "Paused", pausedMessage)
+	} else {
+		if wasPaused {
+			t.events.Normal("Resumed", "Reconcile has been resumed")
+		}
+		t.set(enterprisev1.ConditionPaused, corev1.ConditionFalse, "NotPaused", "")
+	}
+}
+
+// notReady returns the reason and message why a custom resource that was reconciled without error is not ready,
+// or an empty reason if it is ready
+func (t *statusTracker) notReady() (string, string) {
+	if *t.phase != enterprisev1.PhaseReady {
+		return string(*t.phase), fmt.Sprintf("phase is %s", *t.phase)
+	}
+	for _, s := range t.started {
+		if s.condition == "" {
+			continue
+		}
+		c := GetStatusCondition(t.status.Conditions, s.condition)
+		if c != nil && c.Status != corev1.ConditionTrue {
+			return c.Reason, c.Message
+		}
+	}
+	return "", ""
+}
+
+// current returns the step most recently started, or nil if there is none
+func (t *statusTracker) current() *reconcileStep {
+	if len(t.started) == 0 {
+		return nil
+	}
+	return &t.started[len(t.started)-1]
+}
+
+// complete sets the condition for the current step to True, unless it has already been set
+func (t *statusTracker) complete() {
+	step := t.current()
+	if step != nil && !t.recorded && step.condition != "" {
+		t.set(step.condition, corev1.ConditionTrue, step.successReason, "")
+	}
+	t.recorded = true
+}
+
+// wasStarted returns true if the given step was started during this reconcile
+func (t *statusTracker) wasStarted(step reconcileStep) bool {
+	for _, s := range t.started {
+		if s.condition == step.condition {
+			return true
+		}
+	}
+	return false
+}
+
+// set adds or updates a condition using the generation being reconciled
+func (t *statusTracker) set(conditionType enterprisev1.ConditionType, status corev1.ConditionStatus, reason, message string) {
+	SetStatusCondition(&t.status.Conditions, enterprisev1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: t.generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+func TestSetStatusCondition(t *testing.T) {
+	var conditions []enterprisev1.Condition
+	then := metav1.NewTime(time.Now().Add(-time.Hour))
+
+	SetStatusCondition(&conditions, enterprisev1.Condition{Type: enterprisev1.ConditionReady, Status: corev1.ConditionFalse, LastTransitionTime: then, Reason: "Pending"})
+	if len(conditions) != 1 || !conditions[0].LastTransitionTime.Equal(&then) {
+		t.Errorf("SetStatusCondition() = %v; want one condition with LastTransitionTime %v", conditions, then)
+	}
+
+	// transition time is kept if only the reason changes
+	SetStatusCondition(&conditions, enterprisev1.Condition{Type: enterprisev1.ConditionReady, Status: corev1.ConditionFalse, Reason: "Updating", ObservedGeneration: 2})
+	got := GetStatusCondition(conditions, enterprisev1.ConditionReady)
+	if got.Reason != "Updating" || got.ObservedGeneration != 2 || !got.LastTransitionTime.Equal(&then) {
+		t.Errorf("SetStatusCondition() = %v; want Reason=Updating, ObservedGeneration=2, LastTransitionTime=%v", got, then)
+	}
+
+	// transition time is updated when the status changes
+	SetStatusCondition(&conditions, enterprisev1.Condition{Type: enterprisev1.ConditionReady, Status: corev1.ConditionTrue, Reason: "Ready"})
+	got = GetStatusCondition(conditions, enterprisev1.ConditionReady)
+	if got.Status != corev1.ConditionTrue || !then.Before(&got.LastTransitionTime) {
+		t.Errorf("SetStatusCondition() = %v; want Status=True and LastTransitionTime after %v", got, then)
+	}
+
+	if GetStatusCondition(conditions, enterprisev1.ConditionDegraded) != nil {
+		t.Errorf("GetStatusCondition(Degraded) != nil; want nil")
+	}
+}
+
+func TestStatusTracker(t *testing.T) {
+	cr := enterprisev1.Standalone{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "stack1",
+			Namespace:  "test",
+			Generation: 3,
+		},
+	}
+	cr.Status.Phase = enterprisev1.PhaseReady
+
+	test := func(name string, wantPhase enterprisev1.ResourcePhase, want map[enterprisev1.ConditionType]string) {
+		if cr.Status.Phase != wantPhase {
+			t.Errorf("%s: Phase = %s; want %s", name, cr.Status.Phase, wantPhase)
+		}
+		if cr.Status.ObservedGeneration != 3 {
+			t.Errorf("%s: ObservedGeneration = %d; want 3", name, cr.Status.ObservedGeneration)
+		}
+		for conditionType, wantStatus := range want {
+			c := GetStatusCondition(cr.Status.Conditions, conditionType)
+			if c == nil {
+				t.Errorf("%s: condition %s is missing", name, conditionType)
+				continue
+			}
+			if got := string(c.Status) + "/" + c.Reason; got != wantStatus {
+				t.Errorf("%s: condition %s = %s; want %s", name, conditionType, got, wantStatus)
+			}
+			if c.ObservedGeneration != 3 {
+				t.Errorf("%s: condition %s ObservedGeneration = %d; want 3", name, conditionType, c.ObservedGeneration)
+			}
+		}
+	}
+	newTracker := func() *statusTracker {
+		return newStatusTracker(nil, &cr, &cr.Status.Phase, &cr.Status.CommonStatus, stepValidate, stepSecrets, stepServices, stepPods)
+	}
+
+	// failing step is reported, and later steps are unknown
+	tracker := newTracker()
+	tracker.begin(stepValidate)
+	tracker.begin(stepSecrets)
+	tracker.finish(errors.New("secret is invalid"))
+	test("secrets failed", enterprisev1.PhaseError, map[enterprisev1.ConditionType]string{
+		enterprisev1.ConditionSpecValid:     "True/Valid",
+		enterprisev1.ConditionSecretsReady:  "False/SecretsFailed",
+		enterprisev1.ConditionServicesReady: "Unknown/Pending",
+		enterprisev1.ConditionPodsReady:     "Unknown/Pending",
+		enterprisev1.ConditionDegraded:      "True/SecretsFailed",
+		enterprisev1.ConditionReady:         "False/SecretsFailed",
+		enterprisev1.ConditionProgressing:   "False/SecretsFailed",
+	})
+	if c := GetStatusCondition(cr.Status.Conditions, enterprisev1.ConditionSecretsReady); c.Message != "secret is invalid" {
+		t.Errorf("SecretsReady Message = %s; want \"secret is invalid\"", c.Message)
+	}
+
+	// pods that are not ready yet are progressing
+	tracker = newTracker()
+	tracker.begin(stepValidate)
+	tracker.begin(stepSecrets)
+	tracker.begin(stepServices)
+	tracker.begin(stepPods)
+	cr.Status.Phase = enterprisev1.PhaseUpdating
+	tracker.setPhase(enterprisev1.PhaseUpdating)
+	tracker.finish(nil)
+	test("pods updating", enterprisev1.PhaseUpdating, map[enterprisev1.ConditionType]string{
+		enterprisev1.ConditionSecretsReady:  "True/Applied",
+		enterprisev1.ConditionServicesReady: "True/Applied",
+		enterprisev1.ConditionPodsReady:     "False/Updating",
+		enterprisev1.ConditionDegraded:      "False/ReconcileSucceeded",
+		enterprisev1.ConditionReady:         "False/Updating",
+		enterprisev1.ConditionProgressing:   "True/Updating",
+	})
+
+	// everything is ready
+	tracker = newTracker()
+	tracker.begin(stepValidate)
+	tracker.begin(stepSecrets)
+	tracker.begin(stepServices)
+	tracker.begin(stepPods)
+	cr.Status.Phase = enterprisev1.PhaseReady
+	tracker.setPhase(enterprisev1.PhaseReady)
+	tracker.finish(nil)
+	test("ready", enterprisev1.PhaseReady, map[enterprisev1.ConditionType]string{
+		enterprisev1.ConditionPodsReady:   "True/Ready",
+		enterprisev1.ConditionDegraded:    "False/ReconcileSucceeded",
+		enterprisev1.ConditionReady:       "True/Ready",
+		enterprisev1.ConditionProgressing: "False/Ready",
+	})
+
+	// failed deletion keeps the terminating phase and leaves other conditions alone
+	tracker = newTracker()
+	tracker.begin(stepValidate)
+	tracker.begin(stepDeletion)
+	cr.Status.Phase = enterprisev1.PhaseTerminating
+	tracker.finish(errors.New("unable to delete pvc"))
+	test("deletion failed", enterprisev1.PhaseTerminating, map[enterprisev1.ConditionType]string{
+		enterprisev1.ConditionPodsReady: "True/Ready",
+		enterprisev1.ConditionDegraded:  "True/DeletionFailed",
+		enterprisev1.ConditionReady:     "False/DeletionFailed",
+	})
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	splclient "github.com/splunk/splunk-operator/pkg/splunk/client"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+	"github.com/splunk/splunk-operator/pkg/splunk/resources"
+)
+
+// TLSManager is used to manage the TLS certificates used by a Splunk Enterprise custom resource, and to create
+// clients that verify the certificates of its Splunk instances
+type TLSManager struct {
+	log          logr.Logger
+	cr           enterprisev1.MetaObject
+	spec         *enterprisev1.TLSSpec
+	status       *enterprisev1.TLSStatus
+	instanceType enterprise.InstanceType
+
+	// hosts contains the DNS names used by each pod, using the name of the pod as its key
+	hosts map[string][]string
+
+	// events is used to publish events for the custom resource (optional)
+	events *eventPublisher
+
+	// now is used to get the current time (optional, defaults to time.Now)
+	now func() time.Time
+
+	// secret is the TLS Secret, which is set by Apply
+	secret *corev1.Secret
+
+	// rootCAs contains the CA certificates in secret, which is set by Apply
+	rootCAs *x509.CertPool
+}
+
+// Apply for TLSManager reconciles the Kubernetes Secret containing TLS certificates for Splunk Enterprise instances.
+// Certificates are either copied from the user-managed Secret named by spec.SecretName, or issued for each pod by the
+// operator's internal CA, which is created if it does not exist yet. Certificates issued by the operator are renewed
+// 30 days before they expire.
+func (mgr *TLSManager) Apply(client ControllerClient) error {
+	if !mgr.spec.Enabled {
+		mgr.status.CAChecksum = ""
+		mgr.status.Expiration = nil
+		return nil
+	}
+
+	var source *corev1.Secret
+	var err error
+	if mgr.spec.SecretName != "" {
+		source, err = mgr.getUserSecret(client)
+	} else {
+		source, err = mgr.getOperatorCA(client)
+	}
+	if err != nil {
+		return err
+	}
+
+	// create or update the TLS Secret, reusing current certificates where possible
+	name := enterprise.GetSplunkTLSName(mgr.cr.GetIdentifier(), mgr.instanceType)
+	var current corev1.Secret
+	err = client.Get(context.TODO(), types.NamespacedName{Namespace: mgr.cr.GetNamespace(), Name: name}, &current)
+	found := err == nil
+	var previous *corev1.Secret
+	if found {
+		previous = &current
+	}
+	secret, err := enterprise.GetSplunkTLSSecret(mgr.cr, mgr.spec, mgr.instanceType, source, previous, mgr.hosts, mgr.getTime())
+	if err != nil {
+		return err
+	}
+	if !found {
+		secret.SetOwnerReferences(append(secret.GetOwnerReferences(), resources.AsOwner(mgr.cr)))
+		if err = CreateResource(client, secret); err != nil {
+			return err
+		}
+		mgr.events.Normal("IssuedCertificates", "Created TLS Secret %s", name)
+	} else {
+		expiration := secret.GetAnnotations()[enterprise.TLSExpirationAnnotation]
+		renewed := current.GetAnnotations()[enterprise.TLSExpirationAnnotation] != expiration
+		if renewed || !reflect.DeepEqual(secret.Data, current.Data) {
+			current.Data = secret.Data
+			if current.Annotations == nil {
+				current.Annotations = make(map[string]string)
+			}
+			current.Annotations[enterprise.TLSExpirationAnnotation] = expiration
+			if err = UpdateResource(client, &current); err != nil {
+				return err
+			}
+			if renewed {
+				mgr.events.Normal("IssuedCertificates", "Updated certificates in TLS Secret %s, which expire at %s", name, expiration)
+			}
+		}
+		secret = &current
+	}
+
+	mgr.rootCAs = x509.NewCertPool()
+	if !mgr.rootCAs.AppendCertsFromPEM(secret.Data[enterprise.TLSCACertKey]) {
+		return fmt.Errorf("No valid CA certificates found in %s", enterprise.TLSCACertKey)
+	}
+	mgr.secret = secret
+	mgr.status.Expiration = enterprise.GetTLSExpiration(secret)
+	return nil
+}
+
+// Load for TLSManager reads the TLS Secret of a custom resource that is reconciled elsewhere, such as the cluster
+// master referenced by an indexer cluster, so that clients verify the certificates of its instances using its own CA
+// rather than the CA of the referencing resource. The TLS Secret is never modified, and certificates are not verified
+// until it exists and all of the referenced resource's pods have been updated to use it.
+func (mgr *TLSManager) Load(client ControllerClient) error {
+	mgr.secret = nil
+	mgr.rootCAs = nil
+	if !mgr.spec.Enabled {
+		return nil
+	}
+
+	name := enterprise.GetSplunkTLSName(mgr.cr.GetIdentifier(), mgr.instanceType)
+	var secret corev1.Secret
+	err := client.Get(context.TODO(), types.NamespacedName{Namespace: mgr.cr.GetNamespace(), Name: name}, &secret)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to get TLS Secret %s: %v", name, err)
+	}
+
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(secret.Data[enterprise.TLSCACertKey]) {
+		return fmt.Errorf("No valid CA certificates found in %s of TLS Secret %s", enterprise.TLSCACertKey, name)
+	}
+	mgr.secret = &secret
+	mgr.rootCAs = rootCAs
+	return nil
+}
+
+// getUserSecret for TLSManager returns the user-managed Secret named by spec.SecretName
+func (mgr *TLSManager) getUserSecret(client ControllerClient) (*corev1.Secret, error) {
+	namespacedName := types.NamespacedName{Namespace: mgr.cr.GetNamespace(), Name: mgr.spec.SecretName}
+	var secret corev1.Secret
+	err := client.Get(context.TODO(), namespacedName, &secret)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get Secret %s referenced by tls.secretName: %v", mgr.spec.SecretName, err)
+	}
+	return &secret, nil
+}
+
+// getOperatorCA for TLSManager returns the Secret used by the operator's internal CA, creating it if necessary. The CA
+// is shared by all custom resources within a namespace, so it has no owner.
+func (mgr *TLSManager) getOperatorCA(client ControllerClient) (*corev1.Secret, error) {
+	namespacedName := types.NamespacedName{Namespace: mgr.cr.GetNamespace(), Name: enterprise.GetSplunkOperatorCAName()}
+	var secret corev1.Secret
+	err := client.Get(context.TODO(), namespacedName, &secret)
+	if err == nil {
+		return &secret, nil
+	}
+
+	mgr.log.Info("Creating internal CA", "secret", namespacedName.Name)
+	ca, err := enterprise.GetSplunkOperatorCA(mgr.cr.GetNamespace(), mgr.getTime())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create internal CA: %v", err)
+	}
+	if err = CreateResource(client, ca); err != nil {
+		return nil, err
+	}
+	mgr.events.Normal("CreatedCA", "Created internal CA %s", ca.GetName())
+	return ca, nil
+}
+
+// newSplunkClient for TLSManager returns a SplunkClient that verifies certificates using the CA, once all pods have
+// been updated to use it. Until then, certificates are not verified.
+func (mgr *TLSManager) newSplunkClient(managementURI, username, password string) *splclient.SplunkClient {
+	if mgr.rootCAs == nil || mgr.status.CAChecksum == "" || mgr.status.CAChecksum != mgr.getCAChecksum() {
+		return splclient.NewSplunkClient(managementURI, username, password)
+	}
+	return splclient.NewVerifiedSplunkClient(managementURI, username, password, mgr.rootCAs)
+}
+
+// setReady for TLSManager records that all pods have been updated to use the current CA, so that their certificates
+// are verified from now on
+func (mgr *TLSManager) setReady() {
+	if mgr.secret != nil {
+		mgr.status.CAChecksum = mgr.getCAChecksum()
+	}
+}
+
+// setRequeue for TLSManager updates a reconcile result to requeue when certificates issued by the operator are due to
+// be renewed, unless it is already requeued sooner
+func (mgr *TLSManager) setRequeue(result *reconcile.Result) {
+	if mgr.secret == nil || mgr.spec.SecretName != "" || mgr.status.Expiration == nil {
+		return
+	}
+	after := mgr.status.Expiration.Add(-enterprise.TLSRenewBefore).Sub(mgr.getTime())
+	if after < time.Second {
+		after = time.Second
+	}
+	if !result.Requeue || after < result.RequeueAfter {
+		result.Requeue = true
+		result.RequeueAfter = after
+	}
+}
+
+// getCAChecksum for TLSManager returns a checksum of the CA certificates in the TLS Secret
+func (mgr *TLSManager) getCAChecksum() string {
+	hash := sha256.Sum256(mgr.secret.Data[enterprise.TLSCACertKey])
+	return hex.EncodeToString(hash[:])
+}
+
+// getTime for TLSManager returns the current time
+func (mgr *TLSManager) getTime() time.Time {
+	if mgr.now != nil {
+		return mgr.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+)
+
+// verifiesCertificate returns true if a client created by a TLSManager verifies a certificate issued for the pod named
+// by certName within a TLS Secret
+func verifiesCertificate(t *testing.T, mgr *TLSManager, secret *corev1.Secret, certName string) bool {
+	block, _ := pem.Decode(secret.Data[certName+".crt"])
+	if block == nil {
+		t.Fatalf("TLS Secret %s has no certificate %s", secret.GetName(), certName)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("TLS Secret %s has invalid certificate %s: %v", secret.GetName(), certName, err)
+	}
+	c := mgr.newSplunkClient("https://localhost:8089", "admin", "p@ssw0rd")
+	config := c.Client.(*http.Client).Transport.(*http.Transport).TLSClientConfig
+	if config.RootCAs == nil {
+		return false
+	}
+	_, err = cert.Verify(x509.VerifyOptions{Roots: config.RootCAs})
+	return err == nil
+}
+
+// isVerifiedClient returns true if a client created by a TLSManager verifies certificates
+func isVerifiedClient(mgr *TLSManager) bool {
+	c := mgr.newSplunkClient("https://localhost:8089", "admin", "p@ssw0rd")
+	config := c.Client.(*http.Client).Transport.(*http.Transport).TLSClientConfig
+	return !config.InsecureSkipVerify && config.RootCAs != nil
+}
+
+func TestTLSManager(t *testing.T) {
+	method := "TLSManager.Apply()"
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	cr := enterprisev1.Standalone{
+		TypeMeta: metav1.TypeMeta{
+			Kind: "Standalone",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stack1",
+			Namespace: "test",
+		},
+	}
+	c := newMockClient()
+	mgr := TLSManager{
+		log:          log.WithName(method),
+		cr:           &cr,
+		spec:         &cr.Spec.TLS,
+		status:       &cr.Status.TLS,
+		instanceType: enterprise.SplunkStandalone,
+		hosts:        enterprise.GetSplunkTLSHosts("test", enterprise.SplunkStandalone, "stack1", "", 1),
+		events:       newEventPublisher(c, &cr),
+		now:          func() time.Time { return now },
+	}
+	test := func(wantErr string) {
+		err := mgr.Apply(c)
+		if (err == nil && wantErr != "") || (err != nil && err.Error() != wantErr) {
+			t.Errorf("%s = %v; want %s", method, err, wantErr)
+		}
+	}
+	getSecret := func() *corev1.Secret {
+		secret, ok := c.state["*v1.Secret-test-splunk-stack1-standalone-tls"].(*corev1.Secret)
+		if !ok {
+			t.Fatalf("%s did not create TLS Secret", method)
+		}
+		return secret
+	}
+
+	// nothing to do when disabled
+	test("")
+	if len(c.calls["Create"]) != 0 || isVerifiedClient(&mgr) {
+		t.Errorf("%s created %d objects, verified=%t; want 0, false", method, len(c.calls["Create"]), isVerifiedClient(&mgr))
+	}
+
+	// internal CA is created, and used to issue certificates
+	cr.Spec.TLS.Enabled = true
+	test("")
+	ca, ok := c.state["*v1.Secret-test-splunk-operator-ca"].(*corev1.Secret)
+	if !ok || len(ca.GetOwnerReferences()) != 0 {
+		t.Fatalf("%s did not create internal CA without an owner", method)
+	}
+	secret := getSecret()
+	if !bytes.Equal(secret.Data[enterprise.TLSCACertKey], ca.Data[enterprise.TLSCACertKey]) || len(secret.GetOwnerReferences()) != 1 {
+		t.Errorf("%s TLS Secret ca.crt = %s, owners = %v; want internal CA and owner", method, secret.Data[enterprise.TLSCACertKey], secret.GetOwnerReferences())
+	}
+	if len(secret.Data["splunk-stack1-standalone-0.pem"]) == 0 {
+		t.Errorf("%s did not issue certificate for splunk-stack1-standalone-0", method)
+	}
+	if got := (*c.events)[0]; got.reason != "CreatedCA" {
+		t.Errorf("%s published %s; want CreatedCA", method, got.reason)
+	}
+	if got := (*c.events)[1]; got.reason != "IssuedCertificates" {
+		t.Errorf("%s published %s; want IssuedCertificates", method, got.reason)
+	}
+	wantExpiration := now.Add(365 * 24 * time.Hour)
+	if cr.Status.TLS.Expiration == nil || !cr.Status.TLS.Expiration.Time.Equal(wantExpiration) {
+		t.Errorf("%s status expiration = %v; want %s", method, cr.Status.TLS.Expiration, wantExpiration)
+	}
+
+	// certificates are only verified once all pods have been updated
+	if isVerifiedClient(&mgr) {
+		t.Errorf("%s verified certificates before pods were updated", method)
+	}
+	mgr.setReady()
+	if cr.Status.TLS.CAChecksum == "" || !isVerifiedClient(&mgr) {
+		t.Errorf("%s did not verify certificates after pods were updated", method)
+	}
+
+	// nothing changes until certificates are due to be renewed
+	c.resetCalls()
+	now = now.Add(30 * 24 * time.Hour)
+	test("")
+	if len(c.calls["Create"]) != 0 || len(c.calls["Update"]) != 0 {
+		t.Errorf("%s created %d and updated %d objects; want 0", method, len(c.calls["Create"]), len(c.calls["Update"]))
+	}
+	result := reconcile.Result{}
+	mgr.setRequeue(&result)
+	if want := (365 - 30 - 30) * 24 * time.Hour; !result.Requeue || result.RequeueAfter != want {
+		t.Errorf("%s setRequeue() = %v; want %s", method, result, want)
+	}
+
+	// certificates are renewed using the same CA, so they are still verified
+	now = wantExpiration.Add(-24 * time.Hour)
+	test("")
+	if len(c.calls["Update"]) != 1 || bytes.Equal(getSecret().Data["splunk-stack1-standalone-0.key"], secret.Data["splunk-stack1-standalone-0.key"]) {
+		t.Errorf("%s did not renew certificates", method)
+	}
+	if got := (*c.events)[len(*c.events)-1]; got.reason != "IssuedCertificates" {
+		t.Errorf("%s published %s; want IssuedCertificates", method, got.reason)
+	}
+	if !isVerifiedClient(&mgr) {
+		t.Errorf("%s stopped verifying certificates after renewal", method)
+	}
+
+	// certificates are not verified while pods are updated to use a new CA
+	newCA, _ := enterprise.GetSplunkOperatorCA("test", now)
+	c.state[getStateKey(newCA)] = newCA
+	test("")
+	if !bytes.Equal(getSecret().Data[enterprise.TLSCACertKey], newCA.Data[enterprise.TLSCACertKey]) || isVerifiedClient(&mgr) {
+		t.Errorf("%s did not re-issue certificates without verification for new CA", method)
+	}
+
+	// user-managed certificates must exist
+	cr.Spec.TLS.SecretName = "my-certs"
+	test("Unable to get Secret my-certs referenced by tls.secretName: NotFound")
+
+	// nothing is verified when disabled again
+	cr.Spec.TLS = enterprisev1.TLSSpec{}
+	test("")
+	if cr.Status.TLS.CAChecksum != "" || cr.Status.TLS.Expiration != nil || isVerifiedClient(&mgr) {
+		t.Errorf("%s status = %v; want empty", method, cr.Status.TLS)
+	}
+}
+
+func TestUpdateConfigChecksumsTLS(t *testing.T) {
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	cr := enterprisev1.Standalone{ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"}}
+	cr.Spec.TLS.Enabled = true
+	c := newMockClient()
+	secrets := enterprise.GetSplunkSecrets(&cr, enterprise.SplunkStandalone, nil, nil)
+	c.state[getStateKey(secrets)] = secrets
+	ca, _ := enterprise.GetSplunkOperatorCA("test", now)
+	getChecksum := func(replicas int32, now time.Time) string {
+		var current *corev1.Secret
+		if obj, ok := c.state["*v1.Secret-test-splunk-stack1-standalone-tls"]; ok {
+			current = obj.(*corev1.Secret)
+		}
+		hosts := enterprise.GetSplunkTLSHosts("test", enterprise.SplunkStandalone, "stack1", "", replicas)
+		secret, err := enterprise.GetSplunkTLSSecret(&cr, &cr.Spec.TLS, enterprise.SplunkStandalone, ca, current, hosts, now)
+		if err != nil {
+			t.Fatalf("GetSplunkTLSSecret() returned error: %v", err)
+		}
+		c.state[getStateKey(secret)] = secret
+		podTemplateSpec := corev1.PodTemplateSpec{}
+		err = updateConfigChecksums(c, &cr, &cr.Spec.CommonSplunkSpec, enterprise.SplunkStandalone, &podTemplateSpec)
+		if err != nil {
+			t.Errorf("updateConfigChecksums() returned error: %v", err)
+		}
+		return podTemplateSpec.ObjectMeta.Annotations[tlsChecksumAnnotation]
+	}
+
+	checksum := getChecksum(1, now)
+	if checksum == "" {
+		t.Errorf("updateConfigChecksums() missing %s", tlsChecksumAnnotation)
+	}
+	if got := getChecksum(3, now); got != checksum {
+		t.Errorf("updateConfigChecksums() %s changed after scaling up", tlsChecksumAnnotation)
+	}
+	if got := getChecksum(3, now.Add(360*24*time.Hour)); got == checksum {
+		t.Errorf("updateConfigChecksums() %s did not change after renewal", tlsChecksumAnnotation)
+	}
+}
+
+func TestTLSManagerLoad(t *testing.T) {
+	method := "TLSManager.Load()"
+	now := time.Now()
+	c := newMockClient()
+	c.notFoundError = k8serrors.NewNotFound(corev1.Resource("secrets"), "missing")
+	apply := func(cr enterprisev1.MetaObject, spec *enterprisev1.TLSSpec, status *enterprisev1.TLSStatus, instanceType enterprise.InstanceType) (*TLSManager, *corev1.Secret) {
+		mgr := TLSManager{log: log.WithName(method), cr: cr, spec: spec, status: status, instanceType: instanceType,
+			hosts: enterprise.GetSplunkTLSHosts(cr.GetNamespace(), instanceType, cr.GetIdentifier(), "", 1), now: func() time.Time { return now }}
+		if err := mgr.Apply(c); err != nil {
+			t.Fatalf("TLSManager.Apply(%s) returned %v", cr.GetIdentifier(), err)
+		}
+		mgr.setReady()
+		return &mgr, mgr.secret
+	}
+	idxc := enterprisev1.IndexerCluster{
+		TypeMeta:   metav1.TypeMeta{Kind: "IndexerCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "idxc1", Namespace: "test"},
+	}
+	idxc.Spec.TLS.Enabled = true
+	cm := enterprisev1.ClusterMaster{
+		TypeMeta:   metav1.TypeMeta{Kind: "ClusterMaster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "master1", Namespace: "cluster"},
+	}
+	loaded := TLSManager{log: log.WithName(method), cr: &cm, spec: &cm.Spec.TLS, status: &cm.Status.TLS, instanceType: enterprise.SplunkClusterMaster}
+	test := func(wantErr string) {
+		err := loaded.Load(c)
+		if (err == nil && wantErr != "") || (err != nil && err.Error() != wantErr) {
+			t.Errorf("%s = %v; want %s", method, err, wantErr)
+		}
+	}
+	idxcMgr, _ := apply(&idxc, &idxc.Spec.TLS, &idxc.Status.TLS, enterprise.SplunkIndexer)
+
+	// nothing is verified when disabled, or before the TLS Secret exists
+	test("")
+	cm.Spec.TLS.Enabled = true
+	test("")
+	if isVerifiedClient(&loaded) || len(c.calls["Create"]) != 2 {
+		t.Errorf("%s verified certificates or created objects without a TLS Secret", method)
+	}
+
+	// a cluster master in another namespace uses that namespace's internal CA, which the indexers' CA does not trust
+	_, cmSecret := apply(&cm, &cm.Spec.TLS, &cm.Status.TLS, enterprise.SplunkClusterMaster)
+	c.resetCalls()
+	test("")
+	if !verifiesCertificate(t, &loaded, cmSecret, "splunk-master1-cluster-master-0") || verifiesCertificate(t, idxcMgr, cmSecret, "splunk-master1-cluster-master-0") {
+		t.Errorf("%s did not verify cluster master certificate using its own CA", method)
+	}
+	if len(c.calls["Create"]) != 0 || len(c.calls["Update"]) != 0 {
+		t.Errorf("%s created %d and updated %d objects; want 0", method, len(c.calls["Create"]), len(c.calls["Update"]))
+	}
+
+	// certificates are not verified while the cluster master's pods are updated to use a new CA
+	cm.Status.TLS.CAChecksum = "old"
+	test("")
+	if isVerifiedClient(&loaded) {
+		t.Errorf("%s verified certificates before cluster master pods were updated", method)
+	}
+
+	// a cluster master using tls.secretName is verified using the CA in the user-managed Secret, even in the same
+	// namespace as the indexers
+	userCA, _ := enterprise.GetSplunkOperatorCA("user", now)
+	userCerts, err := enterprise.GetSplunkTLSSecret(&cm, &enterprisev1.TLSSpec{Enabled: true}, enterprise.SplunkClusterMaster, userCA, nil,
+		enterprise.GetSplunkTLSHosts("test", enterprise.SplunkClusterMaster, "master1", "", 1), now)
+	if err != nil {
+		t.Fatalf("GetSplunkTLSSecret() returned %v", err)
+	}
+	cm.ObjectMeta.Namespace = "test"
+	cm.Spec.TLS.SecretName = "my-certs"
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-certs", Namespace: "test"},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       userCerts.Data["splunk-master1-cluster-master-0.crt"],
+			corev1.TLSPrivateKeyKey: userCerts.Data["splunk-master1-cluster-master-0.key"],
+			enterprise.TLSCACertKey: userCA.Data[enterprise.TLSCACertKey],
+		},
+	}
+	c.state[getStateKey(source)] = source
+	_, cmSecret = apply(&cm, &cm.Spec.TLS, &cm.Status.TLS, enterprise.SplunkClusterMaster)
+	test("")
+	if !verifiesCertificate(t, &loaded, cmSecret, "server") || verifiesCertificate(t, idxcMgr, cmSecret, "server") {
+		t.Errorf("%s did not verify cluster master certificate using the user-managed CA", method)
+	}
+
+	// TLS Secrets without a valid CA are rejected
+	cmSecret.Data[enterprise.TLSCACertKey] = []byte("invalid")
+	test("No valid CA certificates found in ca.crt of TLS Secret splunk-master1-cluster-master-tls")
+	if isVerifiedClient(&loaded) {
+		t.Errorf("%s verified certificates using an invalid TLS Secret", method)
+	}
+}
@@ -17,6 +17,7 @@ package reconcile
 import (
 	"context"
 	"reflect"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -28,6 +29,7 @@ import (
 	//stdlog "log"
 	//"github.com/go-logr/stdr"
 
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	"github.com/splunk/splunk-operator/pkg/splunk/resources"
 )
 
@@ -43,9 +45,19 @@ type ResourceObject interface {
 	GetObjectMeta() metav1.Object
 }
 
-// The ControllerClient interfaces implements methods of the Kubernetes controller-runtime client
+// The ControllerClient interfaces implements methods of the Kubernetes controller-runtime client, and is
+// used to publish Kubernetes events about custom resources
 type ControllerClient interface {
 	client.Client
+
+	// Eventf publishes a Kubernetes event for an object (see record.EventRecorder)
+	Eventf(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{})
+}
+
+// IsPaused returns true if reconciling a custom resource has been suspended using its paused annotation
+func IsPaused(cr enterprisev1.MetaObject) bool {
+	paused, _ := strconv.ParseBool(cr.GetObjectMeta().GetAnnotations()[enterprisev1.PausedAnnotation])
+	return paused
 }
 
 // CreateResource creates a new Kubernetes resource using the REST API.
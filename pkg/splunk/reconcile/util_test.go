@@ -21,7 +21,7 @@ import (
 	"reflect"
 	"testing"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -49,6 +49,8 @@ func copyResource(dst runtime.Object, src runtime.Object) {
 		*dst.(*appsv1.Deployment) = *src.(*appsv1.Deployment)
 	case *appsv1.StatefulSet:
 		*dst.(*appsv1.StatefulSet) = *src.(*appsv1.StatefulSet)
+	case *enterprisev1.ClusterMaster:
+		*dst.(*enterprisev1.ClusterMaster) = *src.(*enterprisev1.ClusterMaster)
 	case *enterprisev1.IndexerCluster:
 		*dst.(*enterprisev1.IndexerCluster) = *src.(*enterprisev1.IndexerCluster)
 	case *enterprisev1.LicenseMaster:
@@ -59,6 +61,18 @@ func copyResource(dst runtime.Object, src runtime.Object) {
 		*dst.(*enterprisev1.Spark) = *src.(*enterprisev1.Spark)
 	case *enterprisev1.Standalone:
 		*dst.(*enterprisev1.Standalone) = *src.(*enterprisev1.Standalone)
+	case *enterprisev1.MonitoringConsole:
+		*dst.(*enterprisev1.MonitoringConsole) = *src.(*enterprisev1.MonitoringConsole)
+	case *enterprisev1.ClusterMasterList:
+		*dst.(*enterprisev1.ClusterMasterList) = *src.(*enterprisev1.ClusterMasterList)
+	case *enterprisev1.IndexerClusterList:
+		*dst.(*enterprisev1.IndexerClusterList) = *src.(*enterprisev1.IndexerClusterList)
+	case *enterprisev1.LicenseMasterList:
+		*dst.(*enterprisev1.LicenseMasterList) = *src.(*enterprisev1.LicenseMasterList)
+	case *enterprisev1.SearchHeadClusterList:
+		*dst.(*enterprisev1.SearchHeadClusterList) = *src.(*enterprisev1.SearchHeadClusterList)
+	case *enterprisev1.StandaloneList:
+		*dst.(*enterprisev1.StandaloneList) = *src.(*enterprisev1.StandaloneList)
 	default:
 		dst = src
 	}
@@ -80,6 +94,11 @@ func getStateKeyWithKey(key client.ObjectKey, obj runtime.Object) string {
 	return fmt.Sprintf("%s-%s-%s", kind, key.Namespace, key.Name)
 }
 
+// getListStateKey returns a lookup key for list objects in the mockClient's state map
+func getListStateKey(obj runtime.Object) string {
+	return getStateKeyWithKey(client.ObjectKey{}, obj)
+}
+
 // mockFuncCall is used to record a function call to mockClient methods
 type mockFuncCall struct {
 	ctx      context.Context
@@ -121,7 +140,7 @@ type mockClient struct {
 	// status is a StatusWriter mock client returned by Status()
 	status mockStatusWriter
 
-	// listObj is used to assign obj parameter for List() calls
+	// listObj is used to assign obj parameter for List() calls; if nil, lists are retrieved from state using getListStateKey()
 	listObj runtime.Object
 
 	// state is used to maintain a simple state of objects in the cluster, where key = <type>-<namespace>-<name>
@@ -132,6 +151,27 @@ type mockClient struct {
 
 	// error returned when an object is not found
 	notFoundError error
+
+	// events is a record of all events published using Eventf()
+	events *[]mockEvent
+}
+
+// mockEvent is used to record an event published using mockClient
+type mockEvent struct {
+	obj       runtime.Object
+	eventType string
+	reason    string
+	message   string
+}
+
+// Eventf records an event that was published
+func (c mockClient) Eventf(obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	*c.events = append(*c.events, mockEvent{
+		obj:       obj,
+		eventType: eventType,
+		reason:    reason,
+		message:   fmt.Sprintf(messageFmt, args...),
+	})
 }
 
 // Get returns mock client's err field
@@ -157,6 +197,11 @@ func (c mockClient) List(ctx context.Context, obj runtime.Object, opts ...client
 		obj:      obj,
 	})
 	listObj := c.listObj
+	if listObj == nil {
+		if stateObj := c.state[getListStateKey(obj)]; stateObj != nil {
+			listObj = stateObj.(runtime.Object)
+		}
+	}
 	if listObj != nil {
 		copyResource(obj, listObj.(runtime.Object))
 		return nil
@@ -281,6 +326,7 @@ func newMockClient() *mockClient {
 		state:         make(map[string]interface{}),
 		calls:         make(map[string][]mockFuncCall),
 		notFoundError: errors.New("NotFound"),
+		events:        &[]mockEvent{},
 	}
 	return c
 }
@@ -531,3 +577,13 @@ func TestMergeServiceSpecUpdates(t *testing.T) {
 	matcher = func() bool { return current.ExternalTrafficPolicy == revised.ExternalTrafficPolicy }
 	svcUpdateTester("Service ExternalTrafficPolicy changed")
 }
+
+func TestIsPaused(t *testing.T) {
+	cr := enterprisev1.Standalone{}
+	for value, want := range map[string]bool{"": false, "true": true, "True": true, "false": false, "yes": false} {
+		cr.ObjectMeta.Annotations = map[string]string{enterprisev1.PausedAnnotation: value}
+		if got := IsPaused(&cr); got != want {
+			t.Errorf("IsPaused(%q) = %t; want %t", value, got, want)
+		}
+	}
+}
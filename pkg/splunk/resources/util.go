@@ -29,7 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 )
 
 func init() {
@@ -379,6 +379,39 @@ func AppendPodAntiAffinity(affinity *corev1.Affinity, identifier string, typeLab
 	return affinity
 }
 
+// AppendZoneNodeAffinity appends a Kubernetes Affinity object to require scheduling pods in a specific availability zone, and returns the result.
+func AppendZoneNodeAffinity(affinity *corev1.Affinity, zone string) *corev1.Affinity {
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	} else {
+		affinity = affinity.DeepCopy()
+	}
+
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	if affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+
+	zoneRequirement := corev1.NodeSelectorRequirement{
+		Key:      corev1.LabelZoneFailureDomain,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{zone},
+	}
+
+	// node selector terms are ORed, so the zone requirement must be added to each of them
+	selector := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	for idx := range selector.NodeSelectorTerms {
+		selector.NodeSelectorTerms[idx].MatchExpressions = append(selector.NodeSelectorTerms[idx].MatchExpressions, zoneRequirement)
+	}
+
+	return affinity
+}
+
 // ValidateImagePullPolicy checks validity of the ImagePullPolicy spec parameter, and returns error if it is invalid.
 func ValidateImagePullPolicy(imagePullPolicy *string) error {
 	// ImagePullPolicy
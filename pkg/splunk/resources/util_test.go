@@ -26,7 +26,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 )
 
 func TestAsOwner(t *testing.T) {
@@ -539,6 +539,63 @@ func TestAppendPodAffinity(t *testing.T) {
 	})
 }
 
+func TestAppendZoneNodeAffinity(t *testing.T) {
+	test := func(affinity *corev1.Affinity, want corev1.Affinity) {
+		got := AppendZoneNodeAffinity(affinity, "us-west-2a")
+		f := func() bool {
+			return CompareByMarshall(got, want)
+		}
+		compareTester(t, "AppendZoneNodeAffinity()", f, got, want, false)
+	}
+
+	zoneRequirement := corev1.NodeSelectorRequirement{
+		Key:      "failure-domain.beta.kubernetes.io/zone",
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"us-west-2a"},
+	}
+	diskRequirement := corev1.NodeSelectorRequirement{
+		Key:      "disktype",
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"ssd"},
+	}
+
+	test(nil, corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{zoneRequirement}},
+				},
+			},
+		},
+	})
+
+	affinity := corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{diskRequirement}},
+					{MatchExpressions: []corev1.NodeSelectorRequirement{}},
+				},
+			},
+		},
+	}
+	test(&affinity, corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{diskRequirement, zoneRequirement}},
+					{MatchExpressions: []corev1.NodeSelectorRequirement{zoneRequirement}},
+				},
+			},
+		},
+	})
+
+	// original affinity must not be modified
+	if len(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions) != 1 {
+		t.Errorf("AppendZoneNodeAffinity() modified original affinity")
+	}
+}
+
 func TestValidateCommonSpec(t *testing.T) {
 	spec := enterprisev1.CommonSpec{}
 	defaultResources := corev1.ResourceRequirements{
@@ -15,13 +15,15 @@
 package spark
 
 import (
+	"fmt"
+
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 	"github.com/splunk/splunk-operator/pkg/splunk/resources"
 )
 
@@ -99,6 +101,9 @@ func getSparkWorkerServicePorts() []corev1.ServicePort {
 // ValidateSparkSpec checks validity and makes default updates to a SparkSpec, and returns error if something is wrong.
 func ValidateSparkSpec(spec *enterprisev1.SparkSpec) error {
 	spec.CommonSpec.Image = GetSparkImage(spec.CommonSpec.Image)
+	if spec.Replicas < 0 {
+		return fmt.Errorf("replicas must not be negative; value=%d", spec.Replicas)
+	}
 	if spec.Replicas == 0 {
 		spec.Replicas = 1
 	}
@@ -20,7 +20,7 @@ import (
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
-	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
 )
 
 func TestGetSparkDeployment(t *testing.T) {
@@ -62,7 +62,7 @@ func TestGetSparkService(t *testing.T) {
 	cr := enterprisev1.Spark{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Spark",
-			APIVersion: "enterprise.splunk.com/v1alpha2",
+			APIVersion: "enterprise.splunk.com/v1alpha3",
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "stack1",
@@ -82,14 +82,14 @@ func TestGetSparkService(t *testing.T) {
 		}
 	}
 
-	test(SparkMaster, false, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"splunk-stack1-spark-master-service","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-master","app.kubernetes.io/part-of":"splunk-stack1-spark"},"ownerReferences":[{"apiVersion":"enterprise.splunk.com/v1alpha2","kind":"Spark","name":"stack1","uid":"05db21b4-7244-4022-a844-c131a8747f30","controller":true}]},"spec":{"ports":[{"name":"sparkmaster","port":7777,"targetPort":0},{"name":"sparkwebui","port":8009,"targetPort":0}],"selector":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-master","app.kubernetes.io/part-of":"splunk-stack1-spark"}},"status":{"loadBalancer":{}}}`)
-	test(SparkMaster, true, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"splunk-stack1-spark-master-headless","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-master","app.kubernetes.io/part-of":"splunk-stack1-spark"},"ownerReferences":[{"apiVersion":"enterprise.splunk.com/v1alpha2","kind":"Spark","name":"stack1","uid":"05db21b4-7244-4022-a844-c131a8747f30","controller":true}]},"spec":{"ports":[{"name":"sparkmaster","port":7777,"targetPort":0},{"name":"sparkwebui","port":8009,"targetPort":0}],"selector":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-master","app.kubernetes.io/part-of":"splunk-stack1-spark"},"clusterIP":"None"},"status":{"loadBalancer":{}}}`)
+	test(SparkMaster, false, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"splunk-stack1-spark-master-service","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-master","app.kubernetes.io/part-of":"splunk-stack1-spark"},"ownerReferences":[{"apiVersion":"enterprise.splunk.com/v1alpha3","kind":"Spark","name":"stack1","uid":"05db21b4-7244-4022-a844-c131a8747f30","controller":true}]},"spec":{"ports":[{"name":"sparkmaster","port":7777,"targetPort":0},{"name":"sparkwebui","port":8009,"targetPort":0}],"selector":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-master","app.kubernetes.io/part-of":"splunk-stack1-spark"}},"status":{"loadBalancer":{}}}`)
+	test(SparkMaster, true, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"splunk-stack1-spark-master-headless","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-master","app.kubernetes.io/part-of":"splunk-stack1-spark"},"ownerReferences":[{"apiVersion":"enterprise.splunk.com/v1alpha3","kind":"Spark","name":"stack1","uid":"05db21b4-7244-4022-a844-c131a8747f30","controller":true}]},"spec":{"ports":[{"name":"sparkmaster","port":7777,"targetPort":0},{"name":"sparkwebui","port":8009,"targetPort":0}],"selector":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-master","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-master","app.kubernetes.io/part-of":"splunk-stack1-spark"},"clusterIP":"None"},"status":{"loadBalancer":{}}}`)
 
 	cr.Spec.ServiceTemplate.Spec.Type = "LoadBalancer"
 	cr.Spec.ServiceTemplate.ObjectMeta.Labels = map[string]string{"1": "2"}
 	cr.ObjectMeta.Labels = map[string]string{"one": "two"}
 	cr.ObjectMeta.Annotations = map[string]string{"a": "b"}
 
-	test(SparkWorker, false, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"splunk-stack1-spark-worker-service","namespace":"test","creationTimestamp":null,"labels":{"1":"2","app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-worker","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-worker","app.kubernetes.io/part-of":"splunk-stack1-spark","one":"two"},"annotations":{"a":"b"},"ownerReferences":[{"apiVersion":"enterprise.splunk.com/v1alpha2","kind":"Spark","name":"stack1","uid":"05db21b4-7244-4022-a844-c131a8747f30","controller":true}]},"spec":{"ports":[{"name":"workerwebui","port":7000,"targetPort":0},{"name":"dfwreceivedata","port":17500,"targetPort":0}],"selector":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-worker","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-worker","app.kubernetes.io/part-of":"splunk-stack1-spark"},"type":"LoadBalancer"},"status":{"loadBalancer":{}}}`)
-	test(SparkWorker, true, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"splunk-stack1-spark-worker-headless","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-worker","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-worker","app.kubernetes.io/part-of":"splunk-stack1-spark","one":"two"},"annotations":{"a":"b"},"ownerReferences":[{"apiVersion":"enterprise.splunk.com/v1alpha2","kind":"Spark","name":"stack1","uid":"05db21b4-7244-4022-a844-c131a8747f30","controller":true}]},"spec":{"ports":[{"name":"workerwebui","port":7000,"targetPort":0},{"name":"dfwreceivedata","port":17500,"targetPort":0}],"selector":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-worker","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-worker","app.kubernetes.io/part-of":"splunk-stack1-spark"},"clusterIP":"None"},"status":{"loadBalancer":{}}}`)
+	test(SparkWorker, false, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"splunk-stack1-spark-worker-service","namespace":"test","creationTimestamp":null,"labels":{"1":"2","app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-worker","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-worker","app.kubernetes.io/part-of":"splunk-stack1-spark","one":"two"},"annotations":{"a":"b"},"ownerReferences":[{"apiVersion":"enterprise.splunk.com/v1alpha3","kind":"Spark","name":"stack1","uid":"05db21b4-7244-4022-a844-c131a8747f30","controller":true}]},"spec":{"ports":[{"name":"workerwebui","port":7000,"targetPort":0},{"name":"dfwreceivedata","port":17500,"targetPort":0}],"selector":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-worker","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-worker","app.kubernetes.io/part-of":"splunk-stack1-spark"},"type":"LoadBalancer"},"status":{"loadBalancer":{}}}`)
+	test(SparkWorker, true, `{"kind":"Service","apiVersion":"v1","metadata":{"name":"splunk-stack1-spark-worker-headless","namespace":"test","creationTimestamp":null,"labels":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-worker","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-worker","app.kubernetes.io/part-of":"splunk-stack1-spark","one":"two"},"annotations":{"a":"b"},"ownerReferences":[{"apiVersion":"enterprise.splunk.com/v1alpha3","kind":"Spark","name":"stack1","uid":"05db21b4-7244-4022-a844-c131a8747f30","controller":true}]},"spec":{"ports":[{"name":"workerwebui","port":7000,"targetPort":0},{"name":"dfwreceivedata","port":17500,"targetPort":0}],"selector":{"app.kubernetes.io/component":"spark","app.kubernetes.io/instance":"splunk-stack1-spark-worker","app.kubernetes.io/managed-by":"splunk-operator","app.kubernetes.io/name":"spark-worker","app.kubernetes.io/part-of":"splunk-stack1-spark"},"clusterIP":"None"},"status":{"loadBalancer":{}}}`)
 }
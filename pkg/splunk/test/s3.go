@@ -0,0 +1,121 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// MockS3Server is a minimal S3-compatible service, similar to a local MinIO server, used to test remote storage volumes
+type MockS3Server struct {
+	*httptest.Server
+
+	// access key that requests must use
+	AccessKey string
+
+	// buckets that exist on the server
+	Buckets map[string]bool
+
+	// objects that exist on the server, using "<bucket>/<key>" as the map key
+	Objects map[string][]byte
+
+	// paths of requests received by the server
+	GotRequests []string
+
+	mutex sync.Mutex
+}
+
+// NewMockS3Server starts and returns a new MockS3Server with the given access key and buckets.
+// Callers should call Close when finished, to shut it down.
+func NewMockS3Server(accessKey string, buckets ...string) *MockS3Server {
+	s := &MockS3Server{
+		AccessKey: accessKey,
+		Buckets:   make(map[string]bool),
+		Objects:   make(map[string][]byte),
+	}
+	for _, b := range buckets {
+		s.Buckets[b] = true
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// handle method for MockS3Server processes requests to list objects within buckets, and to retrieve objects
+func (s *MockS3Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.GotRequests = append(s.GotRequests, r.URL.RequestURI())
+	s.mutex.Unlock()
+
+	query := r.URL.Query()
+	if query.Get("X-Amz-Credential") != "" {
+		// presigned URL
+		if !strings.HasPrefix(query.Get("X-Amz-Credential"), s.AccessKey+"/") || query.Get("X-Amz-Signature") == "" {
+			s.writeError(w, http.StatusForbidden, "InvalidAccessKeyId", "The Access Key Id you provided does not exist in our records.")
+			return
+		}
+	} else {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/", s.AccessKey)) || !strings.Contains(auth, "Signature=") {
+			s.writeError(w, http.StatusForbidden, "InvalidAccessKeyId", "The Access Key Id you provided does not exist in our records.")
+			return
+		}
+		if r.Header.Get("x-amz-date") == "" || r.Header.Get("x-amz-content-sha256") == "" {
+			s.writeError(w, http.StatusBadRequest, "AuthorizationHeaderMalformed", "The authorization header is malformed.")
+			return
+		}
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	bucket := parts[0]
+	if (r.Method != "GET" && r.Method != "HEAD") || !s.Buckets[bucket] {
+		s.writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+
+	if len(parts) > 1 && parts[1] != "" {
+		s.mutex.Lock()
+		data, ok := s.Objects[fmt.Sprintf("%s/%s", bucket, parts[1])]
+		s.mutex.Unlock()
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		hash := md5.Sum(data)
+		w.Header().Set("ETag", fmt.Sprintf("\"%s\"", hex.EncodeToString(hash[:])))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == "GET" {
+			w.Write(data)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>%s</Name><Prefix>%s</Prefix><KeyCount>0</KeyCount><MaxKeys>1</MaxKeys><IsTruncated>false</IsTruncated></ListBucketResult>`,
+		bucket, r.URL.Query().Get("prefix"))
+}
+
+// writeError method for MockS3Server writes an S3 error response
+func (s *MockS3Server) writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, message)
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+	"github.com/splunk/splunk-operator/pkg/splunk/enterprise"
+	"github.com/splunk/splunk-operator/pkg/splunk/spark"
+)
+
+var log = logf.Log.WithName("webhook")
+
+// ConversionPath is the path used to serve the conversion webhook for all custom resources
+const ConversionPath = "/convert"
+
+// splunkWebhook describes the admission webhooks served for one kind of custom resource
+type splunkWebhook struct {
+	// kind of custom resource
+	kind string
+
+	// newObject returns an empty custom resource of this kind
+	newObject func() enterprisev1.MetaObject
+
+	// validate checks validity and makes default updates to the spec of a custom resource, and returns error if something is wrong
+	validate func(obj enterprisev1.MetaObject) error
+
+	// validateRefs checks that the custom resources referenced by obj are compatible with it (optional)
+	validateRefs func(c client.Client, obj enterprisev1.MetaObject) error
+}
+
+// getSplunkWebhooks returns the admission webhooks for all enterprise.splunk.com custom resources
+func getSplunkWebhooks() []splunkWebhook {
+	return []splunkWebhook{
+		{
+			kind:      "Standalone",
+			newObject: func() enterprisev1.MetaObject { return &enterprisev1.Standalone{} },
+			validate: func(obj enterprisev1.MetaObject) error {
+				return enterprise.ValidateStandaloneSpec(&obj.(*enterprisev1.Standalone).Spec)
+			},
+			validateRefs: func(c client.Client, obj enterprisev1.MetaObject) error {
+				return validateCommonRefs(c, obj, &obj.(*enterprisev1.Standalone).Spec.CommonSplunkSpec)
+			},
+		},
+		{
+			kind:      "LicenseMaster",
+			newObject: func() enterprisev1.MetaObject { return &enterprisev1.LicenseMaster{} },
+			validate: func(obj enterprisev1.MetaObject) error {
+				return enterprise.ValidateLicenseMasterSpec(&obj.(*enterprisev1.LicenseMaster).Spec)
+			},
+			validateRefs: func(c client.Client, obj enterprisev1.MetaObject) error {
+				return validateCommonRefs(c, obj, &obj.(*enterprisev1.LicenseMaster).Spec.CommonSplunkSpec)
+			},
+		},
+		{
+			kind:      "SearchHeadCluster",
+			newObject: func() enterprisev1.MetaObject { return &enterprisev1.SearchHeadCluster{} },
+			validate: func(obj enterprisev1.MetaObject) error {
+				return enterprise.ValidateSearchHeadClusterSpec(&obj.(*enterprisev1.SearchHeadCluster).Spec)
+			},
+			validateRefs: func(c client.Client, obj enterprisev1.MetaObject) error {
+				return validateCommonRefs(c, obj, &obj.(*enterprisev1.SearchHeadCluster).Spec.CommonSplunkSpec)
+			},
+		},
+		{
+			kind:      "ClusterMaster",
+			newObject: func() enterprisev1.MetaObject { return &enterprisev1.ClusterMaster{} },
+			validate: func(obj enterprisev1.MetaObject) error {
+				return enterprise.ValidateClusterMasterSpec(&obj.(*enterprisev1.ClusterMaster).Spec)
+			},
+			validateRefs: func(c client.Client, obj enterprisev1.MetaObject) error {
+				return validateCommonRefs(c, obj, &obj.(*enterprisev1.ClusterMaster).Spec.CommonSplunkSpec)
+			},
+		},
+		{
+			kind:      "IndexerCluster",
+			newObject: func() enterprisev1.MetaObject { return &enterprisev1.IndexerCluster{} },
+			validate: func(obj enterprisev1.MetaObject) error {
+				return enterprise.ValidateIndexerClusterSpec(&obj.(*enterprisev1.IndexerCluster).Spec)
+			},
+			validateRefs: validateIndexerClusterRefs,
+		},
+		{
+			kind:      "MonitoringConsole",
+			newObject: func() enterprisev1.MetaObject { return &enterprisev1.MonitoringConsole{} },
+			validate: func(obj enterprisev1.MetaObject) error {
+				return enterprise.ValidateMonitoringConsoleSpec(&obj.(*enterprisev1.MonitoringConsole).Spec)
+			},
+			validateRefs: func(c client.Client, obj enterprisev1.MetaObject) error {
+				return validateCommonRefs(c, obj, &obj.(*enterprisev1.MonitoringConsole).Spec.CommonSplunkSpec)
+			},
+		},
+		{
+			kind:      "Spark",
+			newObject: func() enterprisev1.MetaObject { return &enterprisev1.Spark{} },
+			validate: func(obj enterprisev1.MetaObject) error {
+				return spark.ValidateSparkSpec(&obj.(*enterprisev1.Spark).Spec)
+			},
+		},
+	}
+}
+
+// validateCommonRefs checks that the secretRef of a custom resource is in its own namespace, and that the license
+// master and cluster master it references are valid. Custom resources may be created before the ones they reference,
+// so this is only checked for those that exist.
+func validateCommonRefs(c client.Client, obj enterprisev1.MetaObject, spec *enterprisev1.CommonSplunkSpec) error {
+	if err := enterprise.ValidateSecretRef(obj, spec); err != nil {
+		return err
+	}
+	if err := validateLicenseMasterRef(c, obj, spec.LicenseMasterRef); err != nil {
+		return err
+	}
+	_, err := getClusterMasterRef(c, obj, spec.ClusterMasterRef)
+	return err
+}
+
+// validateIndexerClusterRefs checks the references of an indexer cluster, and that its sites match the cluster master
+// it references. Indexer clusters may be created before their cluster master, so this is only checked if the cluster
+// master exists.
+func validateIndexerClusterRefs(c client.Client, obj enterprisev1.MetaObject) error {
+	cr := obj.(*enterprisev1.IndexerCluster)
+	if err := enterprise.ValidateSecretRef(obj, &cr.Spec.CommonSplunkSpec); err != nil {
+		return err
+	}
+	if err := validateLicenseMasterRef(c, obj, cr.Spec.LicenseMasterRef); err != nil {
+		return err
+	}
+	clusterMaster, err := getClusterMasterRef(c, obj, cr.Spec.ClusterMasterRef)
+	if err != nil || clusterMaster == nil {
+		return err
+	}
+	return enterprise.ValidateIndexerClusterMultisite(&cr.Spec, &clusterMaster.Spec)
+}
+
+// validateLicenseMasterRef checks that the license master referenced by obj is valid, if it exists
+func validateLicenseMasterRef(c client.Client, obj enterprisev1.MetaObject, ref corev1.ObjectReference) error {
+	var licenseMaster enterprisev1.LicenseMaster
+	found, err := getRef(c, obj, ref, "license master", &licenseMaster)
+	if err != nil || !found {
+		return err
+	}
+	if err = enterprise.ValidateLicenseMasterSpec(&licenseMaster.Spec); err != nil {
+		return fmt.Errorf("license master \"%s\" is invalid: %v", ref.Name, err)
+	}
+	return nil
+}
+
+// getClusterMasterRef returns the cluster master referenced by obj, or nil if it does not exist, and returns error if
+// it is invalid
+func getClusterMasterRef(c client.Client, obj enterprisev1.MetaObject, ref corev1.ObjectReference) (*enterprisev1.ClusterMaster, error) {
+	var clusterMaster enterprisev1.ClusterMaster
+	found, err := getRef(c, obj, ref, "cluster master", &clusterMaster)
+	if err != nil || !found {
+		return nil, err
+	}
+	if err = enterprise.ValidateClusterMasterSpec(&clusterMaster.Spec); err != nil {
+		return nil, fmt.Errorf("cluster master \"%s\" is invalid: %v", ref.Name, err)
+	}
+	return &clusterMaster, nil
+}
+
+// getRef retrieves the custom resource referenced by obj into target, and returns false if there is no reference or
+// the custom resource does not exist. References without a namespace are to the namespace of obj.
+func getRef(c client.Client, obj enterprisev1.MetaObject, ref corev1.ObjectReference, description string, target runtime.Object) (bool, error) {
+	if ref.Name == "" {
+		return false, nil
+	}
+	namespacedName := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+	if namespacedName.Namespace == "" {
+		namespacedName.Namespace = obj.GetNamespace()
+	}
+	err := c.Get(context.TODO(), namespacedName, target)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("Unable to get %s %s: %v", description, namespacedName, err)
+	}
+	return true, nil
+}
+
+// GetMutatingPath returns the path used to serve the mutating admission webhook for a kind of custom resource
+func GetMutatingPath(kind string) string {
+	return fmt.Sprintf("/mutate-enterprise-splunk-com-v1alpha3-%s", strings.ToLower(kind))
+}
+
+// GetValidatingPath returns the path used to serve the validating admission webhook for a kind of custom resource
+func GetValidatingPath(kind string) string {
+	return fmt.Sprintf("/validate-enterprise-splunk-com-v1alpha3-%s", strings.ToLower(kind))
+}
+
+// AddToManager registers mutating and validating admission webhooks for all enterprise.splunk.com custom resources
+// with the manager's webhook server. Mutating webhooks persist default values to each custom resource, and
+// validating webhooks reject custom resources that would otherwise fail to reconcile. A conversion webhook is
+// also registered, which converts custom resources between API versions using the hub version (v1alpha3).
+func AddToManager(mgr manager.Manager) error {
+	// Use a new go client to work-around issues with the operator sdk design (see controllers)
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return err
+	}
+
+	server := mgr.GetWebhookServer()
+	server.Register(ConversionPath, &conversion.Webhook{})
+	for _, hook := range getSplunkWebhooks() {
+		server.Register(GetMutatingPath(hook.kind), &admission.Webhook{Handler: &mutatingHandler{hook: hook}})
+		server.Register(GetValidatingPath(hook.kind), &admission.Webhook{Handler: &validatingHandler{hook: hook, client: c}})
+	}
+	return nil
+}
+
+// mutatingHandler persists default values to custom resources
+type mutatingHandler struct {
+	hook    splunkWebhook
+	decoder *admission.Decoder
+}
+
+// InjectDecoder injects the decoder into a mutatingHandler
+func (h *mutatingHandler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+	return nil
+}
+
+// Handle for mutatingHandler applies defaults to a custom resource, and returns a patch with the changes
+func (h *mutatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := h.hook.newObject()
+	err := h.decoder.Decode(req, obj)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// leave invalid custom resources alone, so that they are rejected by the validating webhook
+	err = h.hook.validate(obj)
+	if err != nil {
+		return admission.Allowed("")
+	}
+
+	marshalled, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshalled)
+}
+
+// validatingHandler rejects invalid custom resources
+type validatingHandler struct {
+	hook    splunkWebhook
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+// InjectDecoder injects the decoder into a validatingHandler
+func (h *validatingHandler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+	return nil
+}
+
+// Handle for validatingHandler returns a response that denies the request if a custom resource is invalid
+func (h *validatingHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation == admissionv1beta1.Delete {
+		return admission.Allowed("")
+	}
+
+	obj := h.hook.newObject()
+	err := h.decoder.Decode(req, obj)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// don't prevent finalizers from being removed
+	if obj.GetObjectMeta().GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	err = h.hook.validate(obj)
+	if err == nil && h.hook.validateRefs != nil {
+		err = h.hook.validateRefs(h.client, obj)
+	}
+	if err != nil {
+		log.Info("Rejecting invalid custom resource", "kind", h.hook.kind, "name", obj.GetIdentifier(), "namespace", obj.GetNamespace(), "error", err.Error())
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
@@ -0,0 +1,350 @@
+// Copyright (c) 2018-2020 Splunk Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	apix "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+
+	"github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha2"
+	enterprisev1 "github.com/splunk/splunk-operator/pkg/apis/enterprise/v1alpha3"
+)
+
+// getTestScheme returns a runtime scheme with all enterprise.splunk.com custom resources
+func getTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	if err := enterprisev1.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() returned %v", err)
+	}
+	return scheme
+}
+
+// getTestHook returns the splunkWebhook for a kind of custom resource
+func getTestHook(t *testing.T, kind string) splunkWebhook {
+	for _, hook := range getSplunkWebhooks() {
+		if hook.kind == kind {
+			return hook
+		}
+	}
+	t.Fatalf("getSplunkWebhooks() has no webhook for %s", kind)
+	return splunkWebhook{}
+}
+
+// newTestRequest returns an admission request for a custom resource
+func newTestRequest(t *testing.T, operation admissionv1beta1.Operation, obj runtime.Object) admission.Request {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned %v", err)
+	}
+	return admission.Request{
+		AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Operation: operation,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+}
+
+func TestGetWebhookPaths(t *testing.T) {
+	if got, want := GetMutatingPath("IndexerCluster"), "/mutate-enterprise-splunk-com-v1alpha3-indexercluster"; got != want {
+		t.Errorf("GetMutatingPath() = %s; want %s", got, want)
+	}
+	if got, want := GetValidatingPath("SearchHeadCluster"), "/validate-enterprise-splunk-com-v1alpha3-searchheadcluster"; got != want {
+		t.Errorf("GetValidatingPath() = %s; want %s", got, want)
+	}
+}
+
+func TestMutatingHandler(t *testing.T) {
+	decoder, _ := admission.NewDecoder(getTestScheme(t))
+	h := &mutatingHandler{hook: getTestHook(t, "SearchHeadCluster")}
+	if err := h.InjectDecoder(decoder); err != nil {
+		t.Fatalf("InjectDecoder() returned %v", err)
+	}
+
+	cr := enterprisev1.SearchHeadCluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "enterprise.splunk.com/v1alpha3", Kind: "SearchHeadCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+
+	// defaults are returned as patches
+	resp := h.Handle(context.TODO(), newTestRequest(t, admissionv1beta1.Create, &cr))
+	if !resp.Allowed {
+		t.Errorf("mutatingHandler.Handle() Allowed = false; want true")
+	}
+	want := map[string]interface{}{
+		"/spec/replicas":        float64(3),
+		"/spec/imagePullPolicy": "IfNotPresent",
+		"/spec/schedulerName":   "default-scheduler",
+	}
+	got := map[string]interface{}{}
+	for _, patch := range resp.Patches {
+		got[patch.Path] = patch.Value
+	}
+	for path, value := range want {
+		if got[path] != value {
+			t.Errorf("mutatingHandler.Handle() patch %s = %v; want %v", path, got[path], value)
+		}
+	}
+
+	// invalid custom resources are not changed
+	cr.Spec.ImagePullPolicy = "Never"
+	resp = h.Handle(context.TODO(), newTestRequest(t, admissionv1beta1.Create, &cr))
+	if !resp.Allowed || len(resp.Patches) != 0 {
+		t.Errorf("mutatingHandler.Handle() = %t,%v; want true,[]", resp.Allowed, resp.Patches)
+	}
+}
+
+func TestValidatingHandler(t *testing.T) {
+	scheme := getTestScheme(t)
+	decoder, _ := admission.NewDecoder(scheme)
+	clusterMaster := &enterprisev1.ClusterMaster{
+		ObjectMeta: metav1.ObjectMeta{Name: "master1", Namespace: "test"},
+		Spec: enterprisev1.ClusterMasterSpec{
+			Multisite: enterprisev1.ClusterMasterMultisiteSpec{Sites: []string{"site1", "site2"}},
+		},
+	}
+	c := fake.NewFakeClientWithScheme(scheme, clusterMaster)
+
+	test := func(kind string, operation admissionv1beta1.Operation, obj runtime.Object, wantAllowed bool, wantReason string) {
+		h := &validatingHandler{hook: getTestHook(t, kind), client: c}
+		if err := h.InjectDecoder(decoder); err != nil {
+			t.Fatalf("InjectDecoder() returned %v", err)
+		}
+		resp := h.Handle(context.TODO(), newTestRequest(t, operation, obj))
+		if resp.Allowed != wantAllowed {
+			t.Errorf("validatingHandler.Handle(%s) Allowed = %t; want %t", kind, resp.Allowed, wantAllowed)
+		}
+		if !wantAllowed && (resp.Result == nil || string(resp.Result.Reason) != wantReason) {
+			t.Errorf("validatingHandler.Handle(%s) Result = %v; want %s", kind, resp.Result, wantReason)
+		}
+	}
+
+	standalone := enterprisev1.Standalone{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "enterprise.splunk.com/v1alpha3", Kind: "Standalone"},
+		ObjectMeta: metav1.ObjectMeta{Name: "stack1", Namespace: "test"},
+	}
+	test("Standalone", admissionv1beta1.Create, &standalone, true, "")
+
+	standalone.Spec.VarStorage = "lots"
+	test("Standalone", admissionv1beta1.Update, &standalone, false,
+		`varStorage: Invalid resource quantity "lots": quantities must match the regular expression '^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$'`)
+
+	// deletion should never be prevented
+	test("Standalone", admissionv1beta1.Delete, &standalone, true, "")
+	currentTime := metav1.Now()
+	standalone.ObjectMeta.DeletionTimestamp = &currentTime
+	test("Standalone", admissionv1beta1.Update, &standalone, true, "")
+
+	indexerCluster := enterprisev1.IndexerCluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "enterprise.splunk.com/v1alpha3", Kind: "IndexerCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "idxc1", Namespace: "test"},
+	}
+	test("IndexerCluster", admissionv1beta1.Create, &indexerCluster, false, "clusterMasterRef is required")
+
+	// sites must match the cluster master, if it exists
+	indexerCluster.Spec.ClusterMasterRef.Name = "master1"
+	test("IndexerCluster", admissionv1beta1.Create, &indexerCluster, false,
+		`cluster master "master1" uses multisite clustering, but no sites are defined`)
+	indexerCluster.Spec.Multisite.Sites = []enterprisev1.IndexerClusterSiteSpec{{Name: "site2", Replicas: 3}}
+	test("IndexerCluster", admissionv1beta1.Create, &indexerCluster, true, "")
+	indexerCluster.Spec.ClusterMasterRef.Name = "master2"
+	test("IndexerCluster", admissionv1beta1.Create, &indexerCluster, true, "")
+
+	spark := enterprisev1.Spark{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "enterprise.splunk.com/v1alpha3", Kind: "Spark"},
+		ObjectMeta: metav1.ObjectMeta{Name: "spark1", Namespace: "test"},
+		Spec:       enterprisev1.SparkSpec{Replicas: -2},
+	}
+	test("Spark", admissionv1beta1.Create, &spark, false, "replicas must not be negative; value=-2")
+}
+
+func TestValidateRefs(t *testing.T) {
+	scheme := getTestScheme(t)
+	c := fake.NewFakeClientWithScheme(scheme,
+		&enterprisev1.LicenseMaster{ObjectMeta: metav1.ObjectMeta{Name: "lm1", Namespace: "test"}},
+		&enterprisev1.LicenseMaster{
+			ObjectMeta: metav1.ObjectMeta{Name: "lm2", Namespace: "other"},
+			Spec: enterprisev1.LicenseMasterSpec{
+				CommonSplunkSpec: enterprisev1.CommonSplunkSpec{CommonSpec: enterprisev1.CommonSpec{ImagePullPolicy: "Never"}},
+			},
+		},
+		&enterprisev1.ClusterMaster{ObjectMeta: metav1.ObjectMeta{Name: "master1", Namespace: "test"}},
+		&enterprisev1.ClusterMaster{
+			ObjectMeta: metav1.ObjectMeta{Name: "master2", Namespace: "test"},
+			Spec: enterprisev1.ClusterMasterSpec{
+				CommonSplunkSpec: enterprisev1.CommonSplunkSpec{CommonSpec: enterprisev1.CommonSpec{ImagePullPolicy: "Never"}},
+			},
+		},
+	)
+	invalidPullPolicy := `ImagePullPolicy must be one of "Always" or "IfNotPresent"; value="Never"`
+
+	tests := []struct {
+		name             string
+		licenseMasterRef corev1.ObjectReference
+		clusterMasterRef corev1.ObjectReference
+		secretRef        corev1.ObjectReference
+		want             string
+	}{
+		{name: "no references"},
+		{name: "valid references", licenseMasterRef: corev1.ObjectReference{Name: "lm1"}, clusterMasterRef: corev1.ObjectReference{Name: "master1"}},
+		{name: "missing references", licenseMasterRef: corev1.ObjectReference{Name: "lm3"}, clusterMasterRef: corev1.ObjectReference{Name: "master3"}},
+		{name: "invalid license master", licenseMasterRef: corev1.ObjectReference{Name: "lm2", Namespace: "other"},
+			want: `license master "lm2" is invalid: ` + invalidPullPolicy},
+		{name: "license master in another namespace", licenseMasterRef: corev1.ObjectReference{Name: "lm2"}},
+		{name: "invalid cluster master", licenseMasterRef: corev1.ObjectReference{Name: "lm1"}, clusterMasterRef: corev1.ObjectReference{Name: "master2"},
+			want: `cluster master "master2" is invalid: ` + invalidPullPolicy},
+		{name: "secretRef in same namespace", secretRef: corev1.ObjectReference{Name: "my-secrets", Namespace: "test"}},
+		{name: "secretRef in another namespace", secretRef: corev1.ObjectReference{Name: "my-secrets", Namespace: "other"},
+			want: `secretRef must be in the same namespace as the custom resource; namespace="other"`},
+	}
+	kinds := []string{"Standalone", "LicenseMaster", "SearchHeadCluster", "ClusterMaster", "IndexerCluster", "MonitoringConsole"}
+	for _, kind := range kinds {
+		hook := getTestHook(t, kind)
+		if hook.validateRefs == nil {
+			t.Errorf("getSplunkWebhooks() %s has no validateRefs", kind)
+			continue
+		}
+		for _, test := range tests {
+			obj := hook.newObject()
+			obj.GetObjectMeta().SetNamespace("test")
+			var spec *enterprisev1.CommonSplunkSpec
+			switch cr := obj.(type) {
+			case *enterprisev1.Standalone:
+				spec = &cr.Spec.CommonSplunkSpec
+			case *enterprisev1.LicenseMaster:
+				spec = &cr.Spec.CommonSplunkSpec
+			case *enterprisev1.SearchHeadCluster:
+				spec = &cr.Spec.CommonSplunkSpec
+			case *enterprisev1.ClusterMaster:
+				spec = &cr.Spec.CommonSplunkSpec
+			case *enterprisev1.IndexerCluster:
+				spec = &cr.Spec.CommonSplunkSpec
+			case *enterprisev1.MonitoringConsole:
+				spec = &cr.Spec.CommonSplunkSpec
+			}
+			spec.LicenseMasterRef = test.licenseMasterRef
+			spec.ClusterMasterRef = test.clusterMasterRef
+			spec.SecretRef = test.secretRef
+			err := hook.validateRefs(c, obj)
+			if (test.want == "" && err != nil) || (test.want != "" && (err == nil || err.Error() != test.want)) {
+				t.Errorf("validateRefs(%s, %s) returned %v; want %s", kind, test.name, err, test.want)
+			}
+		}
+	}
+}
+
+func TestConversionWebhook(t *testing.T) {
+	scheme := getTestScheme(t)
+	if err := v1alpha2.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() returned %v", err)
+	}
+
+	// all kinds must be convertible using the hub version
+	for _, hook := range getSplunkWebhooks() {
+		obj := hook.newObject()
+		ok, err := conversion.IsConvertible(scheme, obj)
+		if err != nil || !ok {
+			t.Errorf("IsConvertible(%s) = %t,%v; want true,nil", hook.kind, ok, err)
+		}
+	}
+
+	wh := &conversion.Webhook{}
+	if err := wh.InjectScheme(scheme); err != nil {
+		t.Fatalf("InjectScheme() returned %v", err)
+	}
+	convert := func(desiredAPIVersion string, obj runtime.Object) string {
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatalf("json.Marshal() returned %v", err)
+		}
+		review, err := json.Marshal(&apix.ConversionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1beta1", Kind: "ConversionReview"},
+			Request: &apix.ConversionRequest{
+				UID:               "test",
+				DesiredAPIVersion: desiredAPIVersion,
+				Objects:           []runtime.RawExtension{{Raw: raw}},
+			},
+		})
+		if err != nil {
+			t.Fatalf("json.Marshal() returned %v", err)
+		}
+		w := httptest.NewRecorder()
+		wh.ServeHTTP(w, httptest.NewRequest(http.MethodPost, ConversionPath, bytes.NewReader(review)))
+		var resp apix.ConversionReview
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("json.Decode() returned %v", err)
+		}
+		if resp.Response.Result.Status != metav1.StatusSuccess || len(resp.Response.ConvertedObjects) != 1 {
+			t.Fatalf("Conversion to %s failed: %v", desiredAPIVersion, resp.Response.Result)
+		}
+		return strings.TrimSpace(string(resp.Response.ConvertedObjects[0].Raw))
+	}
+
+	oldCR := v1alpha2.IndexerCluster{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "enterprise.splunk.com/v1alpha2", Kind: "IndexerCluster"},
+		ObjectMeta: metav1.ObjectMeta{Name: "idxc1", Namespace: "test"},
+		Spec: v1alpha2.IndexerClusterSpec{
+			CommonSplunkSpec: v1alpha2.CommonSplunkSpec{IndexerClusterRef: corev1.ObjectReference{Name: "idxc1"}},
+			Replicas:         3,
+		},
+		Status: v1alpha2.IndexerClusterStatus{Phase: "Ready", Initialized: true},
+	}
+	got := convert("enterprise.splunk.com/v1alpha3", &oldCR)
+	for _, want := range []string{
+		`"apiVersion":"enterprise.splunk.com/v1alpha3"`,
+		`"annotations":{"enterprise.splunk.com/v1alpha2-deprecated-refs":"{\"indexerClusterRef\":{\"name\":\"idxc1\"},\"clusterMasterRef\":{}}"}`,
+		`"clusterMasterRef":{"name":"idxc1"}`,
+		`"initialized":true`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Conversion to v1alpha3 = %s; want %s", got, want)
+		}
+	}
+	if strings.Contains(got, `"indexerClusterRef":{`) || strings.Contains(got, "initialized_flag") {
+		t.Errorf("Conversion to v1alpha3 = %s; want no v1alpha2 fields", got)
+	}
+
+	var newCR enterprisev1.IndexerCluster
+	if err := json.Unmarshal([]byte(got), &newCR); err != nil {
+		t.Fatalf("json.Unmarshal() returned %v", err)
+	}
+	got = convert("enterprise.splunk.com/v1alpha2", &newCR)
+	for _, want := range []string{
+		`"apiVersion":"enterprise.splunk.com/v1alpha2"`,
+		`"clusterMasterRef":{}`,
+		`"indexerClusterRef":{"name":"idxc1"}`,
+		`"initialized_flag":true`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Conversion to v1alpha2 = %s; want %s", got, want)
+		}
+	}
+	if strings.Contains(got, "annotations") {
+		t.Errorf("Conversion to v1alpha2 = %s; want no annotations", got)
+	}
+}